@@ -0,0 +1,99 @@
+// Package atomicfile implements functionality for writing files atomically.
+//
+// The contents of a file are written to a temporary file, located in the same
+// directory as the file, which replaces the file once it has been completely
+// written. Interrupted runs therefore never leave truncated files behind, and
+// concurrent writers of the same file never observe each other's partial
+// contents. Below is a description of how the files are written:
+//
+// Atomic write:
+//    1) Create a uniquely named temporary file (".name.tmp*") in the directory
+//       of the file.
+//    2) Write the contents to the temporary file.
+//    3) Rename the temporary file to the file, or remove it if an error
+//       occurred.
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// A File is a temporary file which replaces the file at its path once
+// committed.
+type File struct {
+	*os.File
+	// filePath is the path of the file replaced by the temporary file.
+	filePath string
+	// done specifies if the temporary file has been committed or removed.
+	done bool
+}
+
+// Create creates a temporary file which replaces the file at filePath once
+// committed. The temporary file is removed when closed before being committed,
+// which makes it safe to defer Close.
+func Create(filePath string) (f *File, err error) {
+	dir, name := filepath.Split(filePath)
+	if len(dir) == 0 {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: tmp, filePath: filePath}, nil
+}
+
+// Commit closes the temporary file and renames it to the path of the file,
+// using the permissions 0644.
+func (f *File) Commit() (err error) {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	err = f.File.Chmod(0644)
+	if err != nil {
+		f.File.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	err = f.File.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	err = os.Rename(f.Name(), f.filePath)
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Close closes and removes the temporary file, unless it has already been
+// committed.
+func (f *File) Close() (err error) {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	err = f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// WriteFile writes data to the file at filePath atomically, using the
+// permissions 0644.
+func WriteFile(filePath string, data []byte) (err error) {
+	f, err := Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	if err != nil {
+		return err
+	}
+	return f.Commit()
+}
@@ -0,0 +1,185 @@
+// Package budget implements the concurrency and memory limits shared by the
+// parallel parts of the commands (e.g. the palette variants rendered by
+// dun_dump, and the frames decoded by cel.DecodeFrames), as set by the
+// -max-workers and -max-mem flags registered by RegisterFlags.
+//
+// Each job acquires its estimated memory use from the budget before starting,
+// and releases it once done. Jobs wait while the budget is exhausted, which
+// keeps conversions on small machines from running out of memory, while jobs
+// on big machines run on every CPU.
+package budget
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxWorkers is the maximum number of jobs run concurrently; the number of CPUs
+// if 0.
+var MaxWorkers int
+
+// MaxMem is the memory budget of the jobs run concurrently, in bytes; unlimited
+// if 0.
+var MaxMem Size
+
+// RegisterFlags registers the -max-workers flag, its alias -j, and the
+// -max-mem flag, which set MaxWorkers and MaxMem, on the command line flag set.
+// It is called by the init function of each command before flag.Parse.
+func RegisterFlags() {
+	flag.IntVar(&MaxWorkers, "j", 0, "Alias of -max-workers.")
+	flag.Var(&MaxMem, "max-mem", `Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.`)
+	flag.IntVar(&MaxWorkers, "max-workers", 0, "Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).")
+}
+
+// Workers returns the maximum number of jobs run concurrently.
+func Workers() int {
+	if MaxWorkers > 0 {
+		return MaxWorkers
+	}
+	return runtime.NumCPU()
+}
+
+// A Size is a number of bytes, which implements the flag.Value interface to
+// support sizes with a unit suffix (e.g. "512M" or "2G").
+type Size int64
+
+// units maps from the unit suffixes of sizes to their number of bytes.
+var units = map[string]int64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// ParseSize parses the given size, which is a number of bytes optionally
+// followed by a unit suffix (K, M, G or T, optionally followed by "B" or "iB").
+func ParseSize(s string) (size Size, err error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "B"), "I")
+	i := strings.IndexFunc(t, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	if i == -1 {
+		i = len(t)
+	}
+	unit, ok := units[t[i:]]
+	if !ok || i == 0 {
+		return 0, fmt.Errorf("invalid size %q.", s)
+	}
+	n, err := strconv.ParseInt(t[:i], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q; %v", s, err)
+	}
+	return Size(n * unit), nil
+}
+
+// String returns the size in bytes, using the largest unit which divides it.
+func (size Size) String() string {
+	for _, suffix := range []string{"T", "G", "M", "K"} {
+		if unit := units[suffix]; size != 0 && int64(size)%unit == 0 {
+			return strconv.FormatInt(int64(size)/unit, 10) + suffix
+		}
+	}
+	return strconv.FormatInt(int64(size), 10)
+}
+
+// Set sets the size to the parsed value of s.
+func (size *Size) Set(s string) (err error) {
+	*size, err = ParseSize(s)
+	return err
+}
+
+// mu protects inUse, and cond signals jobs waiting for memory once memory has
+// been released.
+var (
+	mu    sync.Mutex
+	cond  = sync.NewCond(&mu)
+	inUse int64
+)
+
+// Acquire blocks until n bytes of the memory budget are available, and
+// reserves them for the calling job. A job which needs more memory than the
+// entire budget is run once no other job holds memory, rather than never.
+func Acquire(n int64) {
+	if MaxMem <= 0 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for inUse > 0 && inUse+n > int64(MaxMem) {
+		cond.Wait()
+	}
+	inUse += n
+}
+
+// TryAcquire reserves n bytes of the memory budget for the calling job, if
+// available without waiting, and reports whether it did. It is used by jobs
+// which may be run by other jobs holding memory (e.g. the frames decoded while
+// rendering a palette variant), where waiting could deadlock.
+func TryAcquire(n int64) bool {
+	if MaxMem <= 0 {
+		return true
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if inUse > 0 && inUse+n > int64(MaxMem) {
+		return false
+	}
+	inUse += n
+	return true
+}
+
+// Release returns n bytes, reserved by Acquire or TryAcquire, to the memory budget.
+func Release(n int64) {
+	if MaxMem <= 0 {
+		return
+	}
+	mu.Lock()
+	inUse -= n
+	mu.Unlock()
+	cond.Broadcast()
+}
+
+// ImageSize returns the memory use of an RGBA image of the given bounds.
+func ImageSize(rect image.Rectangle) int64 {
+	return 4 * int64(rect.Dx()) * int64(rect.Dy())
+}
+
+// Run runs the function f once for each of the n jobs, with the job index as
+// parameter, using at most Workers() goroutines. Each job acquires mem(i)
+// bytes of the memory budget while running; mem may be nil if the memory use
+// of the jobs is negligible. The error of the first failing job is returned,
+// once every job has finished.
+func Run(n int, mem func(i int) int64, f func(i int) error) error {
+	errs := make([]error, n)
+	sem := make(chan bool, Workers())
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- true
+		go func(i int) {
+			defer wg.Done()
+			var size int64
+			if mem != nil {
+				size = mem(i)
+			}
+			Acquire(size)
+			errs[i] = f(i)
+			Release(size)
+			<-sem
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
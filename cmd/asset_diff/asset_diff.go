@@ -0,0 +1,297 @@
+// asset_diff is a tool for rendering the same asset from two extracted MPQ
+// files side-by-side, together with a pixel-diff heat map, and storing the
+// result as a png image. It is useful for hunting regressions and analysing
+// the changes of game patches.
+//
+// Usage:
+//
+//    asset_diff [OPTION]... ASSET
+//
+// Assets:
+//
+//    name.cel:frameNum  // frame of a CEL or CL2 image, e.g. "l1braz.cel:0"
+//    name.min:pillarNum // pillar of a MIN file, e.g. "l1.min:12"
+//    name               // dungeon of the dun.ini file, e.g. "l1-banner1"
+//
+// Flags:
+//
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -dunini="dun.ini"
+//            Path to an ini file containing starting coordinate information.
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqdump1="mpqdump/"
+//            Path to the first extracted MPQ file.
+//    -mpqdump2="mpqdump2/"
+//            Path to the second extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -o="_dump_/_diff_.png"
+//            Output path of the comparison image.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/configs/dunconf"
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/cl2"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/mongfx"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagDump1 specifies the path to the first extracted MPQ file.
+	flagDump1 string
+	// flagDump2 specifies the path to the second extracted MPQ file.
+	flagDump2 string
+	// flagOutput specifies the output path of the comparison image.
+	flagOutput string
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.StringVar(&dunconf.IniPath, "dunini", "dun.ini", "Path to an ini file containing starting coordinate information.")
+	flag.StringVar(&flagDump1, "mpqdump1", "mpqdump/", "Path to the first extracted MPQ file.")
+	flag.StringVar(&flagDump2, "mpqdump2", "mpqdump2/", "Path to the second extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.StringVar(&flagOutput, "o", "_dump_/_diff_.png", "Output path of the comparison image.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := dunconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... ASSET\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	asset := flag.Arg(0)
+	if path.Ext(strings.Split(asset, ":")[0]) == ".cl2" && imgconf.IniPath == "cel.ini" {
+		imgconf.IniPath = "cl2.ini"
+	}
+	err := imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if path.Base(imgconf.IniPath) == "cl2.ini" {
+		mongfx.InitConf()
+	}
+
+	// Render the asset once for each extracted MPQ file.
+	img1, err := renderDump(flagDump1, asset)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	img2, err := renderDump(flagDump2, asset)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	heat, n := diff(img1, img2)
+	fmt.Printf("%d pixels differ.\n", n)
+	err = os.MkdirAll(path.Dir(flagOutput), 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = pngprof.WriteFile(flagOutput, sideBySide(img1, img2, heat))
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// renderDump renders the given asset, using the frames and palettes of the
+// extracted MPQ file at extractPath.
+func renderDump(extractPath, asset string) (img image.Image, err error) {
+	s, err := mpq.OpenStore(mpq.Options{IniPath: mpq.IniPath, ExtractPath: extractPath})
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return render(s, asset)
+}
+
+// render renders the given asset, using the frames and palettes of the store.
+func render(s *mpq.Store, asset string) (img image.Image, err error) {
+	pos := strings.LastIndex(asset, ":")
+	if pos == -1 {
+		return renderDungeon(s, asset)
+	}
+	name := asset[:pos]
+	num, err := strconv.Atoi(asset[pos+1:])
+	if err != nil {
+		return nil, err
+	}
+	switch path.Ext(name) {
+	case ".cel", ".cl2":
+		return renderFrame(s, name, num)
+	case ".min":
+		return renderPillar(s, name, num)
+	}
+	return nil, fmt.Errorf("unable to render asset %q; unknown extension.", asset)
+}
+
+// renderFrame decodes the given frame of a CEL or CL2 image.
+func renderFrame(s *mpq.Store, imgName string, frameNum int) (img image.Image, err error) {
+	relPalPath := imgconf.GetRelPalPaths(imgName)[0]
+	conf, err := cel.GetConfFrom(s, imgName, relPalPath)
+	if err != nil {
+		return nil, err
+	}
+	imgs, err := cl2.DecodeAllFrom(s, imgName, conf)
+	if err != nil {
+		return nil, err
+	}
+	if frameNum < 0 || frameNum >= len(imgs) {
+		return nil, fmt.Errorf("invalid frame %d of %q (frame count: %d).", frameNum, imgName, len(imgs))
+	}
+	return imgs[frameNum], nil
+}
+
+// renderPillar constructs the given pillar of a MIN file.
+func renderPillar(s *mpq.Store, minName string, pillarNum int) (img image.Image, err error) {
+	pillars, err := min.ParseFrom(s, minName)
+	if err != nil {
+		return nil, err
+	}
+	if pillarNum < 0 || pillarNum >= len(pillars) {
+		return nil, fmt.Errorf("invalid pillar %d of %q (pillar count: %d).", pillarNum, minName, len(pillars))
+	}
+	levelFrames, err := getLevelFrames(s, strings.TrimSuffix(minName, ".min"))
+	if err != nil {
+		return nil, err
+	}
+	return pillars[pillarNum].Image(levelFrames), nil
+}
+
+// renderDungeon constructs the given dungeon, based on its layout.
+func renderDungeon(s *mpq.Store, dungeonName string) (img image.Image, err error) {
+	layout, err := dunconf.GetLevelLayout(dungeonName)
+	if err != nil {
+		return nil, err
+	}
+	dungeon := dun.New()
+	for _, p := range layout.Duns {
+		err = dungeon.ParseAtFrom(s, p.DunName, p.ColStart, p.RowStart)
+		if err != nil {
+			if _, ok := err.(*dun.SquareError); !ok {
+				return nil, err
+			}
+			// report invalid squares but render the remaining dungeon.
+			log.Println(err)
+		}
+	}
+	colCount, rowCount, err := dun.GetLayoutSizeFrom(s, layout)
+	if err != nil {
+		return nil, err
+	}
+	nameWithoutExt, err := dun.GetLevelNameFrom(s, layout.Duns[0].DunName)
+	if err != nil {
+		return nil, err
+	}
+	pillars, err := min.ParseFrom(s, nameWithoutExt+".min")
+	if err != nil {
+		return nil, err
+	}
+	levelFrames, err := getLevelFrames(s, nameWithoutExt)
+	if err != nil {
+		return nil, err
+	}
+	return dungeon.Image(colCount, rowCount, pillars, levelFrames, 1), nil
+}
+
+// getLevelFrames decodes the frames of the CEL image level file of the given
+// level (e.g. "l1"), using its first palette.
+func getLevelFrames(s *mpq.Store, nameWithoutExt string) (levelFrames []image.Image, err error) {
+	lc, err := cel.GetLevelConfFrom(s, nameWithoutExt)
+	if err != nil {
+		return nil, err
+	}
+	return cel.DecodeAllFrom(s, lc.CelName, lc.Conf)
+}
+
+// diff returns a heat map of the pixel differences between the two images,
+// and the number of differing pixels. The heat map is a dimmed grayscale
+// version of the first image, with each differing pixel colored from yellow
+// (small difference) to red (large difference).
+func diff(img1, img2 image.Image) (heat *image.RGBA, n int) {
+	bounds := img1.Bounds().Union(img2.Bounds())
+	heat = image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c1 := color.NRGBAModel.Convert(img1.At(x, y)).(color.NRGBA)
+			c2 := color.NRGBAModel.Convert(img2.At(x, y)).(color.NRGBA)
+			if c1 == c2 {
+				gray := color.GrayModel.Convert(c1).(color.Gray)
+				heat.Set(x, y, color.NRGBA{R: gray.Y / 3, G: gray.Y / 3, B: gray.Y / 3, A: c1.A})
+				continue
+			}
+			n++
+			// Scale the difference to the range [0, 255].
+			d := (absDiff(c1.R, c2.R) + absDiff(c1.G, c2.G) + absDiff(c1.B, c2.B) + absDiff(c1.A, c2.A)) / 4
+			heat.Set(x, y, color.NRGBA{R: 0xFF, G: uint8(0xFF - d), B: 0x00, A: 0xFF})
+		}
+	}
+	return heat, n
+}
+
+// absDiff returns the absolute difference between a and b.
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// sideBySide returns an image with the given images placed next to each other,
+// from left to right.
+func sideBySide(imgs ...image.Image) (img *image.RGBA) {
+	var width, height int
+	for _, src := range imgs {
+		bounds := src.Bounds()
+		width += bounds.Dx()
+		if bounds.Dy() > height {
+			height = bounds.Dy()
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	var x int
+	for _, src := range imgs {
+		bounds := src.Bounds()
+		rect := image.Rect(x, 0, x+bounds.Dx(), bounds.Dy())
+		draw.Draw(dst, rect, src, bounds.Min, draw.Src)
+		x += bounds.Dx()
+	}
+	return dst
+}
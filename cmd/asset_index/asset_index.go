@@ -0,0 +1,369 @@
+// asset_index is a tool for building a searchable index of the assets of an
+// extracted MPQ file, and for querying it.
+//
+// Usage:
+//
+//    asset_index [OPTION]... [query]...
+//
+// Queries:
+//
+//    text        // name or relative path contains text, e.g. "l1"
+//    field:text  // field contains text, e.g. "type:cl2" or "pal:town.pal"
+//
+// The fields are name, type, path, pal and ref (referenced-by). Each query must
+// match for an asset to be listed, and all matches are case-insensitive.
+//
+// Flags:
+//
+//    -build=false
+//            Build the index before querying it.
+//    -celini="cel.ini"
+//            Path to an ini file containing CEL image information.
+//    -cl2ini="cl2.ini"
+//            Path to an ini file containing CL2 image information.
+//    -dunini="dun.ini"
+//            Path to an ini file containing starting coordinate information.
+//    -index="_dump_/_index_.json"
+//            Path to the index file.
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	dbg "fmt"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/configs/dunconf"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/mongfx"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagBuild specifies if the index should be built before querying it or
+	// not.
+	flagBuild bool
+	// flagCelIni specifies the path to the ini file containing CEL image
+	// information.
+	flagCelIni string
+	// flagCl2Ini specifies the path to the ini file containing CL2 image
+	// information.
+	flagCl2Ini string
+	// flagIndex specifies the path to the index file.
+	flagIndex string
+)
+
+func init() {
+	flag.Usage = usage
+	flag.BoolVar(&flagBuild, "build", false, "Build the index before querying it.")
+	flag.StringVar(&flagCelIni, "celini", "cel.ini", "Path to an ini file containing CEL image information.")
+	flag.StringVar(&flagCl2Ini, "cl2ini", "cl2.ini", "Path to an ini file containing CL2 image information.")
+	flag.StringVar(&dunconf.IniPath, "dunini", "dun.ini", "Path to an ini file containing starting coordinate information.")
+	flag.StringVar(&flagIndex, "index", "_dump_/_index_.json", "Path to the index file.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.Parse()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... [query]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if !flagBuild && flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	var assets []*Asset
+	var err error
+	if flagBuild {
+		assets, err = build()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = store(flagIndex, assets)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if flag.NArg() < 1 {
+			return
+		}
+	} else {
+		assets, err = load(flagIndex)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, asset := range assets {
+		if asset.Match(flag.Args()) {
+			fmt.Fprintln(w, asset)
+		}
+	}
+	w.Flush()
+}
+
+// An Asset is an entry of the index, which describes a file of the extracted
+// MPQ file.
+type Asset struct {
+	// Name is the name of the file (e.g. "l1.cel").
+	Name string `json:"name"`
+	// Type is the extension of the file, without the leading dot (e.g. "cel").
+	Type string `json:"type"`
+	// Path is the path of the file, relative to the extracted MPQ file.
+	Path string `json:"path"`
+	// Width and Height are the default frame dimensions of images, and the
+	// dimensions in squares of DUN files.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// FrameCount is the number of frames of images.
+	FrameCount int `json:"frameCount,omitempty"`
+	// ImageCount is the number of archived images of image archives.
+	ImageCount int `json:"imageCount,omitempty"`
+	// Pals is the relative paths to the palettes of images.
+	Pals []string `json:"pals,omitempty"`
+	// ReferencedBy is the names of the assets and dungeons referencing the
+	// file.
+	ReferencedBy []string `json:"referencedBy,omitempty"`
+}
+
+// String returns a tab separated description of the asset.
+func (asset *Asset) String() string {
+	var dims, frames string
+	if asset.Width != 0 || asset.Height != 0 {
+		dims = fmt.Sprintf("%dx%d", asset.Width, asset.Height)
+	}
+	switch {
+	case asset.ImageCount != 0:
+		frames = fmt.Sprintf("%d images", asset.ImageCount)
+	case asset.FrameCount != 0:
+		frames = fmt.Sprintf("%d frames", asset.FrameCount)
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s", asset.Name, asset.Type, dims, frames, strings.Join(asset.Pals, ","), strings.Join(asset.ReferencedBy, ","))
+}
+
+// Match returns true if the asset matches each of the given queries, as
+// described above.
+func (asset *Asset) Match(queries []string) bool {
+	for _, query := range queries {
+		query = strings.ToLower(query)
+		var fields []string
+		pos := strings.Index(query, ":")
+		if pos == -1 {
+			fields = []string{asset.Name, asset.Path}
+		} else {
+			switch query[:pos] {
+			case "name":
+				fields = []string{asset.Name}
+			case "type":
+				fields = []string{asset.Type}
+			case "path":
+				fields = []string{asset.Path}
+			case "pal":
+				fields = asset.Pals
+			case "ref":
+				fields = asset.ReferencedBy
+			default:
+				// unknown fields never match.
+				return false
+			}
+			query = query[pos+1:]
+		}
+		if !containsAny(fields, query) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAny returns true if any of the fields contains the lower case text,
+// ignoring case.
+func containsAny(fields []string, text string) bool {
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// build returns an asset for each file of the mpq.ini file, based on the image
+// information of the CEL and CL2 ini files and the dungeons of the dun.ini
+// file.
+func build() (assets []*Asset, err error) {
+	err = mpq.Init()
+	if err != nil {
+		return nil, err
+	}
+	err = dunconf.Init()
+	if err != nil {
+		return nil, err
+	}
+	// names maps from name and relative path to the asset of a file.
+	names := make(map[string]*Asset)
+	err = mpq.AllFunc(func(name string) error {
+		relPath, err := mpq.GetRelPath(name)
+		if err != nil {
+			return err
+		}
+		asset := &Asset{
+			Name: name,
+			Type: strings.TrimPrefix(path.Ext(name), "."),
+			Path: relPath,
+		}
+		assets = append(assets, asset)
+		names[name] = asset
+		names[relPath] = asset
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// ref records that referrer references the file of the given name or
+	// relative path.
+	ref := func(name, referrer string) {
+		if asset, ok := names[name]; ok {
+			asset.ReferencedBy = append(asset.ReferencedBy, referrer)
+		}
+	}
+
+	// Images.
+	for _, iniPath := range []string{flagCelIni, flagCl2Ini} {
+		dbg.Println("Indexing images:", iniPath)
+		imgconf.IniPath = iniPath
+		err = imgconf.Init()
+		if err != nil {
+			return nil, err
+		}
+		if iniPath == flagCl2Ini {
+			// register the monster CL2 images, which aren't listed in cl2.ini.
+			mongfx.InitConf()
+		}
+		err = imgconf.AllFunc(func(imgName string) error {
+			asset, ok := names[imgName]
+			if !ok {
+				return nil
+			}
+			err := indexImage(asset)
+			if err != nil {
+				// report the image but index the remaining ones.
+				log.Println(err)
+			}
+			for _, relPath := range asset.Pals {
+				ref(relPath, imgName)
+			}
+			for _, relPath := range imgconf.GetRelTrnPaths(imgName) {
+				ref(relPath, imgName)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Dungeons.
+	dbg.Println("Indexing dungeons:", dunconf.IniPath)
+	for _, dungeonName := range dunconf.DungeonNames() {
+		layout, err := dunconf.GetLevelLayout(dungeonName)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range layout.Duns {
+			ref(p.DunName, dungeonName)
+		}
+	}
+	for _, asset := range assets {
+		if asset.Type != "dun" {
+			continue
+		}
+		asset.Width, asset.Height, err = dun.GetSize(asset.Name)
+		if err != nil {
+			log.Println(err)
+		}
+		nameWithoutExt, err := dun.GetLevelName(asset.Name)
+		if err != nil {
+			continue
+		}
+		for _, ext := range []string{".cel", ".min", ".til", ".sol", ".amp"} {
+			ref(nameWithoutExt+ext, asset.Name)
+		}
+	}
+
+	for _, asset := range assets {
+		sort.Strings(asset.ReferencedBy)
+	}
+	return assets, nil
+}
+
+// indexImage stores the dimensions, frame count, image count and palettes of
+// the given image in its asset. The frame count is read from the header of the
+// image, and is left out for image archives.
+func indexImage(asset *Asset) (err error) {
+	asset.Pals = imgconf.GetRelPalPaths(asset.Name)
+	asset.Width, _ = imgconf.GetWidth(asset.Name)
+	asset.Height, _ = imgconf.GetHeight(asset.Name)
+	imageCount, found := imgconf.GetImageCount(asset.Name)
+	if found {
+		asset.ImageCount = imageCount
+		return nil
+	}
+	f, err := mpq.OpenRel(asset.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var frameCount uint32
+	err = binary.Read(f, binary.LittleEndian, &frameCount)
+	if err != nil {
+		return fmt.Errorf("unable to read frame count for %q: %v", asset.Name, err)
+	}
+	asset.FrameCount = int(frameCount)
+	return nil
+}
+
+// store writes the assets to the given index file, as JSON.
+func store(indexPath string, assets []*Asset) (err error) {
+	buf, err := json.MarshalIndent(assets, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Dir(indexPath), 0755)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(indexPath, append(buf, '\n'))
+}
+
+// load reads the assets of the given index file.
+func load(indexPath string) (assets []*Asset, err error) {
+	buf, err := ioutil.ReadFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read index; run with -build first: %v", err)
+	}
+	err = json.Unmarshal(buf, &assets)
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
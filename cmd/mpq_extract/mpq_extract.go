@@ -0,0 +1,221 @@
+// mpq_extract is a tool for listing and extracting the files of MPQ archives
+// which match the given glob patterns.
+//
+// MPQ archives only store hashes of file names, so the files are located using
+// the listfile of the archives and the relative paths of the ini file.
+//
+// Usage:
+//
+//    mpq_extract [OPTION]... PATTERN...
+//
+// Patterns:
+//
+//    levels/l1data/*.dun // files matching the pattern, as used by path.Match
+//    levels/*/*.til
+//    monsters/**/*.cl2   // "**" matches any number of directories
+//
+// Flags:
+//
+//    -flat=false
+//            Store the files directly in the output directory, rather than preserving their directory structure.
+//    -l=false
+//            List the matching files rather than extracting them.
+//    -meta=false
+//            List how each matching file is stored (sizes, compression, encryption and locale); implies -l.
+//    -mpqarchive="diabdat.mpq"
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ); several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information; disabled if empty.
+//    -o="mpqdump/"
+//            Output directory of the extracted files.
+//    -verify=false
+//            Verify the sector checksums of the files, and report the files which have none.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagArchive specifies the paths of the MPQ archives.
+	flagArchive string
+	// flagFlat specifies if the directory structure of the files should be
+	// discarded or not.
+	flagFlat bool
+	// flagIni specifies the path of the ini file.
+	flagIni string
+	// flagList specifies if the matching files should be listed rather than
+	// extracted.
+	flagList bool
+	// flagMeta specifies if the metadata of the matching files should be
+	// listed or not.
+	flagMeta bool
+	// flagOutput specifies the output directory of the extracted files.
+	flagOutput string
+	// flagVerify specifies if the sector checksums of the files should be
+	// verified or not.
+	flagVerify bool
+)
+
+func init() {
+	flag.Usage = usage
+	flag.BoolVar(&flagFlat, "flat", false, "Store the files directly in the output directory, rather than preserving their directory structure.")
+	flag.BoolVar(&flagList, "l", false, "List the matching files rather than extracting them.")
+	flag.BoolVar(&flagMeta, "meta", false, "List how each matching file is stored (sizes, compression, encryption and locale); implies -l.")
+	flag.StringVar(&flagArchive, "mpqarchive", "diabdat.mpq", "Path to an MPQ archive (e.g. DIABDAT.MPQ); several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&flagIni, "mpqini", "mpq.ini", "Path to an ini file containing relative path information; disabled if empty.")
+	flag.StringVar(&flagOutput, "o", "mpqdump/", "Output directory of the extracted files.")
+	flag.BoolVar(&flagVerify, "verify", false, "Verify the sector checksums of the files, and report the files which have none.")
+	flag.Parse()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... PATTERN...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	s, err := mpq.OpenStore(mpq.Options{IniPath: flagIni, ArchivePath: flagArchive})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer s.Close()
+	relPaths, err := match(s, flag.Args())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if flagMeta {
+		err = listMeta(s.Chain(), relPaths)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	if flagList {
+		for _, relPath := range relPaths {
+			fmt.Println(relPath)
+		}
+		return
+	}
+	err = extract(s.Chain(), relPaths)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// match returns the relative paths of the files of the MPQ archives of the
+// store which match any of the given patterns, sorted by path.
+func match(s *mpq.Store, patterns []string) (relPaths []string, err error) {
+	found := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := s.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		n := 0
+		for _, relPath := range matches {
+			// Skip the files of the ini file which are not part of the
+			// archives.
+			if _, err := s.Chain().Stat(relPath); err != nil {
+				continue
+			}
+			n++
+			if !found[relPath] {
+				found[relPath] = true
+				relPaths = append(relPaths, relPath)
+			}
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("no files match %q.", pattern)
+		}
+	}
+	sort.Strings(relPaths)
+	return relPaths, nil
+}
+
+// listMeta lists how each file at the given relative paths is stored in the
+// chain.
+func listMeta(chain mpq.Chain, relPaths []string) (err error) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "path\tsize\tcompressed\tcompression\tencryption\tlocale")
+	for _, relPath := range relPaths {
+		meta, err := chain.Meta(relPath)
+		if err != nil {
+			return err
+		}
+		encryption := "none"
+		if meta.FixKey() {
+			encryption = "fixkey"
+		} else if meta.Encrypted() {
+			encryption = "encrypted"
+		}
+		compression := meta.Compression()
+		if meta.SingleUnit() {
+			compression += " (single unit)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t0x%04X\n", relPath, meta.Size, meta.CompressedSize, compression, encryption, meta.Locale)
+	}
+	return w.Flush()
+}
+
+// extract extracts the files at the given relative paths from the chain to the
+// output directory.
+func extract(chain mpq.Chain, relPaths []string) (err error) {
+	if flagFlat {
+		// Files of different directories may share their names once the
+		// directory structure is discarded.
+		names := make(map[string]string)
+		for _, relPath := range relPaths {
+			name := path.Base(relPath)
+			if other, ok := names[name]; ok {
+				return fmt.Errorf("files %q and %q share the same name; unable to extract both using -flat.", other, relPath)
+			}
+			names[name] = relPath
+		}
+	}
+	for _, relPath := range relPaths {
+		var buf []byte
+		if flagVerify {
+			var checked bool
+			buf, checked, err = chain.ReadFileChecked(relPath)
+			if err == nil && !checked {
+				fmt.Printf("%s: no sector checksums.\n", relPath)
+			}
+		} else {
+			buf, err = chain.ReadFile(relPath)
+		}
+		if err != nil {
+			return err
+		}
+		name := relPath
+		if flagFlat {
+			name = path.Base(relPath)
+		}
+		filePath := path.Join(flagOutput, name)
+		err = os.MkdirAll(mpq.OSPath(path.Dir(filePath)), 0755)
+		if err != nil {
+			return err
+		}
+		err = atomicfile.WriteFile(mpq.OSPath(filePath), buf)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Extracted %q.\n", relPath)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// mpq_listfile is a tool for generating the listfile of an MPQ archive, which
+// lists the relative path of each of its files with a known name.
+//
+// The names are based on the listfile of the archive, the relative paths of
+// the ini file and the known files of Diablo and Hellfire embedded in the mpq
+// package. The names of the remaining files may be recovered by brute force,
+// by looking up each combination of the known directories, the known base
+// names (and the words of a word list) and the known extensions.
+//
+// Usage:
+//
+//    mpq_listfile [OPTION]... ARCHIVE
+//
+// Flags:
+//
+//    -mpqini=""
+//            Path to an ini file containing relative path information; disabled if empty.
+//    -o="_dump_/listfile.txt"
+//            Output path of the listfile.
+//    -recover=false
+//            Recover the names of unnamed files by brute force.
+//    -words=""
+//            Path to a word list, with one base name (e.g. "banner1") per line, used by -recover.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagIni specifies the path of the ini file.
+	flagIni string
+	// flagOutput specifies the output path of the listfile.
+	flagOutput string
+	// flagRecover specifies if the names of unnamed files should be recovered
+	// by brute force or not.
+	flagRecover bool
+	// flagWords specifies the path of a word list used by -recover.
+	flagWords string
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&flagIni, "mpqini", "", "Path to an ini file containing relative path information; disabled if empty.")
+	flag.StringVar(&flagOutput, "o", "_dump_/listfile.txt", "Output path of the listfile.")
+	flag.BoolVar(&flagRecover, "recover", false, "Recover the names of unnamed files by brute force.")
+	flag.StringVar(&flagWords, "words", "", `Path to a word list, with one base name (e.g. "banner1") per line, used by -recover.`)
+	flag.Parse()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... ARCHIVE\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	s, err := mpq.OpenStore(mpq.Options{IniPath: flagIni, ArchivePath: flag.Arg(0)})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer s.Close()
+	a := s.Chain()[0]
+	names := a.Names()
+	fmt.Printf("Named %d files.\n", len(names))
+	if flagRecover {
+		recovered, err := recoverNames(a)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		names = merge(names, recovered)
+		fmt.Printf("Recovered the names of %d files.\n", len(recovered))
+	}
+	if n := a.Unnamed(names); n > 0 {
+		fmt.Printf("%d files remain unnamed.\n", n)
+	}
+	err = os.MkdirAll(path.Dir(flagOutput), 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = atomicfile.WriteFile(flagOutput, []byte(strings.Join(names, "\n")+"\n"))
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// recoverNames recovers the names of the files of the archive by brute force,
+// using the directories, base names and extensions of the known files and the
+// words of the word list.
+func recoverNames(a *mpq.Archive) (relPaths []string, err error) {
+	dirs := make(map[string]bool)
+	bases := make(map[string]bool)
+	exts := make(map[string]bool)
+	for _, relPath := range mpq.KnownFiles() {
+		dirs[path.Dir(relPath)] = true
+		ext := path.Ext(relPath)
+		bases[strings.TrimSuffix(path.Base(relPath), ext)] = true
+		exts[ext] = true
+	}
+	if len(flagWords) > 0 {
+		words, err := readWords(flagWords)
+		if err != nil {
+			return nil, err
+		}
+		for _, word := range words {
+			bases[word] = true
+		}
+	}
+	return a.Recover(keys(dirs), keys(bases), keys(exts)), nil
+}
+
+// readWords returns the words of the given word list, with one word per line.
+func readWords(wordsPath string) (words []string, err error) {
+	f, err := os.Open(wordsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if word := strings.ToLower(strings.TrimSpace(s.Text())); len(word) > 0 {
+			words = append(words, word)
+		}
+	}
+	return words, s.Err()
+}
+
+// keys returns the keys of the set, sorted.
+func keys(set map[string]bool) (keys []string) {
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// merge returns the sorted union of the given relative paths.
+func merge(a, b []string) (relPaths []string) {
+	set := make(map[string]bool)
+	for _, relPath := range append(a, b...) {
+		set[relPath] = true
+	}
+	return keys(set)
+}
@@ -0,0 +1,110 @@
+// mpq_verify is a tool for verifying the integrity of MPQ archives, e.g. those
+// copied from damaged CDs or ISO images, before dumping their assets.
+//
+// Each file of an archive is decompressed and its sector checksums, if any,
+// are verified; truncated and corrupt files are reported. The names of the
+// files are located using the listfile of the archive, the relative paths of
+// the ini file and the known files of Diablo and Hellfire. Encrypted files
+// which remain unnamed can't be decrypted, and are only checked for
+// truncation.
+//
+// Usage:
+//
+//    mpq_verify [OPTION]... ARCHIVE...
+//
+// Flags:
+//
+//    -mpqini=""
+//            Path to an ini file containing relative path information; disabled if empty.
+//    -v=false
+//            Report every file, rather than only the truncated and corrupt ones.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagIni specifies the path of the ini file.
+	flagIni string
+	// flagVerbose specifies if every file should be reported or not.
+	flagVerbose bool
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&flagIni, "mpqini", "", "Path to an ini file containing relative path information; disabled if empty.")
+	flag.BoolVar(&flagVerbose, "v", false, "Report every file, rather than only the truncated and corrupt ones.")
+	flag.Parse()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... ARCHIVE...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	intact := true
+	for _, archivePath := range flag.Args() {
+		ok, err := verify(archivePath)
+		if err != nil {
+			log.Println(err)
+			ok = false
+		}
+		intact = intact && ok
+	}
+	if !intact {
+		os.Exit(1)
+	}
+}
+
+// verify verifies the integrity of each file of the MPQ archive, and reports
+// whether all files are intact.
+func verify(archivePath string) (ok bool, err error) {
+	s, err := mpq.OpenStore(mpq.Options{IniPath: flagIni, ArchivePath: archivePath})
+	if err != nil {
+		return false, err
+	}
+	defer s.Close()
+	results, err := s.Chain()[0].Verify()
+	if err != nil {
+		return false, err
+	}
+	var checked, skipped, corrupt int
+	for _, result := range results {
+		name := result.RelPath
+		if len(name) == 0 {
+			name = fmt.Sprintf("block %d", result.BlockIndex)
+		}
+		switch {
+		case result.Err != nil:
+			corrupt++
+			fmt.Printf("%s: %s: %v\n", archivePath, name, result.Err)
+		case result.Skipped:
+			skipped++
+			if flagVerbose {
+				fmt.Printf("%s: %s: skipped; encrypted and unnamed.\n", archivePath, name)
+			}
+		default:
+			if result.Checked {
+				checked++
+			}
+			if flagVerbose {
+				fmt.Printf("%s: %s: ok.\n", archivePath, name)
+			}
+		}
+	}
+	fmt.Printf("%s: verified %d files (%d with sector checksums); %d corrupt, %d skipped.\n", archivePath, len(results)-skipped, checked, corrupt, skipped)
+	return corrupt == 0, nil
+}
@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"log"
 	"path/filepath"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
 )
 
 var mpqpath string
@@ -67,7 +69,7 @@ func (fix Fix) Apply() error {
 	if oldsum != fix.oldsum {
 		return fmt.Errorf("MD5 checksum mismatch for unpatched version of %q.", fix.path)
 	}
-	err = ioutil.WriteFile(path+".orig", buf, 0644)
+	err = atomicfile.WriteFile(path+".orig", buf)
 	if err != nil {
 		return fmt.Errorf("failed to create backup for %q; %v", fix.path, err)
 	}
@@ -78,5 +80,5 @@ func (fix Fix) Apply() error {
 	if newsum != fix.newsum {
 		return fmt.Errorf("MD5 checksum mismatch for patched version of %q.", fix.path)
 	}
-	return ioutil.WriteFile(path, buf, 0644)
+	return atomicfile.WriteFile(path, buf)
 }
@@ -0,0 +1,61 @@
+// mpqpack is a tool for repacking an extracted MPQ archive, e.g. after
+// modifying its DUN or CEL files, into a new MPQ archive which may be used by
+// the game.
+//
+// Usage:
+//
+//    mpqpack [OPTION]...
+//
+// Flags:
+//
+//    -compress=true
+//            Compress the files using PKWARE implode, as supported by the game.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -o="_dump_/diabdat.mpq"
+//            Output path of the MPQ archive.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagCompress specifies if the files should be compressed or not.
+	flagCompress bool
+	// flagOutput specifies the output path of the MPQ archive.
+	flagOutput string
+)
+
+func init() {
+	flag.Usage = usage
+	flag.BoolVar(&flagCompress, "compress", true, "Compress the files using PKWARE implode, as supported by the game.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&flagOutput, "o", "_dump_/diabdat.mpq", "Output path of the MPQ archive.")
+	flag.Parse()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	err := os.MkdirAll(path.Dir(flagOutput), 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Printf("Creating %q from %q.\n", flagOutput, mpq.ExtractPath)
+	err = mpq.CreateArchive(flagOutput, os.DirFS(mpq.ExtractPath), flagCompress)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,100 @@
+// seed_info is a tool for printing the level seeds and the available quests of
+// a single player game as JSON, based on the seed of the game.
+//
+// Usage:
+//
+//    seed_info [OPTION]... SEED
+//
+// Flags:
+//
+//    -o=""
+//            Output path of the JSON file; standard output by default.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/quests"
+	"github.com/mewrnd/blizzconv/rng"
+)
+
+// flagOutput specifies the output path of the JSON file.
+var flagOutput string
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&flagOutput, "o", "", "Output path of the JSON file; standard output by default.")
+	flag.Parse()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... SEED\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+// gameInfo contains the level seeds and available quests of a game.
+type gameInfo struct {
+	Seed   int32       `json:"seed"`
+	Levels []levelInfo `json:"levels"`
+}
+
+// levelInfo contains the seed and available quests of a dungeon level.
+type levelInfo struct {
+	Level  int         `json:"level"`
+	Seed   int32       `json:"seed"`
+	Quests []questInfo `json:"quests,omitempty"`
+}
+
+// questInfo identifies a quest.
+type questInfo struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func main() {
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	seed, err := strconv.ParseInt(flag.Arg(0), 0, 32)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	info := getGameInfo(int32(seed))
+	buf, err := json.MarshalIndent(info, "", "\t")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	buf = append(buf, '\n')
+	if flagOutput == "" {
+		os.Stdout.Write(buf)
+		return
+	}
+	err = atomicfile.WriteFile(flagOutput, buf)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// getGameInfo returns the level seeds and available quests of the game.
+func getGameInfo(gameSeed int32) (info gameInfo) {
+	info.Seed = gameSeed
+	seeds := rng.LevelSeeds(gameSeed)
+	levelQuests := quests.LevelQuests(quests.Select(seeds[15]))
+	for level, seed := range seeds {
+		li := levelInfo{Level: level, Seed: seed}
+		for _, quest := range levelQuests[level] {
+			li.Quests = append(li.Quests, questInfo{ID: int(quest), Name: quest.String()})
+		}
+		info.Levels = append(info.Levels, li)
+	}
+	return info
+}
@@ -0,0 +1,107 @@
+// sfx_dump is a tool for storing the sounds of an extracted MPQ file, named by
+// the event, monster or character which uses them rather than by their sound
+// file names.
+//
+// Usage:
+//
+//    sfx_dump [OPTION]...
+//
+// Flags:
+//
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/mpq"
+	"github.com/mewrnd/blizzconv/sounds"
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	shareware = mpq.IsShareware()
+	err := mpq.AllFunc(sfxDump)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if skipped > 0 {
+		fmt.Printf("Shareware version detected; skipped %d sounds missing from the archive.\n", skipped)
+	}
+}
+
+// shareware specifies if the MPQ archive is that of the shareware version, in
+// which case the sounds it lacks are skipped silently.
+var shareware bool
+
+// skipped is the number of sounds skipped, as they are missing from the
+// shareware version.
+var skipped int
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// sfxDump stores the sound file of the given name, if it is a sound, using the
+// name of the event, monster or character which uses it.
+func sfxDump(name string) (err error) {
+	if path.Ext(name) != ".wav" {
+		return nil
+	}
+	relPath, err := mpq.GetRelPath(name)
+	if err != nil {
+		return err
+	}
+	// The music is stored as WAV files as well.
+	if strings.HasPrefix(relPath, "music/") {
+		return nil
+	}
+	dumpPath := path.Clean(dumpPrefix + "_sounds_/" + sounds.Name(relPath))
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpPath, dumpPrefix) {
+		return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpPath, dumpPrefix)
+	}
+	if shareware && mpq.Missing(name) {
+		skipped++
+		return nil
+	}
+	buf, err := mpq.ReadFile(relPath)
+	if err != nil {
+		// Skip sounds which are missing from the extracted MPQ file.
+		log.Println(err)
+		return nil
+	}
+	err = os.MkdirAll(path.Dir(dumpPath), 0755)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(dumpPath, buf)
+}
@@ -0,0 +1,130 @@
+// Package amp implements functionality for parsing AMP files.
+//
+// AMP files contain information about how to draw each square, which is
+// constructed based on the TIL format, on the automap. Below is a description
+// of the AMP format:
+//
+// AMP format:
+//    tiles []Tile
+//
+// Tile format:
+//    // tile is a bitfield containing both Type and Flags:
+//    //    Type  := tile & 0x00FF
+//    //    Flags := tile & 0xFF00 >> 8
+//    tile uint16
+//
+// The automap tile of a square can be obtained using the squareNum as an offset
+// into the tiles array.
+package amp
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// Tile types, which specify the walls drawn on the automap.
+//
+// ref: DrawAutomapTile
+const (
+	// TypeNone draws nothing.
+	TypeNone = 0
+	// TypeDiamond draws a stand-alone column or other unpassable object.
+	TypeDiamond = 1
+	// TypeVert and TypeVert2 draw a wall along the upper left edge.
+	TypeVert  = 2
+	TypeVert2 = 5
+	// TypeHorz and TypeHorz2 draw a wall along the upper right edge.
+	TypeHorz  = 3
+	TypeHorz2 = 6
+	// TypeCross draws walls along both upper edges.
+	TypeCross = 4
+	// TypeVertCaveHorz draws a wall along the upper left edge and a cave wall
+	// along the lower right edge.
+	TypeVertCaveHorz = 8
+	// TypeHorzCaveVert draws a wall along the upper right edge and a cave wall
+	// along the lower left edge.
+	TypeHorzCaveVert = 9
+	// TypeCaveVert draws a cave wall along the lower left edge.
+	TypeCaveVert = 10
+	// TypeCaveHorz draws a cave wall along the lower right edge.
+	TypeCaveHorz = 11
+	// TypeCaveCross draws cave walls along both lower edges.
+	TypeCaveCross = 12
+)
+
+// Tile flags, which specify additional details drawn on the automap.
+//
+// ref: MAPFLAG_*
+const (
+	FlagVertDoor  = 0x01
+	FlagHorzDoor  = 0x02
+	FlagVertArch  = 0x04
+	FlagHorzArch  = 0x08
+	FlagVertGrate = 0x10
+	FlagHorzGrate = 0x20
+	FlagDirt      = 0x40
+	FlagStairs    = 0x80
+)
+
+// Tile contains information about how to draw a square on the automap.
+type Tile struct {
+	Type  int
+	Flags int
+}
+
+// Edges returns which edges of the square that contain walls (upper left,
+// upper right) and cave walls (lower left, lower right).
+func (tile Tile) Edges() (vert, horz, caveVert, caveHorz bool) {
+	switch tile.Type {
+	case TypeVert, TypeVert2:
+		vert = true
+	case TypeHorz, TypeHorz2:
+		horz = true
+	case TypeCross:
+		vert, horz = true, true
+	case TypeVertCaveHorz:
+		vert, caveHorz = true, true
+	case TypeHorzCaveVert:
+		horz, caveVert = true, true
+	case TypeCaveVert:
+		caveVert = true
+	case TypeCaveHorz:
+		caveHorz = true
+	case TypeCaveCross:
+		caveVert, caveHorz = true, true
+	}
+	return vert, horz, caveVert, caveHorz
+}
+
+// Parse parses a given AMP file and returns a slice of tiles, based on the AMP
+// format described above.
+func Parse(ampName string) (tiles []Tile, err error) {
+	return ParseFrom(mpq.Default(), ampName)
+}
+
+// ParseFrom parses the given AMP file of the store s, as described by Parse.
+func ParseFrom(s *mpq.Store, ampName string) (tiles []Tile, err error) {
+	fr, err := s.Open(ampName)
+	if err != nil {
+		return nil, err
+	}
+	defer fr.Close()
+	for {
+		var x uint16
+		err = binary.Read(fr, binary.LittleEndian, &x)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		tile := Tile{
+			Type:  int(x & 0x00FF),
+			Flags: int(x&0xFF00) >> 8,
+		}
+		tiles = append(tiles, tile)
+	}
+	return tiles, nil
+}
@@ -9,43 +9,261 @@
 //
 //    -a=false
 //            Dump all dungeons.
+//    -ambient=false
+//            Add the ambient objects (e.g. braziers and wall torches) placed by the game at load time; implies -objects.
+//    -audit=false
+//            Store a draw order audit image, which numbers each pillar and highlights pillars drawn out of order.
+//    -automap=false
+//            Store the automap of the dungeon as an SVG image (not available for the town).
+//    -bg=""
+//            Background of the dungeon images: "black", "checker" or a color (e.g. "#202020"); transparent by default.
+//    -cache-mem=0
+//            Memory limit of the files (e.g. MIN, TIL and PAL files) kept in memory while rendering (e.g. "64M"); disabled if 0.
 //    -celini="cel.ini"
 //            Path to an ini file containing image information.
 //            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -doors=""
+//            Render all doors "open" or "closed"; leave them as is by default.
+//    -gamma=1
+//            Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).
+//    -j=0
+//            Alias of -max-workers.
+//    -labels=false
+//            Annotate the town with the names and shops of its NPCs.
+//    -legend=false
+//            Append a legend strip describing the dungeon and its markers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -monsters=false
+//            Draw the monsters placed in the dungeon, using the first frame of their standing animation.
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
 //    -mpqdump="mpqdump/"
 //            Path to an extracted MPQ file.
 //    -mpqini="mpq.ini"
 //            Path to an ini file containing relative path information.
+//    -objclass=""
+//            Only draw the objects of the given comma-separated interaction classes (e.g. "chest,lever" or "interactive"); implies -objects.
+//    -objects=false
+//            Draw the objects placed in the dungeon.
+//    -path=""
+//            Mark the shortest walkable path between two cells (e.g. "10,20:30,40"), based on the SOL file of the level.
+//    -place=""
+//            Path to a CSV file of object and monster placements (col,row,object,monster) applied to the dungeons; implies -objects.
+//    -quirksini=""
+//            Path to an ini file containing the quirks of early game versions (e.g. 1.00); disabled if empty.
+//    -raw=false
+//            Treat the arguments as raw pillar layers dumped from the game process.
+//    -regions=false
+//            Mark connected walkable regions and store them as JSON.
+//    -renderbench=false
+//            Render each dungeon image using every renderer, and report their timings and any differences.
+//    -renderer="draw"
+//            Renderer backend used to draw the dungeon images: "draw" (image/draw) or "pix" (direct pixel copying).
+//    -scale=1
+//            Render the dungeon at 1/scale of its size (1, 2, 4 or 8).
+//    -seed=0
+//            Seed used for the random placement of ambient objects (e.g. the wall torches of the catacombs).
+//    -specials=false
+//            Draw the special CEL overlays (e.g. arches) of the level.
+//    -srgb=false
+//            Tag the exported PNG images as sRGB.
+//    -stairs=false
+//            Mark stairs and other level transitions.
+//    -strict=false
+//            Abort when optional assets (e.g. special CEL images or palette variants) are missing.
+//    -text=false
+//            Store a text map of the dungeon.
+//    -tiles=false
+//            Store the dungeon as a tile pyramid (z/x/y.png) for web map viewers.
+//    -tileset="l1"
+//            Level (e.g. "l1") whose tileset is used to render raw pillar layers.
+//    -traps=false
+//            Mark wall traps and their triggers and store them as JSON.
+//    -walkmap=false
+//            Store a walk map of the dungeon, with one pixel per cell colored by its solid properties, and its cell counts as JSON.
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	dbg "fmt"
 	"fmt"
+	"image"
+	"image/draw"
 	"log"
 	"os"
 	"path"
 	"strings"
+	"time"
 
-	"github.com/mewkiz/pkg/imgutil"
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/configs/amp"
 	"github.com/mewrnd/blizzconv/configs/dun"
 	"github.com/mewrnd/blizzconv/configs/dunconf"
 	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/configs/sol"
 	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/cl2"
+	"github.com/mewrnd/blizzconv/images/imgarchive"
+	"github.com/mewrnd/blizzconv/images/imgcache"
 	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/mongfx"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/images/tiles"
 	"github.com/mewrnd/blizzconv/mpq"
+	"github.com/mewrnd/blizzconv/quirks"
+	"github.com/mewrnd/blizzconv/rng"
 )
 
 var flagAll bool
 
+// flagAmbient specifies if the ambient objects placed by the game at load time
+// should be added or not.
+var flagAmbient bool
+
+// flagAudit specifies if a draw order audit image of the dungeon should be
+// stored or not.
+var flagAudit bool
+
+// flagAutomap specifies if the automap should be stored as an SVG image or not.
+var flagAutomap bool
+
+// flagBg specifies the background of the dungeon images ("black", "checker" or
+// "#RRGGBB").
+var flagBg string
+
+// flagCacheMem specifies the memory limit of the files kept in memory.
+var flagCacheMem budget.Size
+
+// background is the background image parsed from flagBg, or nil if the
+// dungeon images should have a transparent background.
+var background image.Image
+
+// flagDoors specifies the state of the doors ("open" or "closed").
+var flagDoors string
+
+// flagLabels specifies if the NPCs of the town should be annotated or not.
+var flagLabels bool
+
+// flagLegend specifies if a legend strip should be appended to the dungeon
+// images or not.
+var flagLegend bool
+
+// flagMonsters specifies if the monsters placed in the dungeon should be drawn
+// or not.
+var flagMonsters bool
+
+// flagObjClass specifies the comma-separated interaction classes of the objects
+// to draw; all objects are drawn if empty.
+var flagObjClass string
+
+// flagObjects specifies if the objects placed in the dungeon should be drawn or
+// not.
+var flagObjects bool
+
+// flagPath specifies the two cells between which the shortest walkable path
+// should be marked, if non-empty.
+var flagPath string
+
+// flagPlace specifies the path of a CSV file of object and monster placements
+// applied to the dungeons.
+var flagPlace string
+
+// flagRaw specifies if the arguments are raw pillar layers dumped from the
+// game process, rather than dungeon names.
+var flagRaw bool
+
+// flagRenderBench specifies if each dungeon image should be rendered using
+// every renderer, in order to compare their timings and output.
+var flagRenderBench bool
+
+// flagRenderer specifies the renderer backend used to draw the dungeon images.
+var flagRenderer string
+
+// flagRegions specifies if walkable regions should be marked and stored or not.
+var flagRegions bool
+
+// flagScale specifies the dungeon image to be rendered at 1/scale of its size.
+var flagScale int
+
+// flagSeed specifies the seed used for the random placement of ambient
+// objects.
+var flagSeed int
+
+// flagSpecials specifies if the special CEL overlays should be drawn or not.
+var flagSpecials bool
+
+// flagStairs specifies if level transitions should be marked or not.
+var flagStairs bool
+
+// flagStrict specifies if missing optional assets should abort the dump or
+// only be reported as warnings.
+var flagStrict bool
+
+// flagText specifies if a text map of the dungeon should be stored or not.
+var flagText bool
+
+// flagTiles specifies if the dungeon should be stored as a tile pyramid or
+// not.
+var flagTiles bool
+
+// flagTileset specifies the level whose tileset is used to render raw pillar
+// layers.
+var flagTileset string
+
+// flagTraps specifies if wall traps and their triggers should be marked and
+// stored or not.
+var flagTraps bool
+
+// flagWalkMap specifies if a walk map of the dungeon should be stored or not.
+var flagWalkMap bool
+
 func init() {
 	flag.Usage = usage
 	flag.BoolVar(&flagAll, "a", false, "Dump all dungeons.")
+	flag.BoolVar(&flagAmbient, "ambient", false, "Add the ambient objects (e.g. braziers and wall torches) placed by the game at load time; implies -objects.")
+	flag.BoolVar(&flagAudit, "audit", false, "Store a draw order audit image, which numbers each pillar and highlights pillars drawn out of order.")
+	flag.BoolVar(&flagAutomap, "automap", false, "Store the automap of the dungeon as an SVG image (not available for the town).")
+	flag.StringVar(&flagBg, "bg", "", `Background of the dungeon images: "black", "checker" or a color (e.g. "#202020"); transparent by default.`)
+	flag.Var(&flagCacheMem, "cache-mem", `Memory limit of the files (e.g. MIN, TIL and PAL files) kept in memory while rendering (e.g. "64M"); disabled if 0.`)
+	flag.StringVar(&flagDoors, "doors", "", `Render all doors "open" or "closed"; leave them as is by default.`)
+	flag.Float64Var(&pngprof.Gamma, "gamma", 1, "Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).")
+	flag.BoolVar(&flagLabels, "labels", false, "Annotate the town with the names and shops of its NPCs.")
+	flag.BoolVar(&flagLegend, "legend", false, "Append a legend strip describing the dungeon and its markers.")
+	flag.BoolVar(&flagMonsters, "monsters", false, "Draw the monsters placed in the dungeon, using the first frame of their standing animation.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&flagObjClass, "objclass", "", `Only draw the objects of the given comma-separated interaction classes (e.g. "chest,lever" or "interactive"); implies -objects.`)
+	flag.BoolVar(&flagObjects, "objects", false, "Draw the objects placed in the dungeon.")
+	flag.StringVar(&flagPath, "path", "", `Mark the shortest walkable path between two cells (e.g. "10,20:30,40"), based on the SOL file of the level.`)
+	flag.StringVar(&flagPlace, "place", "", "Path to a CSV file of object and monster placements (col,row,object,monster) applied to the dungeons; implies -objects.")
+	flag.StringVar(&quirks.IniPath, "quirksini", "", "Path to an ini file containing the quirks of early game versions (e.g. 1.00); disabled if empty.")
+	flag.BoolVar(&flagRaw, "raw", false, "Treat the arguments as raw pillar layers dumped from the game process.")
+	flag.BoolVar(&flagRegions, "regions", false, "Mark connected walkable regions and store them as JSON.")
+	flag.BoolVar(&flagRenderBench, "renderbench", false, "Render each dungeon image using every renderer, and report their timings and any differences.")
+	flag.StringVar(&flagRenderer, "renderer", "draw", `Renderer backend used to draw the dungeon images: "draw" (image/draw) or "pix" (direct pixel copying).`)
+	flag.IntVar(&flagScale, "scale", 1, "Render the dungeon at 1/scale of its size (1, 2, 4 or 8).")
+	flag.IntVar(&flagSeed, "seed", 0, "Seed used for the random placement of ambient objects (e.g. the wall torches of the catacombs).")
+	flag.BoolVar(&flagSpecials, "specials", false, "Draw the special CEL overlays (e.g. arches) of the level.")
+	flag.BoolVar(&pngprof.SRGB, "srgb", false, "Tag the exported PNG images as sRGB.")
+	flag.BoolVar(&flagStairs, "stairs", false, "Mark stairs and other level transitions.")
+	flag.BoolVar(&flagStrict, "strict", false, "Abort when optional assets (e.g. special CEL images or palette variants) are missing.")
+	flag.BoolVar(&flagText, "text", false, "Store a text map of the dungeon.")
+	flag.BoolVar(&flagTiles, "tiles", false, "Store the dungeon as a tile pyramid (z/x/y.png) for web map viewers.")
+	flag.StringVar(&flagTileset, "tileset", "l1", `Level (e.g. "l1") whose tileset is used to render raw pillar layers.`)
+	flag.BoolVar(&flagTraps, "traps", false, "Mark wall traps and their triggers and store them as JSON.")
+	flag.BoolVar(&flagWalkMap, "walkmap", false, "Store a walk map of the dungeon, with one pixel per cell colored by its solid properties, and its cell counts as JSON.")
 	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
 	flag.StringVar(&dunconf.IniPath, "dunini", "dun.ini", "Path to an ini file containing starting coordinate information.")
 	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
 	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	budget.RegisterFlags()
 	flag.Parse()
+	mpq.CacheSize = int64(flagCacheMem)
 	err := mpq.Init()
 	if err != nil {
 		log.Fatalln(err)
@@ -58,6 +276,14 @@ func init() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	mongfx.InitConf()
+	version, err := quirks.DetectAndApply()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(version) > 0 {
+		dbg.Println("Applying the quirks of version:", version)
+	}
 }
 
 func usage() {
@@ -69,14 +295,71 @@ func usage() {
 
 func main() {
 	var dungeonNames []string
+	if flagAll && flagRaw {
+		log.Fatalln("the -a and -raw flags are mutually exclusive.")
+	}
 	if flagAll {
-		dungeonNames = dunconf.DungeonNames()
+		// Skip the dungeons missing from the shareware version.
+		shareware := mpq.IsShareware()
+		skipped := 0
+		for _, dungeonName := range dunconf.DungeonNames() {
+			if shareware && missing(dungeonName) {
+				skipped++
+				continue
+			}
+			dungeonNames = append(dungeonNames, dungeonName)
+		}
+		if skipped > 0 {
+			dbg.Printf("Shareware version detected; skipped %d dungeons missing from the archive.\n", skipped)
+		}
 	} else if flag.NArg() > 0 {
 		dungeonNames = flag.Args()
 	} else {
 		flag.Usage()
 		os.Exit(1)
 	}
+	if !dun.ValidScale(flagScale) {
+		log.Fatalf("invalid scale %d; expected 1, 2, 4 or 8.\n", flagScale)
+	}
+	var err error
+	background, err = dun.ParseBackground(flagBg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	dun.DefaultRenderer, err = dun.ParseRenderer(flagRenderer)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if budget.MaxWorkers < 0 {
+		log.Fatalf("invalid number of workers %d.\n", budget.MaxWorkers)
+	}
+	if flagAmbient {
+		flagObjects = true
+	}
+	if len(flagObjClass) > 0 {
+		flagObjects = true
+		var err error
+		objClasses, err = parseObjClasses(flagObjClass)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if len(flagPlace) > 0 {
+		flagObjects = true
+		placements, err = readPlacements(flagPlace)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if len(flagPath) > 0 {
+		pathStart, pathGoal, err = parsePath(flagPath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if flagScale != 1 && (flagLabels || flagMonsters || flagObjects || len(flagPath) > 0 || flagRegions || flagStairs || flagTraps) {
+		log.Fatalln("the -labels, -monsters, -objects, -path, -regions, -stairs and -traps flags require a scale of 1.")
+	}
 	for _, dungeonName := range dungeonNames {
 		err := dungeonDump(dungeonName)
 		if err != nil {
@@ -85,78 +368,737 @@ func main() {
 	}
 }
 
+// missing reports whether any DUN file of the dungeon is missing from the MPQ
+// archive, as is the case for most dungeons of the shareware version.
+func missing(dungeonName string) bool {
+	dunNames, err := dunconf.GetDunNames(dungeonName)
+	if err != nil {
+		return false
+	}
+	for _, dunName := range dunNames {
+		if mpq.Missing(dunName) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathStart and pathGoal are the cells between which the shortest walkable path
+// is marked.
+var pathStart, pathGoal [2]int
+
+// parsePath parses the start and goal cells of the path (e.g. "10,20:30,40").
+func parsePath(s string) (start, goal [2]int, err error) {
+	var cells [2][2]int
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return start, goal, fmt.Errorf("invalid path %q; expected two cells (e.g. \"10,20:30,40\").", s)
+	}
+	for i, part := range parts {
+		_, err = fmt.Sscanf(part, "%d,%d", &cells[i][0], &cells[i][1])
+		if err != nil {
+			return start, goal, fmt.Errorf("invalid cell %q of path %q; expected col,row.", part, s)
+		}
+		if cells[i][0] < 0 || cells[i][0] >= dun.ColMax || cells[i][1] < 0 || cells[i][1] >= dun.RowMax {
+			return start, goal, fmt.Errorf("cell %q of path %q outside of the dungeon map.", part, s)
+		}
+	}
+	return cells[0], cells[1], nil
+}
+
+// placements are the object and monster placements applied to the dungeons.
+var placements []dun.Placement
+
+// readPlacements returns the placements of the given CSV file.
+func readPlacements(csvPath string) (placements []dun.Placement, err error) {
+	f, err := os.Open(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return dun.ParsePlacements(f)
+}
+
+// objClasses is the set of interaction classes of the objects to draw, or nil
+// if all objects should be drawn.
+var objClasses map[dun.ObjectClass]bool
+
+// parseObjClasses returns the set of object interaction classes of the given
+// comma-separated list, in which "interactive" denotes all classes but
+// decoration.
+func parseObjClasses(list string) (classes map[dun.ObjectClass]bool, err error) {
+	classes = make(map[dun.ObjectClass]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name == "interactive" {
+			for _, class := range dun.ObjectClasses() {
+				if class.Interactive() {
+					classes[class] = true
+				}
+			}
+			continue
+		}
+		class, err := dun.ParseObjectClass(name)
+		if err != nil {
+			return nil, err
+		}
+		classes[class] = true
+	}
+	return classes, nil
+}
+
 // dumpPrefix is the name of the dump directory.
 const dumpPrefix = "_dump_/"
 
 // dungeonDump creates a dump directory and stores the dungeon, which has been
 // constructed based on the given DUN files, as a png image once for each image
-// config (pal).
+// config (pal). The images of each pal are rendered concurrently.
+//
+// When flagRaw is set, the dungeon is instead constructed from the raw pillar
+// layer stored at the given path, using the tileset of flagTileset.
 func dungeonDump(dungeonName string) (err error) {
-	dunNames, err := dunconf.GetDunNames(dungeonName)
+	var dungeon *dun.Dungeon
+	var colCount, rowCount int
+	var nameWithoutExt string
+	if flagRaw {
+		dungeon, err = parseRaw(dungeonName)
+		colCount, rowCount, nameWithoutExt = dun.ColMax, dun.RowMax, flagTileset
+		dungeonName = strings.TrimSuffix(path.Base(dungeonName), path.Ext(dungeonName))
+	} else {
+		dungeon, colCount, rowCount, nameWithoutExt, err = parseDungeon(dungeonName)
+	}
 	if err != nil {
 		return err
 	}
-	dungeon := dun.New()
-	for _, dunName := range dunNames {
-		err = dungeon.Parse(dunName)
+	minName := nameWithoutExt + ".min"
+	pillars, err := min.Parse(minName)
+	if err != nil {
+		return err
+	}
+	if flagSpecials {
+		dungeon.SetSpecials(nameWithoutExt)
+	}
+	switch flagDoors {
+	case "open":
+		dungeon.SetDoors(nameWithoutExt, dun.DoorsOpen)
+	case "closed":
+		dungeon.SetDoors(nameWithoutExt, dun.DoorsClosed)
+	case "":
+	default:
+		return fmt.Errorf("invalid door state %q.", flagDoors)
+	}
+	// Apply the placements before adding ambient objects, which avoid the
+	// cells of objects.
+	dungeon.Place(placements)
+	if flagAmbient {
+		n := dungeon.AddAmbientObjects(nameWithoutExt, rng.New(int32(flagSeed)))
+		dbg.Printf("Added %d ambient objects.\n", n)
+	}
+	if objClasses != nil {
+		dungeon.RemoveObjects(func(object dun.Object) bool {
+			return objClasses[object.Class]
+		})
+	}
+	if flagAutomap && nameWithoutExt != "town" {
+		err = dumpAutomap(dungeon, pillars[0].GetMetrics(), dungeonName, nameWithoutExt, colCount, rowCount)
+		if err != nil {
+			return err
+		}
+	}
+	// The SOL file of the level is parsed once, if required by any of the
+	// dumps below.
+	var solids []sol.Solid
+	if flagText || flagWalkMap || flagRegions || flagTraps || len(flagPath) > 0 {
+		solids, err = sol.Parse(nameWithoutExt + ".sol")
 		if err != nil {
-			return fmt.Errorf("failed to parse %q: %s", dungeonName, err)
+			return err
+		}
+	}
+	if flagText {
+		err = dumpText(dungeon, dungeonName, nameWithoutExt, colCount, rowCount, solids)
+		if err != nil {
+			return err
+		}
+	}
+	if flagWalkMap {
+		err = dumpWalkMap(dungeon, dungeonName, colCount, rowCount, solids)
+		if err != nil {
+			return err
+		}
+	}
+	if flagAudit {
+		err = dumpAudit(dungeon, dungeonName, nameWithoutExt, colCount, rowCount, pillars)
+		if err != nil {
+			return err
+		}
+	}
+	var regions []dun.Region
+	if flagRegions {
+		regions, err = dumpRegions(dungeon, dungeonName, solids)
+		if err != nil {
+			return err
 		}
 	}
-	colCount, err := dunconf.GetColCount(dungeonName)
+	var traps []dun.Trap
+	if flagTraps {
+		traps, err = dumpTraps(dungeon, dungeonName, nameWithoutExt, solids)
+		if err != nil {
+			return err
+		}
+	}
+	var pathCells [][2]int
+	if len(flagPath) > 0 {
+		pathCells, err = findPath(dungeon, solids)
+		if err != nil {
+			return err
+		}
+	}
+	var objectFrames map[string][]image.Image
+	if flagObjects {
+		objectFrames, err = getObjectFrames(dungeon, nameWithoutExt)
+		if err != nil {
+			return err
+		}
+	}
+	imgName := nameWithoutExt + ".cel"
+	relPalPaths := imgconf.GetRelPalPaths(imgName)
+	var monsters []dun.MonsterPlacement
+	var monsterFrames map[string]image.Image
+	if flagMonsters {
+		monsters, err = dungeon.Monsters()
+		if err != nil {
+			// report invalid dunMonsterIDs but draw the remaining monsters.
+			log.Println(err)
+		}
+		monsterFrames, err = getMonsterFrames(monsters, relPalPaths[0])
+		if err != nil {
+			return err
+		}
+	}
+	lvl := &level{
+		dungeon:        dungeon,
+		dungeonName:    dungeonName,
+		nameWithoutExt: nameWithoutExt,
+		colCount:       colCount,
+		rowCount:       rowCount,
+		pillars:        pillars,
+		objectFrames:   objectFrames,
+		monsters:       monsters,
+		monsterFrames:  monsterFrames,
+		regions:        regions,
+		traps:          traps,
+		path:           pathCells,
+		multiPal:       len(relPalPaths) > 1,
+	}
+	// Render the palette variants concurrently, within the limits of the
+	// budget. The parsed dungeon is shared, as rendering only reads it. Each
+	// render holds the dungeon image and a derived copy (e.g. the image with
+	// background or legend).
+	rect := dun.GetWindowRect(pillars[0].GetMetrics(), image.Rect(0, 0, colCount, rowCount), pillars[0].Height())
+	mem := 2 * budget.ImageSize(rect) / int64(flagScale*flagScale)
+	return budget.Run(len(relPalPaths), func(int) int64 { return mem }, func(i int) error {
+		return paletteDump(lvl, relPalPaths[i])
+	})
+}
+
+// parseDungeon constructs the dungeon based on the layout of the given dungeon
+// name, and returns it along with its dimensions and level name.
+func parseDungeon(dungeonName string) (dungeon *dun.Dungeon, colCount, rowCount int, nameWithoutExt string, err error) {
+	layout, err := dunconf.GetLevelLayout(dungeonName)
 	if err != nil {
-		return err
+		return nil, 0, 0, "", err
+	}
+	dungeon = dun.New()
+	for _, p := range layout.Duns {
+		err = dungeon.ParseAt(p.DunName, p.ColStart, p.RowStart)
+		if err != nil {
+			if _, ok := err.(*dun.SquareError); !ok {
+				return nil, 0, 0, "", fmt.Errorf("failed to parse %q: %s", dungeonName, err)
+			}
+			// report invalid squares but render the remaining dungeon.
+			log.Println(err)
+		}
+	}
+	colCount, rowCount, err = dun.GetLayoutSize(layout)
+	if err != nil {
+		return nil, 0, 0, "", err
 	}
-	rowCount, err := dunconf.GetRowCount(dungeonName)
+	nameWithoutExt, err = dun.GetLevelName(layout.Duns[0].DunName)
+	if err != nil {
+		return nil, 0, 0, "", err
+	}
+	return dungeon, colCount, rowCount, nameWithoutExt, nil
+}
+
+// parseRaw constructs the dungeon based on the raw pillar layer stored at
+// rawPath, using the tileset of flagTileset.
+func parseRaw(rawPath string) (dungeon *dun.Dungeon, err error) {
+	fr, err := os.Open(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fr.Close()
+	dungeon = dun.New()
+	err = dungeon.ParsePillars(fr, flagTileset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %s", rawPath, err)
+	}
+	return dungeon, nil
+}
+
+// A level holds the parsed geometry of a dungeon, and the information derived
+// from it, which is shared by the renders of each image config (pal).
+type level struct {
+	dungeon        *dun.Dungeon
+	dungeonName    string
+	nameWithoutExt string
+	colCount       int
+	rowCount       int
+	pillars        []min.Pillar
+	// objectFrames is nil unless the objects should be drawn.
+	objectFrames map[string][]image.Image
+	// monsters and monsterFrames are nil unless the monsters should be drawn.
+	monsters      []dun.MonsterPlacement
+	monsterFrames map[string]image.Image
+	regions       []dun.Region
+	traps         []dun.Trap
+	// path is nil unless a path should be marked, or if no path was found.
+	path [][2]int
+	// multiPal specifies if the level has more than one image config (pal), in
+	// which case each render is stored with the name of its pal.
+	multiPal bool
+}
+
+// paletteDump renders the dungeon of the level using the given image config
+// (pal), and stores it as a png image.
+func paletteDump(lvl *level, relPalPath string) (err error) {
+	lc, err := cel.GetLevelConfPal(lvl.nameWithoutExt, relPalPath)
+	if err != nil {
+		// skip missing palette variants.
+		return optional(err)
+	}
+	var palDir string
+	if lvl.multiPal {
+		dbg.Println("using pal:", relPalPath)
+		palDir = lvl.dungeonName + "/"
+	}
+	levelFrames, err := cel.DecodeAll(lc.CelName, lc.Conf)
 	if err != nil {
 		return err
 	}
-	nameWithoutExt, err := dun.GetLevelName(dunNames[0])
+	specialFrames, err := getSpecialFrames(lc)
 	if err != nil {
 		return err
 	}
-	minName := nameWithoutExt + ".min"
-	pillars, err := min.Parse(minName)
+	dumpDir, err := dungeonsDir()
 	if err != nil {
 		return err
 	}
-	imgName := nameWithoutExt + ".cel"
-	relPalPaths := imgconf.GetRelPalPaths(imgName)
-	for _, relPalPath := range relPalPaths {
-		conf, err := cel.GetConf(imgName, relPalPath)
+	if lvl.multiPal {
+		dumpDir += palDir
+		err = os.MkdirAll(dumpDir, 0755)
 		if err != nil {
 			return err
 		}
-		var palDir string
-		if len(relPalPaths) > 1 {
-			dbg.Println("using pal:", relPalPath)
-			palDir = dungeonName + "/"
-		}
-		levelFrames, err := cel.DecodeAll(imgName, conf)
+	}
+	dungeonPath := dumpDir + lvl.dungeonName + ".png"
+	if lvl.multiPal {
+		palName := path.Base(relPalPath)
+		palNameWithoutExt := palName[:len(palName)-len(path.Ext(palName))]
+		dungeonPath = dumpDir + lvl.dungeonName + "_" + palNameWithoutExt + ".png"
+	}
+	if flagRenderBench {
+		err = benchRenderers(lvl, levelFrames, specialFrames)
 		if err != nil {
 			return err
 		}
-		dumpDir := path.Clean(dumpPrefix+"_dungeons_/") + "/" + palDir
-		// prevent directory traversal
-		if !strings.HasPrefix(dumpDir, dumpPrefix) {
-			return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+	}
+	dbg.Println("Creating image:", path.Base(dungeonPath))
+	var img image.Image
+	if imgcache.Enabled() {
+		// Use the pillar images cached on disk, keyed on the content of the
+		// MIN, CEL and PAL files.
+		levelKey, err := cel.ContentKey(lc.CelName, lc.Conf)
+		if err != nil {
+			return err
 		}
-		err = os.MkdirAll(dumpDir, 0755)
+		pillarImgs, err := min.CachedImages(lvl.pillars, levelFrames, levelKey)
 		if err != nil {
 			return err
 		}
-		dungeonPath := dumpDir + dungeonName + ".png"
-		if len(relPalPaths) > 1 {
-			palName := path.Base(relPalPath)
-			palNameWithoutExt := palName[:len(palName)-len(path.Ext(palName))]
-			dungeonPath = dumpDir + dungeonName + "_" + palNameWithoutExt + ".png"
+		img = lvl.dungeon.ImageWithPillarImages(lvl.colCount, lvl.rowCount, lvl.pillars, pillarImgs, specialFrames, flagScale)
+	} else {
+		img = lvl.dungeon.ImageWithSpecials(lvl.colCount, lvl.rowCount, lvl.pillars, levelFrames, specialFrames, flagScale)
+	}
+	m := lvl.pillars[0].GetMetrics()
+	pillarHeight := lvl.pillars[0].Height()
+	if flagObjects {
+		unknown := lvl.dungeon.DrawObjects(img.(draw.Image), m, lvl.nameWithoutExt, lvl.colCount, lvl.rowCount, pillarHeight, lvl.objectFrames)
+		if len(unknown) > 0 {
+			log.Printf("unknown object idxs in %q: %v\n", lvl.dungeonName, unknown)
+		}
+	}
+	if flagMonsters {
+		unknown := dun.DrawMonsters(img.(draw.Image), m, lvl.monsters, pillarHeight, lvl.monsterFrames)
+		if len(unknown) > 0 {
+			log.Printf("unknown monsters in %q: %v\n", lvl.dungeonName, unknown)
 		}
-		dbg.Println("Creating image:", path.Base(dungeonPath))
-		img := dungeon.Image(colCount, rowCount, pillars, levelFrames)
-		err = imgutil.WriteFile(dungeonPath, img)
+	}
+	if flagStairs {
+		stairs := lvl.dungeon.Stairs(lvl.nameWithoutExt)
+		dun.MarkStairs(img.(draw.Image), m, stairs, pillarHeight)
+	}
+	if flagRegions {
+		dun.MarkRegions(img.(draw.Image), m, lvl.regions, pillarHeight)
+	}
+	if flagTraps {
+		dun.MarkTraps(img.(draw.Image), m, lvl.traps, pillarHeight)
+	}
+	if len(lvl.path) > 0 {
+		dun.MarkPath(img.(draw.Image), m, lvl.path, pillarHeight)
+	}
+	if flagLabels && lvl.nameWithoutExt == "town" {
+		dun.LabelTowners(img.(draw.Image), m, dun.Towners, pillarHeight)
+	}
+	// The tile pyramid keeps its transparent background, as map viewers
+	// provide their own.
+	tileImg := img
+	if background != nil {
+		img = dun.WithBackground(img, background)
+	}
+	if flagLegend {
+		img = dun.AddLegend(img, legendTitles(lvl, relPalPath), legendEntries(lvl))
+	}
+	// The source of the dungeon is the dungeon name of the ini file, or the
+	// raw pillar layer.
+	meta := pngprof.Source(lvl.dungeonName, relPalPath)
+	err = pngprof.WriteFileMeta(dungeonPath, img, meta)
+	if err != nil {
+		return err
+	}
+	if flagTiles {
+		tilesDir := strings.TrimSuffix(dungeonPath, ".png") + "_tiles/"
+		_, err = tiles.WritePyramid(tilesDir, tileImg)
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// benchRenderers renders the dungeon of the level using each renderer, and
+// reports the time spent by each. The renders are compared to the render of the
+// "draw" renderer, which is the reference of the other renderers.
+func benchRenderers(lvl *level, levelFrames, specialFrames []image.Image) (err error) {
+	ref, err := dun.ParseRenderer("draw")
+	if err != nil {
+		return err
+	}
+	refImg := lvl.dungeon.ImageWithRenderer(ref, lvl.colCount, lvl.rowCount, lvl.pillars, levelFrames, specialFrames, flagScale).(*image.RGBA)
+	for _, name := range dun.RendererNames() {
+		r, err := dun.ParseRenderer(name)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		img := lvl.dungeon.ImageWithRenderer(r, lvl.colCount, lvl.rowCount, lvl.pillars, levelFrames, specialFrames, flagScale).(*image.RGBA)
+		dbg.Printf("renderer %q: %v\n", name, time.Since(start))
+		if !bytes.Equal(img.Pix, refImg.Pix) {
+			return fmt.Errorf("render of %q using renderer %q differs from renderer %q.", lvl.dungeonName, name, "draw")
+		}
+	}
+	return nil
+}
+
+// legendTitles returns the title lines of the legend strip, which describe the
+// dungeon and the image config (pal) of the dungeon image.
+func legendTitles(lvl *level, relPalPath string) (titles []string) {
+	titles = append(titles, fmt.Sprintf("%s (level %s, %dx%d cells)", lvl.dungeonName, lvl.nameWithoutExt, lvl.colCount, lvl.rowCount))
+	titles = append(titles, "pal: "+relPalPath)
+	if flagObjects {
+		titles = append(titles, fmt.Sprintf("objects: %d", lvl.dungeon.ObjectCount()))
+	}
+	if flagMonsters {
+		titles = append(titles, fmt.Sprintf("monsters: %d", len(lvl.monsters)))
+	}
+	if flagRegions {
+		titles = append(titles, fmt.Sprintf("walkable regions: %d", len(lvl.regions)))
+	}
+	if len(lvl.path) > 0 {
+		titles = append(titles, fmt.Sprintf("path: %d cells", len(lvl.path)))
+	}
+	return titles
+}
+
+// legendEntries returns the legend entries of the markers drawn on the dungeon
+// image.
+func legendEntries(lvl *level) (entries []dun.LegendEntry) {
+	if flagStairs {
+		entries = append(entries, dun.StairsLegend()...)
+	}
+	if flagTraps {
+		entries = append(entries, dun.TrapsLegend()...)
+	}
+	if len(lvl.path) > 0 {
+		entries = append(entries, dun.PathLegend()...)
+	}
+	if flagLabels && lvl.nameWithoutExt == "town" {
+		entries = append(entries, dun.LegendEntry{Color: dun.TownerColor, Text: "NPC"})
+	}
+	return entries
+}
+
+// getSpecialFrames decodes the frames of the special CEL image of the level,
+// using the image config (pal) of the level conf. It returns no frames if
+// neither the special CEL overlays nor the door states should be drawn, or if
+// the level has no special CEL image.
+func getSpecialFrames(lc *cel.LevelConf) (specialFrames []image.Image, err error) {
+	if !flagSpecials && len(flagDoors) == 0 {
+		return nil, nil
+	}
+	if lc.SpecialConf == nil {
+		return nil, nil
+	}
+	specialFrames, err = cel.DecodeAll(lc.SpecialName, lc.SpecialConf)
+	if err != nil {
+		// render without the special CEL overlays if the image is missing.
+		return nil, optional(err)
+	}
+	return specialFrames, nil
+}
+
+// dungeonsDir creates the directory of the dumped dungeons, below the dump
+// prefix, and returns its path with a trailing slash.
+func dungeonsDir() (dumpDir string, err error) {
+	dumpDir = path.Clean(dumpPrefix+"_dungeons_/") + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		return "", fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+	}
+	err = os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		return "", err
+	}
+	return dumpDir, nil
+}
+
+// optional returns nil and reports err as a warning if err is caused by a
+// missing file, unless flagStrict is set. Any other error is returned as is.
+func optional(err error) error {
+	if err == nil || flagStrict || !os.IsNotExist(err) {
+		return err
+	}
+	log.Println("warning:", err)
+	return nil
+}
+
+// dumpRegions segments the dungeon into connected walkable regions, based on
+// the SOL file of the level, and stores the regions as JSON.
+func dumpRegions(dungeon *dun.Dungeon, dungeonName string, solids []sol.Solid) (regions []dun.Region, err error) {
+	regions = dungeon.Regions(solids)
+	dumpDir, err := dungeonsDir()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := json.MarshalIndent(regions, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	err = atomicfile.WriteFile(dumpDir+dungeonName+"_regions.json", append(buf, '\n'))
+	if err != nil {
+		return nil, err
+	}
+	return regions, nil
+}
+
+// dumpTraps locates the wall traps of the dungeon and their triggers, and
+// stores them as JSON.
+func dumpTraps(dungeon *dun.Dungeon, dungeonName, nameWithoutExt string, solids []sol.Solid) (traps []dun.Trap, err error) {
+	traps = dungeon.Traps(nameWithoutExt, solids)
+	dumpDir, err := dungeonsDir()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := json.MarshalIndent(traps, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	err = atomicfile.WriteFile(dumpDir+dungeonName+"_traps.json", append(buf, '\n'))
+	if err != nil {
+		return nil, err
+	}
+	return traps, nil
+}
+
+// findPath locates the shortest walkable path between pathStart and pathGoal,
+// based on the SOL file of the level. Unreachable goals are reported, but the
+// dungeon is rendered without the path, as they may point out walls which are
+// misinterpreted as walkable or vice versa.
+func findPath(dungeon *dun.Dungeon, solids []sol.Solid) (cells [][2]int, err error) {
+	cells, err = dungeon.Path(pathStart, pathGoal, solids)
+	if err != nil {
+		log.Println("warning:", err)
+		return nil, nil
+	}
+	dbg.Printf("Found a path of %d cells.\n", len(cells))
+	return cells, nil
+}
+
+// dumpAudit stores a draw order audit image of the dungeon, using the first
+// image config (pal) of the level, and stores the pillars drawn out of order
+// as JSON.
+func dumpAudit(dungeon *dun.Dungeon, dungeonName, nameWithoutExt string, colCount, rowCount int, pillars []min.Pillar) (err error) {
+	lc, err := cel.GetLevelConf(nameWithoutExt)
+	if err != nil {
+		return err
+	}
+	levelFrames, err := cel.DecodeAll(lc.CelName, lc.Conf)
+	if err != nil {
+		return err
+	}
+	img, violations := dungeon.AuditImage(colCount, rowCount, pillars, levelFrames)
+	dbg.Printf("Found %d pillars drawn out of order.\n", len(violations))
+	dumpDir, err := dungeonsDir()
+	if err != nil {
+		return err
+	}
+	if violations == nil {
+		violations = []dun.OrderViolation{}
+	}
+	buf, err := json.MarshalIndent(violations, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = atomicfile.WriteFile(dumpDir+dungeonName+"_audit.json", append(buf, '\n'))
+	if err != nil {
+		return err
+	}
+	return pngprof.WriteFileMeta(dumpDir+dungeonName+"_audit.png", img, pngprof.Source(dungeonName, lc.RelPalPath))
+}
+
+// dumpAutomap stores the automap of the dungeon as an SVG image, based on the
+// AMP file of the level.
+func dumpAutomap(dungeon *dun.Dungeon, m min.Metrics, dungeonName, nameWithoutExt string, colCount, rowCount int) (err error) {
+	tiles, err := amp.Parse(nameWithoutExt + ".amp")
+	if err != nil {
+		return err
+	}
+	dumpDir, err := dungeonsDir()
+	if err != nil {
+		return err
+	}
+	f, err := atomicfile.Create(dumpDir + dungeonName + "_automap.svg")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = dungeon.WriteAutomapSVG(f, m, colCount, rowCount, tiles)
+	if err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// dumpText stores a text map of the dungeon, based on the SOL file of the
+// level.
+func dumpText(dungeon *dun.Dungeon, dungeonName, nameWithoutExt string, colCount, rowCount int, solids []sol.Solid) (err error) {
+	dumpDir, err := dungeonsDir()
+	if err != nil {
+		return err
+	}
+	text := dungeon.Text(colCount, rowCount, nameWithoutExt, solids)
+	return atomicfile.WriteFile(dumpDir+dungeonName+".txt", []byte(text))
+}
+
+// dumpWalkMap stores a walk map of the dungeon as a png image, based on the
+// SOL file of the level, and stores its cell counts as JSON.
+func dumpWalkMap(dungeon *dun.Dungeon, dungeonName string, colCount, rowCount int, solids []sol.Solid) (err error) {
+	img, stats := dungeon.WalkMap(colCount, rowCount, solids)
+	dbg.Printf("Walkable cells: %d of %d (%.1f%%).\n", stats.Floor, stats.Cells, 100*stats.Openness())
+	dumpDir, err := dungeonsDir()
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(stats, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = atomicfile.WriteFile(dumpDir+dungeonName+"_walk.json", append(buf, '\n'))
+	if err != nil {
+		return err
+	}
+	return pngprof.WriteFileMeta(dumpDir+dungeonName+"_walk.png", img, pngprof.Source(dungeonName, ""))
+}
+
+// getObjectFrames decodes the frames of the CEL images of the objects placed
+// in the dungeon, using the first image config (pal) of each CEL image.
+func getObjectFrames(dungeon *dun.Dungeon, nameWithoutExt string) (objectFrames map[string][]image.Image, err error) {
+	objectFrames = make(map[string][]image.Image)
+	for _, celName := range dungeon.ObjectCelNames(nameWithoutExt) {
+		relPalPath := imgconf.GetRelPalPaths(celName)[0]
+		conf, err := cel.GetConf(celName, relPalPath)
+		if err != nil {
+			return nil, optional(err)
+		}
+		frames, err := cel.DecodeAll(celName, conf)
+		if err != nil {
+			// objects of missing CEL images are reported as unknown.
+			err = optional(err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		objectFrames[celName] = frames
+	}
+	return objectFrames, nil
+}
+
+// getMonsterFrames decodes the first frame of the standing animation facing
+// south of each monster graphics of the placed monsters, using the given image
+// config (pal) of the level.
+func getMonsterFrames(monsters []dun.MonsterPlacement, relPalPath string) (monsterFrames map[string]image.Image, err error) {
+	monsterFrames = make(map[string]image.Image)
+	for _, p := range monsters {
+		gfxName := p.Type.Graphics()
+		if _, ok := monsterFrames[gfxName]; ok || p.Unique {
+			continue
+		}
+		mon := mongfx.Monster(gfxName)
+		if !mon.HasAnim(mongfx.Stand) {
+			// monsters of unknown graphics are reported as unknown.
+			continue
+		}
+		archiveName := mon.ArchiveName(mongfx.Stand)
+		if !imgarchive.IsExtracted(archiveName) {
+			err = imgarchive.Extract(archiveName)
+			if err != nil {
+				err = optional(err)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		imgName := mon.ImgName(mongfx.Stand, 0)
+		conf, err := cel.GetConf(imgName, relPalPath)
+		if err != nil {
+			err = optional(err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		frames, err := cl2.DecodeAll(imgName, conf)
+		if err != nil {
+			err = optional(err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(frames) > 0 {
+			monsterFrames[gfxName] = frames[0]
+		}
+	}
+	return monsterFrames, nil
+}
@@ -0,0 +1,236 @@
+// dun_find is a tool for locating the DUN files which place a given object or
+// monster, and optionally storing a cropped png image of each occurrence.
+//
+// Usage:
+//
+//    dun_find [OPTION]... -obj=ID|-mon=ID
+//
+// Flags:
+//
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -crop=false
+//            Store a cropped png image of the surroundings of each occurrence.
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mon=-1
+//            Monster ID (dunMonsterID) to locate.
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -obj=-1
+//            Object ID (dunObjectID) to locate.
+//    -radius=4
+//            Number of cells surrounding each occurrence in cropped images.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagCrop specifies if a cropped image of each occurrence should be
+	// stored or not.
+	flagCrop bool
+	// flagMon specifies the monster ID to locate.
+	flagMon int
+	// flagObj specifies the object ID to locate.
+	flagObj int
+	// flagRadius specifies the number of cells surrounding each occurrence in
+	// cropped images.
+	flagRadius int
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.BoolVar(&flagCrop, "crop", false, "Store a cropped png image of the surroundings of each occurrence.")
+	flag.IntVar(&flagMon, "mon", -1, "Monster ID (dunMonsterID) to locate.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.IntVar(&flagObj, "obj", -1, "Object ID (dunObjectID) to locate.")
+	flag.IntVar(&flagRadius, "radius", 4, "Number of cells surrounding each occurrence in cropped images.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... -obj=ID|-mon=ID\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	// key is the dungeon key of the cell information to locate.
+	var key string
+	var id int
+	switch {
+	case flagObj > 0 && flagMon > 0:
+		log.Fatalln("the -obj and -mon flags are mutually exclusive.")
+	case flagObj > 0:
+		key, id = "dunObjectID", flagObj
+	case flagMon > 0:
+		key, id = "dunMonsterID", flagMon
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+	// Skip the DUN files missing from the shareware version silently.
+	shareware := mpq.IsShareware()
+	err := mpq.AllFunc(func(dunName string) error {
+		if path.Ext(dunName) != ".dun" {
+			return nil
+		}
+		if shareware && mpq.Missing(dunName) {
+			return nil
+		}
+		err := find(dunName, key, id)
+		if err != nil {
+			// report the DUN file but search the remaining ones.
+			log.Println(err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// find prints the coordinates of the cells of the given DUN file whose key
+// holds id, and stores a cropped image of each of them if flagCrop is set. The
+// coordinates are relative to the top of the DUN file.
+func find(dunName, key string, id int) (err error) {
+	dungeon := dun.New()
+	err = dungeon.ParseAt(dunName, 0, 0)
+	if err != nil {
+		if _, ok := err.(*dun.SquareError); !ok {
+			return err
+		}
+		// report invalid squares but search the remaining DUN file.
+		log.Println(err)
+	}
+	colCount, rowCount, err := dun.GetSize(dunName)
+	if err != nil {
+		return err
+	}
+	for row := 0; row < rowCount; row++ {
+		for col := 0; col < colCount; col++ {
+			if dungeon[col][row][key] != id {
+				continue
+			}
+			desc := ""
+			if key == "dunObjectID" && id < len(dun.Objects) {
+				object := dun.Objects[id]
+				desc = fmt.Sprintf(" (%s, %s)", object.Name, object.Class)
+			}
+			if key == "dunMonsterID" {
+				if p, err := dun.DecodeMonsterID(id); err == nil {
+					desc = fmt.Sprintf(" (%s)", p)
+				}
+			}
+			fmt.Printf("%s: col %d, row %d%s\n", dunName, col, row, desc)
+			if flagCrop {
+				err = crop(dungeon, dunName, col, row)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// crop stores an image of the cells surrounding the given cell of the dungeon,
+// using the first image config (pal) of the level of the DUN file.
+func crop(dungeon *dun.Dungeon, dunName string, col, row int) (err error) {
+	nameWithoutExt, err := dun.GetLevelName(dunName)
+	if err != nil {
+		return err
+	}
+	lvl, err := getLevel(nameWithoutExt)
+	if err != nil {
+		return err
+	}
+	dumpDir := path.Clean(dumpPrefix+"_find_/") + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+	}
+	err = os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		return err
+	}
+	dunNameWithoutExt := dunName[:len(dunName)-len(path.Ext(dunName))]
+	imgPath := fmt.Sprintf("%s%s_%d_%d.png", dumpDir, dunNameWithoutExt, col, row)
+	img := dungeon.ImageRect(col-flagRadius, row-flagRadius, col+flagRadius+1, row+flagRadius+1, lvl.pillars, lvl.levelFrames, nil, 1)
+	return pngprof.WriteFileMeta(imgPath, img, pngprof.Source(dunName, lvl.relPalPath))
+}
+
+// A level holds the pillars and level frames of a level, which are shared by
+// the DUN files of the level.
+type level struct {
+	pillars     []min.Pillar
+	levelFrames []image.Image
+	relPalPath  string
+}
+
+// levels is a map from level name to the parsed level.
+var levels = make(map[string]*level)
+
+// getLevel returns the pillars and level frames of the given level (e.g.
+// "l1"), using its first image config (pal).
+func getLevel(nameWithoutExt string) (lvl *level, err error) {
+	if lvl, ok := levels[nameWithoutExt]; ok {
+		return lvl, nil
+	}
+	pillars, err := min.Parse(nameWithoutExt + ".min")
+	if err != nil {
+		return nil, err
+	}
+	lc, err := cel.GetLevelConf(nameWithoutExt)
+	if err != nil {
+		return nil, err
+	}
+	levelFrames, err := cel.DecodeAll(lc.CelName, lc.Conf)
+	if err != nil {
+		return nil, err
+	}
+	lvl = &level{pillars: pillars, levelFrames: levelFrames, relPalPath: lc.RelPalPath}
+	levels[nameWithoutExt] = lvl
+	return lvl, nil
+}
@@ -0,0 +1,341 @@
+// dun_poster is a tool for rendering several dungeons and composing them into a
+// single labeled poster image (contact sheet).
+//
+// Usage:
+//
+//    dun_poster [OPTION]... [name]...
+//
+// Flags:
+//
+//    -a=false
+//            Include all dungeons of the ini file.
+//    -cache-mem=0
+//            Memory limit of the files (e.g. MIN, TIL and PAL files) kept in memory while rendering (e.g. "64M"); disabled if 0.
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -cols=8
+//            Number of dungeons per row of the poster.
+//    -dir=""
+//            Include each DUN file of the given MPQ directory (e.g. "levels/l1data/").
+//    -gamma=1
+//            Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -o="_dump_/_dungeons_poster_.png"
+//            Output path of the poster image.
+//    -renderer="draw"
+//            Renderer backend used to draw the dungeon images: "draw" (image/draw) or "pix" (direct pixel copying).
+//    -scale=8
+//            Render each dungeon at 1/scale of its size (1, 2, 4 or 8).
+//    -srgb=false
+//            Tag the exported PNG images as sRGB.
+package main
+
+import (
+	"flag"
+	"fmt"
+	dbg "fmt"
+	"image"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/configs/dunconf"
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/gallery"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagAll specifies if all dungeons of the ini file should be included or
+	// not.
+	flagAll bool
+	// flagCacheMem specifies the memory limit of the files kept in memory.
+	flagCacheMem budget.Size
+	// flagCols specifies the number of dungeons per row of the poster.
+	flagCols int
+	// flagDir specifies the MPQ directory whose DUN files should be included.
+	flagDir string
+	// flagOutput specifies the output path of the poster image.
+	flagOutput string
+	// flagRenderer specifies the renderer backend used to draw the dungeon
+	// images.
+	flagRenderer string
+	// flagScale specifies each dungeon to be rendered at 1/scale of its size.
+	flagScale int
+)
+
+func init() {
+	flag.Usage = usage
+	flag.BoolVar(&flagAll, "a", false, "Include all dungeons of the ini file.")
+	flag.IntVar(&flagCols, "cols", 8, "Number of dungeons per row of the poster.")
+	flag.StringVar(&flagDir, "dir", "", `Include each DUN file of the given MPQ directory (e.g. "levels/l1data/").`)
+	flag.Var(&flagCacheMem, "cache-mem", `Memory limit of the files (e.g. MIN, TIL and PAL files) kept in memory while rendering (e.g. "64M"); disabled if 0.`)
+	flag.Float64Var(&pngprof.Gamma, "gamma", 1, "Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).")
+	flag.StringVar(&flagOutput, "o", "_dump_/_dungeons_poster_.png", "Output path of the poster image.")
+	flag.StringVar(&flagRenderer, "renderer", "draw", `Renderer backend used to draw the dungeon images: "draw" (image/draw) or "pix" (direct pixel copying).`)
+	flag.IntVar(&flagScale, "scale", 8, "Render each dungeon at 1/scale of its size (1, 2, 4 or 8).")
+	flag.BoolVar(&pngprof.SRGB, "srgb", false, "Tag the exported PNG images as sRGB.")
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.StringVar(&dunconf.IniPath, "dunini", "dun.ini", "Path to an ini file containing starting coordinate information.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	budget.RegisterFlags()
+	flag.Parse()
+	mpq.CacheSize = int64(flagCacheMem)
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = dunconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... [name]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if !dun.ValidScale(flagScale) {
+		log.Fatalf("invalid scale %d; expected 1, 2, 4 or 8.\n", flagScale)
+	}
+	if budget.MaxWorkers < 0 {
+		log.Fatalf("invalid number of workers %d.\n", budget.MaxWorkers)
+	}
+	if flagCols < 1 {
+		log.Fatalf("invalid number of cols %d.\n", flagCols)
+	}
+	var err error
+	dun.DefaultRenderer, err = dun.ParseRenderer(flagRenderer)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var layouts []dunconf.Layout
+	switch {
+	case flagAll:
+		// Skip the dungeons missing from the shareware version.
+		shareware := mpq.IsShareware()
+		skipped := 0
+		for _, dungeonName := range dunconf.DungeonNames() {
+			if shareware && missing(dungeonName) {
+				skipped++
+				continue
+			}
+			layout, err := dunconf.GetLevelLayout(dungeonName)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			layouts = append(layouts, layout)
+		}
+		if skipped > 0 {
+			dbg.Printf("Shareware version detected; skipped %d dungeons missing from the archive.\n", skipped)
+		}
+	case len(flagDir) > 0:
+		var err error
+		layouts, err = dirLayouts(flagDir)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	case flag.NArg() > 0:
+		for _, dungeonName := range flag.Args() {
+			layout, err := dunconf.GetLevelLayout(dungeonName)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			layouts = append(layouts, layout)
+		}
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+	// Render the dungeons concurrently, within the limits of the budget.
+	imgs := make([]image.Image, len(layouts))
+	mem := func(i int) int64 {
+		return renderSize(layouts[i])
+	}
+	budget.Run(len(layouts), mem, func(i int) error {
+		dbg.Println("Rendering dungeon:", layouts[i].Name)
+		img, err := render(layouts[i])
+		if err != nil {
+			// report the dungeon but render the remaining ones.
+			log.Println(err)
+		}
+		imgs[i] = img
+		return nil
+	})
+	var rows [][]gallery.Tile
+	for i, layout := range layouts {
+		if i%flagCols == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], gallery.Tile{Label: layout.Name, Img: imgs[i]})
+	}
+	err = os.MkdirAll(path.Dir(flagOutput), 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = pngprof.WriteFile(flagOutput, gallery.Grid(rows))
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// missing reports whether any DUN file of the dungeon is missing from the MPQ
+// archive, as is the case for most dungeons of the shareware version.
+func missing(dungeonName string) bool {
+	dunNames, err := dunconf.GetDunNames(dungeonName)
+	if err != nil {
+		return false
+	}
+	for _, dunName := range dunNames {
+		if mpq.Missing(dunName) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirLayouts returns a layout for each DUN file of the given MPQ directory,
+// which places the DUN file on its own at the top of the dungeon map.
+func dirLayouts(dir string) (layouts []dunconf.Layout, err error) {
+	dir = strings.TrimSuffix(dir, "/") + "/"
+	err = mpq.AllFunc(func(name string) error {
+		if !strings.HasSuffix(name, ".dun") {
+			return nil
+		}
+		relPath, err := mpq.GetRelPath(name)
+		if err != nil {
+			return err
+		}
+		if path.Dir(relPath)+"/" != dir {
+			return nil
+		}
+		layout := dunconf.Layout{
+			Name: name,
+			Duns: []dunconf.Placement{{DunName: name}},
+		}
+		layouts = append(layouts, layout)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(layouts) == 0 {
+		return nil, fmt.Errorf("no DUN files found in %q.", dir)
+	}
+	return layouts, nil
+}
+
+// A level holds the pillars and level frames of a level, which are shared by
+// the dungeons of the level.
+type level struct {
+	pillars     []min.Pillar
+	levelFrames []image.Image
+}
+
+// levels is a map from level name to the parsed level, which is protected by
+// levelsMu as the dungeons are rendered concurrently.
+var (
+	levels   = make(map[string]*level)
+	levelsMu sync.Mutex
+)
+
+// getLevel returns the pillars and level frames of the given level (e.g.
+// "l1"), using its first image config (pal).
+func getLevel(nameWithoutExt string) (lvl *level, err error) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	if lvl, ok := levels[nameWithoutExt]; ok {
+		return lvl, nil
+	}
+	pillars, err := min.Parse(nameWithoutExt + ".min")
+	if err != nil {
+		return nil, err
+	}
+	lc, err := cel.GetLevelConf(nameWithoutExt)
+	if err != nil {
+		return nil, err
+	}
+	levelFrames, err := cel.DecodeAll(lc.CelName, lc.Conf)
+	if err != nil {
+		return nil, err
+	}
+	lvl = &level{pillars: pillars, levelFrames: levelFrames}
+	levels[nameWithoutExt] = lvl
+	return lvl, nil
+}
+
+// renderSize returns the estimated memory use of rendering the dungeon of the
+// layout; 0 if unknown, in which case render reports the error.
+func renderSize(layout dunconf.Layout) int64 {
+	colCount, rowCount, err := dun.GetLayoutSize(layout)
+	if err != nil {
+		return 0
+	}
+	nameWithoutExt, err := dun.GetLevelName(layout.Duns[0].DunName)
+	if err != nil {
+		return 0
+	}
+	lvl, err := getLevel(nameWithoutExt)
+	if err != nil {
+		return 0
+	}
+	rect := dun.GetWindowRect(lvl.pillars[0].GetMetrics(), image.Rect(0, 0, colCount, rowCount), lvl.pillars[0].Height())
+	return budget.ImageSize(rect) / int64(flagScale*flagScale)
+}
+
+// render returns the image of the dungeon constructed based on the layout.
+func render(layout dunconf.Layout) (img image.Image, err error) {
+	dungeon := dun.New()
+	for _, p := range layout.Duns {
+		err = dungeon.ParseAt(p.DunName, p.ColStart, p.RowStart)
+		if err != nil {
+			if _, ok := err.(*dun.SquareError); !ok {
+				return nil, fmt.Errorf("failed to parse %q: %s", layout.Name, err)
+			}
+			// report invalid squares but render the remaining dungeon.
+			log.Println(err)
+		}
+	}
+	colCount, rowCount, err := dun.GetLayoutSize(layout)
+	if err != nil {
+		return nil, err
+	}
+	nameWithoutExt, err := dun.GetLevelName(layout.Duns[0].DunName)
+	if err != nil {
+		return nil, err
+	}
+	lvl, err := getLevel(nameWithoutExt)
+	if err != nil {
+		return nil, err
+	}
+	return dungeon.Image(colCount, rowCount, lvl.pillars, lvl.levelFrames, flagScale), nil
+}
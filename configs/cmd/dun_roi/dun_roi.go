@@ -0,0 +1,351 @@
+// dun_roi is a tool for locating a quest set-piece DUN file within stitched or
+// generated dungeons, and storing a cropped and annotated png image of the
+// region of interest surrounding it at full resolution.
+//
+// Usage:
+//
+//    dun_roi [OPTION]... -piece=name.dun [name]...
+//
+// Flags:
+//
+//    -all=false
+//            Store an image of each location of the piece, instead of only the best one.
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -j=0
+//            Alias of -max-workers.
+//    -margin=2
+//            Number of cells surrounding the piece in the cropped images.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -minscore=1
+//            Minimum fraction of the pillars of the piece which must match the dungeon (e.g. 0.9).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -piece=""
+//            Quest set-piece DUN file to locate (e.g. "banner1.dun").
+//    -raw=false
+//            Treat the arguments as raw pillar layers dumped from the game process.
+//    -tileset="l1"
+//            Level (e.g. "l1") whose tileset is used to render raw pillar layers.
+package main
+
+import (
+	"flag"
+	dbg "fmt"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/configs/dunconf"
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/label"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagAll specifies if an image of each location of the piece should be
+	// stored, instead of only the best one.
+	flagAll bool
+	// flagMargin specifies the number of cells surrounding the piece in the
+	// cropped images.
+	flagMargin int
+	// flagMinScore specifies the minimum fraction of the pillars of the piece
+	// which must match the dungeon.
+	flagMinScore float64
+	// flagPiece specifies the quest set-piece DUN file to locate.
+	flagPiece string
+	// flagRaw specifies if the arguments are raw pillar layers dumped from the
+	// game process, rather than dungeon names.
+	flagRaw bool
+	// flagTileset specifies the level whose tileset is used to render raw
+	// pillar layers.
+	flagTileset string
+)
+
+func init() {
+	flag.Usage = usage
+	flag.BoolVar(&flagAll, "all", false, "Store an image of each location of the piece, instead of only the best one.")
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.StringVar(&dunconf.IniPath, "dunini", "dun.ini", "Path to an ini file containing starting coordinate information.")
+	flag.IntVar(&flagMargin, "margin", 2, "Number of cells surrounding the piece in the cropped images.")
+	flag.Float64Var(&flagMinScore, "minscore", 1, "Minimum fraction of the pillars of the piece which must match the dungeon (e.g. 0.9).")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.StringVar(&flagPiece, "piece", "", `Quest set-piece DUN file to locate (e.g. "banner1.dun").`)
+	flag.BoolVar(&flagRaw, "raw", false, "Treat the arguments as raw pillar layers dumped from the game process.")
+	flag.StringVar(&flagTileset, "tileset", "l1", `Level (e.g. "l1") whose tileset is used to render raw pillar layers.`)
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = dunconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... -piece=name.dun [name]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if len(flagPiece) == 0 || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if flagMinScore <= 0 || flagMinScore > 1 {
+		log.Fatalf("invalid minimum score %g; expected a fraction in (0, 1].\n", flagMinScore)
+	}
+	if flagMargin < 0 {
+		log.Fatalf("invalid margin %d.\n", flagMargin)
+	}
+	piece, err := parsePiece(flagPiece)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, dungeonName := range flag.Args() {
+		err := roiDump(dungeonName, piece)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// A setPiece holds the pillars of the quest set-piece DUN file to locate.
+type setPiece struct {
+	dunName        string
+	dungeon        *dun.Dungeon
+	colCount       int
+	rowCount       int
+	nameWithoutExt string
+}
+
+// parsePiece parses the given quest set-piece DUN file, placed at the top of
+// an otherwise empty dungeon.
+func parsePiece(dunName string) (piece *setPiece, err error) {
+	piece = &setPiece{dunName: dunName, dungeon: dun.New()}
+	err = piece.dungeon.ParseAt(dunName, 0, 0)
+	if err != nil {
+		if _, ok := err.(*dun.SquareError); !ok {
+			return nil, err
+		}
+		// report invalid squares but locate the remaining piece.
+		log.Println(err)
+	}
+	piece.colCount, piece.rowCount, err = dun.GetSize(dunName)
+	if err != nil {
+		return nil, err
+	}
+	piece.nameWithoutExt, err = dun.GetLevelName(dunName)
+	if err != nil {
+		return nil, err
+	}
+	return piece, nil
+}
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// roiDump locates the piece within the given dungeon, and stores a cropped
+// and annotated image of the region of interest surrounding the best location
+// of the piece, or each location if flagAll is set.
+//
+// When flagRaw is set, the dungeon is instead constructed from the raw pillar
+// layer stored at the given path, using the tileset of flagTileset.
+func roiDump(dungeonName string, piece *setPiece) (err error) {
+	var dungeon *dun.Dungeon
+	var nameWithoutExt string
+	if flagRaw {
+		dungeon, err = parseRaw(dungeonName)
+		nameWithoutExt = flagTileset
+		dungeonName = strings.TrimSuffix(path.Base(dungeonName), path.Ext(dungeonName))
+	} else {
+		dungeon, nameWithoutExt, err = parseDungeon(dungeonName)
+	}
+	if err != nil {
+		return err
+	}
+	if nameWithoutExt != piece.nameWithoutExt {
+		return fmt.Errorf("level mismatch of %q; the piece %q belongs to level %q, not %q.", dungeonName, piece.dunName, piece.nameWithoutExt, nameWithoutExt)
+	}
+	matches := dungeon.Locate(piece.dungeon, piece.colCount, piece.rowCount, flagMinScore)
+	if len(matches) == 0 {
+		return fmt.Errorf("unable to locate %q in %q.", piece.dunName, dungeonName)
+	}
+	if !flagAll {
+		matches = matches[:1]
+	}
+	lvl, err := getLevel(nameWithoutExt)
+	if err != nil {
+		return err
+	}
+	dumpDir := path.Clean(dumpPrefix+"_roi_/") + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+	}
+	err = os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		return err
+	}
+	pieceNameWithoutExt := strings.TrimSuffix(piece.dunName, path.Ext(piece.dunName))
+	for _, m := range matches {
+		fmt.Printf("%s: %s at col %d, row %d (%d of %d pillars)\n", dungeonName, piece.dunName, m.Col, m.Row, m.Matched, m.Total)
+		imgPath := fmt.Sprintf("%s%s_%s_%d_%d.png", dumpDir, dungeonName, pieceNameWithoutExt, m.Col, m.Row)
+		dbg.Println("Creating image:", path.Base(imgPath))
+		img := roiImage(dungeon, lvl, piece, m)
+		err = pngprof.WriteFileMeta(imgPath, img, pngprof.Source(dungeonName, lvl.relPalPath))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Colors of the annotations of the region of interest.
+var (
+	// pieceColor tints the cells of the piece whose pillars match the
+	// dungeon.
+	pieceColor = color.NRGBA{R: 0xFF, G: 0xFF, B: 0x00, A: 0x30}
+	// mismatchColor marks the cells of the piece whose pillars differ from
+	// the dungeon.
+	mismatchColor = color.NRGBA{R: 0xFF, G: 0x00, B: 0x00, A: 0x80}
+	// labelColor is the color of the caption.
+	labelColor = color.White
+)
+
+// roiImage returns a full resolution image of the region of interest
+// surrounding the given location of the piece. The cells of the piece are
+// tinted, its mismatching cells are marked and the image is captioned with the
+// location of the piece.
+func roiImage(dungeon *dun.Dungeon, lvl *level, piece *setPiece, m dun.Match) (img *image.RGBA) {
+	pieceWindow := m.Window(piece.colCount, piece.rowCount)
+	window := pieceWindow.Inset(-flagMargin).Intersect(image.Rect(0, 0, dun.ColMax, dun.RowMax))
+	img = dungeon.ImageRect(window.Min.X, window.Min.Y, window.Max.X, window.Max.Y, lvl.pillars, lvl.levelFrames, nil, 1).(*image.RGBA)
+	// view the cropped image using the coordinate system of the largest
+	// dungeon map, in which the cells are located.
+	metrics := lvl.pillars[0].GetMetrics()
+	pillarHeight := lvl.pillars[0].Height()
+	view := &image.RGBA{Pix: img.Pix, Stride: img.Stride, Rect: dun.GetWindowRect(metrics, window, pillarHeight)}
+	mismatches := make(map[image.Point]bool)
+	for _, pt := range m.Mismatches {
+		mismatches[pt] = true
+		dun.MarkCell(view, metrics, pt.X, pt.Y, metrics.MaxMapWidth(), pillarHeight, mismatchColor)
+	}
+	for row := 0; row < piece.rowCount; row++ {
+		for col := 0; col < piece.colCount; col++ {
+			if _, ok := piece.dungeon[col][row]["pillarNum"]; !ok {
+				continue
+			}
+			pt := image.Pt(m.Col+col, m.Row+row)
+			if !mismatches[pt] {
+				dun.MarkCell(view, metrics, pt.X, pt.Y, metrics.MaxMapWidth(), pillarHeight, pieceColor)
+			}
+		}
+	}
+	caption := fmt.Sprintf("%s at col %d, row %d (%.0f%% of pillars)", piece.dunName, m.Col, m.Row, 100*m.Score())
+	label.Draw(img, image.Pt(4, 4), caption, labelColor)
+	return img
+}
+
+// parseDungeon constructs the dungeon based on the layout of the given dungeon
+// name, and returns it along with its level name.
+func parseDungeon(dungeonName string) (dungeon *dun.Dungeon, nameWithoutExt string, err error) {
+	layout, err := dunconf.GetLevelLayout(dungeonName)
+	if err != nil {
+		return nil, "", err
+	}
+	dungeon = dun.New()
+	for _, p := range layout.Duns {
+		err = dungeon.ParseAt(p.DunName, p.ColStart, p.RowStart)
+		if err != nil {
+			if _, ok := err.(*dun.SquareError); !ok {
+				return nil, "", fmt.Errorf("failed to parse %q: %s", dungeonName, err)
+			}
+			// report invalid squares but search the remaining dungeon.
+			log.Println(err)
+		}
+	}
+	nameWithoutExt, err = dun.GetLevelName(layout.Duns[0].DunName)
+	if err != nil {
+		return nil, "", err
+	}
+	return dungeon, nameWithoutExt, nil
+}
+
+// parseRaw constructs the dungeon based on the raw pillar layer stored at
+// rawPath, using the tileset of flagTileset.
+func parseRaw(rawPath string) (dungeon *dun.Dungeon, err error) {
+	fr, err := os.Open(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fr.Close()
+	dungeon = dun.New()
+	err = dungeon.ParsePillars(fr, flagTileset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %s", rawPath, err)
+	}
+	return dungeon, nil
+}
+
+// A level holds the pillars and level frames of a level, which are shared by
+// the dungeons of the level.
+type level struct {
+	pillars     []min.Pillar
+	levelFrames []image.Image
+	relPalPath  string
+}
+
+// levels is a map from level name to the parsed level.
+var levels = make(map[string]*level)
+
+// getLevel returns the pillars and level frames of the given level (e.g.
+// "l1"), using its first image config (pal).
+func getLevel(nameWithoutExt string) (lvl *level, err error) {
+	if lvl, ok := levels[nameWithoutExt]; ok {
+		return lvl, nil
+	}
+	pillars, err := min.Parse(nameWithoutExt + ".min")
+	if err != nil {
+		return nil, err
+	}
+	lc, err := cel.GetLevelConf(nameWithoutExt)
+	if err != nil {
+		return nil, err
+	}
+	levelFrames, err := cel.DecodeAll(lc.CelName, lc.Conf)
+	if err != nil {
+		return nil, err
+	}
+	lvl = &level{pillars: pillars, levelFrames: levelFrames, relPalPath: lc.RelPalPath}
+	levels[nameWithoutExt] = lvl
+	return lvl, nil
+}
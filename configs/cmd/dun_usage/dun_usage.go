@@ -0,0 +1,161 @@
+// dun_usage is a tool for cross-referencing the pillars and squares used by the
+// DUN files of a given level type. For each pillar and square it reports which
+// DUN files that use it, and it lists the pillars and squares which are never
+// used by any DUN file of the level.
+//
+// Usage:
+//
+//    dun_usage [OPTION]... [level]...
+//
+// Flags:
+//
+//    -dunini="dun.ini"
+//            Path to an ini file containing starting coordinate information.
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//
+// Levels:
+//
+//    town, l1, l2, l3, l4
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/configs/dunconf"
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/configs/til"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&dunconf.IniPath, "dunini", "dun.ini", "Path to an ini file containing starting coordinate information.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = dunconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... [level]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Levels:")
+	fmt.Fprintln(os.Stderr, "  town, l1, l2, l3, l4")
+}
+
+func main() {
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	for _, levelName := range flag.Args() {
+		err := levelUsage(levelName)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
+
+// usages maps from pillarNum or squareNum to the names of the DUN files which
+// use it.
+type usages map[int]map[string]bool
+
+// add records that num is used by the given DUN file.
+func (u usages) add(num int, dunName string) {
+	if u[num] == nil {
+		u[num] = make(map[string]bool)
+	}
+	u[num][dunName] = true
+}
+
+// levelUsage parses each DUN file of the given level type and reports the usage
+// of its pillars and squares.
+func levelUsage(levelName string) (err error) {
+	pillars, err := min.Parse(levelName + ".min")
+	if err != nil {
+		return err
+	}
+	squares, err := til.Parse(levelName + ".til")
+	if err != nil {
+		return err
+	}
+	pillarUsages := make(usages)
+	squareUsages := make(usages)
+	for _, dunName := range dunconf.DunNames() {
+		dunLevelName, err := dun.GetLevelName(dunName)
+		if err != nil || dunLevelName != levelName {
+			continue
+		}
+		// The placement of the DUN file is irrelevant to its usages.
+		dungeon := dun.New()
+		err = dungeon.ParseAt(dunName, 0, 0)
+		if err != nil {
+			if _, ok := err.(*dun.SquareError); !ok {
+				return err
+			}
+			// report invalid squares but count the remaining ones.
+			log.Println(err)
+		}
+		for row := 0; row < dun.RowMax; row++ {
+			for col := 0; col < dun.ColMax; col++ {
+				if pillarNum, ok := dungeon[col][row]["pillarNum"]; ok {
+					pillarUsages.add(pillarNum, dunName)
+				}
+				if squareNum, ok := dungeon[col][row]["squareNum"]; ok {
+					squareUsages.add(squareNum, dunName)
+				}
+			}
+		}
+	}
+	fmt.Printf("=== [ %s ] ===\n\n", levelName)
+	report("pillar", len(pillars), pillarUsages)
+	report("square", len(squares), squareUsages)
+	return nil
+}
+
+// report prints the DUN files which use each of the count pillars or squares,
+// followed by those which are never used.
+func report(kind string, count int, u usages) {
+	var unused []string
+	for num := 0; num < count; num++ {
+		dunNames, ok := u[num]
+		if !ok {
+			unused = append(unused, fmt.Sprint(num))
+			continue
+		}
+		var names []string
+		for dunName := range dunNames {
+			names = append(names, dunName)
+		}
+		sort.Strings(names)
+		fmt.Printf("%s %d: %s\n", kind, num, strings.Join(names, ", "))
+	}
+	for num := range u {
+		if num >= count {
+			fmt.Printf("%s %d: out of range (%d %ss)\n", kind, num, count, kind)
+		}
+	}
+	fmt.Printf("\nunused %ss (%d of %d): %s\n\n", kind, len(unused), count, strings.Join(unused, ", "))
+}
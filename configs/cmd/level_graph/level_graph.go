@@ -0,0 +1,303 @@
+// level_graph is a tool for building the graph of the level transitions (stairs
+// and town warps) which connect the town and the 16 dungeon levels of a single
+// player game, and storing it as a DOT and a JSON file.
+//
+// Each node of the graph is a dungeon level, annotated with its seed and the
+// quests available on it, based on the seed of the game. Dungeon snapshots of
+// the game (see snap_dump) which are given as arguments locate the stairs of
+// their levels on the dungeon map, and are rendered as thumbnails of the nodes.
+//
+// Usage:
+//
+//    level_graph [OPTION]... SEED [snapshot]...
+//
+// Flags:
+//
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -o="_dump_/_level_graph_/"
+//            Output directory of the graph (levels.dot and levels.json) and its thumbnails.
+//    -scale=8
+//            Render the thumbnails at 1/scale of the size of the dungeons (1, 2, 4 or 8).
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	dbg "fmt"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/configs/snapshot"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+	"github.com/mewrnd/blizzconv/quests"
+	"github.com/mewrnd/blizzconv/rng"
+)
+
+var (
+	// flagOutput specifies the output directory of the graph.
+	flagOutput string
+	// flagScale specifies the thumbnails to be rendered at 1/scale of the size
+	// of the dungeons.
+	flagScale int
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.StringVar(&flagOutput, "o", "_dump_/_level_graph_/", "Output directory of the graph (levels.dot and levels.json) and its thumbnails.")
+	flag.IntVar(&flagScale, "scale", 8, "Render the thumbnails at 1/scale of the size of the dungeons (1, 2, 4 or 8).")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... SEED [snapshot]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+// graph is the level transition graph of a game.
+type graph struct {
+	Seed  int32  `json:"seed"`
+	Nodes []node `json:"nodes"`
+	Edges []edge `json:"edges"`
+}
+
+// node is a dungeon level of the graph.
+type node struct {
+	Level int    `json:"level"`
+	Seed  int32  `json:"seed"`
+	Type  string `json:"type"`
+	// Quests contains the names of the quests available on the level.
+	Quests []string `json:"quests,omitempty"`
+	// Snapshot is the path of the dungeon snapshot of the level, if any.
+	Snapshot string `json:"snapshot,omitempty"`
+	// Thumbnail is the file name of the thumbnail of the level, relative to
+	// the output directory, if any.
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+// edge is a level transition of the graph.
+type edge struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Kind string `json:"kind"`
+	// Cells contains the col and row coordinates of the cells of the level
+	// transition on the dungeon map of the From level, if located using a
+	// snapshot.
+	Cells [][2]int `json:"cells,omitempty"`
+}
+
+func main() {
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if !dun.ValidScale(flagScale) {
+		log.Fatalf("invalid scale %d; expected 1, 2, 4 or 8.\n", flagScale)
+	}
+	seed, err := strconv.ParseInt(flag.Arg(0), 0, 32)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = os.MkdirAll(flagOutput, 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	g := newGraph(int32(seed))
+	// stairs maps from dungeon level to the level transitions located using
+	// the snapshot of the level.
+	stairs := make(map[int][]dun.Stairs)
+	for _, snapPath := range flag.Args()[1:] {
+		level, levelStairs, err := addSnapshot(g, snapPath)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		stairs[level] = levelStairs
+	}
+	g.Edges = edges(stairs)
+	err = writeGraph(g)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// levelTypes maps from dungeon level to the level type of its tileset.
+var levelTypes = [rng.LevelCount]snapshot.LevelType{
+	snapshot.Town,
+	snapshot.Cathedral, snapshot.Cathedral, snapshot.Cathedral, snapshot.Cathedral,
+	snapshot.Catacombs, snapshot.Catacombs, snapshot.Catacombs, snapshot.Catacombs,
+	snapshot.Caves, snapshot.Caves, snapshot.Caves, snapshot.Caves,
+	snapshot.Hell, snapshot.Hell, snapshot.Hell, snapshot.Hell,
+}
+
+// newGraph returns the nodes of the graph of the game, annotated with the seed
+// and available quests of each level.
+func newGraph(gameSeed int32) *graph {
+	g := &graph{Seed: gameSeed}
+	seeds := rng.LevelSeeds(gameSeed)
+	levelQuests := quests.LevelQuests(quests.Select(seeds[15]))
+	for level, seed := range seeds {
+		levelName, _ := levelTypes[level].LevelName()
+		n := node{Level: level, Seed: seed, Type: levelName}
+		for _, quest := range levelQuests[level] {
+			n.Quests = append(n.Quests, quest.String())
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+	return g
+}
+
+// addSnapshot attaches the dungeon snapshot to the node of its level, and
+// stores a thumbnail of the dungeon. It returns the level of the snapshot along
+// with the level transitions located on its dungeon map. Snapshots of other
+// games, whose seeds differ from the level seeds of the graph, are rejected.
+func addSnapshot(g *graph, snapPath string) (level int, stairs []dun.Stairs, err error) {
+	snap, err := snapshot.Parse(snapPath)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse %q: %s", snapPath, err)
+	}
+	level = snap.LevelNum
+	if level < 0 || level >= len(g.Nodes) {
+		return 0, nil, fmt.Errorf("invalid dungeon level %d of %q.", level, snapPath)
+	}
+	n := &g.Nodes[level]
+	if snap.Seed != uint32(n.Seed) {
+		return 0, nil, fmt.Errorf("seed 0x%08X of %q does not match the seed 0x%08X of dungeon level %d.", snap.Seed, snapPath, uint32(n.Seed), level)
+	}
+	dungeon, levelName, err := snap.Dungeon()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse %q: %s", snapPath, err)
+	}
+	pillars, err := min.Parse(levelName + ".min")
+	if err != nil {
+		return 0, nil, err
+	}
+	lc, err := cel.GetLevelConf(levelName)
+	if err != nil {
+		return 0, nil, err
+	}
+	levelFrames, err := cel.DecodeAll(lc.CelName, lc.Conf)
+	if err != nil {
+		return 0, nil, err
+	}
+	n.Snapshot = snapPath
+	n.Thumbnail = fmt.Sprintf("level_%d.png", level)
+	dbg.Println("Creating image:", n.Thumbnail)
+	img := dungeon.Image(dun.ColMax, dun.RowMax, pillars, levelFrames, flagScale)
+	meta := pngprof.Source(snapPath, lc.RelPalPath).With("Seed", fmt.Sprintf("0x%08X", snap.Seed))
+	err = pngprof.WriteFileMeta(path.Join(flagOutput, n.Thumbnail), img, meta)
+	if err != nil {
+		return 0, nil, err
+	}
+	return level, dungeon.Stairs(levelName), nil
+}
+
+// warpLevels contains the dungeon levels of the town warps, which lead directly
+// to and from the town.
+var warpLevels = []int{5, 9, 13}
+
+// edges returns the level transitions of the graph; the stairs between
+// consecutive levels and the town warps. The cells of the level transitions
+// are located using the given stairs of each level, if any.
+func edges(stairs map[int][]dun.Stairs) (edges []edge) {
+	add := func(from, to int, kind dun.StairsKind) {
+		e := edge{From: from, To: to, Kind: kind.String()}
+		for _, s := range stairs[from] {
+			if s.Kind == kind {
+				e.Cells = append(e.Cells, [2]int{s.Col, s.Row})
+			}
+		}
+		edges = append(edges, e)
+	}
+	for level := 0; level < rng.LevelCount; level++ {
+		if level > 0 {
+			add(level, level-1, dun.StairsUp)
+		}
+		if level < rng.LevelCount-1 {
+			add(level, level+1, dun.StairsDown)
+		}
+	}
+	for _, level := range warpLevels {
+		add(0, level, dun.TownWarp)
+		add(level, 0, dun.TownWarp)
+	}
+	return edges
+}
+
+// writeGraph stores the graph as a DOT and a JSON file in the output
+// directory.
+func writeGraph(g *graph) (err error) {
+	buf, err := json.MarshalIndent(g, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = atomicfile.WriteFile(path.Join(flagOutput, "levels.json"), append(buf, '\n'))
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path.Join(flagOutput, "levels.dot"), dot(g))
+}
+
+// dot returns the graph in the DOT language of Graphviz. Nodes with thumbnails
+// display them above their labels.
+func dot(g *graph) []byte {
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, "digraph levels {\n")
+	fmt.Fprintf(b, "\tlabel=%q;\n", fmt.Sprintf("seed 0x%08X", uint32(g.Seed)))
+	fmt.Fprintf(b, "\tnode [shape=box];\n")
+	for _, n := range g.Nodes {
+		label := fmt.Sprintf("level %d (%s)\nseed 0x%08X", n.Level, n.Type, uint32(n.Seed))
+		for _, quest := range n.Quests {
+			label += "\n" + quest
+		}
+		fmt.Fprintf(b, "\tlevel%d [label=%q", n.Level, label)
+		if len(n.Thumbnail) > 0 {
+			fmt.Fprintf(b, ", image=%q, imagepos=tc, labelloc=b", n.Thumbnail)
+		}
+		fmt.Fprintf(b, "];\n")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(b, "\tlevel%d -> level%d [label=%q];\n", e.From, e.To, e.Kind)
+	}
+	fmt.Fprintf(b, "}\n")
+	return b.Bytes()
+}
@@ -10,10 +10,28 @@
 //    -celini="cel.ini"
 //            Path to an ini file containing image information.
 //            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -gamma=1
+//            Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).
+//    -hashdir=""
+//            Store the PNG images by content hash in this directory, with a manifest mapping file paths to hashes.
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
 //    -mpqdump="mpqdump/"
 //            Path to an extracted MPQ file.
 //    -mpqini="mpq.ini"
 //            Path to an ini file containing relative path information.
+//    -srgb=false
+//            Tag the exported PNG images as sRGB.
+//    -types=false
+//            Store block type maps of the pillars instead of the pillars.
+//    -validate=false
+//            Validate the decode algorithm of each block instead of dumping pillars.
 package main
 
 import (
@@ -24,21 +42,39 @@ import (
 	"log"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/0xC3/progress/barcli"
-	"github.com/mewkiz/pkg/imgutil"
+	"github.com/mewrnd/blizzconv/budget"
 	"github.com/mewrnd/blizzconv/configs/min"
 	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgcache"
 	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/pngprof"
 	"github.com/mewrnd/blizzconv/mpq"
 )
 
+// flagTypes specifies whether block type maps should be stored instead of the
+// pillars.
+var flagTypes bool
+
+// flagValidate specifies whether the decode algorithm of each block should be
+// validated instead of dumping pillars.
+var flagValidate bool
+
 func init() {
 	flag.Usage = usage
 	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.Float64Var(&pngprof.Gamma, "gamma", 1, "Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).")
+	flag.StringVar(&pngprof.HashDir, "hashdir", "", "Store the PNG images by content hash in this directory, with a manifest mapping file paths to hashes.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
 	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
 	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.BoolVar(&pngprof.SRGB, "srgb", false, "Tag the exported PNG images as sRGB.")
+	flag.BoolVar(&flagTypes, "types", false, "Store block type maps of the pillars instead of the pillars.")
+	flag.BoolVar(&flagValidate, "validate", false, "Validate the decode algorithm of each block instead of dumping pillars.")
+	budget.RegisterFlags()
 	flag.Parse()
 	err := mpq.Init()
 	if err != nil {
@@ -85,6 +121,21 @@ func minDump(minName string) (err error) {
 	}
 	nameWithoutExt := minName[:len(minName)-len(path.Ext(minName))]
 	imgName := nameWithoutExt + ".cel"
+	if flagValidate {
+		return validate(pillars, imgName)
+	}
+	if flagTypes {
+		dumpDir := path.Clean(dumpPrefix+"_pillar_types_/"+nameWithoutExt) + "/"
+		// prevent directory traversal
+		if !strings.HasPrefix(dumpDir, dumpPrefix) {
+			return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+		}
+		err = os.MkdirAll(dumpDir, 0755)
+		if err != nil {
+			return err
+		}
+		return dumpTypes(pillars, dumpDir, pngprof.Source(minName, ""))
+	}
 	relPalPaths := imgconf.GetRelPalPaths(imgName)
 	for _, relPalPath := range relPalPaths {
 		conf, err := cel.GetConf(imgName, relPalPath)
@@ -104,6 +155,19 @@ func minDump(minName string) (err error) {
 		if err != nil {
 			return err
 		}
+		// The pillar images are cached on disk, keyed on the content of the
+		// MIN, CEL and PAL files, if the cache is enabled.
+		var levelKey string
+		if imgcache.Enabled() {
+			levelKey, err = cel.ContentKey(imgName, conf)
+			if err != nil {
+				return err
+			}
+		}
+		pillarImgs, err := min.CachedImages(pillars, levelFrames, levelKey)
+		if err != nil {
+			return err
+		}
 		dumpDir := path.Clean(dumpPrefix+"_pillars_/"+nameWithoutExt) + "/" + palDir
 		// prevent directory traversal
 		if !strings.HasPrefix(dumpDir, dumpPrefix) {
@@ -113,7 +177,7 @@ func minDump(minName string) (err error) {
 		if err != nil {
 			return err
 		}
-		err = dumpPillars(pillars, levelFrames, dumpDir)
+		err = dumpPillars(pillarImgs, dumpDir, pngprof.Source(minName, relPalPath))
 		if err != nil {
 			return err
 		}
@@ -121,17 +185,63 @@ func minDump(minName string) (err error) {
 	return nil
 }
 
-// dumpPillars stores each pillar as a new png image, using the frames from a
-// CEL image level file.
-func dumpPillars(pillars []min.Pillar, levelFrames []image.Image, dumpDir string) (err error) {
-	for pillarNum, pillar := range pillars {
+// dumpPillars stores each pillar image as a new png image, with the provenance
+// metadata and pillarNum embedded.
+func dumpPillars(pillarImgs []image.Image, dumpDir string, meta pngprof.Meta) (err error) {
+	for pillarNum, img := range pillarImgs {
 		pillarPath := dumpDir + fmt.Sprintf("pillar_%04d.png", pillarNum)
 		bar.Inc()
-		img := pillar.Image(levelFrames)
-		err = imgutil.WriteFile(pillarPath, img)
+		err = pngprof.WriteFileMeta(pillarPath, img, meta.With(pngprof.KeyFrame, strconv.Itoa(pillarNum)))
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// dumpTypes stores the block type map of each pillar as a new png image.
+func dumpTypes(pillars []min.Pillar, dumpDir string, meta pngprof.Meta) (err error) {
+	for pillarNum, pillar := range pillars {
+		typesPath := dumpDir + fmt.Sprintf("pillar_%04d.png", pillarNum)
+		err = pngprof.WriteFileMeta(typesPath, pillar.TypeImage(), meta.With(pngprof.KeyFrame, strconv.Itoa(pillarNum)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the decode algorithm of each block against the frame type
+// detected from the raw frame contents, and reports frames which either
+// disagree with the MIN block type or which contain stray pixels when decoded
+// using the MIN block type.
+func validate(pillars []min.Pillar, imgName string) (err error) {
+	frames, err := cel.GetFrames(imgName)
+	if err != nil {
+		return err
+	}
+	// checked keeps track of the frames that have already been validated.
+	checked := make(map[int]bool)
+	for pillarNum, pillar := range pillars {
+		for blockNum, block := range pillar.Blocks {
+			if !block.IsValid || checked[block.FrameNum] {
+				continue
+			}
+			checked[block.FrameNum] = true
+			if block.FrameNum >= len(frames) {
+				fmt.Printf("pillar %d, block %d: frame %d out of range (%d frames).\n", pillarNum, blockNum, block.FrameNum, len(frames))
+				continue
+			}
+			frame := frames[block.FrameNum]
+			frameType := cel.GetFrameType(imgName, frame, block.FrameNum)
+			if frameType != block.Type {
+				fmt.Printf("pillar %d, block %d: frame %d has MIN type %d but decodes as type %d.\n", pillarNum, blockNum, block.FrameNum, block.Type, frameType)
+			}
+			n := cel.StrayPixels(frame, block.Type)
+			if n > 0 {
+				fmt.Printf("pillar %d, block %d: frame %d has %d stray pixels when decoded as type %d.\n", pillarNum, blockNum, block.FrameNum, n, block.Type)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// obj_dump is a tool for exporting the graphics of each object in the objects
+// registry, storing animated objects as gif images and other objects as png
+// images. The frame information of each object is verified against its CEL
+// image.
+//
+// Usage:
+//
+//    obj_dump [OPTION]...
+//
+// Flags:
+//
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//    -dither=false
+//            Dither colors which are not present in the palette of exported GIF images.
+//    -fps=0
+//            Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.
+//    -interp="crossfade"
+//            Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/images/anim"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.BoolVar(&anim.Dither, "dither", false, "Dither colors which are not present in the palette of exported GIF images.")
+	flag.IntVar(&anim.FrameRate, "fps", 0, "Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.")
+	flag.Var(&anim.Interp, "interp", "Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	dumpDir := path.Clean(dumpPrefix+"_objects_/") + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		log.Fatalf("path (%s) contains no dump prefix (%s).\n", dumpDir, dumpPrefix)
+	}
+	err := os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for objectIdx, object := range dun.Objects {
+		err = objDump(objectIdx, object, dumpDir)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// celFrames is a map from CEL image name to decoded frames. It prevents the CEL
+// images shared by several objects from being decoded more than once.
+var celFrames = make(map[string][]image.Image)
+
+// celPals is a map from CEL image name to the palette used for decoding.
+var celPals = make(map[string]color.Palette)
+
+// objDump verifies the frame information of the object and stores its graphics
+// in the dump directory, as a gif image if animated and a png image otherwise.
+func objDump(objectIdx int, object dun.Object, dumpDir string) (err error) {
+	frames, ok := celFrames[object.CelName]
+	if !ok {
+		relPalPath := imgconf.GetRelPalPaths(object.CelName)[0]
+		conf, err := cel.GetConf(object.CelName, relPalPath)
+		if err != nil {
+			return err
+		}
+		frames, err = cel.DecodeAll(object.CelName, conf)
+		if err != nil {
+			return err
+		}
+		celFrames[object.CelName] = frames
+		celPals[object.CelName] = conf.Pal
+	}
+	if object.Animated {
+		if object.TicksPerFrame < 0 {
+			return fmt.Errorf("object %d (%s): invalid ticksPerFrame (%d).", objectIdx, object.Name, object.TicksPerFrame)
+		}
+		gifPath := dumpDir + fmt.Sprintf("object_%03d.gif", objectIdx)
+		// Each frame is displayed for ticksPerFrame additional game ticks.
+		delay := anim.Delay(object.TicksPerFrame + 1)
+		return anim.WritePalettedGIF(gifPath, frames, delay, celPals[object.CelName])
+	}
+	if object.FrameNum < 0 || object.FrameNum >= len(frames) {
+		return fmt.Errorf("object %d (%s): invalid frame %d of %q (frame count: %d).", objectIdx, object.Name, object.FrameNum, object.CelName, len(frames))
+	}
+	pngPath := dumpDir + fmt.Sprintf("object_%03d.png", objectIdx)
+	meta := pngprof.Source(object.CelName, imgconf.GetRelPalPaths(object.CelName)[0])
+	return pngprof.WriteFileMeta(pngPath, frames[object.FrameNum], meta.With(pngprof.KeyFrame, strconv.Itoa(object.FrameNum)))
+}
@@ -0,0 +1,178 @@
+// snap_dump is a tool for rendering dungeon snapshots, which have been dumped
+// from the memory of a running game, and storing them as png images.
+//
+// Usage:
+//
+//    snap_dump [OPTION]... [snapshot]...
+//
+// Flags:
+//
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -gamma=1
+//            Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).
+//    -j=0
+//            Alias of -max-workers.
+//    -legend=false
+//            Append a legend strip describing the snapshot and its markers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -srgb=false
+//            Tag the exported PNG images as sRGB.
+package main
+
+import (
+	"flag"
+	dbg "fmt"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/configs/snapshot"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// flagLegend specifies if a legend strip should be appended to the images or
+// not.
+var flagLegend bool
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.Float64Var(&pngprof.Gamma, "gamma", 1, "Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).")
+	flag.BoolVar(&flagLegend, "legend", false, "Append a legend strip describing the snapshot and its markers.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.BoolVar(&pngprof.SRGB, "srgb", false, "Tag the exported PNG images as sRGB.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... [snapshot]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	for _, snapPath := range flag.Args() {
+		err := snapDump(snapPath)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// Colors used when marking objects and monsters on snapshot images.
+var (
+	ObjectColor  = color.NRGBA{R: 0x00, G: 0xFF, B: 0xFF, A: 0x80}
+	MonsterColor = color.NRGBA{R: 0xFF, G: 0x40, B: 0x00, A: 0x80}
+)
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// snapDump creates a dump directory and stores the dungeon of the given
+// snapshot as a png image, using the first image config (pal) of its tileset.
+// The cells containing objects and monsters are marked on the image.
+func snapDump(snapPath string) (err error) {
+	snap, err := snapshot.Parse(snapPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %s", snapPath, err)
+	}
+	dungeon, levelName, err := snap.Dungeon()
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %s", snapPath, err)
+	}
+	objects, err := snap.Objects()
+	if err != nil {
+		return err
+	}
+	monsters, err := snap.Monsters()
+	if err != nil {
+		return err
+	}
+	pillars, err := min.Parse(levelName + ".min")
+	if err != nil {
+		return err
+	}
+	lc, err := cel.GetLevelConf(levelName)
+	if err != nil {
+		return err
+	}
+	levelFrames, err := cel.DecodeAll(lc.CelName, lc.Conf)
+	if err != nil {
+		return err
+	}
+	dumpDir := path.Clean(dumpPrefix+"_snapshots_/") + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+	}
+	err = os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		return err
+	}
+	snapName := path.Base(snapPath)
+	snapName = snapName[:len(snapName)-len(path.Ext(snapName))]
+	imgPath := dumpDir + snapName + ".png"
+	dbg.Println("Creating image:", path.Base(imgPath))
+	var img image.Image = dungeon.Image(dun.ColMax, dun.RowMax, pillars, levelFrames, 1)
+	m := pillars[0].GetMetrics()
+	pillarHeight := pillars[0].Height()
+	mapWidth := img.Bounds().Dx()
+	for _, cell := range objects {
+		dun.MarkCell(img.(draw.Image), m, cell.Col, cell.Row, mapWidth, pillarHeight, ObjectColor)
+	}
+	for _, cell := range monsters {
+		dun.MarkCell(img.(draw.Image), m, cell.Col, cell.Row, mapWidth, pillarHeight, MonsterColor)
+	}
+	if flagLegend {
+		titles := []string{
+			fmt.Sprintf("%s (dungeon level %d, level %s)", snapName, snap.LevelNum, levelName),
+			fmt.Sprintf("seed: 0x%08X", snap.Seed),
+			fmt.Sprintf("objects: %d, monsters: %d", len(objects), len(monsters)),
+		}
+		entries := []dun.LegendEntry{
+			{Color: ObjectColor, Text: "Object"},
+			{Color: MonsterColor, Text: "Monster"},
+		}
+		img = dun.AddLegend(img, titles, entries)
+	}
+	meta := pngprof.Source(snapPath, lc.RelPalPath).With("Seed", fmt.Sprintf("0x%08X", snap.Seed))
+	return pngprof.WriteFileMeta(imgPath, img, meta)
+}
@@ -10,10 +10,24 @@
 //    -celini="cel.ini"
 //            Path to an ini file containing image information.
 //            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -gamma=1
+//            Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).
+//    -hashdir=""
+//            Store the PNG images by content hash in this directory, with a manifest mapping file paths to hashes.
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
 //    -mpqdump="mpqdump/"
 //            Path to an extracted MPQ file.
 //    -mpqini="mpq.ini"
 //            Path to an ini file containing relative path information.
+//    -srgb=false
+//            Tag the exported PNG images as sRGB.
 package main
 
 import (
@@ -24,22 +38,29 @@ import (
 	"log"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/0xC3/progress/barcli"
-	"github.com/mewkiz/pkg/imgutil"
+	"github.com/mewrnd/blizzconv/budget"
 	"github.com/mewrnd/blizzconv/configs/min"
 	"github.com/mewrnd/blizzconv/configs/til"
 	"github.com/mewrnd/blizzconv/images/cel"
 	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/pngprof"
 	"github.com/mewrnd/blizzconv/mpq"
 )
 
 func init() {
 	flag.Usage = usage
 	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.Float64Var(&pngprof.Gamma, "gamma", 1, "Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).")
+	flag.StringVar(&pngprof.HashDir, "hashdir", "", "Store the PNG images by content hash in this directory, with a manifest mapping file paths to hashes.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
 	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
 	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.BoolVar(&pngprof.SRGB, "srgb", false, "Tag the exported PNG images as sRGB.")
+	budget.RegisterFlags()
 	flag.Parse()
 	err := mpq.Init()
 	if err != nil {
@@ -120,7 +141,7 @@ func tilDump(tilName string) (err error) {
 		if err != nil {
 			return err
 		}
-		err = dumpSquares(squares, pillars, levelFrames, dumpDir)
+		err = dumpSquares(squares, pillars, levelFrames, dumpDir, pngprof.Source(tilName, relPalPath))
 		if err != nil {
 			return err
 		}
@@ -129,13 +150,13 @@ func tilDump(tilName string) (err error) {
 }
 
 // dumpPillars stores each pillar as a new png image, using the frames from a
-// CEL image level file.
-func dumpSquares(squares []til.Square, pillars []min.Pillar, levelFrames []image.Image, dumpDir string) (err error) {
+// CEL image level file, with the provenance metadata and squareNum embedded.
+func dumpSquares(squares []til.Square, pillars []min.Pillar, levelFrames []image.Image, dumpDir string, meta pngprof.Meta) (err error) {
 	for squareNum, square := range squares {
 		squarePath := dumpDir + fmt.Sprintf("square_%04d.png", squareNum)
 		bar.Inc()
 		img := square.Image(pillars, levelFrames)
-		err = imgutil.WriteFile(squarePath, img)
+		err = pngprof.WriteFileMeta(squarePath, img, meta.With(pngprof.KeyFrame, strconv.Itoa(squareNum)))
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,88 @@
+package dun
+
+import "github.com/mewrnd/blizzconv/rng"
+
+// AmbientObjects contains the graphics of the ambient objects, which the game
+// adds to the levels of a tileset at load time rather than storing them in the
+// DUN files. The cells of ambient objects store their idx using the
+// "ambientObject" key.
+var AmbientObjects = []Object{
+	// ref: OBJ_L1LIGHT
+	0: {"Brazier (light)", "l1braz.cel", 0, true, 1, Decoration},
+	// ref: OBJ_TORCHL
+	1: {"Wall Torch (south east)", "wtorch2.cel", 0, true, 1, Decoration},
+	// ref: OBJ_TORCHR
+	2: {"Wall Torch (south west)", "wtorch1.cel", 0, true, 1, Decoration},
+	// ref: OBJ_TORCHL2
+	3: {"Wall Torch (south east, catacombs)", "wtorch4.cel", 0, true, 1, Decoration},
+	// ref: OBJ_TORCHR2
+	4: {"Wall Torch (south west, catacombs)", "wtorch3.cel", 0, true, 1, Decoration},
+}
+
+// An ambientRule places an ambient object at, or next to, each cell of a given
+// pillar. The pillarNums are stored plus one, as they are referenced by the
+// game.
+type ambientRule struct {
+	PillarNumPlus1 int
+	// Object is the idx of the ambient object in AmbientObjects.
+	Object int
+	// Odds specifies that the object is placed once out of Odds times; 1
+	// always places the object.
+	Odds int
+	// ColOffset and RowOffset locate the cell of the object, relative to the
+	// cell of the pillar.
+	ColOffset, RowOffset int
+}
+
+// ambientRules maps from level name to the ambient object rules of the level.
+var ambientRules = map[string][]ambientRule{
+	// ref: AddL1Objs
+	"l1": {
+		{270, 0, 1, 0, 0},
+	},
+	// ref: AddL2Torches
+	"l2": {
+		{1, 3, 3, 0, 0},
+		{5, 4, 3, 0, 0},
+		{37, 1, 10, -1, 0},
+		{41, 2, 10, 0, -1},
+	},
+}
+
+// AddAmbientObjects places the ambient objects which the game adds to the
+// given level (e.g. "l1") at load time, and returns the number of objects
+// placed. Objects are only placed on cells which contain no other object.
+//
+// Random placements (e.g. the wall torches of the catacombs) are decided using
+// r, in the order of the game. As the state of the generator depends on the
+// preceding dungeon generation, the placements approximate those of the game;
+// if r is nil each candidate is placed.
+func (dungeon *Dungeon) AddAmbientObjects(levelName string, r *rng.Rand) (n int) {
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			pillarNum, ok := dungeon[col][row]["pillarNum"]
+			if !ok {
+				continue
+			}
+			for _, rule := range ambientRules[levelName] {
+				if pillarNum != rule.PillarNumPlus1-1 {
+					continue
+				}
+				if rule.Odds > 1 && r != nil && r.Random(rule.Odds) != 0 {
+					continue
+				}
+				objCol, objRow := col+rule.ColOffset, row+rule.RowOffset
+				if objCol < 0 || objCol >= ColMax || objRow < 0 || objRow >= RowMax {
+					continue
+				}
+				cell := dungeon[objCol][objRow]
+				if _, ok := cell["ambientObject"]; ok || cell["dunObjectID"] > 0 {
+					continue
+				}
+				cell["ambientObject"] = rule.Object
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package dun
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/images/label"
+)
+
+// An OrderViolation is a pair of overlapping pillars which have been drawn out
+// of order, i.e. a pillar which has been drawn on top of a pillar in front of
+// it. A pillar is in front of another if the sum of its col and row is larger.
+type OrderViolation struct {
+	// Col and Row of the pillar which has been drawn last.
+	Col, Row int
+	// Col and Row of the pillar in front, which has been drawn first.
+	FrontCol, FrontRow int
+	// Pixels is the number of pixels of the pillar in front which have been
+	// overdrawn.
+	Pixels int
+}
+
+// AuditColor is the color used to highlight the pixels of pillars which have
+// been overdrawn by pillars behind them.
+var AuditColor = color.NRGBA{R: 0xFF, G: 0x00, B: 0xFF, A: 0xA0}
+
+// AuditImage returns an image of the dungeon, constructed in the same draw order
+// as Image, which is used to verify that the pillars are drawn back to front.
+// Each pillar is labeled with its position in the draw order, and the pixels
+// which have been overdrawn by pillars behind them are highlighted using
+// AuditColor. The overlapping pillars drawn out of order are returned as
+// violations.
+func (dungeon *Dungeon) AuditImage(colCount, rowCount int, pillars []min.Pillar, levelFrames []image.Image) (img *image.RGBA, violations []OrderViolation) {
+	m := pillars[0].GetMetrics()
+	pillarHeight := pillars[0].Height()
+	mapWidth, mapHeight := m.MapSize(colCount, rowCount, pillarHeight)
+	dst := image.NewRGBA(image.Rect(0, 0, mapWidth, mapHeight))
+	// owners contains the idx, within the drawn cells, of the pillar which
+	// has last been drawn at each pixel of dst, or -1 if none.
+	owners := make([]int, mapWidth*mapHeight)
+	for i := range owners {
+		owners[i] = -1
+	}
+	// overdrawn specifies which pixels of dst have been overdrawn by pillars
+	// behind them.
+	overdrawn := make([]bool, len(owners))
+	// pairs maps from the drawn cell idxs of each violation to its idx in
+	// violations.
+	pairs := make(map[[2]int]int)
+	pillarImgs := make(map[int]image.Image)
+	var drawn []image.Point
+	for _, cell := range drawOrder(image.Rect(0, 0, colCount, rowCount)) {
+		pillarNum, ok := dungeon[cell.X][cell.Y]["pillarNum"]
+		if !ok {
+			continue
+		}
+		src, ok := pillarImgs[pillarNum]
+		if !ok {
+			src = pillars[pillarNum].Image(levelFrames)
+			pillarImgs[pillarNum] = src
+		}
+		idx := len(drawn)
+		drawn = append(drawn, cell)
+		rect := m.PillarRect(cell.X, cell.Y, mapWidth, pillarHeight).Intersect(dst.Bounds())
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				_, _, _, a := src.At(x-rect.Min.X, y-rect.Min.Y).RGBA()
+				if a == 0 {
+					continue
+				}
+				i := y*mapWidth + x
+				if owner := owners[i]; owner != -1 {
+					front := drawn[owner]
+					if front.X+front.Y > cell.X+cell.Y {
+						overdrawn[i] = true
+						pair := [2]int{idx, owner}
+						n, ok := pairs[pair]
+						if !ok {
+							n = len(violations)
+							pairs[pair] = n
+							violations = append(violations, OrderViolation{Col: cell.X, Row: cell.Y, FrontCol: front.X, FrontRow: front.Y})
+						}
+						violations[n].Pixels++
+					}
+				}
+				owners[i] = idx
+			}
+		}
+		draw.Draw(dst, rect, src, image.ZP, draw.Over)
+	}
+	highlight := image.NewUniform(AuditColor)
+	for i, ok := range overdrawn {
+		if ok {
+			pt := image.Pt(i%mapWidth, i/mapWidth)
+			draw.Draw(dst, image.Rectangle{Min: pt, Max: pt.Add(image.Pt(1, 1))}, highlight, image.ZP, draw.Over)
+		}
+	}
+	for idx, cell := range drawn {
+		floor := m.FloorRect(cell.X, cell.Y, mapWidth, pillarHeight)
+		center := image.Pt(floor.Min.X+floor.Dx()/2, floor.Min.Y+floor.Dy()/2)
+		label.DrawCentered(dst, center, strconv.Itoa(idx), color.White)
+	}
+	return dst, violations
+}
@@ -0,0 +1,113 @@
+package dun
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/mewrnd/blizzconv/configs/amp"
+	"github.com/mewrnd/blizzconv/configs/min"
+)
+
+// automapStyle contains the CSS style of the SVG automap.
+const automapStyle = `
+	line { stroke-width: 2; stroke-linecap: round; }
+	.wall { stroke: #C8A468; }
+	.cave { stroke: #A48450; }
+	.door { stroke: #6890C8; stroke-width: 4; }
+	.arch { stroke: #C8A468; stroke-dasharray: 4 4; }
+	.grate { stroke: #909090; stroke-dasharray: 2 2; }
+	.stairs { stroke: #C8C8C8; }
+	.column { fill: none; stroke: #C8A468; stroke-width: 2; }
+	.dirt { fill: #806040; }
+`
+
+// WriteAutomapSVG writes the automap of the dungeon to w as a scalable vector
+// graphics (SVG) image, based on the automap tile of each square. The automap
+// uses the same projection as the dungeon image of the tile geometry m, where
+// each square is two pillars in width and two blocks in height (128x64 units
+// for min.DefaultMetrics).
+//
+// ref: DrawAutomapTile
+func (dungeon *Dungeon) WriteAutomapSVG(w io.Writer, m min.Metrics, colCount, rowCount int, tiles []amp.Tile) (err error) {
+	bw := bufio.NewWriter(w)
+	width, height := m.MapSize(colCount, rowCount, m.BlockHeight)
+	fmt.Fprintf(bw, "<svg xmlns=%q width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", "http://www.w3.org/2000/svg", width, height, width, height)
+	fmt.Fprintf(bw, "<style>%s</style>\n", automapStyle)
+	fmt.Fprintf(bw, "<rect width=\"100%%\" height=\"100%%\" fill=\"black\"/>\n")
+	// pt returns the position of the vertex at the col and row coordinates,
+	// where the vertex (col, row) is the top vertex of the floor of the cell
+	// (col, row).
+	pt := func(col, row float64) (x, y float64) {
+		x = (col-row)*float64(m.BlockWidth) + float64(rowCount*m.BlockWidth)
+		y = (col + row) * float64(m.BlockHeight/2)
+		return x, y
+	}
+	line := func(class string, col1, row1, col2, row2 float64) {
+		x1, y1 := pt(col1, row1)
+		x2, y2 := pt(col2, row2)
+		fmt.Fprintf(bw, "<line class=%q x1=\"%g\" y1=\"%g\" x2=\"%g\" y2=\"%g\"/>\n", class, x1, y1, x2, y2)
+	}
+	for row := 0; row < rowCount; row++ {
+		for col := 0; col < colCount; col++ {
+			squareNum, ok := dungeon[col][row]["squareNum"]
+			if !ok || squareNum >= len(tiles) {
+				continue
+			}
+			tile := tiles[squareNum]
+			c, r := float64(col), float64(row)
+			// vertices of the square.
+			topC, topR := c, r
+			leftC, leftR := c, r+2
+			rightC, rightR := c+2, r
+			bottomC, bottomR := c+2, r+2
+			if tile.Type == amp.TypeDiamond {
+				x1, y1 := pt(c+0.5, r+0.5)
+				x2, y2 := pt(c+1.5, r+0.5)
+				x3, y3 := pt(c+1.5, r+1.5)
+				x4, y4 := pt(c+0.5, r+1.5)
+				fmt.Fprintf(bw, "<polygon class=\"column\" points=\"%g,%g %g,%g %g,%g %g,%g\"/>\n", x1, y1, x2, y2, x3, y3, x4, y4)
+			}
+			vert, horz, caveVert, caveHorz := tile.Edges()
+			if vert {
+				line(edgeClass(tile.Flags, amp.FlagVertDoor, amp.FlagVertArch, amp.FlagVertGrate, "wall"), topC, topR, leftC, leftR)
+			}
+			if horz {
+				line(edgeClass(tile.Flags, amp.FlagHorzDoor, amp.FlagHorzArch, amp.FlagHorzGrate, "wall"), topC, topR, rightC, rightR)
+			}
+			if caveVert {
+				line(edgeClass(tile.Flags, amp.FlagVertDoor, 0, 0, "cave"), bottomC, bottomR, leftC, leftR)
+			}
+			if caveHorz {
+				line(edgeClass(tile.Flags, amp.FlagHorzDoor, 0, 0, "cave"), bottomC, bottomR, rightC, rightR)
+			}
+			if tile.Flags&amp.FlagStairs != 0 {
+				// draw the steps of the stairs across the square.
+				for i := 1; i <= 3; i++ {
+					f := float64(i) / 2
+					line("stairs", c+f, r, c+f, r+2)
+				}
+			}
+			if tile.Flags&amp.FlagDirt != 0 {
+				x, y := pt(c+1, r+1)
+				fmt.Fprintf(bw, "<circle class=\"dirt\" cx=\"%g\" cy=\"%g\" r=\"2\"/>\n", x, y)
+			}
+		}
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// edgeClass returns the CSS class of a wall edge, based on the door, arch and
+// grate flags of the edge. The wall class is used for plain walls.
+func edgeClass(flags, door, arch, grate int, wall string) string {
+	switch {
+	case flags&door != 0:
+		return "door"
+	case flags&arch != 0:
+		return "arch"
+	case flags&grate != 0:
+		return "grate"
+	}
+	return wall
+}
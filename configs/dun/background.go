@@ -0,0 +1,83 @@
+package dun
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"strings"
+)
+
+// CheckerSize is the width and height in pixels of each square of the
+// checkerboard background.
+const CheckerSize = 16
+
+// Colors of the squares of the checkerboard background.
+var (
+	CheckerLight color.Color = color.RGBA{R: 0x99, G: 0x99, B: 0x99, A: 0xFF}
+	CheckerDark  color.Color = color.RGBA{R: 0x66, G: 0x66, B: 0x66, A: 0xFF}
+)
+
+// ParseBackground returns the background image described by spec, which is
+// one of:
+//    ""        // transparent; no background image is returned.
+//    "black"
+//    "checker" // checkerboard of CheckerSize x CheckerSize squares.
+//    "#RRGGBB" // solid color.
+func ParseBackground(spec string) (bg image.Image, err error) {
+	switch spec {
+	case "":
+		return nil, nil
+	case "black":
+		return image.NewUniform(color.Black), nil
+	case "checker":
+		return checkerboard{}, nil
+	}
+	if !strings.HasPrefix(spec, "#") || len(spec) != len("#RRGGBB") {
+		return nil, fmt.Errorf("dun.ParseBackground: invalid background %q.", spec)
+	}
+	x, err := strconv.ParseUint(spec[1:], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("dun.ParseBackground: invalid background color %q.", spec)
+	}
+	c := color.RGBA{R: uint8(x >> 16), G: uint8(x >> 8), B: uint8(x), A: 0xFF}
+	return image.NewUniform(c), nil
+}
+
+// WithBackground returns a copy of the image, drawn on top of the background
+// image. The background is aligned with the top left corner of the image.
+func WithBackground(img, bg image.Image) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, bg, image.ZP, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+	return dst
+}
+
+// checkerboard is an image of infinite size, consisting of alternating light
+// and dark squares.
+type checkerboard struct{}
+
+func (checkerboard) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (checkerboard) Bounds() image.Rectangle {
+	return image.Rect(-1e9, -1e9, 1e9, 1e9)
+}
+
+func (checkerboard) At(x, y int) color.Color {
+	if (floorDiv(x, CheckerSize)+floorDiv(y, CheckerSize))%2 == 0 {
+		return CheckerLight
+	}
+	return CheckerDark
+}
+
+// floorDiv returns x divided by n, rounded towards negative infinity.
+func floorDiv(x, n int) int {
+	if x < 0 {
+		return -((n - 1 - x) / n)
+	}
+	return x / n
+}
@@ -0,0 +1,100 @@
+package dun
+
+// DoorState specifies the state of the doors of a dungeon.
+type DoorState int
+
+// Door states.
+const (
+	// DoorsAsIs leaves the doors as stored in the DUN files.
+	DoorsAsIs DoorState = iota
+	// DoorsClosed closes all doors.
+	DoorsClosed
+	// DoorsOpen opens all doors.
+	DoorsOpen
+)
+
+// A doorRule specifies the pillars of a closed door and the pillar and special
+// frame used when the door is open. All pillarNums and frameNums are stored
+// plus one, as they are referenced by the game.
+type doorRule struct {
+	ClosedPillarNumsPlus1 []int
+	OpenPillarNumPlus1    int
+	OpenFrameNumPlus1     int
+}
+
+// doorRules maps from level name to the door rules of the level.
+//
+// Note: The doors of the l3 and l4 levels are not yet supported.
+var doorRules = map[string][]doorRule{
+	// ref: OperateL1LDoor and OperateL1RDoor
+	"l1": {
+		{[]int{44, 51, 214}, 393, 7},
+		{[]int{46, 56}, 395, 8},
+	},
+	// ref: OperateL2LDoor and OperateL2RDoor
+	"l2": {
+		{[]int{538}, 13, 5},
+		{[]int{540}, 17, 6},
+	},
+}
+
+// SetDoors sets the state of each door of the dungeon map, based on the pillars
+// of the given level (e.g. "l1"). Opening a door replaces its pillar and
+// stores the special frame of the open door, which is drawn on top of it.
+// Closing a door restores the pillar of the closed door.
+//
+// Note: SetDoors should be invoked after SetSpecials.
+func (dungeon *Dungeon) SetDoors(levelName string, state DoorState) {
+	if state == DoorsAsIs {
+		return
+	}
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			cell := dungeon[col][row]
+			pillarNum, ok := cell["pillarNum"]
+			if !ok {
+				continue
+			}
+			for _, rule := range doorRules[levelName] {
+				switch state {
+				case DoorsOpen:
+					if contains(rule.ClosedPillarNumsPlus1, pillarNum+1) {
+						cell["pillarNum"] = rule.OpenPillarNumPlus1 - 1
+						cell["specialFrameNum"] = rule.OpenFrameNumPlus1 - 1
+					}
+				case DoorsClosed:
+					if pillarNum+1 == rule.OpenPillarNumPlus1 {
+						cell["pillarNum"] = rule.ClosedPillarNumsPlus1[0] - 1
+						delete(cell, "specialFrameNum")
+					}
+				}
+			}
+		}
+	}
+}
+
+// IsDoor returns true if the pillar is an open or closed door of the given
+// level (e.g. "l1"). The door pillars are those of the door rules used by
+// SetDoors; any of the pillars of a closed door, and the pillar which replaces
+// them once the door is opened. Since the doors of the l3 and l4 levels are not
+// yet supported, their pillars are never reported as doors.
+//
+// ref: doorRules
+func IsDoor(levelName string, pillarNum int) bool {
+	for _, rule := range doorRules[levelName] {
+		if contains(rule.ClosedPillarNumsPlus1, pillarNum+1) || pillarNum+1 == rule.OpenPillarNumPlus1 {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if the slice contains x.
+func contains(xs []int, x int) bool {
+	for _, v := range xs {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
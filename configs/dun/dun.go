@@ -20,8 +20,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
 	"path"
+	"strings"
 
 	"github.com/mewrnd/blizzconv/configs/dunconf"
 	"github.com/mewrnd/blizzconv/configs/til"
@@ -39,130 +39,16 @@ const (
 //
 // The valid keys are:
 //    "pillarNum"
+//    "squareNum" // only present at the top cell of each square.
 //    "unknown" // TODO: update this key once known.
 //    "dunMonstersIDs"
 //    "dunObjectIDs"
 //    "transparencies"
+//    "specialFrameNum" // set by SetSpecials.
+//    "regionID"        // set by Regions.
+//    "ambientObject"   // set by AddAmbientObjects.
 type Dungeon [ColMax][RowMax]map[string]int
 
-// objects maps from object idx to object names.
-var objects = []string{
-	0:   "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	1:   "Lever (position a)",               // lever (frame 0)
-	2:   "Crucified Skeleton (south)",       // cruxsk1 (frame 0)
-	3:   "Crucified Skeleton (south east)",  // cruxsk2 (frame 0)
-	4:   "Crucified Skeleton (south west)",  // cruxsk3 (frame 0)
-	5:   "Angel",                            // angel (frame 0)
-	6:   "Banner (south east, theme 3)",     // banner (frame 1)
-	7:   "Banner (theme 3)",                 // banner (frame 0)
-	8:   "Banner (south west, theme 3)",     // banner (frame 2)
-	9:   "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	10:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	11:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	12:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	13:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	14:  "Ancient Tome or Book of Vileness", // book2 (frame 0)
-	15:  "Mythical Book",                    // book2 (frame 3)
-	16:  "Burning Cross",                    // burncros (animated, ticksPerFrame 0)
-	17:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	18:  "Invalid 1",                        // l1braz (invalid frame)
-	19:  "Candle (theme 1)",                 // candle2 (animated, ticksPerFrame 2)
-	20:  "Invalid 2",                        // l1braz (invalid frame)
-	21:  "Cauldron",                         // cauldren (frame 0)
-	22:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	23:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	24:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	25:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	26:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	27:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	28:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	29:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	30:  "Flame",                            // flame1 (frame 0)
-	31:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	32:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	33:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	34:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	35:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	36:  "Magic Circle Pentagram",           // mcirl (frame 0)
-	37:  "Magic Circle",                     // mcirl (frame 0) [frame 2 in game]
-	38:  "Skull Fire (theme 3)",             // skulfire (animated, ticksPerFrame 2)
-	39:  "Skulpile",                         // skulpile (invalid frame)
-	40:  "Invalid 3",                        // l1braz (invalid frame)
-	41:  "Invalid 4",                        // l1braz (invalid frame)
-	42:  "Invalid 5",                        // l1braz (invalid frame)
-	43:  "Invalid 6",                        // l1braz (invalid frame)
-	44:  "Invalid 7",                        // l1braz (invalid frame)
-	45:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	46:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	47:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	48:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	49:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	50:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	51:  "Skull Lever",                      // switch4 (frame 0)
-	52:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	53:  "Traphole (south west)",            // traphole (frame 0)
-	54:  "Traphole (south east)",            // traphole (frame 1)
-	55:  "Tortured Soul 0",                  // tsoul (frame 0)
-	56:  "Tortured Soul 1",                  // tsoul (frame 1)
-	57:  "Tortured Soul 2",                  // tsoul (frame 2)
-	58:  "Tortured Soul 3",                  // tsoul (frame 3)
-	59:  "Tortured Soul 4",                  // tsoul (frame 4)
-	60:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	61:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	62:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	63:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	64:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	65:  "Nude",                             // nude2 (animated, ticksPerFrame 3)
-	66:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	67:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	68:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	69:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	70:  "Tortured Nude Man 0",              // tnudem (frame 0)
-	71:  "Tortured Nude Man 1 (theme 6)",    // tnudem (frame 1)
-	72:  "Tortured Nude Man 2 (theme 6)",    // tnudem (frame 2)
-	73:  "Tortured Nude Man 3 (theme 6)",    // tnudem (frame 3)
-	74:  "Tortured Nude Woman 0 (theme 6)",  // tnudew (frame 0)
-	75:  "Tortured Nude Woman 1 (theme 6)",  // tnudew (frame 1)
-	76:  "Tortured Nude Woman 2 (theme 6)",  // tnudew (frame 2)
-	77:  "Small Chest",                      // chest1 (frame 0)
-	78:  "Small Chest",                      // chest1 (frame 0)
-	79:  "Small Chest",                      // chest1 (frame 0)
-	80:  "Chest",                            // chest2 (frame 0)
-	81:  "Chest",                            // chest2 (frame 0)
-	82:  "Chest",                            // chest2 (frame 0)
-	83:  "Large Chest",                      // chest3 (frame 0)
-	84:  "Large Chest",                      // chest3 (frame 0)
-	85:  "Large Chest",                      // chest3 (frame 0)
-	86:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	87:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	88:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	89:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	90:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	91:  "Pedestal of Blood",                // pedistl (frame 0)
-	92:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	93:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	94:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	95:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	96:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	97:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	98:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	99:  "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	100: "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	101: "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	102: "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	103: "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	104: "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	105: "Altar Boy",                        // altboy (frame 0)
-	106: "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	107: "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-	108: "Armor Stand (Warlord of Blood)",   // armstand (frame 0)
-	109: "Weapon Rack (Warlord of Blood)",   // weapstnd (frame 0)
-	110: "Wall Torch (south east)",          // wtorch2 (animated, ticksPerFrame 1)
-	111: "Wall Torch (south west)",          // wtorch1 (animated, ticksPerFrame 1)
-	112: "Mushroom Patch",                   // mushptch (frame 0)
-	113: "Brazier",                          // l1braz (animated, ticksPerFrame 1)
-}
-
 // New returns a new Dungeon.
 func New() (dungeon *Dungeon) {
 	dungeon = new(Dungeon)
@@ -175,7 +61,25 @@ func New() (dungeon *Dungeon) {
 }
 
 // Parse parses a given DUN file and stores each pillarNum at a coordinate in
-// the dungeon, based on the DUN format described above.
+// the dungeon, based on the DUN format described above. The DUN file is placed
+// at the starting coordinates specified by the ini file.
+//
+// ref: ParseAt
+func (dungeon *Dungeon) Parse(dunName string) (err error) {
+	colStart, err := dunconf.GetColStart(dunName)
+	if err != nil {
+		return err
+	}
+	rowStart, err := dunconf.GetRowStart(dunName)
+	if err != nil {
+		return err
+	}
+	return dungeon.ParseAt(dunName, colStart, rowStart)
+}
+
+// ParseAt parses a given DUN file and stores each pillarNum at a coordinate in
+// the dungeon, based on the DUN format described above. The DUN file is placed
+// at the starting coordinates colStart and rowStart.
 //
 // Below is a description of how the squares are positioned on the dungeon map:
 //    1) Start at the coordinates colStart, rowStart.
@@ -189,12 +93,24 @@ func New() (dungeon *Dungeon) {
 // ref: GetPillarRect (illustration of map coordinate system)
 //
 // Any additional cell data is stored afterwards using row major.
-func (dungeon *Dungeon) Parse(dunName string) (err error) {
-	dunPath, err := mpq.GetPath(dunName)
-	if err != nil {
-		return err
-	}
-	fr, err := os.Open(dunPath)
+//
+// Squares which refer to squareNums outside of the level's TIL file are skipped
+// and, provided that no other error occurs, reported using a *SquareError once
+// the entire DUN file has been parsed.
+func (dungeon *Dungeon) ParseAt(dunName string, colStart, rowStart int) (err error) {
+	return dungeon.ParseAtFrom(mpq.Default(), dunName, colStart, rowStart)
+}
+
+// ParseAtFrom parses the given DUN file of the store s, as described by
+// ParseAt.
+func (dungeon *Dungeon) ParseAtFrom(s *mpq.Store, dunName string, colStart, rowStart int) (err error) {
+	var invalid []InvalidSquare
+	defer func() {
+		if err == nil && len(invalid) > 0 {
+			err = &SquareError{DunName: dunName, Squares: invalid}
+		}
+	}()
+	fr, err := s.Open(dunName)
 	if err != nil {
 		return err
 	}
@@ -206,21 +122,16 @@ func (dungeon *Dungeon) Parse(dunName string) (err error) {
 	}
 	dunQWidth := int(tmp[0])
 	dunQHeight := int(tmp[1])
-	colStart, err := dunconf.GetColStart(dunName)
-	if err != nil {
-		return err
+	if colStart+2*dunQWidth > ColMax || rowStart+2*dunQHeight > RowMax {
+		return fmt.Errorf("dun.ParseAt: %q (%dx%d) placed at col %d, row %d exceeds the dungeon map.", dunName, 2*dunQWidth, 2*dunQHeight, colStart, rowStart)
 	}
-	rowStart, err := dunconf.GetRowStart(dunName)
-	if err != nil {
-		return err
-	}
-	nameWithoutExt, err := GetLevelName(dunName)
+	nameWithoutExt, err := GetLevelNameFrom(s, dunName)
 	if err != nil {
 		return err
 	}
 
 	// squareNumsPlus1.
-	squares, err := til.Parse(nameWithoutExt + ".til")
+	squares, err := til.ParseFrom(s, nameWithoutExt+".til")
 	if err != nil {
 		return err
 	}
@@ -234,8 +145,13 @@ func (dungeon *Dungeon) Parse(dunName string) (err error) {
 				return err
 			}
 			squareNumPlus1 := int(x)
+			if squareNumPlus1 > len(squares) {
+				invalid = append(invalid, InvalidSquare{Col: col, Row: row, SquareNumPlus1: squareNumPlus1, SquareCount: len(squares)})
+				squareNumPlus1 = 0
+			}
 			if squareNumPlus1 != 0 {
 				square := squares[squareNumPlus1-1]
+				dungeon[col][row]["squareNum"] = squareNumPlus1 - 1
 				dungeon[col][row]["pillarNum"] = square.PillarNumTop
 				dungeon[col+1][row]["pillarNum"] = square.PillarNumRight
 				dungeon[col][row+1]["pillarNum"] = square.PillarNumLeft
@@ -282,7 +198,7 @@ func (dungeon *Dungeon) Parse(dunName string) (err error) {
 				}
 				return err
 			}
-			// TODO: Lookup monster idx from dunMonsterID.
+			// The dunMonsterID is decoded by DecodeMonsterID.
 			// ref: 4B6C98
 			dungeon[col][row]["dunMonsterID"] = int(x)
 			col++
@@ -303,7 +219,7 @@ func (dungeon *Dungeon) Parse(dunName string) (err error) {
 				}
 				return err
 			}
-			// TODO: Lookup object idx from dunObjectID.
+			// The dunObjectID is used as an index into Objects.
 			// ref: 4AAD28
 			dungeon[col][row]["dunObjectID"] = int(x)
 			col++
@@ -333,9 +249,94 @@ func (dungeon *Dungeon) Parse(dunName string) (err error) {
 	return nil
 }
 
+// InvalidSquare is a reference to a square outside of the level's TIL file.
+type InvalidSquare struct {
+	// Col and Row of the top cell of the square on the dungeon map.
+	Col, Row int
+	// SquareNumPlus1 is the invalid reference, as stored in the DUN file.
+	SquareNumPlus1 int
+	// SquareCount is the number of squares in the level's TIL file.
+	SquareCount int
+}
+
+// A SquareError reports the invalid square references of a DUN file.
+type SquareError struct {
+	DunName string
+	Squares []InvalidSquare
+}
+
+func (e *SquareError) Error() string {
+	var lines []string
+	for _, sq := range e.Squares {
+		line := fmt.Sprintf("  squareNumPlus1 %d at col %d, row %d (square count: %d)", sq.SquareNumPlus1, sq.Col, sq.Row, sq.SquareCount)
+		lines = append(lines, line)
+	}
+	return fmt.Sprintf("dun.Parse: %d invalid square references in %q:\n%s", len(e.Squares), e.DunName, strings.Join(lines, "\n"))
+}
+
+// GetSize returns the number of cols and rows of a given DUN file, based on
+// the dimensions stored in its header.
+func GetSize(dunName string) (colCount, rowCount int, err error) {
+	return GetSizeFrom(mpq.Default(), dunName)
+}
+
+// GetSizeFrom returns the number of cols and rows of the given DUN file of the
+// store s, as described by GetSize.
+func GetSizeFrom(s *mpq.Store, dunName string) (colCount, rowCount int, err error) {
+	fr, err := s.Open(dunName)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer fr.Close()
+	var tmp [2]uint16
+	err = binary.Read(fr, binary.LittleEndian, &tmp)
+	if err != nil {
+		return 0, 0, err
+	}
+	// each square is two cols in width and two rows in height.
+	return 2 * int(tmp[0]), 2 * int(tmp[1]), nil
+}
+
+// GetDungeonSize returns the number of cols and rows of a given dungeon map.
+// The dimensions are retrieved from the layout of the dungeon map or, for
+// dungeon maps that consist of a single DUN file without dimensions, from the
+// header of the DUN file.
+func GetDungeonSize(dungeonName string) (colCount, rowCount int, err error) {
+	layout, err := dunconf.GetLevelLayout(dungeonName)
+	if err != nil {
+		return 0, 0, err
+	}
+	return GetLayoutSize(layout)
+}
+
+// GetLayoutSize returns the number of cols and rows of a given layout. The
+// dimensions of layouts that consist of a single DUN file without dimensions
+// are retrieved from the header of the DUN file.
+func GetLayoutSize(layout dunconf.Layout) (colCount, rowCount int, err error) {
+	return GetLayoutSizeFrom(mpq.Default(), layout)
+}
+
+// GetLayoutSizeFrom returns the number of cols and rows of the given layout,
+// whose DUN files are provided by the store s, as described by GetLayoutSize.
+func GetLayoutSizeFrom(s *mpq.Store, layout dunconf.Layout) (colCount, rowCount int, err error) {
+	if layout.ColCount != 0 && layout.RowCount != 0 {
+		return layout.ColCount, layout.RowCount, nil
+	}
+	if len(layout.Duns) != 1 {
+		return 0, 0, fmt.Errorf("dimensions not found for %q.", layout.Name)
+	}
+	return GetSizeFrom(s, layout.Duns[0].DunName)
+}
+
 // GetLevelName returns the level name (without extension) of a given DUN file.
 func GetLevelName(dunName string) (nameWithoutExt string, err error) {
-	relDunPath, err := mpq.GetRelPath(dunName)
+	return GetLevelNameFrom(mpq.Default(), dunName)
+}
+
+// GetLevelNameFrom returns the level name (without extension) of the given DUN
+// file, located using the ini file of the store s.
+func GetLevelNameFrom(s *mpq.Store, dunName string) (nameWithoutExt string, err error) {
+	relDunPath, err := s.GetRelPath(dunName)
 	if err != nil {
 		return "", err
 	}
@@ -351,6 +352,11 @@ func GetLevelName(dunName string) (nameWithoutExt string, err error) {
 		nameWithoutExt = "l4"
 	case "levels/towndata/":
 		nameWithoutExt = "town"
+	// Hellfire levels.
+	case "nlevels/l5data/":
+		nameWithoutExt = "l5"
+	case "nlevels/l6data/":
+		nameWithoutExt = "l6"
 	default:
 		return "", fmt.Errorf("invalid dunDir (%s).", dunDir)
 	}
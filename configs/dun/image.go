@@ -2,28 +2,200 @@ package dun
 
 import (
 	"image"
+	"image/color"
 	"image/draw"
 
 	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/images/cl2"
 )
 
 // Image returns an image constructed from the pillars associated with each
-// coordinate of the dungeon map.
+// coordinate of the dungeon map. The image is rendered at 1/scale of its full
+// size, where scale is 1, 2, 4 or 8.
 //
 // ref: GetPillarRect (illustration of map coordinate system)
-func (dungeon *Dungeon) Image(colCount, rowCount int, pillars []min.Pillar, levelFrames []image.Image) (img image.Image) {
+func (dungeon *Dungeon) Image(colCount, rowCount int, pillars []min.Pillar, levelFrames []image.Image, scale int) (img image.Image) {
+	return dungeon.ImageWithSpecials(colCount, rowCount, pillars, levelFrames, nil, scale)
+}
+
+// ImageWithSpecials returns an image constructed from the pillars associated
+// with each coordinate of the dungeon map, and the frames of the special CEL
+// image drawn on top of them. The image is rendered at 1/scale of its full
+// size, where scale is 1, 2, 4 or 8; any other scale is treated as 1.
+//
+// When scaled, each pillar and special frame is scaled once before being drawn,
+// which is considerably faster than scaling the full-size image.
+//
+// ref: SetSpecials
+func (dungeon *Dungeon) ImageWithSpecials(colCount, rowCount int, pillars []min.Pillar, levelFrames, specialFrames []image.Image, scale int) (img image.Image) {
+	return dungeon.ImageWithRenderer(DefaultRenderer, colCount, rowCount, pillars, levelFrames, specialFrames, scale)
+}
+
+// ImageWithRenderer returns the same image as ImageWithSpecials, rendered using
+// the given renderer instead of DefaultRenderer. It may be used to compare the
+// renderers.
+func (dungeon *Dungeon) ImageWithRenderer(r Renderer, colCount, rowCount int, pillars []min.Pillar, levelFrames, specialFrames []image.Image, scale int) (img image.Image) {
+	return dungeon.image(r, colCount, rowCount, pillars, levelPillarImage(pillars, levelFrames), specialFrames, scale)
+}
+
+// ImageWithPillarImages returns the same image as ImageWithSpecials, using the
+// given images of the pillars (e.g. as cached by min.CachedImages) instead of
+// constructing them from the level frames.
+func (dungeon *Dungeon) ImageWithPillarImages(colCount, rowCount int, pillars []min.Pillar, pillarImgs, specialFrames []image.Image, scale int) (img image.Image) {
+	pillarImage := func(pillarNum int) image.Image {
+		return pillarImgs[pillarNum]
+	}
+	return dungeon.image(DefaultRenderer, colCount, rowCount, pillars, pillarImage, specialFrames, scale)
+}
+
+// image returns the dungeon image, as described by ImageWithSpecials, using the
+// given renderer and the pillar images returned by pillarImage.
+func (dungeon *Dungeon) image(r Renderer, colCount, rowCount int, pillars []min.Pillar, pillarImage func(pillarNum int) image.Image, specialFrames []image.Image, scale int) (img image.Image) {
+	if !ValidScale(scale) {
+		scale = 1
+	}
+	m := pillars[0].GetMetrics()
+	mapWidth, mapHeight := m.MapSize(colCount, rowCount, pillars[0].Height())
+	dst := image.NewRGBA(image.Rect(0, 0, mapWidth/scale, mapHeight/scale))
+	dungeon.drawCells(dst, r, image.Rect(0, 0, colCount, rowCount), mapWidth, pillars, pillarImage, specialFrames, scale)
+	return dst
+}
+
+// levelPillarImage returns a function which constructs the image of a pillar
+// from the level frames.
+func levelPillarImage(pillars []min.Pillar, levelFrames []image.Image) func(pillarNum int) image.Image {
+	return func(pillarNum int) image.Image {
+		return pillars[pillarNum].Image(levelFrames)
+	}
+}
+
+// ImageRect returns an image constructed from the pillars, and the frames of the
+// special CEL image drawn on top of them, of the cells within the col and row
+// window [colMin, colMax) x [rowMin, rowMax) of the dungeon map. The image
+// is the smallest image which encloses the pillars of the window, and is
+// rendered at 1/scale of its full size, where scale is 1, 2, 4 or 8; any other
+// scale is treated as 1. The specialFrames may be nil.
+//
+// Rendering a window of the dungeon map is considerably faster than rendering
+// the full map, e.g. when panning a viewer tile by tile.
+//
+// ref: GetPillarRect (illustration of map coordinate system)
+func (dungeon *Dungeon) ImageRect(colMin, rowMin, colMax, rowMax int, pillars []min.Pillar, levelFrames, specialFrames []image.Image, scale int) (img image.Image) {
+	if !ValidScale(scale) {
+		scale = 1
+	}
+	window := image.Rect(colMin, rowMin, colMax, rowMax).Intersect(image.Rect(0, 0, ColMax, RowMax))
+	if window.Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+	m := pillars[0].GetMetrics()
+	bounds := GetWindowRect(m, window, pillars[0].Height())
+	dst := image.NewRGBA(scaleRect(bounds, scale))
+	dungeon.drawCells(dst, DefaultRenderer, window, m.MaxMapWidth(), pillars, levelPillarImage(pillars, levelFrames), specialFrames, scale)
+	// Move the origin of the image to (0, 0).
+	dst.Rect = dst.Rect.Sub(dst.Rect.Min)
+	return dst
+}
+
+// GetWindowRect returns the smallest image.Rectangle which encloses the pillars
+// of the cells within the col and row window of the dungeon map, using the tile
+// geometry m and the coordinate system of the image of the largest dungeon map
+// (see min.Metrics.MaxMapWidth). The image returned by ImageRect corresponds to
+// this rectangle, moved to the origin.
+//
+// ref: GetPillarRect (illustration of map coordinate system)
+func GetWindowRect(m min.Metrics, window image.Rectangle, pillarHeight int) (rect image.Rectangle) {
+	// The top, right, bottom and left corners of the window are the cells
+	// which enclose its pillars.
+	mapWidth := m.MaxMapWidth()
+	top := m.PillarRect(window.Min.X, window.Min.Y, mapWidth, pillarHeight)
+	right := m.PillarRect(window.Max.X-1, window.Min.Y, mapWidth, pillarHeight)
+	bottom := m.PillarRect(window.Max.X-1, window.Max.Y-1, mapWidth, pillarHeight)
+	left := m.PillarRect(window.Min.X, window.Max.Y-1, mapWidth, pillarHeight)
+	return image.Rect(left.Min.X, top.Min.Y, right.Max.X, bottom.Max.Y)
+}
+
+// drawCells draws the pillars, and the frames of the special CEL image on top
+// of them, of the cells within the col and row window onto dst, using the given
+// renderer and the pillar images returned by pillarImage. The window is a
+// rectangle of cols (x) and rows (y).
+func (dungeon *Dungeon) drawCells(dst *image.RGBA, r Renderer, window image.Rectangle, mapWidth int, pillars []min.Pillar, pillarImage func(pillarNum int) image.Image, specialFrames []image.Image, scale int) {
+	m := pillars[0].GetMetrics()
 	pillarHeight := pillars[0].Height()
-	mapWidth := colCount*min.BlockWidth + rowCount*min.BlockWidth
-	mapHeight := colCount*(min.BlockHeight/2) + rowCount*(min.BlockHeight/2) + (pillarHeight - min.BlockHeight)
-	dst := image.NewRGBA(image.Rect(0, 0, mapWidth, mapHeight))
-	for row := 0; row < rowCount; row++ {
-		for col := 0; col < colCount; col++ {
-			pillarNum, ok := dungeon[col][row]["pillarNum"]
-			if ok {
-				rect := GetPillarRect(col, row, mapWidth, pillarHeight)
-				src := pillars[pillarNum].Image(levelFrames)
-				draw.Draw(dst, rect, src, image.ZP, draw.Over)
+	// pillarSprites is a map from pillarNum to the sprite of the scaled pillar.
+	pillarSprites := make(map[int]Sprite)
+	var specialSprites []Sprite
+	for _, frame := range specialFrames {
+		specialSprites = append(specialSprites, r.Prepare(shrink(frame, scale)))
+	}
+	for _, cell := range drawOrder(window) {
+		col, row := cell.X, cell.Y
+		pillarNum, ok := dungeon[col][row]["pillarNum"]
+		if ok {
+			sprite, ok := pillarSprites[pillarNum]
+			if !ok {
+				sprite = r.Prepare(shrink(pillarImage(pillarNum), scale))
+				pillarSprites[pillarNum] = sprite
+			}
+			rect := scaleRect(m.PillarRect(col, row, mapWidth, pillarHeight), scale)
+			sprite.Draw(dst, rect)
+		}
+		dungeon.drawSpecial(dst, m, col, row, mapWidth, pillarHeight, specialFrames, specialSprites, scale)
+	}
+}
+
+// drawOrder returns the cells of the col and row window in the order they are
+// drawn; one row at the time, from the top right to the bottom left of the
+// dungeon image. Each point holds the col (x) and row (y) of a cell.
+//
+// ref: AuditImage
+func drawOrder(window image.Rectangle) (cells []image.Point) {
+	for row := window.Min.Y; row < window.Max.Y; row++ {
+		for col := window.Min.X; col < window.Max.X; col++ {
+			cells = append(cells, image.Pt(col, row))
+		}
+	}
+	return cells
+}
+
+// ValidScale returns true if the dungeon image may be rendered at 1/scale of
+// its full size.
+func ValidScale(scale int) bool {
+	switch scale {
+	case 1, 2, 4, 8:
+		return true
+	}
+	return false
+}
+
+// scaleRect returns the rectangle with each coordinate divided by scale.
+func scaleRect(rect image.Rectangle, scale int) image.Rectangle {
+	return image.Rect(rect.Min.X/scale, rect.Min.Y/scale, rect.Max.X/scale, rect.Max.Y/scale)
+}
+
+// shrink returns the image scaled to 1/scale of its size, by averaging each
+// scale x scale box of pixels.
+func shrink(src image.Image, scale int) image.Image {
+	if scale == 1 {
+		return src
+	}
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx()/scale, bounds.Dy()/scale))
+	n := uint32(scale * scale)
+	for y := 0; y < dst.Bounds().Dy(); y++ {
+		for x := 0; x < dst.Bounds().Dx(); x++ {
+			var r, g, b, a uint32
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					sr, sg, sb, sa := src.At(bounds.Min.X+x*scale+dx, bounds.Min.Y+y*scale+dy).RGBA()
+					r += sr
+					g += sg
+					b += sb
+					a += sa
+				}
 			}
+			c := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+			dst.Set(x, y, c)
 		}
 	}
 	return dst
@@ -48,10 +220,61 @@ func (dungeon *Dungeon) Image(colCount, rowCount int, pillars []min.Pillar, leve
 //                   \/
 //
 //               (111, 111)
-func GetPillarRect(col, row, mapWidth, pillarHeight int) (rect image.Rectangle) {
-	minX := mapWidth/2 - min.BlockWidth - row*min.BlockWidth + col*min.BlockWidth
-	minY := row*(min.BlockHeight/2) + col*(min.BlockHeight/2)
-	maxX := minX + min.PillarWidth
-	maxY := minY + pillarHeight
-	return image.Rect(minX, minY, maxX, maxY)
+//
+// The pillars are laid out using the tile geometry m of the level; see
+// min.Metrics.PillarRect.
+func GetPillarRect(m min.Metrics, col, row, mapWidth, pillarHeight int) (rect image.Rectangle) {
+	return m.PillarRect(col, row, mapWidth, pillarHeight)
+}
+
+// GetFloorRect returns an image.Rectangle of the floor of the cell at the col
+// and row coordinates. The floor is a diamond located at the bottom of the
+// pillar, and the returned rectangle encloses it.
+//
+// ref: GetPillarRect (illustration of map coordinate system)
+func GetFloorRect(m min.Metrics, col, row, mapWidth, pillarHeight int) (rect image.Rectangle) {
+	return m.FloorRect(col, row, mapWidth, pillarHeight)
+}
+
+// GetCell returns the col and row coordinates of the cell whose floor contains
+// the pixel at x and y of the dungeon image. It is the inverse of GetFloorRect,
+// and may be used to map pixels of a dungeon image back to the dungeon map.
+//
+// ref: GetPillarRect (illustration of map coordinate system)
+func GetCell(m min.Metrics, x, y, mapWidth, pillarHeight int) (col, row int) {
+	return m.Cell(x, y, mapWidth, pillarHeight)
+}
+
+// GetFloorPolygon returns the vertices (top, right, bottom and left) of the
+// floor diamond of the cell at the col and row coordinates.
+//
+// ref: GetFloorRect
+func GetFloorPolygon(m min.Metrics, col, row, mapWidth, pillarHeight int) (vertices [4]image.Point) {
+	floor := GetFloorRect(m, col, row, mapWidth, pillarHeight)
+	midX := floor.Min.X + floor.Dx()/2
+	midY := floor.Min.Y + floor.Dy()/2
+	vertices[0] = image.Pt(midX, floor.Min.Y)
+	vertices[1] = image.Pt(floor.Max.X, midY)
+	vertices[2] = image.Pt(midX, floor.Max.Y)
+	vertices[3] = image.Pt(floor.Min.X, midY)
+	return vertices
+}
+
+// DrawSprite draws the frame of a character, monster or object standing on the
+// cell at the col and row coordinates of the dungeon image, using the draw
+// offset of the frame.
+//
+// ref: cl2.DrawOffset
+func DrawSprite(dst draw.Image, m min.Metrics, col, row, pillarHeight int, frame image.Image) {
+	rect := getSpriteRect(m, col, row, dst.Bounds().Dx(), pillarHeight, frame)
+	draw.Draw(dst, rect, frame, frame.Bounds().Min, draw.Over)
+}
+
+// getSpriteRect returns an image.Rectangle of the frame of a character, monster
+// or object standing on the cell at the col and row coordinates, using the given
+// tile geometry.
+func getSpriteRect(m min.Metrics, col, row, mapWidth, pillarHeight int, frame image.Image) (rect image.Rectangle) {
+	floor := m.FloorRect(col, row, mapWidth, pillarHeight)
+	pt := image.Pt(floor.Min.X, floor.Max.Y).Add(cl2.DrawOffset(frame))
+	return image.Rectangle{Min: pt, Max: pt.Add(frame.Bounds().Size())}
 }
@@ -0,0 +1,92 @@
+package dun
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/mewrnd/blizzconv/images/label"
+)
+
+// A LegendEntry describes the meaning of a color used when marking cells on
+// dungeon images.
+type LegendEntry struct {
+	Color color.Color
+	Text  string
+}
+
+// LegendBackground is the background color of the legend strip.
+var LegendBackground color.Color = color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xFF}
+
+// legendPadding is the padding in pixels around the lines and entries of the
+// legend strip.
+const legendPadding = 8
+
+// StairsLegend returns the legend entries of the colors of StairsColor.
+func StairsLegend() []LegendEntry {
+	return []LegendEntry{
+		{StairsColor[StairsUp], "Stairs up"},
+		{StairsColor[StairsDown], "Stairs down"},
+		{StairsColor[TownWarp], "Town warp"},
+	}
+}
+
+// TrapsLegend returns the legend entries of TrapColor and TriggerColor.
+func TrapsLegend() []LegendEntry {
+	return []LegendEntry{
+		{TrapColor, "Trap"},
+		{TriggerColor, "Trap trigger"},
+	}
+}
+
+// PathLegend returns the legend entries of PathEndColor and PathColor.
+func PathLegend() []LegendEntry {
+	return []LegendEntry{
+		{PathEndColor, "Path start and goal"},
+		{PathColor, "Path"},
+	}
+}
+
+// AddLegend returns a copy of the image with a legend strip appended below it.
+// The strip contains the title lines followed by the entries, each drawn as a
+// color swatch and its text. The entries are wrapped onto additional lines when
+// they exceed the width of the image.
+func AddLegend(img image.Image, titles []string, entries []LegendEntry) image.Image {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	lineHeight := label.Height()
+	// Lay out the entries from left to right; pts holds the top left corner of
+	// each swatch, relative to the top of the entry lines.
+	var pts []image.Point
+	x, y := legendPadding, 0
+	for _, entry := range entries {
+		w := lineHeight + legendPadding/2 + label.Width(entry.Text)
+		if x != legendPadding && x+w > width-legendPadding {
+			x = legendPadding
+			y += lineHeight + legendPadding/2
+		}
+		pts = append(pts, image.Pt(x, y))
+		x += w + 2*legendPadding
+	}
+	stripHeight := legendPadding + len(titles)*(lineHeight+legendPadding/2)
+	if len(entries) > 0 {
+		stripHeight += y + lineHeight + legendPadding/2
+	}
+	stripHeight += legendPadding / 2
+	dst := image.NewRGBA(image.Rect(0, 0, width, bounds.Dy()+stripHeight))
+	draw.Draw(dst, bounds.Sub(bounds.Min), img, bounds.Min, draw.Src)
+	strip := image.Rect(0, bounds.Dy(), width, dst.Bounds().Dy())
+	draw.Draw(dst, strip, image.NewUniform(LegendBackground), image.ZP, draw.Src)
+	top := strip.Min.Y + legendPadding
+	for _, title := range titles {
+		label.Draw(dst, image.Pt(legendPadding, top), title, color.White)
+		top += lineHeight + legendPadding/2
+	}
+	for i, entry := range entries {
+		pt := pts[i].Add(image.Pt(0, top))
+		swatch := image.Rect(pt.X, pt.Y, pt.X+lineHeight, pt.Y+lineHeight)
+		draw.Draw(dst, swatch, image.NewUniform(entry.Color), image.ZP, draw.Over)
+		label.Draw(dst, image.Pt(swatch.Max.X+legendPadding/2, pt.Y), entry.Text, color.White)
+	}
+	return dst
+}
@@ -0,0 +1,88 @@
+package dun
+
+import (
+	"image"
+	"sort"
+)
+
+// A Match is a location of a piece (e.g. a quest set-piece DUN file) within a
+// dungeon.
+type Match struct {
+	// Col and Row are the coordinates of the top cell of the piece within the
+	// dungeon.
+	Col, Row int
+	// Matched is the number of cells of the piece whose pillar is present at
+	// the same location of the dungeon, out of the Total number of cells of the
+	// piece which contain a pillar.
+	Matched, Total int
+	// Mismatches contains the cells of the piece, relative to the dungeon,
+	// whose pillar differs from the dungeon.
+	Mismatches []image.Point
+}
+
+// Score returns the fraction of pillars of the piece which match the dungeon.
+func (m Match) Score() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return float64(m.Matched) / float64(m.Total)
+}
+
+// Window returns the col and row window of the dungeon covered by the piece,
+// which is colCount cols by rowCount rows in size.
+func (m Match) Window(colCount, rowCount int) image.Rectangle {
+	return image.Rect(m.Col, m.Row, m.Col+colCount, m.Row+rowCount)
+}
+
+// Locate returns the locations within the dungeon at which the pillars of the
+// piece, which is colCount cols by rowCount rows in size, match the pillars of
+// the dungeon. Only locations with a score of at least minScore are returned,
+// sorted by decreasing score.
+//
+// The pillars of set-pieces are mostly intact within the dungeons generated by
+// the game, but may be altered in places (e.g. by doors or the fixes applied
+// to the level), so a minScore below 1 allows for a few mismatching cells.
+func (dungeon *Dungeon) Locate(piece *Dungeon, colCount, rowCount int, minScore float64) (matches []Match) {
+	// cells contains the coordinates and pillarNum of each cell of the piece
+	// which contains a pillar.
+	type cell struct {
+		col, row, pillarNum int
+	}
+	var cells []cell
+	for row := 0; row < rowCount; row++ {
+		for col := 0; col < colCount; col++ {
+			if pillarNum, ok := piece[col][row]["pillarNum"]; ok {
+				cells = append(cells, cell{col, row, pillarNum})
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return nil
+	}
+	// maxMismatches is the number of mismatching cells at which a location is
+	// rejected.
+	maxMismatches := len(cells) - int(minScore*float64(len(cells))) + 1
+	for rowStart := 0; rowStart+rowCount <= RowMax; rowStart++ {
+		for colStart := 0; colStart+colCount <= ColMax; colStart++ {
+			m := Match{Col: colStart, Row: rowStart, Total: len(cells)}
+			for _, c := range cells {
+				col, row := colStart+c.col, rowStart+c.row
+				if pillarNum, ok := dungeon[col][row]["pillarNum"]; ok && pillarNum == c.pillarNum {
+					m.Matched++
+					continue
+				}
+				m.Mismatches = append(m.Mismatches, image.Pt(col, row))
+				if len(m.Mismatches) >= maxMismatches {
+					break
+				}
+			}
+			if len(m.Mismatches) < maxMismatches && m.Score() >= minScore {
+				matches = append(matches, m)
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Matched > matches[j].Matched
+	})
+	return matches
+}
@@ -0,0 +1,223 @@
+package dun
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+)
+
+// A MonsterType is a type of monster, as enumerated by the game.
+//
+// ref: _monster_id
+type MonsterType int
+
+// monsterTypeNames maps from monster type to the in-game name of the monster.
+//
+// ref: monsterdata
+var monsterTypeNames = []string{
+	"Zombie", "Ghoul", "Rotting Carcass", "Black Death",
+	"Fallen One (spear)", "Carver (spear)", "Devil Kin (spear)", "Dark One (spear)",
+	"Skeleton (axe)", "Corpse Axe", "Burning Dead (axe)", "Horror (axe)",
+	"Fallen One (sword)", "Carver (sword)", "Devil Kin (sword)", "Dark One (sword)",
+	"Scavenger", "Plague Eater", "Shadow Beast", "Bone Gasher",
+	"Skeleton (bow)", "Corpse Bow", "Burning Dead (bow)", "Horror (bow)",
+	"Skeleton Captain", "Corpse Captain", "Burning Dead Captain", "Horror Captain",
+	"Invisible Lord", "Hidden", "Stalker", "Unseen", "Illusion Weaver",
+	"Lord Sayter",
+	"Flesh Clan (mace)", "Stone Clan (mace)", "Fire Clan (mace)", "Night Clan (mace)",
+	"Fiend", "Blink", "Gloom", "Familiar",
+	"Flesh Clan (bow)", "Stone Clan (bow)", "Fire Clan (bow)", "Night Clan (bow)",
+	"Acid Beast", "Poison Spitter", "Pit Beast", "Lava Maw",
+	"Skeleton King", "The Butcher", "Overlord", "Mud Man", "Toad Demon", "Flayed One",
+	"Wyrm", "Cave Slug", "Devil Wyrm", "Devourer",
+	"Magma Demon", "Blood Stone", "Hell Stone", "Lava Lord",
+	"Horned Demon", "Mud Runner", "Frost Charger", "Obsidian Lord",
+	"Bone Demon", "Red Death", "Litch Demon", "Undead Balrog",
+	"Incinerator", "Flame Lord", "Doom Fire", "Hell Burner",
+	"Red Storm", "Storm Rider", "Storm Lord", "Maelstorm",
+	"Devil Kin Brute", "Winged-Demon", "Gargoyle", "Blood Claw", "Death Wing",
+	"Slayer", "Guardian", "Vortex Lord", "Balrog",
+	"Cave Viper", "Fire Drake", "Gold Viper", "Azure Drake",
+	"Black Knight", "Doom Guard", "Steel Lord", "Blood Knight",
+	"Unraveler", "Hollow One", "Pain Master", "Reality Weaver",
+	"Succubus", "Snow Witch", "Hell Spawn", "Soul Burner",
+	"Counselor", "Magistrate", "Cabalist", "Advocate",
+	"Golem", "The Dark Lord", "The Arch-Litch Malignus",
+}
+
+// String returns the in-game name of the monster type (e.g. "Zombie").
+func (typ MonsterType) String() string {
+	if typ < 0 || int(typ) >= len(monsterTypeNames) {
+		return fmt.Sprintf("MonsterType(%d)", int(typ))
+	}
+	return monsterTypeNames[typ]
+}
+
+// monsterTypeGraphics maps from monster type to the name shared by the CL2
+// archives of its graphics, which several monster types have in common.
+//
+// ref: monsterdata (GraphicType)
+var monsterTypeGraphics = []string{
+	"zombie", "zombie", "zombie", "zombie",
+	"phall", "phall", "phall", "phall",
+	"sklax", "sklax", "sklax", "sklax",
+	"fall", "fall", "fall", "fall",
+	"scav", "scav", "scav", "scav",
+	"sklbw", "sklbw", "sklbw", "sklbw",
+	"sklsr", "sklsr", "sklsr", "sklsr",
+	"tsneak", "sneak", "sneak", "sneak", "sneak",
+	"goatl",
+	"goat", "goat", "goat", "goat",
+	"bat", "bat", "bat", "bat",
+	"goatb", "goatb", "goatb", "goatb",
+	"acid", "acid", "acid", "acid",
+	"sking", "fatc", "fat", "fat", "fat", "fat",
+	"worm", "worm", "worm", "worm",
+	"magma", "magma", "magma", "magma",
+	"rhino", "rhino", "rhino", "rhino",
+	"demskl", "demskl", "demskl", "demskl",
+	"firem", "firem", "firem", "firem",
+	"thin", "thin", "thin", "thin",
+	"fallg", "gargo", "gargo", "gargo", "gargo",
+	"mega", "mega", "mega", "mega",
+	"snake", "snake", "snake", "snake",
+	"black", "black", "black", "black",
+	"unrav", "unrav", "unrav", "unrav",
+	"scbs", "scbs", "scbs", "scbs",
+	"mage", "mage", "mage", "mage",
+	"golem", "diablo", "dmage",
+}
+
+// Graphics returns the name shared by the CL2 archives of the monster type's
+// graphics (e.g. "zombie" of "zombien.cl2"), or an empty string if the monster
+// type is invalid.
+func (typ MonsterType) Graphics() string {
+	if typ < 0 || int(typ) >= len(monsterTypeGraphics) {
+		return ""
+	}
+	return monsterTypeGraphics[typ]
+}
+
+// monsterConv maps from dunMonsterID-1 of normal monsters to monster type.
+// Unused entries hold 0 and are thus placed as zombies, just like in the game.
+//
+// ref: MonstConvTbl
+var monsterConv = [128]MonsterType{
+	0, 1, 2, 3, 4, 5, 6, 7,
+	8, 9, 10, 11, 12, 13, 14, 15,
+	16, 17, 18, 19, 20, 21, 22, 23,
+	24, 25, 26, 27, 29, 30, 31, 32,
+	34, 35, 36, 37, 38, 40, 39, 41,
+	42, 43, 44, 45, 46, 47, 48, 49,
+	50, 52, 53, 54, 55, 56, 57, 59,
+	58, 60, 61, 62, 63, 64, 65, 66,
+	67, 68, 69, 70, 71, 0, 0, 0,
+	0, 72, 73, 74, 75, 0, 0, 0,
+	0, 77, 76, 78, 79, 81, 82, 83,
+	84, 85, 86, 87, 88, 89, 90, 92,
+	91, 93, 94, 95, 96, 97, 98, 99,
+	100, 101, 102, 103, 104, 105, 106, 107,
+	108, 0, 110, 0, 109, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0,
+}
+
+// uniqueMonsterFlag is set in the dunMonsterIDs of unique monsters, whose
+// remaining bits hold the index of the unique monster. The original DUN files
+// contain no unique monsters, which are placed by quests instead; the flag is
+// used by the DUN files of DevilutionX.
+//
+// ref: SetMapMonsters
+const uniqueMonsterFlag = 1 << 15
+
+// A MonsterPlacement is a monster placed on a cell of the dungeon map by the
+// dunMonsterIDs of a DUN file.
+type MonsterPlacement struct {
+	// Col and Row of the cell on the dungeon map.
+	Col, Row int
+	// DunMonsterID is the raw monster ID, as stored in the DUN file.
+	DunMonsterID int
+	// Unique specifies if the monster is a unique monster, in which case
+	// UniqueNum is set instead of Type.
+	Unique bool
+	// Type is the monster type of a normal monster.
+	Type MonsterType
+	// UniqueNum is the index of a unique monster into the unique monster table
+	// of the game.
+	//
+	// ref: UniqMonst
+	UniqueNum int
+}
+
+// String returns a description of the placed monster (e.g. "Zombie").
+func (p MonsterPlacement) String() string {
+	if p.Unique {
+		return fmt.Sprintf("unique monster %d", p.UniqueNum)
+	}
+	return p.Type.String()
+}
+
+// DecodeMonsterID decodes a non-zero dunMonsterID of a DUN file. Normal monsters
+// are converted from the dunMonsterID to their monster type using the
+// conversion table of the game. Unique monsters, which are flagged by the
+// highest bit of the dunMonsterID, hold the index of the unique monster in the
+// remaining bits. The Col and Row of the returned placement are left unset.
+func DecodeMonsterID(dunMonsterID int) (p MonsterPlacement, err error) {
+	p.DunMonsterID = dunMonsterID
+	switch {
+	case dunMonsterID <= 0 || dunMonsterID > 0xFFFF:
+		return MonsterPlacement{}, fmt.Errorf("dun.DecodeMonsterID: invalid dunMonsterID %d.", dunMonsterID)
+	case dunMonsterID&uniqueMonsterFlag != 0:
+		p.Unique = true
+		p.UniqueNum = dunMonsterID &^ uniqueMonsterFlag
+	case dunMonsterID > len(monsterConv):
+		return MonsterPlacement{}, fmt.Errorf("dun.DecodeMonsterID: invalid dunMonsterID %d; expected <= %d for normal monsters.", dunMonsterID, len(monsterConv))
+	default:
+		p.Type = monsterConv[dunMonsterID-1]
+	}
+	return p, nil
+}
+
+// Monsters returns the monsters placed on the dungeon map, ordered by row and
+// then by col. Invalid dunMonsterIDs are reported in err, after the valid ones
+// have been decoded.
+func (dungeon *Dungeon) Monsters() (placements []MonsterPlacement, err error) {
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			id := dungeon[col][row]["dunMonsterID"]
+			if id == 0 {
+				continue
+			}
+			p, pErr := DecodeMonsterID(id)
+			if pErr != nil {
+				if err == nil {
+					err = fmt.Errorf("dun.Dungeon.Monsters: cell (%d, %d): %v", col, row, pErr)
+				}
+				continue
+			}
+			p.Col, p.Row = col, row
+			placements = append(placements, p)
+		}
+	}
+	return placements, err
+}
+
+// DrawMonsters draws the placed monsters on top of the dungeon image. The
+// monsterFrames map from graphics name (e.g. "zombie") to the frame depicting
+// the monster, and the returned unknown placements could not be drawn; unique
+// monsters are always unknown.
+//
+// Note: As the monsters are drawn after all pillars, walls in front of a
+// monster do not occlude it.
+func DrawMonsters(dst draw.Image, m min.Metrics, placements []MonsterPlacement, pillarHeight int, monsterFrames map[string]image.Image) (unknown []MonsterPlacement) {
+	for _, p := range placements {
+		frame, ok := monsterFrames[p.Type.Graphics()]
+		if p.Unique || !ok {
+			unknown = append(unknown, p)
+			continue
+		}
+		DrawSprite(dst, m, p.Col, p.Row, pillarHeight, frame)
+	}
+	return unknown
+}
@@ -0,0 +1,354 @@
+package dun
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+)
+
+// An Object describes the graphics of an object placed in a dungeon.
+type Object struct {
+	// The name of the object.
+	Name string
+	// The CEL image containing the graphics of the object.
+	CelName string
+	// The frame of the CEL image depicting a non-animated object, or -1 if the
+	// object refers to an invalid frame.
+	FrameNum int
+	// Specifies if the object is animated, in which case all frames of the CEL
+	// image are used.
+	Animated bool
+	// The number of additional game ticks each frame of an animated object is
+	// displayed; e.g. 0 advances the animation every tick and 1 every second
+	// tick.
+	TicksPerFrame int
+	// The interaction class of the object.
+	Class ObjectClass
+}
+
+// An ObjectClass specifies how the player interacts with an object.
+type ObjectClass int
+
+// Object interaction classes.
+const (
+	// Decoration objects can't be interacted with.
+	Decoration ObjectClass = iota
+	// Chest objects contain items (e.g. chests, weapon racks and armor stands).
+	Chest
+	// Lever objects open doors or reveal parts of the dungeon.
+	Lever
+	// Shrine objects grant an effect when clicked (e.g. shrines and
+	// cauldrons).
+	Shrine
+	// Readable objects contain text (e.g. books).
+	Readable
+	// Quest objects take part in quests (e.g. the Pedestal of Blood).
+	Quest
+)
+
+// objectClassNames maps from object class to its name.
+var objectClassNames = []string{
+	Decoration: "decoration",
+	Chest:      "chest",
+	Lever:      "lever",
+	Shrine:     "shrine",
+	Readable:   "readable",
+	Quest:      "quest",
+}
+
+// String returns the name of the object class (e.g. "chest").
+func (class ObjectClass) String() string {
+	if class < 0 || int(class) >= len(objectClassNames) {
+		return fmt.Sprintf("ObjectClass(%d)", int(class))
+	}
+	return objectClassNames[class]
+}
+
+// Interactive returns true if the player may interact with objects of the
+// class.
+func (class ObjectClass) Interactive() bool {
+	return class != Decoration
+}
+
+// ObjectClasses returns the object interaction classes.
+func ObjectClasses() (classes []ObjectClass) {
+	for i := range objectClassNames {
+		classes = append(classes, ObjectClass(i))
+	}
+	return classes
+}
+
+// ParseObjectClass returns the object class of the given name (e.g. "chest").
+func ParseObjectClass(name string) (class ObjectClass, err error) {
+	for i, className := range objectClassNames {
+		if className == name {
+			return ObjectClass(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown object class %q.", name)
+}
+
+// Objects maps from object idx, as stored in the dunObjectIDs of DUN files, to
+// object graphics.
+var Objects = []Object{
+	0:   {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	1:   {"Lever (position a)", "lever.cel", 0, false, 0, Lever},
+	2:   {"Crucified Skeleton (south)", "cruxsk1.cel", 0, false, 0, Decoration},
+	3:   {"Crucified Skeleton (south east)", "cruxsk2.cel", 0, false, 0, Decoration},
+	4:   {"Crucified Skeleton (south west)", "cruxsk3.cel", 0, false, 0, Decoration},
+	5:   {"Angel", "angel.cel", 0, false, 0, Decoration},
+	6:   {"Banner (south east, theme 3)", "banner.cel", 1, false, 0, Decoration},
+	7:   {"Banner (theme 3)", "banner.cel", 0, false, 0, Decoration},
+	8:   {"Banner (south west, theme 3)", "banner.cel", 2, false, 0, Decoration},
+	9:   {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	10:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	11:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	12:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	13:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	14:  {"Ancient Tome or Book of Vileness", "book2.cel", 0, false, 0, Readable},
+	15:  {"Mythical Book", "book2.cel", 3, false, 0, Readable},
+	16:  {"Burning Cross", "burncros.cel", 0, true, 0, Decoration},
+	17:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	18:  {"Invalid 1", "l1braz.cel", -1, false, 0, Decoration},
+	19:  {"Candle (theme 1)", "candle2.cel", 0, true, 2, Decoration},
+	20:  {"Invalid 2", "l1braz.cel", -1, false, 0, Decoration},
+	21:  {"Cauldron", "cauldren.cel", 0, false, 0, Shrine},
+	22:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	23:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	24:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	25:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	26:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	27:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	28:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	29:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	30:  {"Flame", "flame1.cel", 0, false, 0, Decoration},
+	31:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	32:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	33:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	34:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	35:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	36:  {"Magic Circle Pentagram", "mcirl.cel", 0, false, 0, Decoration},
+	37:  {"Magic Circle", "mcirl.cel", 0, false, 0, Decoration}, // [frame 2 in game]
+	38:  {"Skull Fire (theme 3)", "skulfire.cel", 0, true, 2, Decoration},
+	39:  {"Skulpile", "skulpile.cel", -1, false, 0, Decoration},
+	40:  {"Invalid 3", "l1braz.cel", -1, false, 0, Decoration},
+	41:  {"Invalid 4", "l1braz.cel", -1, false, 0, Decoration},
+	42:  {"Invalid 5", "l1braz.cel", -1, false, 0, Decoration},
+	43:  {"Invalid 6", "l1braz.cel", -1, false, 0, Decoration},
+	44:  {"Invalid 7", "l1braz.cel", -1, false, 0, Decoration},
+	45:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	46:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	47:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	48:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	49:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	50:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	51:  {"Skull Lever", "switch4.cel", 0, false, 0, Lever},
+	52:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	53:  {"Traphole (south west)", "traphole.cel", 0, false, 0, Decoration},
+	54:  {"Traphole (south east)", "traphole.cel", 1, false, 0, Decoration},
+	55:  {"Tortured Soul 0", "tsoul.cel", 0, false, 0, Decoration},
+	56:  {"Tortured Soul 1", "tsoul.cel", 1, false, 0, Decoration},
+	57:  {"Tortured Soul 2", "tsoul.cel", 2, false, 0, Decoration},
+	58:  {"Tortured Soul 3", "tsoul.cel", 3, false, 0, Decoration},
+	59:  {"Tortured Soul 4", "tsoul.cel", 4, false, 0, Decoration},
+	60:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	61:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	62:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	63:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	64:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	65:  {"Nude", "nude2.cel", 0, true, 3, Decoration},
+	66:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	67:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	68:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	69:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	70:  {"Tortured Nude Man 0", "tnudem.cel", 0, false, 0, Decoration},
+	71:  {"Tortured Nude Man 1 (theme 6)", "tnudem.cel", 1, false, 0, Decoration},
+	72:  {"Tortured Nude Man 2 (theme 6)", "tnudem.cel", 2, false, 0, Decoration},
+	73:  {"Tortured Nude Man 3 (theme 6)", "tnudem.cel", 3, false, 0, Decoration},
+	74:  {"Tortured Nude Woman 0 (theme 6)", "tnudew.cel", 0, false, 0, Decoration},
+	75:  {"Tortured Nude Woman 1 (theme 6)", "tnudew.cel", 1, false, 0, Decoration},
+	76:  {"Tortured Nude Woman 2 (theme 6)", "tnudew.cel", 2, false, 0, Decoration},
+	77:  {"Small Chest", "chest1.cel", 0, false, 0, Chest},
+	78:  {"Small Chest", "chest1.cel", 0, false, 0, Chest},
+	79:  {"Small Chest", "chest1.cel", 0, false, 0, Chest},
+	80:  {"Chest", "chest2.cel", 0, false, 0, Chest},
+	81:  {"Chest", "chest2.cel", 0, false, 0, Chest},
+	82:  {"Chest", "chest2.cel", 0, false, 0, Chest},
+	83:  {"Large Chest", "chest3.cel", 0, false, 0, Chest},
+	84:  {"Large Chest", "chest3.cel", 0, false, 0, Chest},
+	85:  {"Large Chest", "chest3.cel", 0, false, 0, Chest},
+	86:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	87:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	88:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	89:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	90:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	91:  {"Pedestal of Blood", "pedistl.cel", 0, false, 0, Quest},
+	92:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	93:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	94:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	95:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	96:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	97:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	98:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	99:  {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	100: {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	101: {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	102: {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	103: {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	104: {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	105: {"Altar Boy", "altboy.cel", 0, false, 0, Decoration},
+	106: {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	107: {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+	108: {"Armor Stand (Warlord of Blood)", "armstand.cel", 0, false, 0, Chest},
+	109: {"Weapon Rack (Warlord of Blood)", "weapstnd.cel", 0, false, 0, Chest},
+	110: {"Wall Torch (south east)", "wtorch2.cel", 0, true, 1, Decoration},
+	111: {"Wall Torch (south west)", "wtorch1.cel", 0, true, 1, Decoration},
+	112: {"Mushroom Patch", "mushptch.cel", 0, false, 0, Chest},
+	113: {"Brazier", "l1braz.cel", 0, true, 1, Decoration},
+}
+
+// levelCelNames maps from level name to the CEL images which replace the
+// graphics of objects on the given level. Hellfire reuses the object idxs of
+// Diablo for the crypt (l5) and nest (l6) levels, but loads different graphics.
+//
+// Note: The object idxs added by Hellfire are missing from Objects, and are
+// thus reported as unknown by DrawObjects. The frame sizes of the Hellfire CEL
+// images in cel.ini are copied from the Diablo CEL images they replace, and
+// have not been verified.
+//
+// ref: ObjMasterLoadList
+var levelCelNames = map[string]map[string]string{
+	"l5": {
+		"barrel.cel":   "urn.cel",
+		"barrelex.cel": "urnexpld.cel",
+		"l1braz.cel":   "l5light.cel",
+		"lever.cel":    "l5lever.cel",
+		"sarc.cel":     "l5sarco.cel",
+	},
+	"l6": {
+		"barrel.cel":   "l6pod1.cel",
+		"barrelex.cel": "l6pod2.cel",
+	},
+}
+
+// LevelCelName returns the name of the CEL image containing the graphics of the
+// object on the given level (e.g. "l5").
+func (object Object) LevelCelName(levelName string) string {
+	if celName, ok := levelCelNames[levelName][object.CelName]; ok {
+		return celName
+	}
+	return object.CelName
+}
+
+// Frame returns the frame depicting the object, based on the decoded frames of
+// its CEL image; the first frame of animated objects, or nil if the object
+// refers to an invalid frame.
+func (object Object) Frame(frames []image.Image) image.Image {
+	frameNum := object.FrameNum
+	if object.Animated {
+		frameNum = 0
+	}
+	if frameNum < 0 || frameNum >= len(frames) {
+		return nil
+	}
+	return frames[frameNum]
+}
+
+// ObjectCelNames returns the names of the CEL images containing the graphics
+// of the objects placed in the dungeon, including ambient objects, based on the
+// given level (e.g. "l1").
+func (dungeon *Dungeon) ObjectCelNames(levelName string) (celNames []string) {
+	found := make(map[string]bool)
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			object, ok := dungeon.object(col, row)
+			if !ok {
+				continue
+			}
+			celName := object.LevelCelName(levelName)
+			if found[celName] {
+				continue
+			}
+			found[celName] = true
+			celNames = append(celNames, celName)
+		}
+	}
+	return celNames
+}
+
+// object returns the object placed at the given cell, which is either an
+// object of the DUN file or an ambient object. It returns false if the cell
+// contains no known object.
+func (dungeon *Dungeon) object(col, row int) (object Object, ok bool) {
+	cell := dungeon[col][row]
+	if id, ok := cell["ambientObject"]; ok && id < len(AmbientObjects) {
+		return AmbientObjects[id], true
+	}
+	id := cell["dunObjectID"]
+	if id <= 0 || id >= len(Objects) {
+		return Object{}, false
+	}
+	return Objects[id], true
+}
+
+// ObjectCount returns the number of objects placed in the dungeon, including
+// ambient objects.
+func (dungeon *Dungeon) ObjectCount() (n int) {
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			if _, ok := dungeon[col][row]["ambientObject"]; ok || dungeon[col][row]["dunObjectID"] > 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// RemoveObjects removes the objects placed in the dungeon, including ambient
+// objects, for which keep returns false. It returns the number of removed
+// objects.
+func (dungeon *Dungeon) RemoveObjects(keep func(object Object) bool) (n int) {
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			object, ok := dungeon.object(col, row)
+			if !ok || keep(object) {
+				continue
+			}
+			delete(dungeon[col][row], "ambientObject")
+			delete(dungeon[col][row], "dunObjectID")
+			n++
+		}
+	}
+	return n
+}
+
+// DrawObjects draws the objects placed in the dungeon on top of the dungeon
+// image of the given level (e.g. "l1"), using the object registry to select
+// the frame of each object. The objectFrames map from CEL image name to
+// decoded frames, and the returned unknown object idxs could not be drawn.
+//
+// Note: As the objects are drawn after all pillars, walls in front of an object
+// do not occlude it.
+func (dungeon *Dungeon) DrawObjects(dst draw.Image, m min.Metrics, levelName string, colCount, rowCount, pillarHeight int, objectFrames map[string][]image.Image) (unknown []int) {
+	for row := 0; row < rowCount; row++ {
+		for col := 0; col < colCount; col++ {
+			id := dungeon[col][row]["dunObjectID"]
+			object, ok := dungeon.object(col, row)
+			var frame image.Image
+			if ok {
+				frame = object.Frame(objectFrames[object.LevelCelName(levelName)])
+			}
+			if frame == nil {
+				// ambient objects have no dunObjectID to report.
+				if id > 0 {
+					unknown = append(unknown, id)
+				}
+				continue
+			}
+			DrawSprite(dst, m, col, row, pillarHeight, frame)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,146 @@
+package dun
+
+import (
+	"container/heap"
+	"fmt"
+	"image/color"
+	"image/draw"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/configs/sol"
+)
+
+// Path costs of straight and diagonal steps, as used by the game.
+//
+// ref: path_check_equal
+const (
+	straightCost = 2
+	diagonalCost = 3
+)
+
+// pathDirs contains the col and row offsets of the eight directions in which a
+// path may step.
+var pathDirs = [][2]int{
+	{-1, 0}, {1, 0}, {0, -1}, {0, 1},
+	{-1, -1}, {-1, 1}, {1, -1}, {1, 1},
+}
+
+// Path returns the shortest path of walkable cells from the start cell to the
+// goal cell, including both, using the A* search algorithm. The walkable cells
+// are those of Regions. A path may step diagonally, but may not cut the corner
+// of a cell which blocks movement, just like in the game.
+//
+// ref: FindPath
+// ref: path_solid_pieces
+func (dungeon *Dungeon) Path(start, goal [2]int, solids []sol.Solid) (cells [][2]int, err error) {
+	for _, cell := range [][2]int{start, goal} {
+		if !dungeon.walkable(cell[0], cell[1], solids) {
+			return nil, fmt.Errorf("dun.Dungeon.Path: cell (%d, %d) is not walkable.", cell[0], cell[1])
+		}
+	}
+	// estimate returns the cost of the path from the cell to the goal, if
+	// nothing blocks movement.
+	estimate := func(cell [2]int) int {
+		dc, dr := abs(goal[0]-cell[0]), abs(goal[1]-cell[1])
+		if dc > dr {
+			dc, dr = dr, dc
+		}
+		return dc*diagonalCost + (dr-dc)*straightCost
+	}
+	// costs maps from a visited cell to the cost of the cheapest known path
+	// from the start cell, and prevs from a visited cell to its predecessor on
+	// that path.
+	costs := map[[2]int]int{start: 0}
+	prevs := make(map[[2]int][2]int)
+	open := &pathQueue{{cell: start, priority: estimate(start)}}
+	for open.Len() > 0 {
+		node := heap.Pop(open).(pathNode)
+		cell := node.cell
+		if cell == goal {
+			for cell != start {
+				cells = append(cells, cell)
+				cell = prevs[cell]
+			}
+			cells = append(cells, start)
+			// reverse the path, which was traced from the goal.
+			for i, j := 0, len(cells)-1; i < j; i, j = i+1, j-1 {
+				cells[i], cells[j] = cells[j], cells[i]
+			}
+			return cells, nil
+		}
+		// skip outdated entries of cells whose cost has since been lowered.
+		if node.priority-estimate(cell) > costs[cell] {
+			continue
+		}
+		for _, dir := range pathDirs {
+			n := [2]int{cell[0] + dir[0], cell[1] + dir[1]}
+			if !dungeon.walkable(n[0], n[1], solids) {
+				continue
+			}
+			cost := costs[cell] + straightCost
+			if dir[0] != 0 && dir[1] != 0 {
+				// diagonal steps may not cut the corner of solid cells.
+				if !dungeon.walkable(cell[0]+dir[0], cell[1], solids) || !dungeon.walkable(cell[0], cell[1]+dir[1], solids) {
+					continue
+				}
+				cost = costs[cell] + diagonalCost
+			}
+			if prev, ok := costs[n]; ok && prev <= cost {
+				continue
+			}
+			costs[n] = cost
+			prevs[n] = cell
+			heap.Push(open, pathNode{cell: n, priority: cost + estimate(n)})
+		}
+	}
+	return nil, fmt.Errorf("dun.Dungeon.Path: no path from (%d, %d) to (%d, %d).", start[0], start[1], goal[0], goal[1])
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// A pathNode is a cell of the open set of the path search, prioritized by the
+// estimated cost of the path through it.
+type pathNode struct {
+	cell     [2]int
+	priority int
+}
+
+// pathQueue is a priority queue of path nodes, which implements the
+// heap.Interface.
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+// Colors used when marking paths on dungeon images.
+var (
+	PathColor    = color.NRGBA{R: 0x00, G: 0xFF, B: 0xFF, A: 0x80}
+	PathEndColor = color.NRGBA{R: 0x00, G: 0x80, B: 0xFF, A: 0xC0}
+)
+
+// MarkPath marks each cell of the path on the dungeon image using PathColor,
+// and its start and goal cells using PathEndColor.
+func MarkPath(dst draw.Image, m min.Metrics, cells [][2]int, pillarHeight int) {
+	mapWidth := dst.Bounds().Dx()
+	for i, cell := range cells {
+		c := PathColor
+		if i == 0 || i == len(cells)-1 {
+			c = PathEndColor
+		}
+		MarkCell(dst, m, cell[0], cell[1], mapWidth, pillarHeight, c)
+	}
+}
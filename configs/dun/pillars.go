@@ -0,0 +1,58 @@
+package dun
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// ParsePillars parses a raw dump of the pillar layer of a dungeon, as stored in
+// the memory of the game process, and stores each pillarNum at a coordinate in
+// the dungeon. The pillars refer to the MIN file of the given level (e.g.
+// "l1"), which is the tileset the dungeon is rendered with.
+//
+// Below is a description of the pillar layer:
+//
+// Pillar layer:
+//    pillarNumsPlus1 [ColMax][RowMax]int32 // column major; 0 if no pillar.
+//
+// Pillars are only stored once the entire layer has been validated.
+func (dungeon *Dungeon) ParsePillars(r io.Reader, levelName string) (err error) {
+	return dungeon.ParsePillarsFrom(mpq.Default(), r, levelName)
+}
+
+// ParsePillarsFrom parses a raw dump of the pillar layer of a dungeon, as
+// described by ParsePillars, whose MIN file is provided by the store s.
+func (dungeon *Dungeon) ParsePillarsFrom(s *mpq.Store, r io.Reader, levelName string) (err error) {
+	pillars, err := min.ParseFrom(s, levelName+".min")
+	if err != nil {
+		return err
+	}
+	var pillarNumsPlus1 [ColMax][RowMax]int32
+	err = binary.Read(r, binary.LittleEndian, &pillarNumsPlus1)
+	if err != nil {
+		return fmt.Errorf("dun.ParsePillars: unable to read pillar layer; %s", err)
+	}
+	for col := 0; col < ColMax; col++ {
+		for row := 0; row < RowMax; row++ {
+			pillarNumPlus1 := int(pillarNumsPlus1[col][row])
+			if pillarNumPlus1 < 0 || pillarNumPlus1 > len(pillars) {
+				return fmt.Errorf("dun.ParsePillars: invalid pillarNumPlus1 (%d) at col %d, row %d (pillar count: %d).", pillarNumPlus1, col, row, len(pillars))
+			}
+		}
+	}
+	for col := 0; col < ColMax; col++ {
+		for row := 0; row < RowMax; row++ {
+			pillarNumPlus1 := int(pillarNumsPlus1[col][row])
+			if pillarNumPlus1 == 0 {
+				delete(dungeon[col][row], "pillarNum")
+				continue
+			}
+			dungeon[col][row]["pillarNum"] = pillarNumPlus1 - 1
+		}
+	}
+	return nil
+}
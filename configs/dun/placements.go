@@ -0,0 +1,128 @@
+package dun
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Placement places an object and/or a monster on a cell of the dungeon map.
+type Placement struct {
+	// Col and Row of the cell on the dungeon map.
+	Col, Row int
+	// ObjectID is the dunObjectID of the placed object (an index into
+	// Objects), 0 to remove the object of the cell, or -1 to leave the object
+	// of the cell as is.
+	ObjectID int
+	// MonsterID is the dunMonsterID of the placed monster, 0 to remove the
+	// monster of the cell, or -1 to leave the monster of the cell as is.
+	MonsterID int
+}
+
+// ParsePlacements parses the placements of a CSV file, as a lightweight
+// alternative to editing DUN files. The first line of the CSV file names its
+// columns; "col", "row" and at least one of "object" and "monster", in any
+// order. Empty object and monster fields leave the cell as is. Below is an
+// example which places a chest (object 5) and removes the monster of a cell:
+//
+//    col,row,object,monster
+//    30,40,5,
+//    32,44,,0
+func ParsePlacements(r io.Reader) (placements []Placement, err error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// columns maps from column name to the index of the column.
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "col", "row", "object", "monster":
+		default:
+			return nil, fmt.Errorf("dun.ParsePlacements: invalid column %q; expected col, row, object or monster.", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("dun.ParsePlacements: duplicate column %q.", name)
+		}
+		columns[name] = i
+	}
+	_, hasCol := columns["col"]
+	_, hasRow := columns["row"]
+	_, hasObject := columns["object"]
+	_, hasMonster := columns["monster"]
+	if !hasCol || !hasRow || !(hasObject || hasMonster) {
+		return nil, errors.New("dun.ParsePlacements: missing columns; expected col, row and object or monster.")
+	}
+	// line is the line number of the record, as the placements contain no
+	// multi-line fields.
+	line := 1
+	for {
+		line++
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// field returns the value of the given column, or -1 if the column or
+		// its field is empty.
+		field := func(name string) (int, error) {
+			i, ok := columns[name]
+			if !ok || len(strings.TrimSpace(record[i])) == 0 {
+				return -1, nil
+			}
+			x, err := strconv.Atoi(strings.TrimSpace(record[i]))
+			if err != nil || x < 0 {
+				return 0, fmt.Errorf("dun.ParsePlacements: line %d: invalid %s %q.", line, name, record[i])
+			}
+			return x, nil
+		}
+		var p Placement
+		for _, f := range []struct {
+			name string
+			x    *int
+		}{{"col", &p.Col}, {"row", &p.Row}, {"object", &p.ObjectID}, {"monster", &p.MonsterID}} {
+			*f.x, err = field(f.name)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if p.Col == -1 || p.Row == -1 {
+			return nil, fmt.Errorf("dun.ParsePlacements: line %d: missing col or row.", line)
+		}
+		if p.Col >= ColMax || p.Row >= RowMax {
+			return nil, fmt.Errorf("dun.ParsePlacements: line %d: cell (%d, %d) outside of the dungeon map.", line, p.Col, p.Row)
+		}
+		if p.ObjectID >= len(Objects) {
+			return nil, fmt.Errorf("dun.ParsePlacements: line %d: invalid object %d; expected below %d.", line, p.ObjectID, len(Objects))
+		}
+		placements = append(placements, p)
+	}
+	return placements, nil
+}
+
+// Place places the objects and monsters of the placements on the dungeon map,
+// replacing those of the DUN file; the ambient objects of replaced cells are
+// removed as well. Later placements of the same cell take precedence.
+func (dungeon *Dungeon) Place(placements []Placement) {
+	for _, p := range placements {
+		cell := dungeon[p.Col][p.Row]
+		if p.ObjectID >= 0 {
+			delete(cell, "ambientObject")
+			cell["dunObjectID"] = p.ObjectID
+		}
+		if p.MonsterID >= 0 {
+			cell["dunMonsterID"] = p.MonsterID
+		}
+	}
+}
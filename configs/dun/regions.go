@@ -0,0 +1,135 @@
+package dun
+
+import (
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/configs/sol"
+)
+
+// Region is a connected area of walkable cells on the dungeon map.
+type Region struct {
+	// ID of the region, starting at 1.
+	ID int `json:"id"`
+	// Cells contains the col and row coordinates of each cell of the region.
+	Cells [][2]int `json:"cells"`
+}
+
+// Regions segments the dungeon into connected regions of walkable cells. A cell
+// is walkable if it has a pillar which does not block movement, according to
+// the solid properties of the level's pillars. Two walkable cells are connected
+// if they are neighbours on the same col or row.
+//
+// The "regionID" key of each walkable cell is set to the ID of its region, after
+// removing the "regionID" keys of any previous segmentation; the keys are only
+// output, so the dungeon may be segmented again (e.g. using other solids).
+//
+// ref: nSolidTable (sol & 0x01 blocks movement)
+func (dungeon *Dungeon) Regions(solids []sol.Solid) (regions []Region) {
+	walkable := func(col, row int) bool {
+		return dungeon.walkable(col, row, solids)
+	}
+	for col := 0; col < ColMax; col++ {
+		for row := 0; row < RowMax; row++ {
+			delete(dungeon[col][row], "regionID")
+		}
+	}
+	// visited tracks the cells which have been added to a region.
+	var visited [ColMax][RowMax]bool
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			if visited[col][row] || !walkable(col, row) {
+				continue
+			}
+			// flood fill a new region, starting at the current cell.
+			region := Region{ID: len(regions) + 1}
+			queue := [][2]int{{col, row}}
+			visited[col][row] = true
+			for len(queue) > 0 {
+				cell := queue[0]
+				queue = queue[1:]
+				region.Cells = append(region.Cells, cell)
+				dungeon[cell[0]][cell[1]]["regionID"] = region.ID
+				neighbours := [][2]int{
+					{cell[0] - 1, cell[1]},
+					{cell[0] + 1, cell[1]},
+					{cell[0], cell[1] - 1},
+					{cell[0], cell[1] + 1},
+				}
+				for _, n := range neighbours {
+					if !walkable(n[0], n[1]) {
+						continue
+					}
+					if visited[n[0]][n[1]] {
+						continue
+					}
+					visited[n[0]][n[1]] = true
+					queue = append(queue, n)
+				}
+			}
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// walkable reports whether the cell at the given col and row has a pillar which
+// does not block movement, according to the solid properties of the level's
+// pillars.
+//
+// ref: nSolidTable (sol & 0x01 blocks movement)
+func (dungeon *Dungeon) walkable(col, row int, solids []sol.Solid) bool {
+	if col < 0 || col >= ColMax || row < 0 || row >= RowMax {
+		return false
+	}
+	pillarNum, ok := dungeon[col][row]["pillarNum"]
+	if !ok || pillarNum >= len(solids) {
+		return false
+	}
+	return !solids[pillarNum].Sol0x01
+}
+
+// RegionColor returns a distinct semi-transparent color for the region with
+// the given ID.
+func RegionColor(id int) color.Color {
+	// spread the hues of consecutive regions using the golden ratio.
+	hue := float64(id) * 0.618033988749895
+	hue -= float64(int(hue))
+	return hueColor(hue, 0x80)
+}
+
+// hueColor returns the fully saturated color of the given hue (0 <= hue < 1).
+func hueColor(hue float64, alpha uint8) color.Color {
+	h := hue * 6
+	x := uint8(255 * (1 - math.Abs(math.Mod(h, 2)-1)))
+	var r, g, b uint8
+	switch int(h) {
+	case 0:
+		r, g, b = 255, x, 0
+	case 1:
+		r, g, b = x, 255, 0
+	case 2:
+		r, g, b = 0, 255, x
+	case 3:
+		r, g, b = 0, x, 255
+	case 4:
+		r, g, b = x, 0, 255
+	default:
+		r, g, b = 255, 0, x
+	}
+	return color.NRGBA{R: r, G: g, B: b, A: alpha}
+}
+
+// MarkRegions marks each cell of the regions on the dungeon image, using the
+// colors of RegionColor.
+func MarkRegions(dst draw.Image, m min.Metrics, regions []Region, pillarHeight int) {
+	mapWidth := dst.Bounds().Dx()
+	for _, region := range regions {
+		c := RegionColor(region.ID)
+		for _, cell := range region.Cells {
+			MarkCell(dst, m, cell[0], cell[1], mapWidth, pillarHeight, c)
+		}
+	}
+}
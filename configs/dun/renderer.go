@@ -0,0 +1,184 @@
+package dun
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"sort"
+)
+
+// A Renderer is a backend which draws the pillars and special frames of the
+// dungeon image. Each source image is prepared once before it is drawn onto any
+// number of cells, which lets backends precompute whatever speeds up drawing.
+type Renderer interface {
+	// Prepare returns a sprite which draws the given source image.
+	Prepare(src image.Image) Sprite
+}
+
+// A Sprite is a prepared source image of a Renderer.
+type Sprite interface {
+	// Draw draws the sprite onto the rectangle of dst, using the Porter-Duff
+	// "src over dst" operator. The sprite is aligned with the top left corner
+	// of the rectangle, and clipped to it.
+	Draw(dst *image.RGBA, rect image.Rectangle)
+}
+
+// DefaultRenderer is the renderer used by Image, ImageWithSpecials and
+// ImageRect. It may be changed, e.g. based on a command line flag, before any
+// dungeon image is rendered.
+var DefaultRenderer Renderer = DrawRenderer{}
+
+// renderers maps from renderer name to renderer.
+var renderers = map[string]Renderer{
+	"draw": DrawRenderer{},
+	"pix":  PixRenderer{},
+}
+
+// ParseRenderer returns the renderer of the given name ("draw" or "pix").
+func ParseRenderer(name string) (r Renderer, err error) {
+	r, ok := renderers[name]
+	if !ok {
+		return nil, fmt.Errorf("dun.ParseRenderer: unknown renderer %q; expected one of %v.", name, RendererNames())
+	}
+	return r, nil
+}
+
+// RendererNames returns the sorted names of the available renderers.
+func RendererNames() (names []string) {
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DrawRenderer is a renderer which draws each sprite using image/draw. It
+// supports any source image, and is the reference of the other renderers.
+type DrawRenderer struct{}
+
+// Prepare returns a sprite which draws src using draw.Draw.
+func (DrawRenderer) Prepare(src image.Image) Sprite {
+	return drawSprite{src}
+}
+
+// A drawSprite is a sprite of DrawRenderer.
+type drawSprite struct {
+	src image.Image
+}
+
+// Draw draws the sprite onto the rectangle of dst.
+func (sprite drawSprite) Draw(dst *image.RGBA, rect image.Rectangle) {
+	draw.Draw(dst, rect, sprite.src, sprite.src.Bounds().Min, draw.Over)
+}
+
+// PixRenderer is a renderer which copies the pixels of each sprite directly
+// between the Pix slices of the images. The pixels of each source image are
+// classified once, as pillars and special frames consist mostly of fully opaque
+// and fully transparent pixels; runs of opaque pixels are copied as is,
+// transparent pixels are skipped and only the remaining pixels are blended.
+//
+// The rendered images are identical to those of DrawRenderer.
+type PixRenderer struct{}
+
+// Prepare returns a sprite which draws src by copying the pixels of its opaque
+// runs.
+func (PixRenderer) Prepare(src image.Image) Sprite {
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		bounds := src.Bounds()
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Rect, src, bounds.Min, draw.Src)
+	}
+	sprite := &pixSprite{src: rgba, rows: make([][]pixRun, rgba.Rect.Dy())}
+	for y := range sprite.rows {
+		sprite.rows[y] = pixRuns(rgba, y)
+	}
+	return sprite
+}
+
+// A pixSprite is a sprite of PixRenderer.
+type pixSprite struct {
+	src *image.RGBA
+	// rows contains the runs of each row of the source image.
+	rows [][]pixRun
+}
+
+// A pixRun is a run of pixels within a row of a source image, which are either
+// all opaque or all translucent. Transparent pixels are not part of any run.
+type pixRun struct {
+	// start and end are the x offsets of the run [start, end), relative to the
+	// left edge of the source image.
+	start, end int
+	opaque     bool
+}
+
+// pixRuns returns the runs of the given row of src.
+func pixRuns(src *image.RGBA, y int) (runs []pixRun) {
+	i := src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y)
+	for x := 0; x < src.Rect.Dx(); x++ {
+		a := src.Pix[i+4*x+3]
+		if a == 0 {
+			continue
+		}
+		opaque := a == 0xFF
+		if n := len(runs); n > 0 && runs[n-1].end == x && runs[n-1].opaque == opaque {
+			runs[n-1].end++
+			continue
+		}
+		runs = append(runs, pixRun{start: x, end: x + 1, opaque: opaque})
+	}
+	return runs
+}
+
+// Draw draws the sprite onto the rectangle of dst.
+func (sprite *pixSprite) Draw(dst *image.RGBA, rect image.Rectangle) {
+	src := sprite.src
+	// clip the rectangle to the sprite and the destination image, and locate
+	// the first source pixel.
+	rect = rect.Intersect(image.Rectangle{Min: rect.Min, Max: rect.Min.Add(src.Rect.Size())})
+	clipped := rect.Intersect(dst.Rect)
+	if clipped.Empty() {
+		return
+	}
+	sp := clipped.Min.Sub(rect.Min)
+	for y := 0; y < clipped.Dy(); y++ {
+		srcRow := src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+sp.Y+y)
+		dstRow := dst.PixOffset(clipped.Min.X-sp.X, clipped.Min.Y+y)
+		for _, run := range sprite.rows[sp.Y+y] {
+			start, end := run.start, run.end
+			if start < sp.X {
+				start = sp.X
+			}
+			if end > sp.X+clipped.Dx() {
+				end = sp.X + clipped.Dx()
+			}
+			if start >= end {
+				continue
+			}
+			spix := src.Pix[srcRow+4*start : srcRow+4*end]
+			dpix := dst.Pix[dstRow+4*start : dstRow+4*end]
+			if run.opaque {
+				copy(dpix, spix)
+				continue
+			}
+			blendOver(dpix, spix)
+		}
+	}
+}
+
+// blendOver blends the premultiplied pixels of spix over those of dpix, using
+// the same arithmetic as image/draw.
+func blendOver(dpix, spix []byte) {
+	const m = 1<<16 - 1
+	for i := 0; i+4 <= len(spix); i += 4 {
+		sr := uint32(spix[i+0]) * 0x101
+		sg := uint32(spix[i+1]) * 0x101
+		sb := uint32(spix[i+2]) * 0x101
+		sa := uint32(spix[i+3]) * 0x101
+		a := (m - sa) * 0x101
+		dpix[i+0] = uint8((uint32(dpix[i+0])*a/m + sr) >> 8)
+		dpix[i+1] = uint8((uint32(dpix[i+1])*a/m + sg) >> 8)
+		dpix[i+2] = uint8((uint32(dpix[i+2])*a/m + sb) >> 8)
+		dpix[i+3] = uint8((uint32(dpix[i+3])*a/m + sa) >> 8)
+	}
+}
@@ -0,0 +1,108 @@
+package dun
+
+import (
+	"image"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/images/cel"
+)
+
+// SpecialCels maps from level name to the special CEL image of the level,
+// which contains overlay graphics (e.g. arches and door frames) drawn on top of
+// specific pillars, in order to let the player walk behind them.
+//
+// ref: cel.SpecialCels
+var SpecialCels = cel.SpecialCels
+
+// A specialRule specifies that a special frame should be drawn on the cell at
+// the relative offset (dCol, dRow) of each cell containing the given pillar.
+// Both the pillarNum and the frameNum are stored plus one, as they are
+// referenced by the game.
+type specialRule struct {
+	PillarNumPlus1 int
+	DCol, DRow     int
+	FrameNumPlus1  int
+}
+
+// specialRules maps from level name to the special rules of the level.
+//
+// Note: No special rules are known for the town, and the l3 and l4 levels have
+// no special CEL images.
+var specialRules = map[string][]specialRule{
+	// ref: DRLG_InitL1Vals
+	"l1": {
+		{12, 0, 0, 1},
+		{11, 0, 0, 2},
+		{71, 0, 0, 1},
+		{253, 0, 0, 3},
+		{267, 0, 0, 6},
+		{259, 0, 0, 5},
+		{249, 0, 0, 2},
+		{325, 0, 0, 2},
+		{321, 0, 0, 1},
+		{255, 0, 0, 4},
+		{211, 0, 0, 1},
+		{344, 0, 0, 2},
+		{341, 0, 0, 1},
+		{331, 0, 0, 2},
+		{418, 0, 0, 1},
+		{421, 0, 0, 2},
+	},
+	// ref: DRLG_InitL2Vals
+	"l2": {
+		{541, 0, 0, 5},
+		{178, 0, 0, 5},
+		{551, 0, 0, 5},
+		{542, 0, 0, 6},
+		{553, 0, 0, 6},
+		{13, 0, 0, 5},
+		{17, 0, 0, 6},
+		{132, 0, 1, 2},
+		{132, 0, 2, 1},
+		{135, 1, 0, 3},
+		{135, 2, 0, 4},
+		{139, 1, 0, 3},
+		{139, 2, 0, 4},
+	},
+}
+
+// SetSpecials stores the frameNum of the special CEL image to draw on top of
+// each cell of the dungeon map, based on the pillars of the given level (e.g.
+// "l1"). The special frameNums are stored using the "specialFrameNum" key.
+//
+// ref: SpecialCels
+func (dungeon *Dungeon) SetSpecials(levelName string) {
+	rules := specialRules[levelName]
+	if len(rules) == 0 {
+		return
+	}
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			pillarNum, ok := dungeon[col][row]["pillarNum"]
+			if !ok {
+				continue
+			}
+			for _, rule := range rules {
+				if pillarNum != rule.PillarNumPlus1-1 {
+					continue
+				}
+				c, r := col+rule.DCol, row+rule.DRow
+				if c >= ColMax || r >= RowMax {
+					continue
+				}
+				dungeon[c][r]["specialFrameNum"] = rule.FrameNumPlus1 - 1
+			}
+		}
+	}
+}
+
+// drawSpecial draws the special frame of the cell, if any.
+func (dungeon *Dungeon) drawSpecial(dst *image.RGBA, m min.Metrics, col, row, mapWidth, pillarHeight int, specialFrames []image.Image, specialSprites []Sprite, scale int) {
+	frameNum, ok := dungeon[col][row]["specialFrameNum"]
+	if !ok || frameNum < 0 || frameNum >= len(specialFrames) {
+		return
+	}
+	// locate the frame using its full size, and draw it scaled.
+	rect := scaleRect(getSpriteRect(m, col, row, mapWidth, pillarHeight, specialFrames[frameNum]), scale)
+	specialSprites[frameNum].Draw(dst, rect)
+}
@@ -0,0 +1,135 @@
+package dun
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+)
+
+// StairsKind specifies the kind of a level transition.
+type StairsKind int
+
+// Level transition kinds.
+const (
+	// StairsUp leads to the previous level.
+	StairsUp StairsKind = iota
+	// StairsDown leads to the next level.
+	StairsDown
+	// TownWarp leads directly to town.
+	TownWarp
+)
+
+func (kind StairsKind) String() string {
+	switch kind {
+	case StairsUp:
+		return "stairs up"
+	case StairsDown:
+		return "stairs down"
+	case TownWarp:
+		return "town warp"
+	}
+	return "unknown stairs"
+}
+
+// Stairs represents the location of a level transition on the dungeon map.
+type Stairs struct {
+	Col, Row int
+	Kind     StairsKind
+}
+
+// stairsPillars maps from level name to the pillars of each level transition
+// kind. The pillarNums are stored plus one, as they are referenced by the game.
+//
+// ref: trigger lists of the game (L1UpList, L1DownList, etc)
+var stairsPillars = map[string]map[StairsKind][]int{
+	"town": {
+		StairsDown: {715, 716, 719, 720, 721, 723, 724, 725, 726, 727},
+	},
+	"l1": {
+		StairsUp:   {127, 129, 130, 131, 132, 133, 135, 137, 138, 139, 140},
+		StairsDown: {106, 107, 108, 109, 110, 112, 114, 115, 118},
+	},
+	"l2": {
+		StairsUp:   {266, 267},
+		StairsDown: {269, 270, 271, 272},
+		TownWarp:   {558, 559},
+	},
+	"l3": {
+		StairsUp:   {170, 171, 172, 173, 174, 175, 176, 177, 178, 179, 180, 181, 182, 183},
+		StairsDown: {162, 163, 164, 165, 166, 167, 168, 169},
+		TownWarp:   {548, 549, 550, 551, 552, 553, 554, 555, 556, 557, 558, 559, 560},
+	},
+	"l4": {
+		StairsUp:   {82, 83, 90},
+		StairsDown: {120, 130, 131, 132, 133},
+		TownWarp:   {421, 422, 429},
+	},
+}
+
+// Stairs locates the level transitions of the dungeon, based on the pillars
+// associated with each coordinate of the dungeon map. The level name (e.g.
+// "l1") is used to identify which pillars that belong to a level transition.
+//
+// ref: GetLevelName
+func (dungeon *Dungeon) Stairs(levelName string) (stairs []Stairs) {
+	kinds := make(map[int]StairsKind)
+	for kind, pillarNumsPlus1 := range stairsPillars[levelName] {
+		for _, pillarNumPlus1 := range pillarNumsPlus1 {
+			kinds[pillarNumPlus1-1] = kind
+		}
+	}
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			pillarNum, ok := dungeon[col][row]["pillarNum"]
+			if !ok {
+				continue
+			}
+			kind, ok := kinds[pillarNum]
+			if !ok {
+				continue
+			}
+			stairs = append(stairs, Stairs{Col: col, Row: row, Kind: kind})
+		}
+	}
+	return stairs
+}
+
+// StairsColor maps from level transition kind to the color used when marking
+// it on dungeon images.
+var StairsColor = map[StairsKind]color.Color{
+	StairsUp:   color.NRGBA{R: 0x00, G: 0xFF, B: 0x00, A: 0x80},
+	StairsDown: color.NRGBA{R: 0xFF, G: 0x00, B: 0x00, A: 0x80},
+	TownWarp:   color.NRGBA{R: 0x00, G: 0x80, B: 0xFF, A: 0x80},
+}
+
+// MarkStairs marks each level transition on the dungeon image, using the
+// colors of StairsColor.
+func MarkStairs(dst draw.Image, m min.Metrics, stairs []Stairs, pillarHeight int) {
+	mapWidth := dst.Bounds().Dx()
+	for _, s := range stairs {
+		MarkCell(dst, m, s.Col, s.Row, mapWidth, pillarHeight, StairsColor[s.Kind])
+	}
+}
+
+// MarkCell draws a diamond of the given color on the floor of the cell at the
+// col and row coordinates of the dungeon image.
+//
+// ref: GetFloorRect
+func MarkCell(dst draw.Image, m min.Metrics, col, row, mapWidth, pillarHeight int, c color.Color) {
+	floor := GetFloorRect(m, col, row, mapWidth, pillarHeight)
+	src := image.NewUniform(c)
+	midX := floor.Min.X + floor.Dx()/2
+	midY := floor.Min.Y + floor.Dy()/2
+	for y := floor.Min.Y; y < floor.Max.Y; y++ {
+		// half width of the diamond at the current line.
+		dy := y - midY
+		if dy < 0 {
+			dy = -dy - 1
+		}
+		halfWidth := floor.Dx()/2 - dy*floor.Dx()/floor.Dy()
+		line := image.Rect(midX-halfWidth, y, midX+halfWidth, y+1)
+		draw.Draw(dst, line, src, image.ZP, draw.Over)
+	}
+}
@@ -0,0 +1,68 @@
+package dun
+
+import (
+	"bytes"
+
+	"github.com/mewrnd/blizzconv/configs/sol"
+)
+
+// Characters of the text map.
+const (
+	TextEmpty      = ' '
+	TextWall       = '#'
+	TextFloor      = '.'
+	TextDoor       = 'D'
+	TextStairsUp   = '<'
+	TextStairsDown = '>'
+	TextTownWarp   = '^'
+)
+
+// Text returns a compact text map of the dungeon, with one line per row and
+// one character per col. Cells whose pillars block movement are drawn as walls
+// ('#'), other cells as floor ('.'); doors ('D'), stairs up ('<'), stairs down
+// ('>') and town warps ('^') are located using the pillars of the given level
+// (e.g. "l1"). Cells without pillars are left blank.
+//
+// ref: nSolidTable (sol & 0x01 blocks movement)
+func (dungeon *Dungeon) Text(colCount, rowCount int, levelName string, solids []sol.Solid) string {
+	stairs := make(map[[2]int]StairsKind)
+	for _, s := range dungeon.Stairs(levelName) {
+		stairs[[2]int{s.Col, s.Row}] = s.Kind
+	}
+	buf := new(bytes.Buffer)
+	for row := 0; row < rowCount; row++ {
+		line := make([]byte, colCount)
+		for col := 0; col < colCount; col++ {
+			line[col] = dungeon.textCell(col, row, levelName, solids, stairs)
+		}
+		buf.Write(bytes.TrimRight(line, " "))
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// textCell returns the text map character of the cell at the col and row
+// coordinates.
+func (dungeon *Dungeon) textCell(col, row int, levelName string, solids []sol.Solid, stairs map[[2]int]StairsKind) byte {
+	pillarNum, ok := dungeon[col][row]["pillarNum"]
+	if !ok {
+		return TextEmpty
+	}
+	if kind, ok := stairs[[2]int{col, row}]; ok {
+		switch kind {
+		case StairsUp:
+			return TextStairsUp
+		case StairsDown:
+			return TextStairsDown
+		case TownWarp:
+			return TextTownWarp
+		}
+	}
+	if IsDoor(levelName, pillarNum) {
+		return TextDoor
+	}
+	if pillarNum < len(solids) && solids[pillarNum].Sol0x01 {
+		return TextWall
+	}
+	return TextFloor
+}
@@ -0,0 +1,63 @@
+package dun
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/images/label"
+)
+
+// A Towner represents an NPC of the town, located at a given col and row of
+// the dungeon map.
+type Towner struct {
+	// The name of the NPC.
+	Name string
+	// The shop or service of the NPC, if any.
+	Shop string
+	// The location of the NPC.
+	Col, Row int
+}
+
+// Towners contains the NPCs of the town, located at their starting positions.
+//
+// ref: InitTowners
+var Towners = []Towner{
+	{Name: "Griswold", Shop: "Blacksmith", Col: 62, Row: 63},
+	{Name: "Ogden", Shop: "Tavern of the Rising Sun", Col: 55, Row: 62},
+	{Name: "Wounded Townsman", Col: 24, Row: 32},
+	{Name: "Adria", Shop: "Witch", Col: 80, Row: 20},
+	{Name: "Gillian", Shop: "Barmaid", Col: 43, Row: 66},
+	{Name: "Wirt", Shop: "Peg-legged boy", Col: 11, Row: 53},
+	{Name: "Pepin", Shop: "Healer", Col: 55, Row: 79},
+	{Name: "Cain", Shop: "Elder", Col: 62, Row: 71},
+	{Name: "Farnham", Shop: "Drunk", Col: 71, Row: 84},
+	{Name: "Cow", Col: 58, Row: 16},
+	{Name: "Cow", Col: 56, Row: 14},
+	{Name: "Cow", Col: 59, Row: 20},
+}
+
+// TownerColor is the color used when annotating NPCs on dungeon images.
+var TownerColor color.Color = color.NRGBA{R: 0xFF, G: 0xD7, B: 0x00, A: 0xFF}
+
+// townerMarkColor is the color used when marking the location of NPCs.
+var townerMarkColor = color.NRGBA{R: 0xFF, G: 0xD7, B: 0x00, A: 0x80}
+
+// LabelTowners marks the location of each NPC on the town image and labels it
+// with the name and shop of the NPC.
+func LabelTowners(dst draw.Image, m min.Metrics, towners []Towner, pillarHeight int) {
+	mapWidth := dst.Bounds().Dx()
+	for _, towner := range towners {
+		MarkCell(dst, m, towner.Col, towner.Row, mapWidth, pillarHeight, townerMarkColor)
+	}
+	for _, towner := range towners {
+		floor := GetFloorRect(m, towner.Col, towner.Row, mapWidth, pillarHeight)
+		pt := image.Pt(floor.Min.X+floor.Dx()/2, floor.Min.Y-label.Height())
+		label.DrawCentered(dst, pt, towner.Name, TownerColor)
+		if len(towner.Shop) > 0 {
+			pt.Y -= label.Height()
+			label.DrawCentered(dst, pt, towner.Shop, color.White)
+		}
+	}
+}
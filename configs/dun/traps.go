@@ -0,0 +1,102 @@
+package dun
+
+import (
+	"image/color"
+	"image/draw"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/configs/sol"
+)
+
+// A Trap is a wall trap placed in the dungeon, which fires a missile when the
+// object at its trigger cell is operated.
+type Trap struct {
+	// Col and Row of the trap on the dungeon map.
+	Col, Row int
+	// The name of the trap object.
+	Name string
+	// Col and Row of the object which triggers the trap, or -1 if no trigger
+	// was located.
+	TriggerCol, TriggerRow int
+	// The name of the trigger object, or "Door" if the trap is triggered by a
+	// door.
+	TriggerName string
+}
+
+// trapDirs maps from the object idx of a wall trap to the direction, in cols
+// and rows, in which the trap faces its trigger.
+//
+// ref: AddObjTraps
+var trapDirs = map[int][2]int{
+	// Traphole (south west): placed at a lower col than its trigger.
+	53: {1, 0},
+	// Traphole (south east): placed at a lower row than its trigger.
+	54: {0, 1},
+}
+
+// Traps locates the wall traps of the dungeon and the object which triggers
+// each trap, based on the pillars of the given level (e.g. "l1"). The trigger
+// is the first object or door in front of the trap, before the line of sight
+// is blocked by a solid pillar.
+//
+// ref: AddObjTraps
+func (dungeon *Dungeon) Traps(levelName string, solids []sol.Solid) (traps []Trap) {
+	for row := 0; row < RowMax; row++ {
+		for col := 0; col < ColMax; col++ {
+			id := dungeon[col][row]["dunObjectID"]
+			dir, ok := trapDirs[id]
+			if !ok {
+				continue
+			}
+			trap := Trap{Col: col, Row: row, Name: Objects[id].Name}
+			trap.TriggerCol, trap.TriggerRow, trap.TriggerName = dungeon.trapTrigger(col, row, dir, levelName, solids)
+			traps = append(traps, trap)
+		}
+	}
+	return traps
+}
+
+// trapTrigger returns the location and name of the object which triggers the
+// trap at the given col and row, or -1 if no trigger was located.
+func (dungeon *Dungeon) trapTrigger(col, row int, dir [2]int, levelName string, solids []sol.Solid) (triggerCol, triggerRow int, triggerName string) {
+	for {
+		col += dir[0]
+		row += dir[1]
+		if col >= ColMax || row >= RowMax {
+			return -1, -1, ""
+		}
+		cell := dungeon[col][row]
+		id := cell["dunObjectID"]
+		if _, isTrap := trapDirs[id]; id > 0 && id < len(Objects) && !isTrap {
+			return col, row, Objects[id].Name
+		}
+		pillarNum, ok := cell["pillarNum"]
+		if !ok {
+			return -1, -1, ""
+		}
+		if IsDoor(levelName, pillarNum) {
+			return col, row, "Door"
+		}
+		if pillarNum < len(solids) && solids[pillarNum].Sol0x01 {
+			return -1, -1, ""
+		}
+	}
+}
+
+// Colors used when marking traps and their triggers on dungeon images.
+var (
+	TrapColor    = color.NRGBA{R: 0xFF, G: 0x00, B: 0xFF, A: 0x80}
+	TriggerColor = color.NRGBA{R: 0xFF, G: 0xFF, B: 0x00, A: 0x80}
+)
+
+// MarkTraps marks each trap and its trigger on the dungeon image, using
+// TrapColor and TriggerColor respectively.
+func MarkTraps(dst draw.Image, m min.Metrics, traps []Trap, pillarHeight int) {
+	mapWidth := dst.Bounds().Dx()
+	for _, trap := range traps {
+		MarkCell(dst, m, trap.Col, trap.Row, mapWidth, pillarHeight, TrapColor)
+		if trap.TriggerCol != -1 {
+			MarkCell(dst, m, trap.TriggerCol, trap.TriggerRow, mapWidth, pillarHeight, TriggerColor)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package dun
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/mewrnd/blizzconv/configs/sol"
+)
+
+// Colors of the walk map.
+var (
+	// WalkFloorColor is the color of cells which may be walked on.
+	WalkFloorColor = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	// WalkRangeColor is the color of cells which may be walked on but block
+	// range (e.g. pillars partially covered by walls).
+	WalkRangeColor = color.RGBA{0xFF, 0xA0, 0x00, 0xFF}
+	// WalkPitColor is the color of cells which block movement but not range
+	// (e.g. lava and chasms).
+	WalkPitColor = color.RGBA{0x30, 0x60, 0xFF, 0xFF}
+	// WalkWallColor is the color of cells which block both movement and range.
+	WalkWallColor = color.RGBA{0x20, 0x20, 0x20, 0xFF}
+)
+
+// WalkStats contains the number of cells of a dungeon for each kind of cell of
+// its walk map.
+type WalkStats struct {
+	// Cells is the number of cells which contain a pillar.
+	Cells int `json:"cells"`
+	// Floor is the number of cells which may be walked on, out of which Range
+	// block range.
+	Floor int `json:"floor"`
+	Range int `json:"range"`
+	// Pits is the number of cells which only block movement.
+	Pits int `json:"pits"`
+	// Walls is the number of cells which block both movement and range.
+	Walls int `json:"walls"`
+}
+
+// Openness returns the fraction of cells which may be walked on.
+func (stats WalkStats) Openness() float64 {
+	if stats.Cells == 0 {
+		return 0
+	}
+	return float64(stats.Floor) / float64(stats.Cells)
+}
+
+// WalkMap returns a compact walk map of the dungeon, with one pixel per cell
+// colored by the solid properties of its pillar, along with the number of cells
+// of each kind. Cells without pillars are left transparent.
+//
+// ref: nSolidTable (sol & 0x01 blocks movement)
+// ref: nMissileTable (sol & 0x04 blocks range)
+func (dungeon *Dungeon) WalkMap(colCount, rowCount int, solids []sol.Solid) (img *image.RGBA, stats WalkStats) {
+	img = image.NewRGBA(image.Rect(0, 0, colCount, rowCount))
+	for row := 0; row < rowCount; row++ {
+		for col := 0; col < colCount; col++ {
+			pillarNum, ok := dungeon[col][row]["pillarNum"]
+			if !ok {
+				continue
+			}
+			stats.Cells++
+			var solid sol.Solid
+			if pillarNum < len(solids) {
+				solid = solids[pillarNum]
+			}
+			var c color.RGBA
+			switch {
+			case solid.Sol0x01 && solid.Sol0x04:
+				c = WalkWallColor
+				stats.Walls++
+			case solid.Sol0x01:
+				c = WalkPitColor
+				stats.Pits++
+			case solid.Sol0x04:
+				c = WalkRangeColor
+				stats.Floor++
+				stats.Range++
+			default:
+				c = WalkFloorColor
+				stats.Floor++
+			}
+			img.SetRGBA(col, row, c)
+		}
+	}
+	return img, stats
+}
@@ -1,5 +1,8 @@
 // Package dunconf implements functions for retrieving relevant information
 // required for parsing DUN files.
+//
+// The layouts of dungeon maps are specified by an ini file, or registered in
+// code using RegisterLevel; registered layouts take precedence.
 package dunconf
 
 import (
@@ -26,19 +29,50 @@ func Init() (err error) {
 	return nil
 }
 
-// DungeonNames returns a slice of dungeon names based on the ini file.
+// DungeonNames returns a slice of dungeon names based on the ini file and the
+// registered layouts.
 func DungeonNames() (dungeonNames []string) {
 	for dungeonName := range dict {
 		if dungeonName == "" || strings.HasSuffix(dungeonName, ".dun") {
 			continue
 		}
+		if _, ok := layouts[dungeonName]; ok {
+			continue
+		}
 		dungeonNames = append(dungeonNames, dungeonName)
 	}
+	dungeonNames = append(dungeonNames, registeredDungeonNames()...)
 	sort.Strings(dungeonNames)
 	return dungeonNames
 }
 
-// GetColStart returns the starting col of a given DUN file.
+// DunNames returns a slice of DUN file names based on the ini file and the
+// registered layouts.
+func DunNames() (dunNames []string) {
+	found := make(map[string]bool)
+	for dunName := range dict {
+		if !strings.HasSuffix(dunName, ".dun") {
+			continue
+		}
+		found[dunName] = true
+	}
+	for _, layout := range layouts {
+		for _, dun := range layout.Duns {
+			found[dun.DunName] = true
+		}
+	}
+	for dunName := range found {
+		dunNames = append(dunNames, dunName)
+	}
+	sort.Strings(dunNames)
+	return dunNames
+}
+
+// GetColStart returns the starting col of a given DUN file, as specified by the
+// ini file.
+//
+// Note: A DUN file may be placed differently by registered layouts; use
+// GetLevelLayout to retrieve the placement of a DUN file within a dungeon map.
 func GetColStart(dunName string) (colStart int, err error) {
 	colStart, found := dict.GetInt(dunName, "col_start")
 	if !found {
@@ -47,7 +81,8 @@ func GetColStart(dunName string) (colStart int, err error) {
 	return colStart, nil
 }
 
-// GetRowStart returns the starting row of a given DUN file.
+// GetRowStart returns the starting row of a given DUN file, as specified by the
+// ini file.
 func GetRowStart(dunName string) (rowStart int, err error) {
 	rowStart, found := dict.GetInt(dunName, "row_start")
 	if !found {
@@ -58,6 +93,18 @@ func GetRowStart(dunName string) (rowStart int, err error) {
 
 // GetDunNames returns the DUN file names of a given dungeon map.
 func GetDunNames(dungeonName string) (dunNames []string, err error) {
+	if layout, ok := layouts[dungeonName]; ok {
+		for _, dun := range layout.Duns {
+			dunNames = append(dunNames, dun.DunName)
+		}
+		return dunNames, nil
+	}
+	return iniDunNames(dungeonName)
+}
+
+// iniDunNames returns the DUN file names of a given dungeon map, as specified
+// by the ini file.
+func iniDunNames(dungeonName string) (dunNames []string, err error) {
 	rawDunNames, found := dict.GetString(dungeonName, "duns")
 	if !found {
 		return nil, fmt.Errorf("duns not found for %q.", dungeonName)
@@ -67,6 +114,12 @@ func GetDunNames(dungeonName string) (dunNames []string, err error) {
 
 // GetColCount returns the number of cols of a given dungeon map.
 func GetColCount(dungeonName string) (colCount int, err error) {
+	if layout, ok := layouts[dungeonName]; ok {
+		if layout.ColCount == 0 {
+			return 0, fmt.Errorf("col_count not registered for %q.", dungeonName)
+		}
+		return layout.ColCount, nil
+	}
 	colCount, found := dict.GetInt(dungeonName, "col_count")
 	if !found {
 		return 0, fmt.Errorf("col_count not found for %q.", dungeonName)
@@ -76,6 +129,12 @@ func GetColCount(dungeonName string) (colCount int, err error) {
 
 // GetRowCount returns the number of rows of a given dungeon map.
 func GetRowCount(dungeonName string) (rowCount int, err error) {
+	if layout, ok := layouts[dungeonName]; ok {
+		if layout.RowCount == 0 {
+			return 0, fmt.Errorf("row_count not registered for %q.", dungeonName)
+		}
+		return layout.RowCount, nil
+	}
 	rowCount, found := dict.GetInt(dungeonName, "row_count")
 	if !found {
 		return 0, fmt.Errorf("row_count not found for %q.", dungeonName)
@@ -0,0 +1,101 @@
+package dunconf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Layout describes how the DUN files of a dungeon map are assembled.
+type Layout struct {
+	// Name is the dungeon name (e.g. "l1-banner1").
+	Name string
+	// Duns specifies the placement of each DUN file.
+	Duns []Placement
+	// ColCount and RowCount are the number of cols and rows of the dungeon
+	// map; both are 0 if the dimensions should be retrieved from the header of
+	// a single DUN file.
+	ColCount, RowCount int
+}
+
+// A Placement specifies the starting coordinates of a DUN file on the dungeon
+// map.
+type Placement struct {
+	DunName            string
+	ColStart, RowStart int
+}
+
+// layouts maps from dungeon name to the layouts registered in code, which take
+// precedence over the ini file.
+var layouts = make(map[string]Layout)
+
+// RegisterLevel registers the layout of a dungeon map, which may be used by
+// the DRLG subsystem and external tools to define layouts in code rather than
+// in the ini file. A registered layout replaces any previous layout with the
+// same name, including the one of the ini file.
+func RegisterLevel(layout Layout) (err error) {
+	if len(layout.Name) == 0 || strings.HasSuffix(layout.Name, ".dun") {
+		return fmt.Errorf("dunconf.RegisterLevel: invalid dungeon name %q.", layout.Name)
+	}
+	if len(layout.Duns) == 0 {
+		return fmt.Errorf("dunconf.RegisterLevel: no DUN files in %q.", layout.Name)
+	}
+	for _, dun := range layout.Duns {
+		if !strings.HasSuffix(dun.DunName, ".dun") {
+			return fmt.Errorf("dunconf.RegisterLevel: invalid DUN file name %q in %q.", dun.DunName, layout.Name)
+		}
+		if dun.ColStart < 0 || dun.RowStart < 0 {
+			return fmt.Errorf("dunconf.RegisterLevel: invalid starting coordinates (%d, %d) of %q in %q.", dun.ColStart, dun.RowStart, dun.DunName, layout.Name)
+		}
+	}
+	if layout.ColCount < 0 || layout.RowCount < 0 || (layout.ColCount == 0) != (layout.RowCount == 0) {
+		return fmt.Errorf("dunconf.RegisterLevel: invalid dimensions (%dx%d) of %q.", layout.ColCount, layout.RowCount, layout.Name)
+	}
+	if layout.ColCount == 0 && len(layout.Duns) != 1 {
+		return fmt.Errorf("dunconf.RegisterLevel: missing dimensions of %q, which consists of %d DUN files.", layout.Name, len(layout.Duns))
+	}
+	layout.Duns = append([]Placement(nil), layout.Duns...)
+	layouts[layout.Name] = layout
+	return nil
+}
+
+// GetLevelLayout returns the layout of a given dungeon map, as registered in
+// code or specified by the ini file.
+func GetLevelLayout(dungeonName string) (layout Layout, err error) {
+	if layout, ok := layouts[dungeonName]; ok {
+		layout.Duns = append([]Placement(nil), layout.Duns...)
+		return layout, nil
+	}
+	layout.Name = dungeonName
+	dunNames, err := iniDunNames(dungeonName)
+	if err != nil {
+		return Layout{}, err
+	}
+	for _, dunName := range dunNames {
+		colStart, err := GetColStart(dunName)
+		if err != nil {
+			return Layout{}, err
+		}
+		rowStart, err := GetRowStart(dunName)
+		if err != nil {
+			return Layout{}, err
+		}
+		layout.Duns = append(layout.Duns, Placement{DunName: dunName, ColStart: colStart, RowStart: rowStart})
+	}
+	colCount, colFound := dict.GetInt(dungeonName, "col_count")
+	rowCount, rowFound := dict.GetInt(dungeonName, "row_count")
+	if colFound && rowFound {
+		layout.ColCount, layout.RowCount = colCount, rowCount
+	}
+	return layout, nil
+}
+
+// registeredDungeonNames returns the sorted names of the dungeon maps
+// registered in code.
+func registeredDungeonNames() (dungeonNames []string) {
+	for dungeonName := range layouts {
+		dungeonNames = append(dungeonNames, dungeonName)
+	}
+	sort.Strings(dungeonNames)
+	return dungeonNames
+}
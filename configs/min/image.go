@@ -1,30 +1,34 @@
 package min
 
 import (
+	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
+
+	"github.com/mewrnd/blizzconv/images/imgcache"
 )
 
-// The width and height of a pillar block in pixels.
+// The width and height of a pillar block in pixels, as used by DefaultMetrics.
 const (
 	BlockWidth  = 32
 	BlockHeight = 32
 )
 
-// PillarWidth is the width of a pillar in pixels.
+// PillarWidth is the width of a pillar in pixels, as used by DefaultMetrics.
 const PillarWidth = BlockWidth * 2
 
 // Width returns the width of the pillar in pixels.
 func (pillar Pillar) Width() int {
 	// the pillar is two blocks in width.
-	return PillarWidth
+	return pillar.GetMetrics().PillarWidth()
 }
 
 // Height returns the height of the pillar in pixels.
 func (pillar Pillar) Height() int {
 	// the pillar is five (for l1.min, l2.min and l3.min) or eight (for l4.min
 	// and town.min) blocks in height.
-	return BlockHeight * len(pillar.Blocks) / 2
+	return pillar.GetMetrics().PillarHeight(len(pillar.Blocks))
 }
 
 // Image returns an image constructed from the pillar's blocks.
@@ -41,10 +45,89 @@ func (pillar Pillar) Image(levelFrames []image.Image) (img image.Image) {
 	return dst
 }
 
+// CachedImages returns the images of the pillars, constructed from their blocks
+// as by Image. If imgcache is enabled, the images are cached on disk, keyed on
+// the blocks of the pillars and on levelKey, which identifies the content of
+// the level frames and their palette (e.g. as returned by cel.ContentKey).
+func CachedImages(pillars []Pillar, levelFrames []image.Image, levelKey string) (imgs []image.Image, err error) {
+	// Use the cached images if present.
+	var key string
+	if imgcache.Enabled() {
+		key = cacheKey(pillars, levelKey)
+		if imgs, ok := imgcache.Load(key); ok && len(imgs) == len(pillars) {
+			return imgs, nil
+		}
+	}
+
+	// Construct images.
+	for _, pillar := range pillars {
+		imgs = append(imgs, pillar.Image(levelFrames))
+	}
+
+	if imgcache.Enabled() {
+		err = imgcache.Store(key, imgs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return imgs, nil
+}
+
+// cacheKey returns the imgcache key of the images of the pillars, constructed
+// from the level frames identified by levelKey.
+func cacheKey(pillars []Pillar, levelKey string) string {
+	data := [][]byte{[]byte("pillars"), []byte(levelKey)}
+	for _, pillar := range pillars {
+		data = append(data, []byte(fmt.Sprint(pillar.GetMetrics(), pillar.Blocks)))
+	}
+	return imgcache.Key(data...)
+}
+
+// TypeColor maps from block type to the color used when rendering block type
+// maps.
+//
+// Block types:
+//    0: plain
+//    1: transparent
+//    2: left triangle
+//    3: right triangle
+//    4: left trapezoid
+//    5: right trapezoid
+var TypeColor = map[int]color.Color{
+	0: color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF},
+	1: color.RGBA{R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+	2: color.RGBA{R: 0xFF, G: 0x00, B: 0x00, A: 0xFF},
+	3: color.RGBA{R: 0x00, G: 0x00, B: 0xFF, A: 0xFF},
+	4: color.RGBA{R: 0xFF, G: 0x80, B: 0x00, A: 0xFF},
+	5: color.RGBA{R: 0x00, G: 0xC0, B: 0xFF, A: 0xFF},
+}
+
+// TypeImage returns an image of the pillar where each valid block is filled with
+// the color of its block type, as specified by TypeColor. Invalid blocks are
+// left transparent. Block types without an associated color are drawn in
+// white.
+func (pillar Pillar) TypeImage() (img image.Image) {
+	dst := image.NewRGBA(image.Rect(0, 0, pillar.Width(), pillar.Height()))
+	for blockNum, block := range pillar.Blocks {
+		if !block.IsValid {
+			continue
+		}
+		c, ok := TypeColor[block.Type]
+		if !ok {
+			c = color.White
+		}
+		// leave a one pixel border between blocks.
+		rect := pillar.GetMetrics().BlockRect(blockNum).Inset(1)
+		draw.Draw(dst, rect, image.NewUniform(c), image.ZP, draw.Src)
+	}
+	return dst
+}
+
 // BlockRect is a map from blockNum to an image.Rectangle of the block.
 //
-// The size of each pillar block is 32x32 pixels. The blocks are arranged as
-// illustrated below, forming a pillar:
+// The size of each pillar block is 32x32 pixels, as specified by
+// DefaultMetrics; Metrics.BlockRect locates blocks of other sizes. The blocks
+// are arranged as illustrated below, forming a pillar:
 //
 //    +----+----+
 //    |  0 |  1 |
@@ -109,7 +192,7 @@ func (pillar Pillar) drawSide(dst draw.Image, levelFrames []image.Image, blockNu
 				}
 			}
 			first = false
-			rect := BlockRect[blockNum]
+			rect := pillar.GetMetrics().BlockRect(blockNum)
 			if moveUp {
 				rect.Min.Y--
 				rect.Max.Y--
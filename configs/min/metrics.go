@@ -0,0 +1,94 @@
+package min
+
+import (
+	"image"
+	"math"
+)
+
+// Metrics specifies the tile geometry of a tileset; the size of the blocks of
+// its pillars, from which the size of the pillars and the projection of the
+// dungeon map are derived. Each pillar is two blocks in width, and the floor of
+// each cell is a diamond of one pillar in width and one block in height.
+type Metrics struct {
+	// The width and height of a pillar block in pixels.
+	BlockWidth, BlockHeight int
+}
+
+// DefaultMetrics is the tile geometry of the tilesets of the game, whose floor
+// tiles are 64x32 pixels.
+var DefaultMetrics = Metrics{BlockWidth: BlockWidth, BlockHeight: BlockHeight}
+
+// PillarWidth returns the width of a pillar in pixels.
+func (m Metrics) PillarWidth() int {
+	return m.BlockWidth * 2
+}
+
+// PillarHeight returns the height in pixels of a pillar of blockCount blocks.
+func (m Metrics) PillarHeight(blockCount int) int {
+	return m.BlockHeight * blockCount / 2
+}
+
+// BlockRect returns an image.Rectangle of the given block of a pillar, as
+// arranged by BlockRect.
+func (m Metrics) BlockRect(blockNum int) image.Rectangle {
+	x := blockNum % 2 * m.BlockWidth
+	y := blockNum / 2 * m.BlockHeight
+	return image.Rect(x, y, x+m.BlockWidth, y+m.BlockHeight)
+}
+
+// MapSize returns the width and height in pixels of the image of a dungeon map
+// of colCount cols and rowCount rows, whose pillars are pillarHeight pixels in
+// height.
+func (m Metrics) MapSize(colCount, rowCount, pillarHeight int) (width, height int) {
+	width = colCount*m.BlockWidth + rowCount*m.BlockWidth
+	height = colCount*(m.BlockHeight/2) + rowCount*(m.BlockHeight/2) + (pillarHeight - m.BlockHeight)
+	return width, height
+}
+
+// maxColCount and maxRowCount are the number of cols and rows of the largest
+// dungeon map.
+//
+// ref: dun.ColMax, dun.RowMax
+const (
+	maxColCount = 112
+	maxRowCount = 112
+)
+
+// MaxMapWidth returns the width in pixels of the image of the largest dungeon
+// map, whose coordinate system is used to locate windows of the dungeon map.
+func (m Metrics) MaxMapWidth() int {
+	width, _ := m.MapSize(maxColCount, maxRowCount, 0)
+	return width
+}
+
+// PillarRect returns an image.Rectangle of the pillar of the cell at the col and
+// row coordinates of a dungeon image which is mapWidth pixels in width.
+//
+// ref: dun.GetPillarRect (illustration of map coordinate system)
+func (m Metrics) PillarRect(col, row, mapWidth, pillarHeight int) (rect image.Rectangle) {
+	minX := mapWidth/2 - m.BlockWidth - row*m.BlockWidth + col*m.BlockWidth
+	minY := row*(m.BlockHeight/2) + col*(m.BlockHeight/2)
+	maxX := minX + m.PillarWidth()
+	maxY := minY + pillarHeight
+	return image.Rect(minX, minY, maxX, maxY)
+}
+
+// FloorRect returns an image.Rectangle which encloses the floor diamond of the
+// cell at the col and row coordinates, located at the bottom of its pillar.
+func (m Metrics) FloorRect(col, row, mapWidth, pillarHeight int) (rect image.Rectangle) {
+	rect = m.PillarRect(col, row, mapWidth, pillarHeight)
+	rect.Min.Y = rect.Max.Y - m.BlockHeight
+	return rect
+}
+
+// Cell returns the col and row coordinates of the cell whose floor contains the
+// pixel at x and y of the dungeon image. It is the inverse of FloorRect.
+func (m Metrics) Cell(x, y, mapWidth, pillarHeight int) (col, row int) {
+	// distance from the top vertex of the floor of cell (0, 0), measured in
+	// floor widths and floor heights.
+	fx := float64(x-mapWidth/2) / float64(m.PillarWidth())
+	fy := float64(y-(pillarHeight-m.BlockHeight)) / float64(m.BlockHeight)
+	col = int(math.Floor(fy + fx))
+	row = int(math.Floor(fy - fx))
+	return col, row
+}
@@ -8,8 +8,8 @@
 //    pillars []Pillar
 //
 // Pillar format:
-//    // blocks contains 10 blocks for l1.min, l2.min and l3.min and 16 blocks
-//    // for l4.min and town.min.
+//    // blocks contains 10 blocks for l1.min, l2.min, l3.min, l5.min and
+//    // l6.min and 16 blocks for l4.min and town.min.
 //    //
 //    // ref: BlockRect (block arrangement illustration)
 //    blocks [blockCount]uint16
@@ -24,7 +24,6 @@ package min
 import (
 	"encoding/binary"
 	"io"
-	"os"
 
 	"github.com/mewrnd/blizzconv/mpq"
 )
@@ -35,6 +34,17 @@ import (
 // ref: BlockRect (block arrangement illustration)
 type Pillar struct {
 	Blocks []Block
+	// Metrics specifies the tile geometry of the pillar; the zero value is
+	// equivalent to DefaultMetrics.
+	Metrics Metrics
+}
+
+// GetMetrics returns the tile geometry of the pillar.
+func (pillar Pillar) GetMetrics() Metrics {
+	if pillar.Metrics == (Metrics{}) {
+		return DefaultMetrics
+	}
+	return pillar.Metrics
 }
 
 // Block contains information about which CEL decode algorithm (Type) that
@@ -48,18 +58,19 @@ type Block struct {
 // Parse parses a given MIN file and returns a slice of pillars, based on the
 // MIN format described above.
 func Parse(minName string) (pillars []Pillar, err error) {
-	minPath, err := mpq.GetPath(minName)
-	if err != nil {
-		return nil, err
-	}
-	fr, err := os.Open(minPath)
+	return ParseFrom(mpq.Default(), minName)
+}
+
+// ParseFrom parses the given MIN file of the store s, as described by Parse.
+func ParseFrom(s *mpq.Store, minName string) (pillars []Pillar, err error) {
+	fr, err := s.Open(minName)
 	if err != nil {
 		return nil, err
 	}
 	defer fr.Close()
 	var blockCount int
 	switch minName {
-	case "l1.min", "l2.min", "l3.min":
+	case "l1.min", "l2.min", "l3.min", "l5.min", "l6.min":
 		blockCount = 10
 	case "l4.min", "town.min":
 		blockCount = 16
@@ -0,0 +1,313 @@
+// Package snapshot implements functionality for parsing dungeon snapshots.
+//
+// Dungeon snapshots contain the dungeon layers of a running game, as dumped
+// from the memory of the game process, in order for the dungeon to be rendered
+// outside of the game. Below is a description of the snapshot format:
+//
+// Snapshot format:
+//    magic      [4]byte // "DSNP"
+//    version    uint32  // 1
+//    levelType  uint32  // ref: LevelType
+//    levelNum   uint32  // dungeon level (0 is the town).
+//    seed       uint32  // seed of the dungeon level.
+//    layerCount uint32
+//    layers     [layerCount]Layer
+//
+// Layer format:
+//    id   [4]byte // ref: LayerPillars, LayerObjects, LayerMonsters, LayerTrans
+//    size uint32
+//    data [size]byte
+//
+// Each layer is a [112][112] array stored in column major, using the element
+// type of its id. Unknown layers are skipped, and all integers are stored in
+// little endian.
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/mewrnd/blizzconv/configs/dun"
+)
+
+// Magic is the magic signature of dungeon snapshots.
+const Magic = "DSNP"
+
+// Version is the supported version of the snapshot format.
+const Version = 1
+
+// Layer ids and the game arrays they were dumped from.
+const (
+	// LayerPillars is the pillar layer (dPiece); int32 pillarNum plus one.
+	LayerPillars = "PIEC"
+	// LayerObjects is the object layer (dObject); int8 object index plus one,
+	// negated for the cells occupied by, but not containing, an object.
+	LayerObjects = "OBJ "
+	// LayerMonsters is the monster layer (dMonster); int32 monster index plus
+	// one, negated for monsters moving onto the cell.
+	LayerMonsters = "MONS"
+	// LayerTrans is the transparency layer (dTransVal); int8 transparency
+	// index.
+	LayerTrans = "TRAN"
+)
+
+// layerSizes maps from the id of each known layer to its size in bytes; a
+// [112][112] array of the element type of the layer.
+var layerSizes = map[string]int64{
+	LayerPillars:  dun.ColMax * dun.RowMax * 4,
+	LayerObjects:  dun.ColMax * dun.RowMax * 1,
+	LayerMonsters: dun.ColMax * dun.RowMax * 4,
+	LayerTrans:    dun.ColMax * dun.RowMax * 1,
+}
+
+// LevelType specifies the tileset of a dungeon level, as used by the game.
+type LevelType int
+
+// Level types.
+const (
+	Town LevelType = iota
+	Cathedral
+	Catacombs
+	Caves
+	Hell
+	// Hellfire level types.
+	Nest
+	Crypt
+)
+
+// levelNames maps from level type to level name.
+var levelNames = map[LevelType]string{
+	Town:      "town",
+	Cathedral: "l1",
+	Catacombs: "l2",
+	Caves:     "l3",
+	Hell:      "l4",
+	Nest:      "l6",
+	Crypt:     "l5",
+}
+
+// LevelName returns the level name (e.g. "l1") of the tileset.
+func (typ LevelType) LevelName() (levelName string, err error) {
+	levelName, ok := levelNames[typ]
+	if !ok {
+		return "", fmt.Errorf("snapshot.LevelType.LevelName: invalid level type (%d).", int(typ))
+	}
+	return levelName, nil
+}
+
+// A Snapshot contains the dungeon layers of a dungeon level of a running game.
+type Snapshot struct {
+	LevelType LevelType
+	LevelNum  int
+	Seed      uint32
+	// Layers maps from layer id to the raw data of the layer.
+	Layers map[string][]byte
+}
+
+// Parse parses a given snapshot file, based on the snapshot format described
+// above.
+func Parse(snapPath string) (snap *Snapshot, err error) {
+	fr, err := os.Open(snapPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fr.Close()
+	return Read(fr)
+}
+
+// Read reads a snapshot from r, based on the snapshot format described above.
+func Read(r io.Reader) (snap *Snapshot, err error) {
+	var hdr struct {
+		Magic      [4]byte
+		Version    uint32
+		LevelType  uint32
+		LevelNum   uint32
+		Seed       uint32
+		LayerCount uint32
+	}
+	err = binary.Read(r, binary.LittleEndian, &hdr)
+	if err != nil {
+		return nil, err
+	}
+	if string(hdr.Magic[:]) != Magic {
+		return nil, fmt.Errorf("snapshot.Read: invalid magic %q.", hdr.Magic[:])
+	}
+	if hdr.Version != Version {
+		return nil, fmt.Errorf("snapshot.Read: unsupported version (%d).", hdr.Version)
+	}
+	snap = &Snapshot{
+		LevelType: LevelType(hdr.LevelType),
+		LevelNum:  int(hdr.LevelNum),
+		Seed:      hdr.Seed,
+		Layers:    make(map[string][]byte),
+	}
+	for i := 0; i < int(hdr.LayerCount); i++ {
+		var layerHdr struct {
+			ID   [4]byte
+			Size uint32
+		}
+		err = binary.Read(r, binary.LittleEndian, &layerHdr)
+		if err != nil {
+			return nil, err
+		}
+		// The size of known layers is checked before allocating, so that a
+		// corrupt size fails instead of allocating up to 4 GiB.
+		id := string(layerHdr.ID[:])
+		size, ok := layerSizes[id]
+		if !ok {
+			// Skip unknown layers.
+			_, err = io.CopyN(ioutil.Discard, r, int64(layerHdr.Size))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if int64(layerHdr.Size) != size {
+			return nil, fmt.Errorf("snapshot.Read: invalid size (%d) of layer %q; expected %d.", layerHdr.Size, id, size)
+		}
+		data := make([]byte, size)
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return nil, err
+		}
+		snap.Layers[id] = data
+	}
+	return snap, nil
+}
+
+// Write writes the snapshot to w, based on the snapshot format described
+// above. Only the known layers are stored, in the order LayerPillars,
+// LayerObjects, LayerMonsters and LayerTrans.
+func (snap *Snapshot) Write(w io.Writer) (err error) {
+	var ids []string
+	for _, id := range []string{LayerPillars, LayerObjects, LayerMonsters, LayerTrans} {
+		if _, ok := snap.Layers[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	hdr := []uint32{Version, uint32(snap.LevelType), uint32(snap.LevelNum), snap.Seed, uint32(len(ids))}
+	_, err = io.WriteString(w, Magic)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w, binary.LittleEndian, hdr)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		data := snap.Layers[id]
+		_, err = io.WriteString(w, id)
+		if err != nil {
+			return err
+		}
+		err = binary.Write(w, binary.LittleEndian, uint32(len(data)))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dungeon returns the dungeon constructed from the pillar layer of the
+// snapshot, using the tileset of its level type, along with the level name.
+// The transparency layer, if present, is stored using the "transparency" key.
+func (snap *Snapshot) Dungeon() (dungeon *dun.Dungeon, levelName string, err error) {
+	levelName, err = snap.LevelType.LevelName()
+	if err != nil {
+		return nil, "", err
+	}
+	data, ok := snap.Layers[LayerPillars]
+	if !ok {
+		return nil, "", fmt.Errorf("snapshot.Snapshot.Dungeon: missing pillar layer.")
+	}
+	if len(data) != dun.ColMax*dun.RowMax*4 {
+		return nil, "", fmt.Errorf("snapshot.Snapshot.Dungeon: invalid size (%d) of pillar layer; expected %d.", len(data), dun.ColMax*dun.RowMax*4)
+	}
+	dungeon = dun.New()
+	err = dungeon.ParsePillars(bytes.NewReader(data), levelName)
+	if err != nil {
+		return nil, "", err
+	}
+	var trans [dun.ColMax][dun.RowMax]int8
+	ok, err = snap.layer(LayerTrans, &trans)
+	if err != nil {
+		return nil, "", err
+	}
+	if ok {
+		for col := 0; col < dun.ColMax; col++ {
+			for row := 0; row < dun.RowMax; row++ {
+				dungeon[col][row]["transparency"] = int(trans[col][row])
+			}
+		}
+	}
+	return dungeon, levelName, nil
+}
+
+// A Cell is a coordinate of the dungeon map which contains an object or a
+// monster, and the index of the object or monster in the arrays of the game.
+type Cell struct {
+	Col, Row int
+	Index    int
+}
+
+// Objects returns the cells containing objects, based on the object layer of
+// the snapshot. It returns no cells if the snapshot has no object layer.
+func (snap *Snapshot) Objects() (cells []Cell, err error) {
+	var objects [dun.ColMax][dun.RowMax]int8
+	ok, err := snap.layer(LayerObjects, &objects)
+	if !ok || err != nil {
+		return nil, err
+	}
+	for col := 0; col < dun.ColMax; col++ {
+		for row := 0; row < dun.RowMax; row++ {
+			if objects[col][row] > 0 {
+				cells = append(cells, Cell{Col: col, Row: row, Index: int(objects[col][row]) - 1})
+			}
+		}
+	}
+	return cells, nil
+}
+
+// Monsters returns the cells containing monsters, based on the monster layer
+// of the snapshot. It returns no cells if the snapshot has no monster layer.
+func (snap *Snapshot) Monsters() (cells []Cell, err error) {
+	var monsters [dun.ColMax][dun.RowMax]int32
+	ok, err := snap.layer(LayerMonsters, &monsters)
+	if !ok || err != nil {
+		return nil, err
+	}
+	for col := 0; col < dun.ColMax; col++ {
+		for row := 0; row < dun.RowMax; row++ {
+			if monsters[col][row] > 0 {
+				cells = append(cells, Cell{Col: col, Row: row, Index: int(monsters[col][row]) - 1})
+			}
+		}
+	}
+	return cells, nil
+}
+
+// layer decodes the layer with the given id into v, which points to an array
+// of the element type of the layer. It returns false if the snapshot has no
+// such layer.
+func (snap *Snapshot) layer(id string, v interface{}) (ok bool, err error) {
+	data, ok := snap.Layers[id]
+	if !ok {
+		return false, nil
+	}
+	if len(data) != binary.Size(v) {
+		return false, fmt.Errorf("snapshot.Snapshot.layer: invalid size (%d) of layer %q; expected %d.", len(data), id, binary.Size(v))
+	}
+	err = binary.Read(bytes.NewReader(data), binary.LittleEndian, v)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -25,7 +25,6 @@ package sol
 import (
 	"encoding/binary"
 	"io"
-	"os"
 
 	"github.com/mewrnd/blizzconv/mpq"
 )
@@ -45,11 +44,12 @@ type Solid struct {
 // Parse parses a given SOL file and returns a slice of solids, based on the
 // SOL format described above.
 func Parse(solName string) (solids []Solid, err error) {
-	solPath, err := mpq.GetPath(solName)
-	if err != nil {
-		return nil, err
-	}
-	fr, err := os.Open(solPath)
+	return ParseFrom(mpq.Default(), solName)
+}
+
+// ParseFrom parses the given SOL file of the store s, as described by Parse.
+func ParseFrom(s *mpq.Store, solName string) (solids []Solid, err error) {
+	fr, err := s.Open(solName)
 	if err != nil {
 		return nil, err
 	}
@@ -19,19 +19,20 @@ import (
 //
 //          bottom
 func (square Square) Image(pillars []min.Pillar, levelFrames []image.Image) (img image.Image) {
+	m := pillars[0].GetMetrics()
 	// the square is two pillars in width.
-	width := min.PillarWidth * 2
+	width := m.PillarWidth() * 2
 	// the square is one pillar and one block in height.
-	height := pillars[0].Height() + min.BlockHeight
+	height := pillars[0].Height() + m.BlockHeight
 	dst := image.NewRGBA(image.Rect(0, 0, width, height))
 	imgTop := pillars[square.PillarNumTop].Image(levelFrames)
 	imgRight := pillars[square.PillarNumRight].Image(levelFrames)
 	imgLeft := pillars[square.PillarNumLeft].Image(levelFrames)
 	imgBottom := pillars[square.PillarNumBottom].Image(levelFrames)
-	pointTop := image.Pt(min.PillarWidth/2, 0)
-	pointRight := image.Pt(min.PillarWidth, min.BlockHeight/2)
-	pointLeft := image.Pt(0, min.BlockHeight/2)
-	pointBottom := image.Pt(min.PillarWidth/2, min.BlockHeight)
+	pointTop := image.Pt(m.PillarWidth()/2, 0)
+	pointRight := image.Pt(m.PillarWidth(), m.BlockHeight/2)
+	pointLeft := image.Pt(0, m.BlockHeight/2)
+	pointBottom := image.Pt(m.PillarWidth()/2, m.BlockHeight)
 	bounds := imgTop.Bounds()
 	draw.Draw(dst, bounds.Add(pointTop), imgTop, image.ZP, draw.Over)
 	draw.Draw(dst, bounds.Add(pointRight), imgRight, image.ZP, draw.Over)
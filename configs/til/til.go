@@ -19,7 +19,6 @@ package til
 import (
 	"encoding/binary"
 	"io"
-	"os"
 
 	"github.com/mewrnd/blizzconv/mpq"
 )
@@ -37,11 +36,12 @@ type Square struct {
 // Parse parses a given TIL file and returns a slice of squares, based on the
 // TIL format described above.
 func Parse(tilName string) (squares []Square, err error) {
-	tilPath, err := mpq.GetPath(tilName)
-	if err != nil {
-		return nil, err
-	}
-	fr, err := os.Open(tilPath)
+	return ParseFrom(mpq.Default(), tilName)
+}
+
+// ParseFrom parses the given TIL file of the store s, as described by Parse.
+func ParseFrom(s *mpq.Store, tilName string) (squares []Square, err error) {
+	fr, err := s.Open(tilName)
 	if err != nil {
 		return nil, err
 	}
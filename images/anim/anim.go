@@ -0,0 +1,212 @@
+// Package anim implements functionality for exporting animations.
+//
+// The frames of CEL and CL2 images are displayed in-game for a number of game
+// ticks, and the game runs at 20 ticks per second.
+package anim
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+)
+
+// TickDelay is the duration of one game tick in 100ths of a second.
+const TickDelay = 5
+
+// Delay returns the duration in 100ths of a second of each frame, based on the
+// number of game ticks a frame is displayed.
+func Delay(ticksPerFrame int) int {
+	return ticksPerFrame * TickDelay
+}
+
+// Dither specifies if colors which are not present in the palette of a GIF
+// image should be approximated using Floyd-Steinberg error diffusion, rather
+// than being replaced by the nearest color of the palette. Such colors arise
+// when translucent layers (e.g. markers or overlays) are blended on top of the
+// frames.
+var Dither bool
+
+// drawer returns the drawer used when quantizing frames to paletted images.
+func drawer() draw.Drawer {
+	if Dither {
+		return draw.FloydSteinberg
+	}
+	return draw.Src
+}
+
+// WriteGIF stores the frames as an animated GIF image, using the delay (in
+// 100ths of a second) between each frame.
+//
+// The animation is upsampled to FrameRate, if set.
+func WriteGIF(gifPath string, frames []image.Image, delay int) (err error) {
+	frames, delay = Upsample(frames, delay, FrameRate, Interp)
+	g := &gif.GIF{}
+	for _, frame := range frames {
+		addFrame(g, toPaletted(frame), delay)
+	}
+	return writeFile(gifPath, g)
+}
+
+// WritePalettedGIF stores the frames as an animated GIF image, using the delay
+// (in 100ths of a second) between each frame. The color table of the GIF image
+// is built directly from the game palette (with any color transitions applied)
+// used to decode the frames, which avoids the color banding and the cost of
+// re-quantization.
+//
+// One palette index which is not used by any frame is reserved for transparent
+// pixels. Colors outside of the palette, such as blended translucent pixels,
+// are quantized to the palette as specified by Dither. Should the frames use
+// all 256 palette indices, the frames are re-quantized as done by WriteGIF.
+//
+// The animation is upsampled to FrameRate, if set; the blended frames of
+// CrossFade are quantized to the palette as specified by Dither.
+func WritePalettedGIF(gifPath string, frames []image.Image, delay int, pal color.Palette) (err error) {
+	// Map each color to its first palette index.
+	index := make(map[color.RGBA]uint8)
+	for i := len(pal) - 1; i >= 0; i-- {
+		c := color.RGBAModel.Convert(pal[i]).(color.RGBA)
+		index[c] = uint8(i)
+	}
+
+	// Locate the palette indices used by the frames.
+	used := make([]bool, 256)
+	offPal := false
+	for _, frame := range frames {
+		bounds := frame.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.RGBAModel.Convert(frame.At(x, y)).(color.RGBA)
+				if c.A == 0 {
+					continue
+				}
+				i, ok := index[c]
+				if !ok {
+					offPal = true
+					continue
+				}
+				used[i] = true
+			}
+		}
+	}
+	transIdx := -1
+	for i := range used {
+		if !used[i] {
+			transIdx = i
+			break
+		}
+	}
+	if transIdx == -1 {
+		return WriteGIF(gifPath, frames, delay)
+	}
+	gifPal := make(color.Palette, 256)
+	for i := range gifPal {
+		gifPal[i] = color.Black
+		if i < len(pal) {
+			gifPal[i] = pal[i]
+		}
+	}
+	gifPal[transIdx] = color.Transparent
+
+	// Upsample the frames once the palette has been built from the original
+	// frames; cross-faded frames contain colors outside of the palette.
+	n := len(frames)
+	frames, delay = Upsample(frames, delay, FrameRate, Interp)
+	if len(frames) != n && Interp == CrossFade {
+		offPal = true
+	}
+
+	// Convert frames to paletted images.
+	g := &gif.GIF{}
+	for _, frame := range frames {
+		bounds := frame.Bounds()
+		dst := image.NewPaletted(bounds, gifPal)
+		if offPal {
+			drawer().Draw(dst, bounds, frame, bounds.Min)
+			addFrame(g, dst, delay)
+			continue
+		}
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.RGBAModel.Convert(frame.At(x, y)).(color.RGBA)
+				i := uint8(transIdx)
+				if c.A != 0 {
+					i = index[c]
+				}
+				dst.Pix[dst.PixOffset(x, y)] = i
+			}
+		}
+		addFrame(g, dst, delay)
+	}
+	return writeFile(gifPath, g)
+}
+
+// addFrame adds the frame to the GIF image, using the delay (in 100ths of a
+// second) before the next frame.
+func addFrame(g *gif.GIF, frame *image.Paletted, delay int) {
+	g.Image = append(g.Image, frame)
+	g.Delay = append(g.Delay, delay)
+	// Clear the frame before drawing the next one, since the frames may
+	// contain transparent pixels.
+	g.Disposal = append(g.Disposal, gif.DisposalBackground)
+}
+
+// writeFile stores the GIF image at gifPath.
+func writeFile(gifPath string, g *gif.GIF) (err error) {
+	f, err := atomicfile.Create(gifPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = gif.EncodeAll(f, g)
+	if err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// quantPal is the palette used when re-quantizing frames to paletted images.
+// The first color is reserved for transparent pixels.
+var quantPal = append(color.Palette{color.Transparent}, palette.Plan9[:255]...)
+
+// toPaletted converts the frame to a paletted image.
+func toPaletted(frame image.Image) *image.Paletted {
+	bounds := frame.Bounds()
+	dst := image.NewPaletted(bounds, quantPal)
+	drawer().Draw(dst, bounds, frame, bounds.Min)
+	return dst
+}
+
+// Sheet returns a sprite sheet of the animations, with one row of frames per
+// animation (e.g. one row per direction). Each cell of the sheet is large
+// enough to fit the largest frame.
+func Sheet(anims [][]image.Image) (img *image.RGBA) {
+	var cellWidth, cellHeight, colCount int
+	for _, frames := range anims {
+		if len(frames) > colCount {
+			colCount = len(frames)
+		}
+		for _, frame := range frames {
+			bounds := frame.Bounds()
+			if bounds.Dx() > cellWidth {
+				cellWidth = bounds.Dx()
+			}
+			if bounds.Dy() > cellHeight {
+				cellHeight = bounds.Dy()
+			}
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, colCount*cellWidth, len(anims)*cellHeight))
+	for rowNum, frames := range anims {
+		for colNum, frame := range frames {
+			bounds := frame.Bounds()
+			pt := image.Pt(colNum*cellWidth, rowNum*cellHeight)
+			rect := image.Rectangle{Min: pt, Max: pt.Add(bounds.Size())}
+			draw.Draw(dst, rect, frame, bounds.Min, draw.Src)
+		}
+	}
+	return dst
+}
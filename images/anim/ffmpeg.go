@@ -0,0 +1,189 @@
+package anim
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+)
+
+// FFmpeg is a Muxer which pipes the frames of videos as raw video to an
+// ffmpeg process, which encodes MP4 videos using H.264 and AAC, and WebM
+// videos using VP9 and Opus.
+type FFmpeg struct {
+	// Path is the path of the ffmpeg executable; "ffmpeg" is located using
+	// the PATH environment variable if empty.
+	Path string
+}
+
+// containers maps from the extension of a video to the ffmpeg arguments of
+// its container and codecs.
+var containers = map[string][]string{
+	".mp4":  {"-c:v", "libx264", "-c:a", "aac", "-movflags", "+faststart", "-f", "mp4"},
+	".webm": {"-c:v", "libvpx-vp9", "-c:a", "libopus", "-f", "webm"},
+}
+
+// Create creates a video at videoPath. The frames are written to the standard
+// input of an ffmpeg process, which is started by Create.
+func (m FFmpeg) Create(videoPath string, size image.Point, delay int, audio []byte) (fw FrameWriter, err error) {
+	codecArgs, ok := containers[strings.ToLower(path.Ext(videoPath))]
+	if !ok {
+		return nil, fmt.Errorf("anim.FFmpeg.Create: unsupported video format of %q; expected .mp4 or .webm.", videoPath)
+	}
+	if delay <= 0 {
+		return nil, fmt.Errorf("anim.FFmpeg.Create: invalid delay (%d).", delay)
+	}
+	ffmpegPath := m.Path
+	if len(ffmpegPath) == 0 {
+		ffmpegPath = "ffmpeg"
+	}
+	w := &ffmpegWriter{size: size}
+	w.f, err = atomicfile.Create(videoPath)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{
+		"-loglevel", "error", "-y",
+		"-f", "rawvideo", "-pix_fmt", "rgba",
+		"-video_size", fmt.Sprintf("%dx%d", size.X, size.Y),
+		"-framerate", fmt.Sprintf("100/%d", delay),
+		"-i", "pipe:0",
+	}
+	if audio != nil {
+		// ffmpeg reads the audio track from a file, as the frames are piped
+		// to its standard input.
+		err = w.writeAudio(audio)
+		if err != nil {
+			w.f.Close()
+			return nil, err
+		}
+		args = append(args, "-i", w.audioPath)
+	}
+	// The chroma subsampling of yuv420p requires even dimensions.
+	args = append(args, "-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2", "-pix_fmt", "yuv420p")
+	args = append(args, codecArgs...)
+	args = append(args, w.f.Name())
+	w.cmd = exec.Command(ffmpegPath, args...)
+	w.cmd.Stderr = &w.stderr
+	w.stdin, err = w.cmd.StdinPipe()
+	if err == nil {
+		err = w.cmd.Start()
+	}
+	if err != nil {
+		w.removeAudio()
+		w.f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// ffmpegWriter writes the frames of a video to an ffmpeg process.
+type ffmpegWriter struct {
+	// size is the size of each frame.
+	size image.Point
+	// f is the temporary file written by ffmpeg.
+	f *atomicfile.File
+	// audioPath is the path of a temporary file containing the audio track, if
+	// any.
+	audioPath string
+	// cmd is the ffmpeg process.
+	cmd *exec.Cmd
+	// stdin is the standard input of the ffmpeg process.
+	stdin io.WriteCloser
+	// stderr records the error messages of the ffmpeg process.
+	stderr bytes.Buffer
+	// done specifies if the ffmpeg process has exited.
+	done bool
+}
+
+// writeAudio stores the audio track in a temporary file.
+func (w *ffmpegWriter) writeAudio(audio []byte) (err error) {
+	f, err := ioutil.TempFile("", "blizzconv_audio_*.wav")
+	if err != nil {
+		return err
+	}
+	w.audioPath = f.Name()
+	_, err = f.Write(audio)
+	if err != nil {
+		f.Close()
+		w.removeAudio()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		w.removeAudio()
+		return err
+	}
+	return nil
+}
+
+// removeAudio removes the temporary file of the audio track, if any.
+func (w *ffmpegWriter) removeAudio() {
+	if len(w.audioPath) > 0 {
+		os.Remove(w.audioPath)
+		w.audioPath = ""
+	}
+}
+
+// WriteFrame writes the pixels of the frame to the ffmpeg process.
+func (w *ffmpegWriter) WriteFrame(frame *image.RGBA) (err error) {
+	bounds := frame.Bounds()
+	if bounds.Size() != w.size {
+		return fmt.Errorf("anim.ffmpegWriter.WriteFrame: frame size %v doesn't match video size %v.", bounds.Size(), w.size)
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		i := frame.PixOffset(bounds.Min.X, y)
+		_, err = w.stdin.Write(frame.Pix[i : i+4*w.size.X])
+		if err != nil {
+			return w.wait(err)
+		}
+	}
+	return nil
+}
+
+// Commit waits for the ffmpeg process to finish encoding the video, and stores
+// the video at its path.
+func (w *ffmpegWriter) Commit() (err error) {
+	if w.done {
+		return nil
+	}
+	err = w.wait(w.stdin.Close())
+	if err != nil {
+		return err
+	}
+	return w.f.Commit()
+}
+
+// Close kills the ffmpeg process and discards the video, unless it has already
+// been committed.
+func (w *ffmpegWriter) Close() (err error) {
+	if !w.done {
+		w.cmd.Process.Kill()
+		w.wait(nil)
+	}
+	return w.f.Close()
+}
+
+// wait waits for the ffmpeg process to exit and removes the temporary file of
+// the audio track. The error messages of ffmpeg, if any, are reported in
+// favour of err, which is reported otherwise.
+func (w *ffmpegWriter) wait(err error) error {
+	w.stdin.Close()
+	waitErr := w.cmd.Wait()
+	w.done = true
+	w.removeAudio()
+	if waitErr != nil {
+		if msg := strings.TrimSpace(w.stderr.String()); len(msg) > 0 {
+			return fmt.Errorf("ffmpeg: %v; %s", waitErr, msg)
+		}
+		return fmt.Errorf("ffmpeg: %v", waitErr)
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package anim
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// FrameRate is the frame rate of exported animations in frames per second.
+// Animations whose frames are displayed longer than 1/FrameRate of a second
+// are upsampled, by inserting intermediate frames generated as specified by
+// Interp; animations are exported at the frame rate of the game if 0.
+var FrameRate int
+
+// Interp specifies how the intermediate frames of upsampled animations are
+// generated.
+var Interp = CrossFade
+
+// An Interpolation is a method for generating the intermediate frames of
+// upsampled animations.
+type Interpolation string
+
+// Interpolation methods.
+const (
+	// Nearest repeats the nearest frame, which keeps the look of the game.
+	Nearest Interpolation = "nearest"
+	// CrossFade blends consecutive frames, for smoother previews.
+	CrossFade Interpolation = "crossfade"
+)
+
+// ParseInterpolation returns the interpolation method of the given name
+// ("nearest" or "crossfade").
+func ParseInterpolation(name string) (interp Interpolation, err error) {
+	switch interp := Interpolation(name); interp {
+	case Nearest, CrossFade:
+		return interp, nil
+	}
+	return "", fmt.Errorf("invalid interpolation %q; expected %q or %q.", name, Nearest, CrossFade)
+}
+
+// String returns the name of the interpolation method.
+func (interp Interpolation) String() string {
+	return string(interp)
+}
+
+// Set sets the interpolation method to the method of the given name, which
+// allows it to be used as a flag.Value.
+func (interp *Interpolation) Set(name string) (err error) {
+	*interp, err = ParseInterpolation(name)
+	return err
+}
+
+// minDelay is the shortest delay between frames, in 100ths of a second, which
+// is honoured by GIF viewers; shorter delays are slowed down by most browsers.
+const minDelay = 2
+
+// Upsample returns the frames of an animation, which are displayed for delay
+// 100ths of a second each, upsampled to approximately fps frames per second
+// using the given interpolation method, along with the delay of the upsampled
+// frames. The animation loops, as GIF images do, so the last frame is
+// interpolated towards the first frame.
+func Upsample(frames []image.Image, delay, fps int, interp Interpolation) (out []image.Image, outDelay int) {
+	if fps <= 0 || len(frames) == 0 {
+		return frames, delay
+	}
+	// steps is the number of frames displayed per frame of the animation.
+	steps := (delay*fps + 50) / 100
+	if max := delay / minDelay; steps > max {
+		steps = max
+	}
+	if steps <= 1 {
+		return frames, delay
+	}
+	for i, frame := range frames {
+		next := frames[(i+1)%len(frames)]
+		out = append(out, frame)
+		for step := 1; step < steps; step++ {
+			switch interp {
+			case CrossFade:
+				out = append(out, crossFade(frame, next, step, steps))
+			default:
+				// Repeat the nearest frame.
+				if 2*step < steps {
+					out = append(out, frame)
+				} else {
+					out = append(out, next)
+				}
+			}
+		}
+	}
+	return out, (delay + steps/2) / steps
+}
+
+// crossFade returns the blend of the frames a and b, weighted by step/steps
+// towards b. The blended frame covers the bounds of both frames, and the
+// colors are blended with premultiplied alpha, so transparent pixels fade in
+// and out.
+func crossFade(a, b image.Image, step, steps int) image.Image {
+	bounds := a.Bounds().Union(b.Bounds())
+	src := [2]*image.RGBA{image.NewRGBA(bounds), image.NewRGBA(bounds)}
+	draw.Draw(src[0], a.Bounds(), a, a.Bounds().Min, draw.Src)
+	draw.Draw(src[1], b.Bounds(), b, b.Bounds().Min, draw.Src)
+	dst := image.NewRGBA(bounds)
+	wb := step
+	wa := steps - step
+	for i := range dst.Pix {
+		dst.Pix[i] = uint8((int(src[0].Pix[i])*wa + int(src[1].Pix[i])*wb + steps/2) / steps)
+	}
+	return dst
+}
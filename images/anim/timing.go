@@ -0,0 +1,58 @@
+package anim
+
+import "image"
+
+// TicksPerSecond is the number of game ticks per second.
+const TicksPerSecond = 20
+
+// End specifies how an animation continues once its last frame has been
+// displayed.
+type End string
+
+// Animation ends.
+const (
+	// Loop restarts the animation at its first frame (e.g. stand and walk).
+	Loop End = "loop"
+	// Return returns to the stand animation once the animation has been played
+	// (e.g. attack and hit).
+	Return End = "return"
+	// Hold displays the last frame once the animation has been played (e.g.
+	// death).
+	Hold End = "hold"
+)
+
+// A Timing describes the timing of an animation in game ticks, which allows
+// the animation to be played back exactly as in-game.
+type Timing struct {
+	// FrameCounts is the number of frames of each direction.
+	FrameCounts []int `json:"framesPerDirection"`
+	// TicksPerFrame is the number of game ticks each frame is displayed.
+	TicksPerFrame int `json:"ticksPerFrame"`
+	// Durations is the number of game ticks of each direction.
+	Durations []int `json:"durationTicks"`
+	// End specifies how the animation continues after its last frame.
+	End End `json:"end"`
+	// LoopFrames is the frame displayed after the last frame of each
+	// direction; 0 for Loop, the last frame for Hold and -1 for Return.
+	LoopFrames []int `json:"loopFrames"`
+}
+
+// NewTiming returns the timing of an animation, with one slice of frames per
+// direction, based on the number of game ticks each frame is displayed.
+func NewTiming(dirs [][]image.Image, ticksPerFrame int, end End) Timing {
+	t := Timing{TicksPerFrame: ticksPerFrame, End: end}
+	for _, frames := range dirs {
+		frameCount := len(frames)
+		loopFrame := -1
+		switch end {
+		case Loop:
+			loopFrame = 0
+		case Hold:
+			loopFrame = frameCount - 1
+		}
+		t.FrameCounts = append(t.FrameCounts, frameCount)
+		t.Durations = append(t.Durations, frameCount*ticksPerFrame)
+		t.LoopFrames = append(t.LoopFrames, loopFrame)
+	}
+	return t
+}
@@ -0,0 +1,68 @@
+package anim
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// VideoMuxer is the muxer used by WriteVideo to encode videos.
+var VideoMuxer Muxer = FFmpeg{}
+
+// VideoBackground is the background color of exported videos, whose frames
+// don't support transparency.
+var VideoBackground color.Color = color.Black
+
+// A Muxer encodes the frames of a video, and optionally an audio track, into a
+// video container (e.g. MP4 or WebM).
+type Muxer interface {
+	// Create creates a video at videoPath, whose container is decided by the
+	// extension of videoPath (".mp4" or ".webm"). Each frame of the video has
+	// the given size and is displayed for delay 100ths of a second. The audio
+	// track is decoded from the WAV file audio, unless it is nil.
+	Create(videoPath string, size image.Point, delay int, audio []byte) (FrameWriter, error)
+}
+
+// A FrameWriter writes the frames of a video created by a Muxer. The video
+// replaces the file at its path once committed, as done by atomicfile.
+type FrameWriter interface {
+	// WriteFrame writes the next frame of the video, whose size must match the
+	// size of the video.
+	WriteFrame(frame *image.RGBA) error
+	// Commit finishes the video and stores it at its path.
+	Commit() error
+	// Close discards the video, unless it has already been committed, which
+	// makes it safe to defer Close.
+	Close() error
+}
+
+// WriteVideo stores the frames as a video (e.g. MP4 or WebM, as decided by the
+// extension of videoPath), using the delay (in 100ths of a second) between
+// each frame and the WAV file audio as its audio track, unless audio is nil.
+// The video covers the union of the bounds of the frames, which are drawn on
+// top of VideoBackground, and is encoded by VideoMuxer.
+//
+// The animation is upsampled to FrameRate, if set.
+func WriteVideo(videoPath string, frames []image.Image, delay int, audio []byte) (err error) {
+	frames, delay = Upsample(frames, delay, FrameRate, Interp)
+	var bounds image.Rectangle
+	for _, frame := range frames {
+		bounds = bounds.Union(frame.Bounds())
+	}
+	w, err := VideoMuxer.Create(videoPath, bounds.Size(), delay, audio)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	bg := image.NewUniform(VideoBackground)
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for _, frame := range frames {
+		draw.Draw(dst, dst.Bounds(), bg, image.ZP, draw.Src)
+		draw.Draw(dst, frame.Bounds().Sub(bounds.Min), frame, frame.Bounds().Min, draw.Over)
+		err = w.WriteFrame(dst)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Commit()
+}
@@ -24,12 +24,19 @@
 package cel
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"image"
 	"image/color"
-	"os"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
 
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/images/imgcache"
 	"github.com/mewrnd/blizzconv/images/imgconf"
 	"github.com/mewrnd/blizzconv/mpq"
 )
@@ -48,96 +55,390 @@ type Config struct {
 	FrameHeight map[int]int
 	// The palette used for decoding.
 	Pal color.Palette
+	// The name of the image (e.g. "l1.cel"), which determines the frame types
+	// of level CEL images when decoded by Decode or DecodeAt; may be empty.
+	Name string
+	// The size in bytes of the optional header of each frame, which is skipped
+	// by Decode and DecodeAt.
+	HeaderSize int
+}
+
+// FrameSize returns the width and height of the given frame, which are
+// overridden by FrameWidth and FrameHeight for specific frames.
+func (conf *Config) FrameSize(frameNum int) (width, height int) {
+	width, ok := conf.FrameWidth[frameNum]
+	if !ok {
+		// Use default frame width.
+		width = conf.Width
+	}
+	height, ok = conf.FrameHeight[frameNum]
+	if !ok {
+		// Use default frame height.
+		height = conf.Height
+	}
+	return width, height
 }
 
 // DecodeAll returns the sequential frames of a CEL image based on a given conf.
 //
-// Note: The absolute path of celName is resolved using mpq.GetPath.
+// Note: The file of celName is opened using mpq.Open.
 func DecodeAll(celName string, conf *Config) (imgs []image.Image, err error) {
+	return DecodeAllFrom(mpq.Default(), celName, conf)
+}
+
+// DecodeAllFrom returns the sequential frames of the given CEL image of the
+// store s, as described by DecodeAll.
+func DecodeAllFrom(s *mpq.Store, celName string, conf *Config) (imgs []image.Image, err error) {
 	// Get frame contents.
-	frames, err := GetFrames(celName)
+	frames, err := GetFramesFrom(s, celName)
 	if err != nil {
 		return nil, err
 	}
 
-	// Decode frames.
-	for frameNum, frame := range frames {
-		width, ok := conf.FrameWidth[frameNum]
-		if !ok {
-			// Use default frame width.
-			width = conf.Width
+	// Use the cached frames if present.
+	var key string
+	if imgcache.Enabled() {
+		key = CacheKey("cel", celName, frames, conf)
+		if imgs, ok := imgcache.Load(key); ok {
+			return imgs, nil
 		}
-		height, ok := conf.FrameHeight[frameNum]
-		if !ok {
-			// Use default frame height.
-			height = conf.Height
+	}
+
+	// Decode frames.
+	imgs = decodeFrames(celName, frames, conf)
+
+	if imgcache.Enabled() {
+		err = imgcache.Store(key, imgs)
+		if err != nil {
+			return nil, err
 		}
+	}
+	return imgs, nil
+}
+
+// DecodeFrame returns a single frame of a CEL image based on a given conf. The
+// frame is located using the frame offset table, so that the other frames are
+// neither read nor decoded.
+//
+// Note: The file of celName is opened using mpq.Open.
+func DecodeFrame(celName string, conf *Config, frameNum int) (img image.Image, err error) {
+	return DecodeFrameFrom(mpq.Default(), celName, conf, frameNum)
+}
+
+// DecodeFrameFrom returns a single frame of the given CEL image of the store s,
+// as described by DecodeFrame.
+func DecodeFrameFrom(s *mpq.Store, celName string, conf *Config, frameNum int) (img image.Image, err error) {
+	frame, err := GetFrameFrom(s, celName, frameNum)
+	if err != nil {
+		return nil, err
+	}
+	width, height := conf.FrameSize(frameNum)
+	decodeFrame := GetFrameDecoder(celName, frame, frameNum)
+	return decodeFrame(frame, width, height, conf.Pal), nil
+}
 
-		// Decode frame.
-		decodeFrame := GetFrameDecoder(celName, frame, frameNum)
-		img := decodeFrame(frame, width, height, conf.Pal)
-		imgs = append(imgs, img)
+// Decode reads a CEL image from r and returns its sequential frames based on a
+// given conf, as described by DecodeAt. The entire image is read into memory.
+func Decode(r io.Reader, conf *Config) (imgs []image.Image, err error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
 	}
+	return DecodeAt(bytes.NewReader(buf), conf)
+}
 
-	return imgs, nil
+// DecodeAt returns the sequential frames of the CEL image read from r based on
+// a given conf. Unlike DecodeAll, no files are opened and the frames are not
+// cached; the frame header size and the name of the image are given by
+// conf.HeaderSize and conf.Name respectively. The size of the image is given by
+// the Size or Stat method of r (e.g. *bytes.Reader, *io.SectionReader or
+// *os.File).
+func DecodeAt(r io.ReaderAt, conf *Config) (imgs []image.Image, err error) {
+	frames, err := readFrames(r, conf.HeaderSize)
+	if err != nil {
+		return nil, fmt.Errorf("cel.DecodeAt: %v", err)
+	}
+	return decodeFrames(conf.Name, frames, conf), nil
+}
+
+// decodeFrames returns the decoded frames of the given CEL image based on a
+// given conf, using the decoders returned by GetFrameDecoder.
+func decodeFrames(celName string, frames [][]byte, conf *Config) (imgs []image.Image) {
+	getDecoder := func(frame []byte, frameNum int) func(frame []byte, width int, height int, pal color.Palette) image.Image {
+		return GetFrameDecoder(celName, frame, frameNum)
+	}
+	return DecodeFrames(frames, conf, getDecoder)
+}
+
+// DecodeFrames returns the given frames of an image based on a given conf, each
+// decoded by the decoder returned by getDecoder. Since each frame is decoded
+// independently of the others, the frames are decoded concurrently by at most
+// budget.Workers() goroutines, and returned in order. It is used by DecodeAll,
+// and by the decoders of other versions of the CEL format (e.g. cl2.DecodeAll).
+//
+// The memory of the decoded frames is reserved from the budget while decoding
+// concurrently. If the budget is exhausted, the frames are decoded by a single
+// goroutine rather than waiting, since DecodeFrames is called by jobs of
+// budget.Run which hold memory themselves.
+func DecodeFrames(frames [][]byte, conf *Config, getDecoder func(frame []byte, frameNum int) func(frame []byte, width int, height int, pal color.Palette) image.Image) (imgs []image.Image) {
+	if len(frames) == 0 {
+		return nil
+	}
+	imgs = make([]image.Image, len(frames))
+	workers := budget.Workers()
+	if workers > len(frames) {
+		workers = len(frames)
+	}
+	var mem int64
+	for frameNum := range frames {
+		width, height := conf.FrameSize(frameNum)
+		mem += budget.ImageSize(image.Rect(0, 0, width, height))
+	}
+	if budget.TryAcquire(mem) {
+		defer budget.Release(mem)
+	} else {
+		workers = 1
+	}
+	// next is the frame number of the next frame to decode.
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				frameNum := int(atomic.AddInt64(&next, 1))
+				if frameNum >= len(frames) {
+					return
+				}
+				frame := frames[frameNum]
+				width, height := conf.FrameSize(frameNum)
+
+				// Decode frame.
+				decodeFrame := getDecoder(frame, frameNum)
+				imgs[frameNum] = decodeFrame(frame, width, height, conf.Pal)
+			}
+		}()
+	}
+	wg.Wait()
+	return imgs
+}
+
+// CacheKey returns the imgcache key of the frames of an image, decoded by the
+// given decoder (e.g. "cel") based on a given conf.
+func CacheKey(decoder, imgName string, frames [][]byte, conf *Config) string {
+	data := [][]byte{[]byte(decoder), []byte(imgName)}
+	// fmt prints maps sorted by key.
+	dims := fmt.Sprint(conf.Width, conf.Height, conf.FrameWidth, conf.FrameHeight)
+	data = append(data, []byte(dims))
+	pal := make([]byte, 0, 4*len(conf.Pal))
+	for _, c := range conf.Pal {
+		r, g, b, a := c.RGBA()
+		pal = append(pal, byte(r>>8), byte(g>>8), byte(b>>8), byte(a>>8))
+	}
+	data = append(data, pal)
+	data = append(data, frames...)
+	return imgcache.Key(data...)
+}
+
+// ContentKey returns the imgcache key of the frames of the given CEL image,
+// decoded based on a given conf, which identifies the content of the CEL image
+// and its palette. It may be used as part of the keys of images derived from
+// the frames (e.g. min.CachedImages).
+func ContentKey(celName string, conf *Config) (key string, err error) {
+	frames, err := GetFrames(celName)
+	if err != nil {
+		return "", err
+	}
+	return CacheKey("cel", celName, frames, conf), nil
 }
 
 // GetFrames returns a slice of frames, whose content has been retrieved based
 // on the CEL format described above.
 //
-// Note: The absolute path of celName is resolved using mpq.GetPath.
+// Note: The file of celName is opened using mpq.Open.
 func GetFrames(celName string) (frames [][]byte, err error) {
+	return GetFramesFrom(mpq.Default(), celName)
+}
+
+// GetFramesFrom returns the frames of the given CEL image of the store s, as
+// described by GetFrames.
+func GetFramesFrom(s *mpq.Store, celName string) (frames [][]byte, err error) {
 	// Open CEL file.
-	celPath, err := mpq.GetPath(celName)
+	f, err := s.Open(celName)
 	if err != nil {
 		return nil, err
 	}
-	f, err := os.Open(celPath)
+	defer f.Close()
+
+	frames, err = readFrames(f, imgconf.GetHeaderSize(celName))
+	if err != nil {
+		return nil, fmt.Errorf("cel.GetFrames: %v for %q", err, celName)
+	}
+	return frames, nil
+}
+
+// GetFrame returns the content of a single frame, whose position is looked up
+// in the frame offset table without reading the other frames.
+//
+// Note: The file of celName is opened using mpq.Open.
+func GetFrame(celName string, frameNum int) (frame []byte, err error) {
+	return GetFrameFrom(mpq.Default(), celName, frameNum)
+}
+
+// GetFrameFrom returns the content of a single frame of the given CEL image of
+// the store s, as described by GetFrame.
+func GetFrameFrom(s *mpq.Store, celName string, frameNum int) (frame []byte, err error) {
+	// Open CEL file.
+	f, err := s.Open(celName)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	// Read frame count.
-	var frameCount uint32
-	err = binary.Read(f, binary.LittleEndian, &frameCount)
+	frame, err = readFrame(f, imgconf.GetHeaderSize(celName), frameNum)
 	if err != nil {
-		return nil, fmt.Errorf("cel.GetFrames: unable to read frame count for %q: %v", celName, err)
+		return nil, fmt.Errorf("cel.GetFrame: %v for %q", err, celName)
 	}
+	return frame, nil
+}
 
+// readFrames returns the frames of the CEL image read from r, whose frame
+// headers of headerSize bytes are skipped.
+func readFrames(r io.ReaderAt, headerSize int) (frames [][]byte, err error) {
 	// Read frame offsets.
-	frameOffsets := make([]uint32, frameCount+1)
-	err = binary.Read(f, binary.LittleEndian, frameOffsets)
+	frameOffsets, err := readFrameOffsets(r)
 	if err != nil {
-		return nil, fmt.Errorf("cel.GetFrames: unable to read frame offsets for %q: %v", celName, err)
+		return nil, err
 	}
 
 	// Read frame contents.
-	frames = make([][]byte, frameCount)
-	for frameNum := range frames {
-		// Ignore frame header.
-		headerSize := imgconf.GetHeaderSize(celName)
-		frameStart := int64(frameOffsets[frameNum]) + int64(headerSize)
-
-		// Read frame content.
-		frameEnd := int64(frameOffsets[frameNum+1])
-		frameSize := frameEnd - frameStart
-		frame := make([]byte, frameSize)
-		_, err = f.ReadAt(frame, frameStart)
+	for frameNum := 0; frameNum < len(frameOffsets)-1; frameNum++ {
+		frame, err := readFrameContent(r, frameOffsets[frameNum], frameOffsets[frameNum+1], headerSize, frameNum)
 		if err != nil {
-			return nil, fmt.Errorf("cel.GetFrames: unable to read frame content for %q: %v", celName, err)
+			return nil, err
 		}
-		frames[frameNum] = frame
+		frames = append(frames, frame)
 	}
 
 	return frames, nil
 }
 
+// readFrameOffsets returns the frame offset table of the CEL image read from r;
+// the offsets to each frame, followed by the end of the last frame. The size of
+// the table is checked against the size of the image before it is read in one
+// call, so that a corrupt frame count fails instead of allocating for it.
+func readFrameOffsets(r io.ReaderAt) (frameOffsets []int64, err error) {
+	size, err := readerSize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read frame count.
+	frameCount, err := readUint32(r, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read frame count: %v", err)
+	}
+
+	// Read frame offsets.
+	tableSize := 4 * (int64(frameCount) + 1)
+	if 4+tableSize > size {
+		return nil, fmt.Errorf("invalid frame count %d; the frame offset table (%d bytes) exceeds the image size (%d bytes)", frameCount, tableSize, size)
+	}
+	buf := make([]byte, tableSize)
+	_, err = r.ReadAt(buf, 4)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read frame offsets: %v", err)
+	}
+	frameOffsets = make([]int64, frameCount+1)
+	for i := range frameOffsets {
+		frameOffsets[i] = int64(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return frameOffsets, nil
+}
+
+// readerSize returns the size of r, which is provided by either a Size method
+// (e.g. *bytes.Reader and *io.SectionReader) or a Stat method (e.g. *os.File
+// and mpq.File).
+func readerSize(r io.ReaderAt) (size int64, err error) {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), nil
+	case interface{ Stat() (fs.FileInfo, error) }:
+		fi, err := r.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return fi.Size(), nil
+	}
+	return 0, fmt.Errorf("unable to determine the image size; %T has neither a Size nor a Stat method", r)
+}
+
+// readFrame returns the content of a single frame of the CEL image read from
+// r, whose frame header of headerSize bytes is skipped. Only the frame count,
+// the two offsets of the frame and the frame itself are read.
+func readFrame(r io.ReaderAt, headerSize, frameNum int) (frame []byte, err error) {
+	// Read frame count.
+	frameCount, err := readUint32(r, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read frame count: %v", err)
+	}
+	if frameNum < 0 || int64(frameNum) >= int64(frameCount) {
+		return nil, fmt.Errorf("invalid frame number %d; expected < %d", frameNum, frameCount)
+	}
+
+	// Read the offsets of the frame.
+	frameStart, err := readUint32(r, 4+4*int64(frameNum))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read frame offsets: %v", err)
+	}
+	frameEnd, err := readUint32(r, 4+4*int64(frameNum+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read frame offsets: %v", err)
+	}
+
+	return readFrameContent(r, int64(frameStart), int64(frameEnd), headerSize, frameNum)
+}
+
+// readFrameContent returns the content of the frame between the given offsets
+// of r, whose frame header of headerSize bytes is skipped.
+func readFrameContent(r io.ReaderAt, frameOffset, frameEnd int64, headerSize, frameNum int) (frame []byte, err error) {
+	// Ignore frame header.
+	frameStart := frameOffset + int64(headerSize)
+
+	// Read frame content.
+	if frameEnd < frameStart {
+		return nil, fmt.Errorf("invalid offsets %d and %d of frame %d", frameOffset, frameEnd, frameNum)
+	}
+	frame = make([]byte, frameEnd-frameStart)
+	_, err = r.ReadAt(frame, frameStart)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read frame content: %v", err)
+	}
+	return frame, nil
+}
+
+// readUint32 returns the little endian uint32 at the given offset of r.
+func readUint32(r io.ReaderAt, off int64) (x uint32, err error) {
+	var buf [4]byte
+	_, err = r.ReadAt(buf[:], off)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
 // GetConf returns a conf containing the relevant image information.
 //
-// Note: The absolute path of celName is resolved using mpq.GetPath and
-// relPalPath is relative to mpq.ExtractPath.
+// Note: The file of celName is opened using mpq.Open and relPalPath is
+// relative to the extracted MPQ archive.
 func GetConf(celName, relPalPath string) (conf *Config, err error) {
+	return GetConfFrom(mpq.Default(), celName, relPalPath)
+}
+
+// GetConfFrom returns a conf containing the relevant image information, as
+// described by GetConf, whose palette is read from the store s.
+func GetConfFrom(s *mpq.Store, celName, relPalPath string) (conf *Config, err error) {
 	width, err := imgconf.GetWidth(celName)
 	if err != nil {
 		return nil, err
@@ -154,7 +455,7 @@ func GetConf(celName, relPalPath string) (conf *Config, err error) {
 	if err != nil {
 		return nil, err
 	}
-	pal, err := GetPal(relPalPath)
+	pal, err := GetPalFrom(s, relPalPath)
 	if err != nil {
 		return nil, err
 	}
@@ -164,6 +465,8 @@ func GetConf(celName, relPalPath string) (conf *Config, err error) {
 		FrameWidth:  frameWidth,
 		FrameHeight: frameHeight,
 		Pal:         pal,
+		Name:        celName,
+		HeaderSize:  imgconf.GetHeaderSize(celName),
 	}
 	return conf, nil
 }
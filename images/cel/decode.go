@@ -8,33 +8,54 @@ import (
 
 // GetFrameDecoder returns the appropriate function for decoding the frame.
 func GetFrameDecoder(celName string, frame []byte, frameNum int) func(frame []byte, width int, height int, pal color.Palette) image.Image {
+	switch GetFrameType(celName, frame, frameNum) {
+	case 0:
+		return DecodeFrameType0
+	case 2:
+		return DecodeFrameType2
+	case 3:
+		return DecodeFrameType3
+	case 4:
+		return DecodeFrameType4
+	case 5:
+		return DecodeFrameType5
+	}
+	// Regular frame (type 1).
+	return DecodeFrameType1
+}
+
+// GetFrameType returns the type of the frame, which determines the algorithm
+// used to decode it.
+//
+// ref: DecodeFrameType0, DecodeFrameType1, ..., DecodeFrameType5
+func GetFrameType(celName string, frame []byte, frameNum int) (frameType int) {
 	frameSize := len(frame)
 	switch celName {
-	case "l1.cel", "l2.cel", "l3.cel", "l4.cel", "town.cel":
+	case "l1.cel", "l2.cel", "l3.cel", "l4.cel", "l5.cel", "l6.cel", "town.cel":
 		// Some regular (type 1) CEL images just happen to have a frame size of
 		// exactly 0x220, 0x320 or 0x400. Therefore the isType* functions are
 		// required to figure out the appropriate decoding function.
 		switch frameSize {
 		case 0x400:
 			if isType0(celName, frameNum) {
-				return DecodeFrameType0
+				return 0
 			}
 		case 0x220:
 			if isType2or4(frame) {
-				return DecodeFrameType2
+				return 2
 			} else if isType3or5(frame) {
-				return DecodeFrameType3
+				return 3
 			}
 		case 0x320:
 			if isType2or4(frame) {
-				return DecodeFrameType4
+				return 4
 			} else if isType3or5(frame) {
-				return DecodeFrameType5
+				return 5
 			}
 		}
 	}
 	// Regular frame (type 1).
-	return DecodeFrameType1
+	return 1
 }
 
 // isType0 returns true if the image is a plain 32x32.
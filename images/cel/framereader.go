@@ -0,0 +1,122 @@
+package cel
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// A FrameReader reads and decodes the frames of a CEL image one at a time, so
+// that only the current frame is resident in memory, as opposed to DecodeAll
+// which returns every frame at once. It is intended for converting large
+// images, such as the animations of monster CL2 images.
+type FrameReader struct {
+	// r holds the contents of the image.
+	r io.ReaderAt
+	// c closes the file of the image, if opened by OpenFrameReader.
+	c io.Closer
+	// name is the name of the image, which determines the frame types of level
+	// CEL images.
+	name       string
+	conf       *Config
+	headerSize int
+	// frameOffsets contains the offsets to each frame, followed by the end of
+	// the last frame.
+	frameOffsets []int64
+	// frameNum is the frame number of the next frame.
+	frameNum int
+	// decodeFrame decodes every frame if set, instead of the decoder returned
+	// by GetFrameDecoder.
+	decodeFrame func(frame []byte, width int, height int, pal color.Palette) image.Image
+}
+
+// NewFrameReader returns a frame reader of the CEL image read from r based on a
+// given conf. As with DecodeAt, no files are opened; the frame header size and
+// the name of the image are given by conf.HeaderSize and conf.Name, and the
+// size of the image by the Size or Stat method of r.
+func NewFrameReader(r io.ReaderAt, conf *Config) (fr *FrameReader, err error) {
+	fr, err = newFrameReader(r, conf.Name, conf, conf.HeaderSize)
+	if err != nil {
+		return nil, fmt.Errorf("cel.NewFrameReader: %v", err)
+	}
+	return fr, nil
+}
+
+// OpenFrameReader opens the given CEL image and returns a frame reader of it
+// based on a given conf. The frame reader must be closed after use.
+//
+// Note: The file of celName is opened using mpq.Open.
+func OpenFrameReader(celName string, conf *Config) (fr *FrameReader, err error) {
+	return OpenFrameReaderFrom(mpq.Default(), celName, conf)
+}
+
+// OpenFrameReaderFrom opens the given CEL image of the store s, as described
+// by OpenFrameReader.
+func OpenFrameReaderFrom(s *mpq.Store, celName string, conf *Config) (fr *FrameReader, err error) {
+	f, err := s.Open(celName)
+	if err != nil {
+		return nil, err
+	}
+	fr, err = newFrameReader(f, celName, conf, imgconf.GetHeaderSize(celName))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cel.OpenFrameReader: %v for %q", err, celName)
+	}
+	fr.c = f
+	return fr, nil
+}
+
+// newFrameReader returns a frame reader of the CEL image read from r, after
+// reading its frame offset table.
+func newFrameReader(r io.ReaderAt, name string, conf *Config, headerSize int) (fr *FrameReader, err error) {
+	frameOffsets, err := readFrameOffsets(r)
+	if err != nil {
+		return nil, err
+	}
+	fr = &FrameReader{r: r, name: name, conf: conf, headerSize: headerSize, frameOffsets: frameOffsets}
+	return fr, nil
+}
+
+// SetDecoder sets the function used to decode every frame, instead of the
+// decoder returned by GetFrameDecoder; e.g. cl2.DecodeFrameType6 for CL2
+// images.
+func (fr *FrameReader) SetDecoder(decodeFrame func(frame []byte, width int, height int, pal color.Palette) image.Image) {
+	fr.decodeFrame = decodeFrame
+}
+
+// Len returns the number of frames of the image.
+func (fr *FrameReader) Len() int {
+	return len(fr.frameOffsets) - 1
+}
+
+// Next reads and decodes the next frame of the image. It returns io.EOF once
+// every frame has been read.
+func (fr *FrameReader) Next() (img image.Image, err error) {
+	frameNum := fr.frameNum
+	if frameNum >= fr.Len() {
+		return nil, io.EOF
+	}
+	frame, err := readFrameContent(fr.r, fr.frameOffsets[frameNum], fr.frameOffsets[frameNum+1], fr.headerSize, frameNum)
+	if err != nil {
+		return nil, fmt.Errorf("cel.FrameReader.Next: %v", err)
+	}
+	fr.frameNum++
+	width, height := fr.conf.FrameSize(frameNum)
+	decodeFrame := fr.decodeFrame
+	if decodeFrame == nil {
+		decodeFrame = GetFrameDecoder(fr.name, frame, frameNum)
+	}
+	return decodeFrame(frame, width, height, fr.conf.Pal), nil
+}
+
+// Close closes the file of the image, if opened by OpenFrameReader.
+func (fr *FrameReader) Close() error {
+	if fr.c == nil {
+		return nil
+	}
+	return fr.c.Close()
+}
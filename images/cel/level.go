@@ -24,6 +24,14 @@ func DecodeFrameType0(frame []byte, width int, height int, pal color.Palette) im
 	return img
 }
 
+// triangleDecodeCounts contains the number of explicit pixels of each line of
+// type 2 and type 3 frames.
+var triangleDecodeCounts = []int{0, 4, 4, 8, 8, 12, 12, 16, 16, 20, 20, 24, 24, 28, 28, 32, 32, 32, 28, 28, 24, 24, 20, 20, 16, 16, 12, 12, 8, 8, 4, 4}
+
+// trapezoidDecodeCounts contains the number of explicit pixels of each line of
+// type 4 and type 5 frames.
+var trapezoidDecodeCounts = []int{4, 4, 8, 8, 12, 12, 16, 16, 20, 20, 24, 24, 28, 28, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32}
+
 // DecodeFrameType2 returns an image after decoding the frame in the following
 // way:
 //
@@ -78,8 +86,7 @@ func DecodeFrameType0(frame []byte, width int, height int, pal color.Palette) im
 func DecodeFrameType2(frame []byte, width int, height int, pal color.Palette) image.Image {
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
 	setPixel := GetPixelSetter(width, height)
-	decodeCounts := []int{0, 4, 4, 8, 8, 12, 12, 16, 16, 20, 20, 24, 24, 28, 28, 32, 32, 32, 28, 28, 24, 24, 20, 20, 16, 16, 12, 12, 8, 8, 4, 4}
-	for lineNum, decodeCount := range decodeCounts {
+	for lineNum, decodeCount := range triangleDecodeCounts {
 		zeroCount := 0
 		if lineNum%2 == 1 {
 			zeroCount = 2
@@ -145,8 +152,7 @@ func DecodeFrameType2(frame []byte, width int, height int, pal color.Palette) im
 func DecodeFrameType3(frame []byte, width int, height int, pal color.Palette) image.Image {
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
 	setPixel := GetPixelSetter(width, height)
-	decodeCounts := []int{0, 4, 4, 8, 8, 12, 12, 16, 16, 20, 20, 24, 24, 28, 28, 32, 32, 32, 28, 28, 24, 24, 20, 20, 16, 16, 12, 12, 8, 8, 4, 4}
-	for lineNum, decodeCount := range decodeCounts {
+	for lineNum, decodeCount := range triangleDecodeCounts {
 		zeroCount := 0
 		if lineNum%2 == 1 {
 			zeroCount = 2
@@ -212,8 +218,7 @@ func DecodeFrameType3(frame []byte, width int, height int, pal color.Palette) im
 func DecodeFrameType4(frame []byte, width int, height int, pal color.Palette) image.Image {
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
 	setPixel := GetPixelSetter(width, height)
-	decodeCounts := []int{4, 4, 8, 8, 12, 12, 16, 16, 20, 20, 24, 24, 28, 28, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32}
-	for lineNum, decodeCount := range decodeCounts {
+	for lineNum, decodeCount := range trapezoidDecodeCounts {
 		zeroCount := 0
 		switch lineNum {
 		case 0, 2, 4, 6, 8, 10, 12, 14:
@@ -280,8 +285,7 @@ func DecodeFrameType4(frame []byte, width int, height int, pal color.Palette) im
 func DecodeFrameType5(frame []byte, width int, height int, pal color.Palette) image.Image {
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
 	setPixel := GetPixelSetter(width, height)
-	decodeCounts := []int{4, 4, 8, 8, 12, 12, 16, 16, 20, 20, 24, 24, 28, 28, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32}
-	for lineNum, decodeCount := range decodeCounts {
+	for lineNum, decodeCount := range trapezoidDecodeCounts {
 		zeroCount := 0
 		switch lineNum {
 		case 0, 2, 4, 6, 8, 10, 12, 14:
@@ -339,3 +343,49 @@ func decodeLineTransparencyRight(dst draw.Image, setPixel func(dst draw.Image, c
 		setPixel(dst, color.Transparent)
 	}
 }
+
+// StrayPixels returns the number of explicit transparent pixels (zeroes) of a
+// type 2, 3, 4 or 5 frame which contain non-zero color indices. Such pixels are
+// silently discarded by the decoder, and a non-zero count therefore indicates
+// that the frame has been decoded using the wrong algorithm. The stray pixels
+// of other frame types are not tracked.
+func StrayPixels(frame []byte, frameType int) (n int) {
+	var decodeCounts []int
+	switch frameType {
+	case 2, 3:
+		decodeCounts = triangleDecodeCounts
+	case 4, 5:
+		decodeCounts = trapezoidDecodeCounts
+	default:
+		return 0
+	}
+	left := frameType == 2 || frameType == 4
+	for lineNum, decodeCount := range decodeCounts {
+		if len(frame) < decodeCount {
+			// Count missing pixels as stray.
+			return n + decodeCount - len(frame)
+		}
+		zeroCount := 0
+		switch frameType {
+		case 2, 3:
+			if lineNum%2 == 1 {
+				zeroCount = 2
+			}
+		case 4, 5:
+			if lineNum < 16 && lineNum%2 == 0 {
+				zeroCount = 2
+			}
+		}
+		zeros := frame[decodeCount-zeroCount : decodeCount]
+		if left {
+			zeros = frame[:zeroCount]
+		}
+		for _, b := range zeros {
+			if b != 0 {
+				n++
+			}
+		}
+		frame = frame[decodeCount:]
+	}
+	return n
+}
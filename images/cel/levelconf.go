@@ -0,0 +1,90 @@
+package cel
+
+import (
+	"fmt"
+
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// SpecialCels maps from level type to the special CEL image of the level, which
+// contains overlay graphics (e.g. arches and door frames) drawn on top of
+// specific pillars, in order to let the player walk behind them.
+var SpecialCels = map[string]string{
+	"town": "towns.cel",
+	"l1":   "l1s.cel",
+	"l2":   "l2s.cel",
+}
+
+// A LevelConf holds the image information of the CEL images of a level type
+// (e.g. "l1"), which are decoded using the same palette.
+type LevelConf struct {
+	// CelName is the name of the level CEL image (e.g. "l1.cel").
+	CelName string
+	// Conf contains the image information of the level CEL image.
+	Conf *Config
+	// SpecialName is the name of the special CEL image of the level (e.g.
+	// "l1s.cel"), or the empty string if the level has none.
+	SpecialName string
+	// SpecialConf contains the image information of the special CEL image, or
+	// nil if the level has none.
+	SpecialConf *Config
+	// RelPalPath is the path to the palette used by the confs, relative to the
+	// extracted MPQ archive.
+	RelPalPath string
+	// RelPalPaths is the palette set of the level, i.e. the paths to each
+	// palette of the level CEL image.
+	RelPalPaths []string
+}
+
+// GetLevelConf returns the image information of the level CEL image and special
+// CEL image of the given level type (e.g. "l1"), using the first palette of the
+// level.
+func GetLevelConf(levelType string) (lc *LevelConf, err error) {
+	return GetLevelConfFrom(mpq.Default(), levelType)
+}
+
+// GetLevelConfFrom returns the image information of the level CEL image and
+// special CEL image of the given level type, as described by GetLevelConf,
+// whose palette is read from the store s.
+func GetLevelConfFrom(s *mpq.Store, levelType string) (lc *LevelConf, err error) {
+	relPalPaths := imgconf.GetRelPalPaths(levelType + ".cel")
+	if len(relPalPaths) == 0 {
+		return nil, fmt.Errorf("cel.GetLevelConf: no palette found for level %q.", levelType)
+	}
+	return GetLevelConfPalFrom(s, levelType, relPalPaths[0])
+}
+
+// GetLevelConfPal returns the image information of the level CEL image and
+// special CEL image of the given level type (e.g. "l1"), using the given
+// palette of the level.
+//
+// Note: relPalPath is relative to the extracted MPQ archive.
+func GetLevelConfPal(levelType, relPalPath string) (lc *LevelConf, err error) {
+	return GetLevelConfPalFrom(mpq.Default(), levelType, relPalPath)
+}
+
+// GetLevelConfPalFrom returns the image information of the level CEL image and
+// special CEL image of the given level type, as described by GetLevelConfPal,
+// whose palette is read from the store s.
+func GetLevelConfPalFrom(s *mpq.Store, levelType, relPalPath string) (lc *LevelConf, err error) {
+	lc = &LevelConf{
+		CelName:     levelType + ".cel",
+		RelPalPath:  relPalPath,
+		RelPalPaths: imgconf.GetRelPalPaths(levelType + ".cel"),
+	}
+	lc.Conf, err = GetConfFrom(s, lc.CelName, relPalPath)
+	if err != nil {
+		return nil, err
+	}
+	specialName, ok := SpecialCels[levelType]
+	if !ok {
+		return lc, nil
+	}
+	lc.SpecialName = specialName
+	lc.SpecialConf, err = GetConfFrom(s, specialName, relPalPath)
+	if err != nil {
+		return nil, err
+	}
+	return lc, nil
+}
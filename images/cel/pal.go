@@ -3,7 +3,6 @@ package cel
 import (
 	"fmt"
 	"image/color"
-	"io/ioutil"
 
 	"github.com/mewrnd/blizzconv/mpq"
 )
@@ -19,10 +18,14 @@ import (
 //    g byte   // green
 //    b byte   // blue
 //
-// Note: The absolute path of relPalPath is relative to mpq.ExtractPath.
+// Note: relPalPath is relative to the extracted MPQ archive.
 func GetPal(relPalPath string) (pal color.Palette, err error) {
-	palPath := mpq.AbsPath(relPalPath)
-	buf, err := ioutil.ReadFile(palPath)
+	return GetPalFrom(mpq.Default(), relPalPath)
+}
+
+// GetPalFrom parses the given PAL file of the store s, as described by GetPal.
+func GetPalFrom(s *mpq.Store, relPalPath string) (pal color.Palette, err error) {
+	buf, err := s.ReadFile(relPalPath)
 	if err != nil {
 		return nil, err
 	}
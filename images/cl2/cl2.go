@@ -7,42 +7,112 @@ package cl2
 
 import (
 	"image"
+	"image/color"
 	"path"
 
 	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgcache"
+	"github.com/mewrnd/blizzconv/mpq"
 )
 
 // DecodeAll returns the sequential frames of a CEL or CL2 image based on a
 // given conf.
 func DecodeAll(imgName string, conf *cel.Config) (imgs []image.Image, err error) {
+	return DecodeAllFrom(mpq.Default(), imgName, conf)
+}
+
+// DecodeAllFrom returns the sequential frames of the given CEL or CL2 image of
+// the store s, as described by DecodeAll.
+func DecodeAllFrom(s *mpq.Store, imgName string, conf *cel.Config) (imgs []image.Image, err error) {
 	// Decode CEL version 1 images using the cel package.
 	if path.Ext(imgName) == ".cel" {
-		return cel.DecodeAll(imgName, conf)
+		return cel.DecodeAllFrom(s, imgName, conf)
 	}
 
 	// Get frame contents.
-	frames, err := cel.GetFrames(imgName)
+	frames, err := cel.GetFramesFrom(s, imgName)
 	if err != nil {
 		return nil, err
 	}
 
-	// Decode frames.
-	for frameNum, frame := range frames {
-		width, ok := conf.FrameWidth[frameNum]
-		if !ok {
-			// Use default frame width.
-			width = conf.Width
-		}
-		height, ok := conf.FrameHeight[frameNum]
-		if !ok {
-			// Use default frame height.
-			height = conf.Height
+	// Use the cached frames if present.
+	var key string
+	if imgcache.Enabled() {
+		key = cel.CacheKey("cl2", imgName, frames, conf)
+		if imgs, ok := imgcache.Load(key); ok {
+			return imgs, nil
 		}
+	}
 
-		// Decode frame.
-		img := DecodeFrameType6(frame, width, height, conf.Pal)
-		imgs = append(imgs, img)
+	// Decode frames.
+	getDecoder := func(frame []byte, frameNum int) func(frame []byte, width int, height int, pal color.Palette) image.Image {
+		return DecodeFrameType6
 	}
+	imgs = cel.DecodeFrames(frames, conf, getDecoder)
 
+	if imgcache.Enabled() {
+		err = imgcache.Store(key, imgs)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return imgs, nil
 }
+
+// DecodeFrame returns a single frame of a CEL or CL2 image based on a given
+// conf, without reading or decoding the other frames.
+func DecodeFrame(imgName string, conf *cel.Config, frameNum int) (img image.Image, err error) {
+	return DecodeFrameFrom(mpq.Default(), imgName, conf, frameNum)
+}
+
+// DecodeFrameFrom returns a single frame of the given CEL or CL2 image of the
+// store s, as described by DecodeFrame.
+func DecodeFrameFrom(s *mpq.Store, imgName string, conf *cel.Config, frameNum int) (img image.Image, err error) {
+	// Decode CEL version 1 images using the cel package.
+	if path.Ext(imgName) == ".cel" {
+		return cel.DecodeFrameFrom(s, imgName, conf, frameNum)
+	}
+
+	frame, err := cel.GetFrameFrom(s, imgName, frameNum)
+	if err != nil {
+		return nil, err
+	}
+	width, height := conf.FrameSize(frameNum)
+	return DecodeFrameType6(frame, width, height, conf.Pal), nil
+}
+
+// OpenFrameReader opens the given CEL or CL2 image and returns a frame reader
+// of it based on a given conf, which decodes the frames one at a time. The
+// frame reader must be closed after use.
+func OpenFrameReader(imgName string, conf *cel.Config) (fr *cel.FrameReader, err error) {
+	return OpenFrameReaderFrom(mpq.Default(), imgName, conf)
+}
+
+// OpenFrameReaderFrom opens the given CEL or CL2 image of the store s, as
+// described by OpenFrameReader.
+func OpenFrameReaderFrom(s *mpq.Store, imgName string, conf *cel.Config) (fr *cel.FrameReader, err error) {
+	fr, err = cel.OpenFrameReaderFrom(s, imgName, conf)
+	if err != nil {
+		return nil, err
+	}
+	// Decode CEL version 1 images using the cel package.
+	if path.Ext(imgName) != ".cel" {
+		fr.SetDecoder(DecodeFrameType6)
+	}
+	return fr, nil
+}
+
+// TileWidth is the width in pixels of the floor tile a character or monster
+// stands on.
+const TileWidth = 64
+
+// DrawOffset returns the draw offset of a decoded frame, relative to the bottom
+// left corner of the floor tile which the character or monster stands on. The
+// frame is centered horizontally on the tile and its bottom is aligned with the
+// bottom of the tile.
+//
+// ref: width2 (monster and player draw offset of the game)
+func DrawOffset(frame image.Image) (offset image.Point) {
+	bounds := frame.Bounds()
+	return image.Pt(-(bounds.Dx()-TileWidth)/2, -bounds.Dy())
+}
@@ -8,13 +8,33 @@
 //
 //    -a
 //            Dump all image files.
+//    -dirs
+//            Dump the images of archives (e.g. the directions of CL2 animations) to one directory each.
+//    -gamma=1
+//            Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).
+//    -hashdir=""
+//            Store the PNG images by content hash in this directory, with a manifest mapping file paths to hashes.
 //    -imgini="cel.ini"
 //            Path to an ini file containing image information.
 //            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
 //    -mpqdump="mpqdump/"
 //            Path to an extracted MPQ file.
 //    -mpqini="mpq.ini"
 //            Path to an ini file containing relative path information.
+//    -quirksini=""
+//            Path to an ini file containing the quirks of early game versions (e.g. 1.00); disabled if empty.
+//    -srgb
+//            Tag the exported PNG images as sRGB.
+//    -strict
+//            Abort when optional assets (e.g. palette variants or color transitions) are missing.
 package main
 
 import (
@@ -24,27 +44,48 @@ import (
 	"log"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/0xC3/progress/barcli"
-	"github.com/mewkiz/pkg/imgutil"
+	"github.com/mewrnd/blizzconv/budget"
 	"github.com/mewrnd/blizzconv/images/cel"
 	"github.com/mewrnd/blizzconv/images/cl2"
+	"github.com/mewrnd/blizzconv/images/cursor"
 	"github.com/mewrnd/blizzconv/images/imgarchive"
 	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/mongfx"
+	"github.com/mewrnd/blizzconv/images/pngprof"
 	"github.com/mewrnd/blizzconv/images/trn"
 	"github.com/mewrnd/blizzconv/mpq"
+	"github.com/mewrnd/blizzconv/quirks"
 )
 
 // flagAll specifies if all CEL images should be dumped or not.
 var flagAll bool
 
+// flagDirs specifies if the images of archives should be dumped to one
+// directory each.
+var flagDirs bool
+
+// flagStrict specifies if missing optional assets should abort the dump or
+// only be reported as warnings.
+var flagStrict bool
+
 func init() {
 	flag.Usage = usage
 	flag.BoolVar(&flagAll, "a", false, "Dump all image files.")
+	flag.BoolVar(&flagDirs, "dirs", false, "Dump the images of archives (e.g. the directions of CL2 animations) to one directory each.")
+	flag.Float64Var(&pngprof.Gamma, "gamma", 1, "Gamma curve applied to the exported PNG images (e.g. 1.14 approximates a CRT).")
+	flag.StringVar(&pngprof.HashDir, "hashdir", "", "Store the PNG images by content hash in this directory, with a manifest mapping file paths to hashes.")
 	flag.StringVar(&imgconf.IniPath, "imgini", "cel.ini", "Path to an ini file containing image information.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
 	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
 	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.StringVar(&quirks.IniPath, "quirksini", "", "Path to an ini file containing the quirks of early game versions (e.g. 1.00); disabled if empty.")
+	flag.BoolVar(&pngprof.SRGB, "srgb", false, "Tag the exported PNG images as sRGB.")
+	flag.BoolVar(&flagStrict, "strict", false, "Abort when optional assets (e.g. palette variants or color transitions) are missing.")
+	budget.RegisterFlags()
 	flag.Parse()
 	err := mpq.Init()
 	if err != nil {
@@ -62,6 +103,14 @@ func usage() {
 // bar represents the progress bar.
 var bar *barcli.Bar
 
+// shareware specifies if the MPQ archive is that of the shareware version, in
+// which case the images it lacks are skipped when dumping all images.
+var shareware bool
+
+// skipped is the number of images skipped, as they are missing from the
+// shareware version.
+var skipped int
+
 func main() {
 	if flag.NArg() > 0 {
 		if path.Ext(flag.Arg(0)) == ".cl2" && imgconf.IniPath == "cel.ini" {
@@ -72,16 +121,30 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	version, err := quirks.DetectAndApply()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(version) > 0 {
+		fmt.Println("Applying the quirks of version:", version)
+	}
+	if path.Base(imgconf.IniPath) == "cl2.ini" {
+		mongfx.InitConf()
+	}
 	if flagAll {
 		bar, err = barcli.New(imgconf.Len())
 		if err != nil {
 			log.Fatalln(err)
 		}
+		shareware = mpq.IsShareware()
 		// dump all images in the ini file.
 		err := imgconf.AllFunc(dump)
 		if err != nil {
 			log.Fatalln(err)
 		}
+		if skipped > 0 {
+			fmt.Printf("Shareware version detected; skipped %d images missing from the archive.\n", skipped)
+		}
 		return
 	}
 	if flag.NArg() < 1 {
@@ -97,26 +160,54 @@ func main() {
 }
 
 // dump extracts archived images if there are any, decodes image configs (pals)
-// and dumps the image's frames, once for each image config.
+// and dumps the image's frames, once for each image config. The images of
+// archives are only dumped if flagDirs is set.
 func dump(imgName string) (err error) {
 	if flagAll {
 		bar.Inc()
 	}
-	_, found := imgconf.GetImageCount(imgName)
+	if shareware && mpq.Missing(imgName) {
+		skipped++
+		return nil
+	}
+	imageCount, found := imgconf.GetImageCount(imgName)
 	if found {
 		// extract archived images
 		err = imgarchive.Extract(imgName)
 		if err != nil {
 			return err
 		}
+		if !flagDirs {
+			return nil
+		}
+		// dump each archived image to a directory of its own.
+		nameWithoutExt := imgName[:len(imgName)-len(path.Ext(imgName))]
+		for imageNum := 0; imageNum < imageCount; imageNum++ {
+			groupDir := fmt.Sprintf("%s/dir_%d/", nameWithoutExt, imageNum)
+			err = dumpImage(imgarchive.ImageName(imgName, imageNum), groupDir, nameWithoutExt)
+			if err != nil {
+				return err
+			}
+		}
 		return nil
 	}
+	return dumpImage(imgName, "", "")
+}
 
+// dumpImage decodes image configs (pals) and dumps the image's frames, once for
+// each image config. If groupDir is non-empty the frames are stored in groupDir,
+// named after groupName.
+func dumpImage(imgName, groupDir, groupName string) (err error) {
 	relPalPaths := imgconf.GetRelPalPaths(imgName)
 	for _, relPalPath := range relPalPaths {
 		conf, err := cel.GetConf(imgName, relPalPath)
 		if err != nil {
-			return err
+			// skip missing palette variants.
+			err = optional(err)
+			if err != nil {
+				return err
+			}
+			continue
 		}
 		var palDir string
 		if len(relPalPaths) > 1 {
@@ -124,7 +215,8 @@ func dump(imgName string) (err error) {
 		}
 
 		// dump the image's frames using conf (pal) with no color transitions.
-		err = dumpFrames(conf, palDir, "", imgName)
+		meta := pngprof.Source(imgName, relPalPath)
+		err = dumpFrames(conf, meta, palDir, "", imgName, groupDir, groupName)
 		if err != nil {
 			return err
 		}
@@ -138,7 +230,12 @@ func dump(imgName string) (err error) {
 		for _, relTrnPath := range relTrnPaths {
 			conf.Pal, err = trn.ConvertPal(srcPal, relTrnPath)
 			if err != nil {
-				return err
+				// skip missing color transitions.
+				err = optional(err)
+				if err != nil {
+					return err
+				}
+				continue
 			}
 
 			var trnDir string
@@ -147,7 +244,7 @@ func dump(imgName string) (err error) {
 			}
 
 			// dump the image's frames using conf (pal) with color transitions.
-			err = dumpFrames(conf, palDir, trnDir, imgName)
+			err = dumpFrames(conf, meta.With(pngprof.KeyTrn, relTrnPath), palDir, trnDir, imgName, groupDir, groupName)
 			if err != nil {
 				return err
 			}
@@ -156,9 +253,21 @@ func dump(imgName string) (err error) {
 	return nil
 }
 
+// optional returns nil and reports err as a warning if err is caused by a
+// missing file, unless flagStrict is set. Any other error is returned as is.
+func optional(err error) error {
+	if err == nil || flagStrict || !os.IsNotExist(err) {
+		return err
+	}
+	log.Println("warning:", err)
+	return nil
+}
+
 // dumpFrames decodes an image's frames using a given image config (pal),
-// creates a dump directory and stores each frame as a new png image.
-func dumpFrames(conf *cel.Config, palDir, trnDir, imgName string) (err error) {
+// creates a dump directory and stores each frame as a new png image, with the
+// provenance metadata and frame number embedded. If groupDir is non-empty the
+// frames are stored in groupDir, named after groupName.
+func dumpFrames(conf *cel.Config, meta pngprof.Meta, palDir, trnDir, imgName, groupDir, groupName string) (err error) {
 	// decode frames using the given image config (pal)
 	imgs, err := cl2.DecodeAll(imgName, conf)
 	if err != nil {
@@ -167,8 +276,14 @@ func dumpFrames(conf *cel.Config, palDir, trnDir, imgName string) (err error) {
 	// create dumpDir
 	nameWithoutExt := imgName[:len(imgName)-len(path.Ext(imgName))]
 	var frameDir, pngName string
+	if len(groupDir) > 0 {
+		frameDir = groupDir
+		nameWithoutExt = groupName
+	}
 	if len(imgs) > 1 {
-		frameDir = nameWithoutExt + "/"
+		if len(groupDir) == 0 {
+			frameDir = nameWithoutExt + "/"
+		}
 	} else {
 		pngName = nameWithoutExt + ".png"
 	}
@@ -183,7 +298,14 @@ func dumpFrames(conf *cel.Config, palDir, trnDir, imgName string) (err error) {
 		if len(imgs) > 1 {
 			pngName = fmt.Sprintf("%s_%04d.png", nameWithoutExt, frameNum)
 		}
-		err := imgutil.WriteFile(dumpDir+pngName, img)
+		err := pngprof.WriteFileMeta(dumpDir+pngName, img, meta.With(pngprof.KeyFrame, strconv.Itoa(frameNum)))
+		if err != nil {
+			return err
+		}
+	}
+	if imgName == cursor.CelName && len(imgs) > 0 {
+		// store the cursor hotspots in a sidecar file.
+		err = cursor.WriteInfo(dumpDir+nameWithoutExt+".json", imgs)
 		if err != nil {
 			return err
 		}
@@ -198,6 +320,13 @@ const dumpPrefix = "_dump_/"
 //
 //    === [ dumpDir examples ] =================================================
 //
+//    --- [ archive (-dirs), one pal, one trn, many frames ] -------------------
+//
+//       _dump_/imgDir/name/dir_0/name_0000.png
+//       _dump_/imgDir/name/dir_0/name_0001.png
+//       _dump_/imgDir/name/dir_1/name_0000.png
+//       _dump_/imgDir/name/dir_1/name_0001.png
+//
 //    --- [ one pal, one trn, one frame ] --------------------------------------
 //
 //       _dump_/imgDir/name.png
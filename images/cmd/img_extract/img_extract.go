@@ -9,6 +9,8 @@
 //    -celini="cel.ini"
 //            Path to an ini file containing image information.
 //            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
 //    -mpqdump="mpqdump/"
 //            Path to an extracted MPQ file.
 //    -mpqini="mpq.ini"
@@ -24,12 +26,14 @@ import (
 
 	"github.com/mewrnd/blizzconv/images/imgarchive"
 	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/mongfx"
 	"github.com/mewrnd/blizzconv/mpq"
 )
 
 func init() {
 	flag.Usage = usage
 	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
 	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
 	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
 	flag.Parse()
@@ -60,6 +64,9 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	if path.Base(imgconf.IniPath) == "cl2.ini" {
+		mongfx.InitConf()
+	}
 	for _, imgName := range flag.Args() {
 		err = imgarchive.Extract(imgName)
 		if err != nil {
@@ -0,0 +1,125 @@
+// item_dump is a tool for exporting the drop graphics of items, storing the
+// flip animation of each item as a gif image and the graphics displayed while
+// the item lies on the ground as a png image. The output files are named after
+// the items rather than their CEL images.
+//
+// Usage:
+//
+//    item_dump [OPTION]...
+//
+// Flags:
+//
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//    -dither=false
+//            Dither colors which are not present in the palette of exported GIF images.
+//    -fps=0
+//            Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.
+//    -interp="crossfade"
+//            Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/images/anim"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/itemgfx"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.BoolVar(&anim.Dither, "dither", false, "Dither colors which are not present in the palette of exported GIF images.")
+	flag.IntVar(&anim.FrameRate, "fps", 0, "Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.")
+	flag.Var(&anim.Interp, "interp", "Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	dumpDir := path.Clean(dumpPrefix+"_items_/") + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		log.Fatalf("path (%s) contains no dump prefix (%s).\n", dumpDir, dumpPrefix)
+	}
+	err := os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, drop := range itemgfx.Drops {
+		err = itemDump(drop, dumpDir)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// itemDump verifies the frame count of the drop graphics and stores its flip
+// animation as a gif image and its last frame as a png image.
+func itemDump(drop itemgfx.Drop, dumpDir string) (err error) {
+	relPalPath := imgconf.GetRelPalPaths(drop.CelName)[0]
+	conf, err := cel.GetConf(drop.CelName, relPalPath)
+	if err != nil {
+		return err
+	}
+	frames, err := cel.DecodeAll(drop.CelName, conf)
+	if err != nil {
+		return err
+	}
+	if len(frames) != drop.FrameCount {
+		return fmt.Errorf("item %s: frame count mismatch for %q; expected %d, got %d.", drop.Name, drop.CelName, drop.FrameCount, len(frames))
+	}
+	gifPath := dumpDir + drop.Name + ".gif"
+	// The flip animation advances one frame each game tick.
+	err = anim.WritePalettedGIF(gifPath, frames, anim.Delay(1), conf.Pal)
+	if err != nil {
+		return err
+	}
+	pngPath := dumpDir + drop.Name + ".png"
+	meta := pngprof.Source(drop.CelName, relPalPath).With(pngprof.KeyFrame, strconv.Itoa(len(frames)-1))
+	return pngprof.WriteFileMeta(pngPath, frames[len(frames)-1], meta)
+}
@@ -0,0 +1,187 @@
+// mis_dump is a tool for exporting the graphics of a curated set of missiles
+// (arrows, fireballs, lightning). Each missile is stored as a png sprite sheet
+// (one row per direction), as one gif image per direction and as a JSON file
+// containing its timing information.
+//
+// Usage:
+//
+//    mis_dump [OPTION]...
+//
+// Flags:
+//
+//    -dither=false
+//            Dither colors which are not present in the palette of exported GIF images.
+//    -fps=0
+//            Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.
+//    -imgini="cl2.ini"
+//            Path to an ini file containing image information.
+//    -interp="crossfade"
+//            Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/images/anim"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/cl2"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/misgfx"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+func init() {
+	flag.Usage = usage
+	flag.BoolVar(&anim.Dither, "dither", false, "Dither colors which are not present in the palette of exported GIF images.")
+	flag.IntVar(&anim.FrameRate, "fps", 0, "Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.")
+	flag.StringVar(&imgconf.IniPath, "imgini", "cl2.ini", "Path to an ini file containing image information.")
+	flag.Var(&anim.Interp, "interp", "Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	for _, mis := range misgfx.Missiles {
+		err := misDump(mis)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// timing contains the timing information of a missile, as stored in its JSON
+// file.
+type timing struct {
+	Name          string `json:"name"`
+	DirCount      int    `json:"dirs"`
+	FrameCount    int    `json:"frames"`
+	TicksPerFrame int    `json:"ticks_per_frame"`
+	// Delay is the duration of each frame in 100ths of a second.
+	Delay int `json:"delay"`
+}
+
+// misDump decodes each direction of the missile and stores it as a sprite
+// sheet, one gif image per direction and a JSON file with timing information.
+func misDump(mis misgfx.Missile) (err error) {
+	dirs, pal, err := decodeDirs(mis)
+	if err != nil {
+		return err
+	}
+
+	// Store the missile.
+	dumpDir := path.Clean(dumpPrefix+"_missiles_/"+mis.Name) + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+	}
+	err = os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		return err
+	}
+	// The provenance of the sheet is the image of the first direction.
+	imgName := mis.ImgName(0)
+	meta := pngprof.Source(imgName, imgconf.GetRelPalPaths(imgName)[0])
+	err = pngprof.WriteFileMeta(dumpDir+mis.Name+".png", anim.Sheet(dirs), meta)
+	if err != nil {
+		return err
+	}
+	delay := anim.Delay(mis.TicksPerFrame())
+	if mis.FrameCount > 1 {
+		for dir, imgs := range dirs {
+			gifPath := dumpDir + fmt.Sprintf("%s_dir_%d.gif", mis.Name, dir)
+			err = anim.WritePalettedGIF(gifPath, imgs, delay, pal)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	t := timing{
+		Name:          mis.Name,
+		DirCount:      mis.DirCount,
+		FrameCount:    mis.FrameCount,
+		TicksPerFrame: mis.TicksPerFrame(),
+		Delay:         delay,
+	}
+	buf, err := json.MarshalIndent(t, "", "\t")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(dumpDir+mis.Name+".json", append(buf, '\n'))
+}
+
+// decodeDirs decodes the frames of each direction of the missile, and verifies
+// the frame count of each direction.
+func decodeDirs(mis misgfx.Missile) (dirs [][]image.Image, pal color.Palette, err error) {
+	for dir := 0; dir < mis.DirCount; dir++ {
+		imgName := mis.ImgName(dir)
+		relPalPath := imgconf.GetRelPalPaths(imgName)[0]
+		conf, err := cel.GetConf(imgName, relPalPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		imgs, err := cl2.DecodeAll(imgName, conf)
+		if err != nil {
+			return nil, nil, err
+		}
+		pal = conf.Pal
+		if mis.FramePerDir {
+			// each frame corresponds to one direction.
+			if len(imgs) != mis.DirCount {
+				return nil, nil, fmt.Errorf("missile %s: direction count mismatch for %q; expected %d, got %d.", mis.Name, imgName, mis.DirCount, len(imgs))
+			}
+			for _, img := range imgs {
+				dirs = append(dirs, []image.Image{img})
+			}
+			return dirs, pal, nil
+		}
+		if len(imgs) != mis.FrameCount {
+			return nil, nil, fmt.Errorf("missile %s: frame count mismatch for %q; expected %d, got %d.", mis.Name, imgName, mis.FrameCount, len(imgs))
+		}
+		dirs = append(dirs, imgs)
+	}
+	return dirs, pal, nil
+}
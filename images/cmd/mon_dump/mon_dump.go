@@ -0,0 +1,307 @@
+// mon_dump is a tool for exporting the complete animation bundle of monsters.
+// The stand, walk, attack, hit, death and special animations of each monster
+// are grouped in one directory, storing each animation as a png sprite sheet
+// (one row per direction) and as one gif image per direction, together with a
+// JSON manifest of the bundle. The manifest records the timing of each
+// animation in game ticks (frames per direction, duration and loop points).
+//
+// Each direction may also be stored as an MP4 or WebM video, together with the
+// sound of the animation, for sharing; the videos are encoded using ffmpeg.
+//
+// Usage:
+//
+//    mon_dump [OPTION]... [monster]...
+//
+// Flags:
+//
+//    -a
+//            Dump all monsters.
+//    -dither
+//            Dither colors which are not present in the palette of exported GIF images.
+//    -ffmpeg=""
+//            Path of the ffmpeg executable used by -video; located using PATH if empty.
+//    -fps=0
+//            Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.
+//    -imgini="cl2.ini"
+//            Path to an ini file containing image information.
+//    -interp="crossfade"
+//            Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -ticks=1
+//            Number of game ticks each frame is displayed.
+//    -video=""
+//            Video format (mp4 or webm) of videos stored for each direction, with the sound of the animation; disabled if empty.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/images/anim"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/cl2"
+	"github.com/mewrnd/blizzconv/images/imgarchive"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/mongfx"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagAll specifies if all monsters should be dumped or not.
+	flagAll bool
+	// flagTicks specifies the number of game ticks each frame is displayed.
+	flagTicks int
+	// flagFFmpeg specifies the path of the ffmpeg executable.
+	flagFFmpeg string
+	// flagVideo specifies the format of the videos stored for each direction,
+	// if any.
+	flagVideo string
+)
+
+func init() {
+	flag.Usage = usage
+	flag.BoolVar(&flagAll, "a", false, "Dump all monsters.")
+	flag.BoolVar(&anim.Dither, "dither", false, "Dither colors which are not present in the palette of exported GIF images.")
+	flag.StringVar(&flagFFmpeg, "ffmpeg", "", "Path of the ffmpeg executable used by -video; located using PATH if empty.")
+	flag.IntVar(&anim.FrameRate, "fps", 0, "Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.")
+	flag.StringVar(&imgconf.IniPath, "imgini", "cl2.ini", "Path to an ini file containing image information.")
+	flag.Var(&anim.Interp, "interp", "Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.IntVar(&flagTicks, "ticks", 1, "Number of game ticks each frame is displayed.")
+	flag.StringVar(&flagVideo, "video", "", "Video format (mp4 or webm) of videos stored for each direction, with the sound of the animation; disabled if empty.")
+	budget.RegisterFlags()
+	flag.Parse()
+	switch flagVideo {
+	case "", "mp4", "webm":
+	default:
+		log.Fatalf("invalid video format %q; expected mp4 or webm.\n", flagVideo)
+	}
+	anim.VideoMuxer = anim.FFmpeg{Path: flagFFmpeg}
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	mongfx.InitConf()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... [monster]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var mons []mongfx.Monster
+	if flagAll {
+		var err error
+		mons, err = mongfx.Monsters()
+		if err != nil {
+			log.Fatalln(err)
+		}
+	} else {
+		if flag.NArg() < 1 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		for _, name := range flag.Args() {
+			mons = append(mons, mongfx.Monster(name))
+		}
+	}
+	// Skip the monsters missing from the shareware version when dumping all
+	// monsters.
+	shareware := flagAll && mpq.IsShareware()
+	skipped := 0
+	for _, mon := range mons {
+		if shareware && missing(mon) {
+			skipped++
+			continue
+		}
+		err := monDump(mon)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if skipped > 0 {
+		fmt.Printf("Shareware version detected; skipped %d monsters missing from the archive.\n", skipped)
+	}
+}
+
+// missing reports whether the archive of any animation of the monster is
+// missing from the MPQ archive.
+func missing(mon mongfx.Monster) bool {
+	for _, a := range mongfx.Anims {
+		if mon.HasAnim(a) && mpq.Missing(mon.ArchiveName(a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// manifest describes the animation bundle of a monster.
+type manifest struct {
+	Monster string         `json:"monster"`
+	Anims   []animManifest `json:"anims"`
+}
+
+// animManifest describes an animation of the bundle.
+type animManifest struct {
+	Anim       string   `json:"anim"`
+	Archive    string   `json:"archive"`
+	FrameCount int      `json:"frames"`
+	Sheet      string   `json:"sheet"`
+	GIFs       []string `json:"gifs"`
+	Videos     []string `json:"videos,omitempty"`
+	// Delay is the duration of each frame in 100ths of a second.
+	Delay int `json:"delay"`
+	// Timing is the timing of the animation in game ticks.
+	Timing anim.Timing `json:"timing"`
+}
+
+// monDump stores each animation of the monster in a directory of its own,
+// together with a JSON manifest of the bundle.
+func monDump(mon mongfx.Monster) (err error) {
+	dumpDir := path.Clean(dumpPrefix+"_monsters_/"+string(mon)) + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+	}
+	m := manifest{Monster: string(mon)}
+	for _, a := range mongfx.Anims {
+		if !mon.HasAnim(a) {
+			continue
+		}
+		if len(m.Anims) == 0 {
+			err = os.MkdirAll(dumpDir, 0755)
+			if err != nil {
+				return err
+			}
+		}
+		am, err := animDump(mon, a, dumpDir)
+		if err != nil {
+			return err
+		}
+		m.Anims = append(m.Anims, am)
+	}
+	if len(m.Anims) == 0 {
+		return fmt.Errorf("no animations located for monster %q.", mon)
+	}
+	buf, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(dumpDir+"manifest.json", append(buf, '\n'))
+}
+
+// animDump decodes each direction of the monster's animation and stores it as a
+// sprite sheet and one gif image per direction.
+func animDump(mon mongfx.Monster, a mongfx.Anim, dumpDir string) (am animManifest, err error) {
+	archiveName := mon.ArchiveName(a)
+	if !imgarchive.IsExtracted(archiveName) {
+		err = imgarchive.Extract(archiveName)
+		if err != nil {
+			return am, err
+		}
+	}
+	var dirs [][]image.Image
+	var pal color.Palette
+	var meta pngprof.Meta
+	for dir := 0; dir < mongfx.DirCount; dir++ {
+		imgName := mon.ImgName(a, dir)
+		relPalPath := imgconf.GetRelPalPaths(imgName)[0]
+		conf, err := cel.GetConf(imgName, relPalPath)
+		if err != nil {
+			return am, err
+		}
+		imgs, err := cl2.DecodeAll(imgName, conf)
+		if err != nil {
+			return am, err
+		}
+		dirs = append(dirs, imgs)
+		pal = conf.Pal
+		meta = pngprof.Source(archiveName, relPalPath)
+	}
+
+	// Store the animation.
+	nameWithoutExt := strings.TrimSuffix(archiveName, path.Ext(archiveName))
+	am = animManifest{
+		Anim:       a.String(),
+		Archive:    archiveName,
+		FrameCount: len(dirs[0]),
+		Sheet:      nameWithoutExt + ".png",
+		Delay:      anim.Delay(flagTicks),
+		Timing:     anim.NewTiming(dirs, flagTicks, a.End()),
+	}
+	err = pngprof.WriteFileMeta(dumpDir+am.Sheet, anim.Sheet(dirs), meta)
+	if err != nil {
+		return am, err
+	}
+	for dir, imgs := range dirs {
+		gifName := fmt.Sprintf("%s_dir_%d.gif", nameWithoutExt, dir)
+		err = anim.WritePalettedGIF(dumpDir+gifName, imgs, am.Delay, pal)
+		if err != nil {
+			return am, err
+		}
+		am.GIFs = append(am.GIFs, gifName)
+	}
+	if len(flagVideo) == 0 {
+		return am, nil
+	}
+	audio, err := readSound(mon.SoundName(a))
+	if err != nil {
+		return am, err
+	}
+	for dir, imgs := range dirs {
+		videoName := fmt.Sprintf("%s_dir_%d.%s", nameWithoutExt, dir, flagVideo)
+		err = anim.WriteVideo(dumpDir+videoName, imgs, am.Delay, audio)
+		if err != nil {
+			return am, err
+		}
+		am.Videos = append(am.Videos, videoName)
+	}
+	return am, nil
+}
+
+// readSound returns the contents of the sound file of the given name, or nil
+// if the name is empty or not present in the ini file.
+func readSound(name string) (buf []byte, err error) {
+	if len(name) == 0 {
+		return nil, nil
+	}
+	relPath, err := mpq.GetRelPath(name)
+	if err != nil {
+		// Not every monster has sounds of its own.
+		return nil, nil
+	}
+	return mpq.Default().ReadFile(relPath)
+}
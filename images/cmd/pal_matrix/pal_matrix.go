@@ -0,0 +1,195 @@
+// pal_matrix is a tool for rendering one frame of an image under each of its
+// palettes and color transitions (TRN files), and storing the result as a
+// labeled matrix png image.
+//
+// The matrix contains one row per palette (e.g. the palette variants of the
+// caves), and one column per color transition, preceded by a column without
+// color transitions. It helps picking the right tint of an image, and verifies
+// the palette coverage of the ini file; palettes and color transitions which
+// are missing are marked as such in the matrix.
+//
+// Usage:
+//
+//    pal_matrix [OPTION]... name.cel|name.cl2
+//
+// Flags:
+//
+//    -frame=0
+//            Frame number of the rendered frame.
+//    -imgini="cel.ini"
+//            Path to an ini file containing image information.
+//            Note: 'cl2.ini' will be used for files that have the '.cl2' extension.
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -o="_dump_/_pal_matrix_.png"
+//            Output path of the matrix image.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/cl2"
+	"github.com/mewrnd/blizzconv/images/gallery"
+	"github.com/mewrnd/blizzconv/images/imgarchive"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/mongfx"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/images/trn"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagFrame specifies the frame number of the rendered frame.
+	flagFrame int
+	// flagOutput specifies the output path of the matrix image.
+	flagOutput string
+)
+
+func init() {
+	flag.Usage = usage
+	flag.IntVar(&flagFrame, "frame", 0, "Frame number of the rendered frame.")
+	flag.StringVar(&imgconf.IniPath, "imgini", "cel.ini", "Path to an ini file containing image information.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.StringVar(&flagOutput, "o", "_dump_/_pal_matrix_.png", "Output path of the matrix image.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... name.cel|name.cl2\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	imgName := flag.Arg(0)
+	if path.Ext(imgName) == ".cl2" && imgconf.IniPath == "cel.ini" {
+		imgconf.IniPath = "cl2.ini"
+	}
+	err := imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if path.Base(imgconf.IniPath) == "cl2.ini" {
+		mongfx.InitConf()
+	}
+	// Render the first image of archives (e.g. the first direction of CL2
+	// animations).
+	if _, found := imgconf.GetImageCount(imgName); found {
+		if !imgarchive.IsExtracted(imgName) {
+			err = imgarchive.Extract(imgName)
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+		imgName = imgarchive.ImageName(imgName, 0)
+	}
+	rows, missing, err := matrix(imgName)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = os.MkdirAll(path.Dir(flagOutput), 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	meta := pngprof.Source(imgName, imgconf.GetRelPalPaths(imgName)[0])
+	err = pngprof.WriteFileMeta(flagOutput, gallery.Grid(rows), meta)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Printf("Rendered %d palettes and %d color transitions of %q; %d missing.\n", len(rows), len(rows[0])-1, imgName, missing)
+}
+
+// matrix returns the rows of the matrix; the frame rendered under each palette
+// of the image, without and with each of its color transitions applied. The
+// tiles of missing palettes and color transitions are left empty, and counted
+// by missing.
+func matrix(imgName string) (rows [][]gallery.Tile, missing int, err error) {
+	relTrnPaths := imgconf.GetRelTrnPaths(imgName)
+	for _, relPalPath := range imgconf.GetRelPalPaths(imgName) {
+		palName := path.Base(relPalPath)
+		conf, err := cel.GetConf(imgName, relPalPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, 0, err
+			}
+			// Mark the missing palette, along with its color transitions.
+			log.Println("warning:", err)
+			row := []gallery.Tile{{Label: palName + " (missing)"}}
+			for _, relTrnPath := range relTrnPaths {
+				row = append(row, gallery.Tile{Label: path.Base(relTrnPath)})
+			}
+			rows = append(rows, row)
+			missing++
+			continue
+		}
+		tile, err := render(imgName, conf, palName)
+		if err != nil {
+			return nil, 0, err
+		}
+		row := []gallery.Tile{tile}
+		srcPal := make(color.Palette, len(conf.Pal))
+		copy(srcPal, conf.Pal)
+		for _, relTrnPath := range relTrnPaths {
+			trnName := path.Base(relTrnPath)
+			conf.Pal, err = trn.ConvertPal(srcPal, relTrnPath)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					return nil, 0, err
+				}
+				log.Println("warning:", err)
+				row = append(row, gallery.Tile{Label: trnName + " (missing)"})
+				missing++
+				continue
+			}
+			tile, err = render(imgName, conf, trnName)
+			if err != nil {
+				return nil, 0, err
+			}
+			row = append(row, tile)
+		}
+		rows = append(rows, row)
+	}
+	return rows, missing, nil
+}
+
+// render returns a tile of the matrix, containing the frame of the image
+// decoded using the given image config (pal).
+func render(imgName string, conf *cel.Config, tileLabel string) (tile gallery.Tile, err error) {
+	imgs, err := cl2.DecodeAll(imgName, conf)
+	if err != nil {
+		return tile, err
+	}
+	if flagFrame < 0 || flagFrame >= len(imgs) {
+		return tile, fmt.Errorf("invalid frame number %d of %q; expected 0 to %d.", flagFrame, imgName, len(imgs)-1)
+	}
+	return gallery.Tile{Label: tileLabel, Img: imgs[flagFrame]}, nil
+}
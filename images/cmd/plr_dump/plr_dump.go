@@ -0,0 +1,196 @@
+// plr_dump is a tool for exporting the complete animation set of a player
+// character. The player graphics are selected based on the class, armor and
+// weapon of the character, and each animation is stored as a png sprite sheet
+// (one row per direction) and as one gif image per direction.
+//
+// Usage:
+//
+//    plr_dump [OPTION]...
+//
+// Flags:
+//
+//    -armor="light"
+//            Armor tier (light, medium or heavy).
+//    -class="warrior"
+//            Character class (warrior, rogue or sorcerer).
+//    -dither=false
+//            Dither colors which are not present in the palette of exported GIF images.
+//    -fps=0
+//            Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.
+//    -imgini="cl2.ini"
+//            Path to an ini file containing image information.
+//    -interp="crossfade"
+//            Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -ticks=1
+//            Number of game ticks each frame is displayed.
+//    -weapon="none"
+//            Weapon (none, shield, sword, swordshield, bow, axe, mace,
+//            maceshield or staff).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/images/anim"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/cl2"
+	"github.com/mewrnd/blizzconv/images/imgarchive"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/plrgfx"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagClass specifies the character class.
+	flagClass string
+	// flagArmor specifies the armor tier of the character.
+	flagArmor string
+	// flagWeapon specifies the weapon of the character.
+	flagWeapon string
+	// flagTicks specifies the number of game ticks each frame is displayed.
+	flagTicks int
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&flagArmor, "armor", "light", "Armor tier (light, medium or heavy).")
+	flag.StringVar(&flagClass, "class", "warrior", "Character class (warrior, rogue or sorcerer).")
+	flag.BoolVar(&anim.Dither, "dither", false, "Dither colors which are not present in the palette of exported GIF images.")
+	flag.IntVar(&anim.FrameRate, "fps", 0, "Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.")
+	flag.StringVar(&imgconf.IniPath, "imgini", "cl2.ini", "Path to an ini file containing image information.")
+	flag.Var(&anim.Interp, "interp", "Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.IntVar(&flagTicks, "ticks", 1, "Number of game ticks each frame is displayed.")
+	flag.StringVar(&flagWeapon, "weapon", "none", "Weapon (none, shield, sword, swordshield, bow, axe, mace, maceshield or staff).")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var char plrgfx.Character
+	var err error
+	char.Class, err = plrgfx.ParseClass(flagClass)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	char.Armor, err = plrgfx.ParseArmor(flagArmor)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	char.Weapon, err = plrgfx.ParseWeapon(flagWeapon)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// Skip the animations missing from the shareware version, which only
+	// contains those of the warrior.
+	shareware := mpq.IsShareware()
+	for _, a := range plrgfx.Anims {
+		if !char.HasAnim(a) {
+			continue
+		}
+		if shareware && mpq.Missing(char.ArchiveName(a)) {
+			fmt.Printf("Shareware version detected; skipped %q missing from the archive.\n", char.ArchiveName(a))
+			continue
+		}
+		err = animDump(char, a)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// animDump decodes each direction of the character's animation and stores it
+// as a sprite sheet and one gif image per direction.
+func animDump(char plrgfx.Character, a plrgfx.Anim) (err error) {
+	archiveName := char.ArchiveName(a)
+	if !imgarchive.IsExtracted(archiveName) {
+		err = imgarchive.Extract(archiveName)
+		if err != nil {
+			return err
+		}
+	}
+	var dirs [][]image.Image
+	var pal color.Palette
+	var meta pngprof.Meta
+	for dir := 0; dir < plrgfx.DirCount; dir++ {
+		imgName := char.ImgName(a, dir)
+		relPalPath := imgconf.GetRelPalPaths(imgName)[0]
+		conf, err := cel.GetConf(imgName, relPalPath)
+		if err != nil {
+			return err
+		}
+		imgs, err := cl2.DecodeAll(imgName, conf)
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, imgs)
+		pal = conf.Pal
+		meta = pngprof.Source(archiveName, relPalPath)
+	}
+
+	// Store the animation.
+	nameWithoutExt := strings.TrimSuffix(archiveName, path.Ext(archiveName))
+	dumpDir := path.Clean(dumpPrefix+"_plrgfx_/"+char.Class.String()+"/"+nameWithoutExt) + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+	}
+	err = os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		return err
+	}
+	err = pngprof.WriteFileMeta(dumpDir+nameWithoutExt+".png", anim.Sheet(dirs), meta)
+	if err != nil {
+		return err
+	}
+	delay := anim.Delay(flagTicks)
+	for dir, imgs := range dirs {
+		gifPath := dumpDir + fmt.Sprintf("%s_dir_%d.gif", nameWithoutExt, dir)
+		err = anim.WritePalettedGIF(gifPath, imgs, delay, pal)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// spell_dump is a tool for exporting the spell icons of the control panel, the
+// speedbook and the spellbook, storing each icon as a png image named after its
+// spell. A JSON index of the exported icons is stored alongside the images.
+//
+// Usage:
+//
+//    spell_dump [OPTION]...
+//
+// Flags:
+//
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/images/spells"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Skip the icons missing from the shareware version.
+	shareware := mpq.IsShareware()
+	for _, celName := range spells.IconCelNames {
+		if shareware && mpq.Missing(celName) {
+			fmt.Printf("Shareware version detected; skipped %q missing from the archive.\n", celName)
+			continue
+		}
+		err := iconDump(celName)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// indexEntry is an entry of the JSON index of exported spell icons.
+type indexEntry struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	IconNum int    `json:"icon"`
+	File    string `json:"file"`
+}
+
+// iconDump stores the icon of each spell, as located in the given CEL image, as
+// a png image named after the spell. A JSON index is stored in the same
+// directory.
+func iconDump(celName string) (err error) {
+	relPalPath := imgconf.GetRelPalPaths(celName)[0]
+	conf, err := cel.GetConf(celName, relPalPath)
+	if err != nil {
+		return err
+	}
+	frames, err := cel.DecodeAll(celName, conf)
+	if err != nil {
+		return err
+	}
+	nameWithoutExt := celName[:len(celName)-len(path.Ext(celName))]
+	dumpDir := path.Clean(dumpPrefix+"_spells_/"+nameWithoutExt) + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		return fmt.Errorf("path (%s) contains no dump prefix (%s).", dumpDir, dumpPrefix)
+	}
+	err = os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		return err
+	}
+	meta := pngprof.Source(celName, relPalPath)
+	var index []indexEntry
+	for id, spell := range spells.Spells {
+		if id == 0 {
+			// skip the absence of a spell.
+			continue
+		}
+		if spell.IconNum >= len(frames) {
+			return fmt.Errorf("spell %d (%s): invalid icon %d of %q (frame count: %d).", id, spell.Name, spell.IconNum, celName, len(frames))
+		}
+		pngName := spell.FileName() + ".png"
+		err = pngprof.WriteFileMeta(dumpDir+pngName, frames[spell.IconNum], meta.With(pngprof.KeyFrame, strconv.Itoa(spell.IconNum)))
+		if err != nil {
+			return err
+		}
+		entry := indexEntry{
+			ID:      id,
+			Name:    spell.Name,
+			IconNum: spell.IconNum,
+			File:    pngName,
+		}
+		index = append(index, entry)
+	}
+	buf, err := json.MarshalIndent(index, "", "\t")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(dumpDir+"index.json", append(buf, '\n'))
+}
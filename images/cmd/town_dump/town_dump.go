@@ -0,0 +1,177 @@
+// town_dump is a tool for exporting the idle animations of the towners (e.g.
+// Griswold, Adria and the cows). Each towner is stored as a png sprite sheet
+// (one row per direction) and as one gif image per direction. The frame count
+// of each towner is verified against its CEL image.
+//
+// The sprite sheets contain the frames of the CEL images. The gif images play
+// the frames in the order of the game if the frame order table of the game is
+// provided by -animorder, and in sequential order otherwise.
+//
+// Usage:
+//
+//    town_dump [OPTION]...
+//
+// Flags:
+//
+//    -animorder=""
+//            Path to a text file of the frame order table of the game (AnimOrder), one comma-separated row of 1-based frame numbers per line; the frames are played in order if empty.
+//    -celini="cel.ini"
+//            Path to an ini file containing image information.
+//    -dither=false
+//            Dither colors which are not present in the palette of exported GIF images.
+//    -fps=0
+//            Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.
+//    -interp="crossfade"
+//            Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/images/anim"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgarchive"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/images/towngfx"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// flagAnimOrder specifies the path to a text file of the frame order table of
+// the game.
+var flagAnimOrder string
+
+func init() {
+	flag.Usage = usage
+	flag.StringVar(&flagAnimOrder, "animorder", "", "Path to a text file of the frame order table of the game (AnimOrder), one comma-separated row of 1-based frame numbers per line; the frames are played in order if empty.")
+	flag.StringVar(&imgconf.IniPath, "celini", "cel.ini", "Path to an ini file containing image information.")
+	flag.BoolVar(&anim.Dither, "dither", false, "Dither colors which are not present in the palette of exported GIF images.")
+	flag.IntVar(&anim.FrameRate, "fps", 0, "Frame rate of exported GIF images, whose frames are interpolated as specified by -interp; the frame rate of the game if 0.")
+	flag.Var(&anim.Interp, "interp", "Interpolation of the frames of exported GIF images upsampled by -fps (nearest or crossfade).")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(flagAnimOrder) > 0 {
+		err = readAnimOrders(flagAnimOrder)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
+
+// readAnimOrders parses the frame order table of the game stored at the given
+// path.
+func readAnimOrders(animOrderPath string) (err error) {
+	f, err := os.Open(animOrderPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return towngfx.ParseAnimOrders(f)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	dumpDir := path.Clean(dumpPrefix+"_towners_/") + "/"
+	// prevent directory traversal
+	if !strings.HasPrefix(dumpDir, dumpPrefix) {
+		log.Fatalf("path (%s) contains no dump prefix (%s).\n", dumpDir, dumpPrefix)
+	}
+	err := os.MkdirAll(dumpDir, 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, towner := range towngfx.Towners {
+		err = townerDump(towner, dumpDir)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// dumpPrefix is the name of the dump directory.
+const dumpPrefix = "_dump_/"
+
+// townerDump decodes each direction of the towner and stores it as a sprite
+// sheet and one gif image per direction.
+func townerDump(towner towngfx.Towner, dumpDir string) (err error) {
+	if towner.IsArchive() && !imgarchive.IsExtracted(towner.CelName) {
+		err = imgarchive.Extract(towner.CelName)
+		if err != nil {
+			return err
+		}
+	}
+	var dirs [][]image.Image
+	var pal color.Palette
+	var meta pngprof.Meta
+	for dir := 0; dir < towner.DirCount; dir++ {
+		imgName := towner.ImgName(dir)
+		relPalPath := imgconf.GetRelPalPaths(imgName)[0]
+		conf, err := cel.GetConf(imgName, relPalPath)
+		if err != nil {
+			return err
+		}
+		imgs, err := cel.DecodeAll(imgName, conf)
+		if err != nil {
+			return err
+		}
+		if len(imgs) != towner.FrameCount {
+			return fmt.Errorf("towner %s: frame count mismatch for %q; expected %d, got %d.", towner.Name, imgName, towner.FrameCount, len(imgs))
+		}
+		dirs = append(dirs, imgs)
+		pal = conf.Pal
+		meta = pngprof.Source(towner.CelName, relPalPath)
+	}
+	err = pngprof.WriteFileMeta(dumpDir+towner.Name+".png", anim.Sheet(dirs), meta)
+	if err != nil {
+		return err
+	}
+	delay := anim.Delay(towner.TicksPerFrame)
+	for dir, imgs := range dirs {
+		gifName := towner.Name + ".gif"
+		if towner.IsArchive() {
+			gifName = fmt.Sprintf("%s_dir_%d.gif", towner.Name, dir)
+		}
+		err = anim.WritePalettedGIF(dumpDir+gifName, towner.Frames(imgs), delay, pal)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+// trn_gallery is a tool for rendering the base frame of each monster, once
+// without and once for each of its color transitions (TRN files), and storing
+// the result as a labeled gallery png image.
+//
+// The base frame of a monster is the first frame of its standing animation
+// facing south (e.g. 'acidn0.cl2'). The gallery contains one row per monster,
+// or with -u one row per unique monster, and doubles as a visual check of the
+// trn package, since unique monsters are distinguished from regular monsters by
+// their color transitions.
+//
+// Usage:
+//
+//    trn_gallery [OPTION]... [monster]...
+//
+// Flags:
+//
+//    -a
+//            Include all monsters with color transitions.
+//    -imgini="cl2.ini"
+//            Path to an ini file containing image information.
+//    -j=0
+//            Alias of -max-workers.
+//    -max-mem=0
+//            Memory budget of the jobs (e.g. decoded frames or rendered dungeons) run concurrently (e.g. "512M" or "2G"); unlimited if 0.
+//    -max-workers=0
+//            Number of jobs (e.g. decoded frames or rendered dungeons) run concurrently (0 uses the number of CPUs).
+//    -mpqarchive=""
+//            Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.
+//    -mpqdump="mpqdump/"
+//            Path to an extracted MPQ file.
+//    -mpqini="mpq.ini"
+//            Path to an ini file containing relative path information.
+//    -o="_dump_/_trn_gallery_.png"
+//            Output path of the gallery image.
+//    -u
+//            Include all unique monsters with color transitions of their own, one row per unique monster.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/budget"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/cl2"
+	"github.com/mewrnd/blizzconv/images/gallery"
+	"github.com/mewrnd/blizzconv/images/imgarchive"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/images/mongfx"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+	"github.com/mewrnd/blizzconv/images/trn"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+var (
+	// flagAll specifies if all monsters should be included or not.
+	flagAll bool
+	// flagOutput specifies the output path of the gallery image.
+	flagOutput string
+	// flagUnique specifies if the gallery should contain one row per unique
+	// monster or not.
+	flagUnique bool
+)
+
+func init() {
+	flag.Usage = usage
+	flag.BoolVar(&flagAll, "a", false, "Include all monsters with color transitions.")
+	flag.StringVar(&imgconf.IniPath, "imgini", "cl2.ini", "Path to an ini file containing image information.")
+	flag.StringVar(&mpq.ArchivePath, "mpqarchive", "", "Path to an MPQ archive (e.g. DIABDAT.MPQ), whose files are extracted to -mpqdump when needed; several archives (e.g. DIABDAT.MPQ:hellfire.mpq) are chained, later ones shadowing earlier ones.")
+	flag.StringVar(&mpq.ExtractPath, "mpqdump", "mpqdump/", "Path to an extracted MPQ file.")
+	flag.StringVar(&mpq.IniPath, "mpqini", "mpq.ini", "Path to an ini file containing relative path information.")
+	flag.StringVar(&flagOutput, "o", "_dump_/_trn_gallery_.png", "Output path of the gallery image.")
+	flag.BoolVar(&flagUnique, "u", false, "Include all unique monsters with color transitions of their own, one row per unique monster.")
+	budget.RegisterFlags()
+	flag.Parse()
+	err := mpq.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = imgconf.Init()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	mongfx.InitConf()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... [monster]...\n", os.Args[0])
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
+
+// baseSuffix is the suffix of the base frame image of each monster.
+const baseSuffix = "n0.cl2"
+
+func main() {
+	var rows [][]gallery.Tile
+	var monsterNames []string
+	if flagUnique {
+		var err error
+		rows, err = uniqueRows()
+		if err != nil {
+			log.Fatalln(err)
+		}
+	} else if flagAll {
+		// Locate all monsters with color transitions, skipping those missing
+		// from the shareware version.
+		shareware := mpq.IsShareware()
+		skipped := 0
+		err := imgconf.AllFunc(func(imgName string) error {
+			if !strings.HasSuffix(imgName, baseSuffix) || len(imgconf.GetRelTrnPaths(imgName)) == 0 {
+				return nil
+			}
+			if shareware && mpq.Missing(imgName) {
+				skipped++
+				return nil
+			}
+			monsterNames = append(monsterNames, strings.TrimSuffix(imgName, baseSuffix))
+			return nil
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if skipped > 0 {
+			fmt.Printf("Shareware version detected; skipped %d monsters missing from the archive.\n", skipped)
+		}
+	} else if flag.NArg() > 0 {
+		monsterNames = flag.Args()
+	} else {
+		flag.Usage()
+		os.Exit(1)
+	}
+	for _, monsterName := range monsterNames {
+		row, err := monsterRow(monsterName)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		rows = append(rows, row)
+	}
+	err := os.MkdirAll(path.Dir(flagOutput), 0755)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = pngprof.WriteFile(flagOutput, gallery.Grid(rows))
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// uniqueRows returns the rows of the gallery, containing the base frame of the
+// monster without and with the color transition of each unique monster applied,
+// skipping the unique monsters missing from the shareware version.
+func uniqueRows() (rows [][]gallery.Tile, err error) {
+	shareware := mpq.IsShareware()
+	skipped := 0
+	for _, unique := range mongfx.Uniques {
+		imgName := string(unique.Monster) + baseSuffix
+		if shareware && mpq.Missing(imgName) {
+			skipped++
+			continue
+		}
+		img, err := baseFrame(unique.Monster, "")
+		if err != nil {
+			return nil, err
+		}
+		uniqueImg, err := baseFrame(unique.Monster, unique.RelTrnPath)
+		if err != nil {
+			return nil, err
+		}
+		row := []gallery.Tile{
+			{Label: string(unique.Monster), Img: img},
+			{Label: unique.Name, Img: uniqueImg},
+		}
+		rows = append(rows, row)
+	}
+	if skipped > 0 {
+		fmt.Printf("Shareware version detected; skipped %d unique monsters missing from the archive.\n", skipped)
+	}
+	return rows, nil
+}
+
+// monsterRow returns a row of the gallery, containing the base frame of the
+// monster without and with each of its color transitions applied.
+func monsterRow(monsterName string) (row []gallery.Tile, err error) {
+	monster := mongfx.Monster(monsterName)
+	img, err := baseFrame(monster, "")
+	if err != nil {
+		return nil, err
+	}
+	row = append(row, gallery.Tile{Label: monsterName, Img: img})
+	for _, relTrnPath := range imgconf.GetRelTrnPaths(monsterName + baseSuffix) {
+		img, err = baseFrame(monster, relTrnPath)
+		if err != nil {
+			return nil, err
+		}
+		trnName := path.Base(relTrnPath)
+		row = append(row, gallery.Tile{Label: trnName, Img: img})
+	}
+	return row, nil
+}
+
+// baseFrame returns the base frame of the monster, with the provided color
+// transition applied unless relTrnPath is empty.
+func baseFrame(monster mongfx.Monster, relTrnPath string) (img image.Image, err error) {
+	imgName := string(monster) + baseSuffix
+	err = extractArchive(monster.ArchiveName(mongfx.Stand))
+	if err != nil {
+		return nil, err
+	}
+	relPalPath := imgconf.GetRelPalPaths(imgName)[0]
+	conf, err := cel.GetConf(imgName, relPalPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(relTrnPath) > 0 {
+		conf.Pal, err = trn.ConvertPal(conf.Pal, relTrnPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	imgs, err := cl2.DecodeAll(imgName, conf)
+	if err != nil {
+		return nil, err
+	}
+	if len(imgs) < 1 {
+		return nil, fmt.Errorf("no frames in %q.", imgName)
+	}
+	return imgs[0], nil
+}
+
+// extractArchive extracts the images of the archive, unless they have already
+// been extracted.
+func extractArchive(archiveName string) (err error) {
+	_, found := imgconf.GetImageCount(archiveName)
+	if !found {
+		return fmt.Errorf("no image count found for %q.", archiveName)
+	}
+	if imgarchive.IsExtracted(archiveName) {
+		return nil
+	}
+	return imgarchive.Extract(archiveName)
+}
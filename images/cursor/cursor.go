@@ -0,0 +1,103 @@
+// Package cursor implements functions for retrieving information about the
+// mouse cursors of objcurs.cel.
+//
+// The first eleven frames of objcurs.cel contain the mouse cursors of the game
+// and the remaining frames contain the inventory graphics of items, which are
+// used as mouse cursors while an item is held.
+package cursor
+
+import (
+	"encoding/json"
+	"image"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+)
+
+// CelName is the name of the CEL image containing the mouse cursors.
+const CelName = "objcurs.cel"
+
+// FirstItem is the frameNum of the first item cursor.
+//
+// ref: CURSOR_FIRSTITEM
+const FirstItem = 11
+
+// Teleport is the frameNum of the targeting cursor of the teleport spell.
+//
+// ref: CURSOR_TELEPORT
+const Teleport = 8
+
+// names contains the names of the mouse cursors, as specified by frameNum.
+//
+// ref: cursor_id
+var names = []string{
+	"hand",
+	"identify",
+	"repair",
+	"recharge",
+	"disarm",
+	"oil",
+	"telekinesis",
+	"resurrect",
+	"teleport",
+	"heal other",
+	"hourglass",
+}
+
+// Name returns the name of the cursor at the given frameNum.
+func Name(frameNum int) string {
+	if frameNum >= 0 && frameNum < len(names) {
+		return names[frameNum]
+	}
+	return "item"
+}
+
+// Hotspot returns the hotspot of the cursor at the given frameNum, relative to
+// the top left corner of its frame. The game draws the top left corner of each
+// cursor at the mouse position, except for the teleport target which is
+// centered on the mouse position.
+//
+// ref: DrawCursor
+func Hotspot(frameNum int, frame image.Image) (hotspot image.Point) {
+	if frameNum == Teleport {
+		bounds := frame.Bounds()
+		return image.Pt(bounds.Dx()/2, bounds.Dy()/2)
+	}
+	return image.ZP
+}
+
+// Info contains the metadata of a cursor.
+type Info struct {
+	FrameNum int    `json:"frame"`
+	Name     string `json:"name"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	HotspotX int    `json:"hotspot_x"`
+	HotspotY int    `json:"hotspot_y"`
+}
+
+// GetInfo returns the metadata of each cursor frame.
+func GetInfo(frames []image.Image) (infos []Info) {
+	for frameNum, frame := range frames {
+		bounds := frame.Bounds()
+		hotspot := Hotspot(frameNum, frame)
+		info := Info{
+			FrameNum: frameNum,
+			Name:     Name(frameNum),
+			Width:    bounds.Dx(),
+			Height:   bounds.Dy(),
+			HotspotX: hotspot.X,
+			HotspotY: hotspot.Y,
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// WriteInfo stores the metadata of each cursor frame as a JSON file.
+func WriteInfo(jsonPath string, frames []image.Image) (err error) {
+	buf, err := json.MarshalIndent(GetInfo(frames), "", "\t")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(jsonPath, append(buf, '\n'))
+}
@@ -0,0 +1,82 @@
+// Package gallery implements functionality for arranging labeled images into a
+// grid, forming a single gallery image.
+package gallery
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/mewrnd/blizzconv/images/label"
+)
+
+// A Tile is a labeled image of the gallery.
+type Tile struct {
+	// The label displayed below the image.
+	Label string
+	// The image of the tile; a nil image leaves the tile empty.
+	Img image.Image
+}
+
+// Background is the background color of gallery images.
+var Background color.Color = color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xFF}
+
+// padding is the space in pixels between tiles.
+const padding = 4
+
+// Grid returns a gallery image, with the tiles arranged as illustrated below:
+//
+//    +-------+-------+-------+
+//    | [0,0] | [0,1] | [0,2] |
+//    +-------+-------+-------+
+//    | [1,0] | [1,1] |
+//    +-------+-------+
+//
+// Each cell of the grid is large enough to fit the largest tile image and its
+// label.
+func Grid(rows [][]Tile) (img *image.RGBA) {
+	// Locate the cell dimensions and the number of cols.
+	var cellWidth, cellHeight, colCount int
+	for _, row := range rows {
+		if len(row) > colCount {
+			colCount = len(row)
+		}
+		for _, tile := range row {
+			var width, height int
+			if tile.Img != nil {
+				width = tile.Img.Bounds().Dx()
+				height = tile.Img.Bounds().Dy()
+			}
+			if labelWidth := label.Width(tile.Label); labelWidth > width {
+				width = labelWidth
+			}
+			if width > cellWidth {
+				cellWidth = width
+			}
+			if height > cellHeight {
+				cellHeight = height
+			}
+		}
+	}
+	cellWidth += padding
+	cellHeight += label.Height() + padding
+
+	// Draw tiles.
+	width := padding + colCount*cellWidth
+	height := padding + len(rows)*cellHeight
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(Background), image.ZP, draw.Src)
+	for rowNum, row := range rows {
+		for colNum, tile := range row {
+			pt := image.Pt(padding+colNum*cellWidth, padding+rowNum*cellHeight)
+			if tile.Img != nil {
+				bounds := tile.Img.Bounds()
+				rect := image.Rectangle{Min: pt, Max: pt.Add(bounds.Size())}
+				draw.Draw(dst, rect, tile.Img, bounds.Min, draw.Over)
+			}
+			labelPt := image.Pt(pt.X, pt.Y+cellHeight-padding-label.Height())
+			label.Draw(dst, labelPt, tile.Label, color.White)
+		}
+	}
+	return dst
+}
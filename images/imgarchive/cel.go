@@ -18,7 +18,7 @@ import (
 //    // Note: the last image has only an implicit end offset, which is the end of the file.
 //    data           []byte
 //
-func ExtractCel(r *os.File, ws []*os.File) (err error) {
+func ExtractCel(r io.ReadSeeker, ws []*os.File) (err error) {
 	imageCount := len(ws)
 	imageOffsets := make([]uint32, imageCount)
 	err = binary.Read(r, binary.LittleEndian, imageOffsets)
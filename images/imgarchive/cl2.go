@@ -19,7 +19,7 @@ import (
 //    //    end:   headerOffsets[imageNum] + frameOffsets[frameCount]
 //    // Note: Both frameOffsets and frameCount are located in cl2Headers[imageNum].
 //    data           []byte
-func ExtractCl2(r *os.File, ws []*os.File) (err error) {
+func ExtractCl2(r io.ReadSeeker, ws []*os.File) (err error) {
 	imageCount := len(ws)
 	headerOffsets := make([]uint32, imageCount)
 	err = binary.Read(r, binary.LittleEndian, headerOffsets)
@@ -7,6 +7,7 @@ import (
 	"path"
 	"strings"
 
+	"github.com/mewrnd/blizzconv/atomicfile"
 	"github.com/mewrnd/blizzconv/images/imgconf"
 	"github.com/mewrnd/blizzconv/mpq"
 )
@@ -21,7 +22,7 @@ func Extract(archiveName string) (err error) {
 	if err != nil {
 		return err
 	}
-	fr, err := os.Open(archivePath)
+	fr, err := mpq.Open(archiveName)
 	if err != nil {
 		return err
 	}
@@ -31,32 +32,56 @@ func Extract(archiveName string) (err error) {
 		return err
 	}
 	defer closeFiles(fws)
+	ws := make([]*os.File, len(fws))
+	for i, fw := range fws {
+		ws[i] = fw.File
+	}
 	ext := path.Ext(archiveName)
 	switch ext {
 	case ".cel":
-		err = ExtractCel(fr, fws)
+		err = ExtractCel(fr, ws)
 	case ".cl2":
-		err = ExtractCl2(fr, fws)
+		err = ExtractCl2(fr, ws)
 	default:
 		return fmt.Errorf("imgarchive.Extract: unknown extension: %q.", ext)
 	}
 	if err != nil {
 		return fmt.Errorf("imgarchive.Extract: error while extracting %q: %s.", archiveName, err)
 	}
+	// Commit the first image last, as its presence marks the archive as
+	// extracted.
+	for imageNum := len(fws) - 1; imageNum >= 0; imageNum-- {
+		err = fws[imageNum].Commit()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// createOutputImages creates the output images of the archive. Note: remember
-// to close the writers while done using them.
-func createOutputImages(archivePath string, imageCount int) (fws []*os.File, err error) {
-	posExt := strings.LastIndex(archivePath, ".")
-	if posExt == -1 {
+// IsExtracted returns true if the images of the archive have already been
+// extracted.
+func IsExtracted(archiveName string) bool {
+	archivePath, err := mpq.GetPath(archiveName)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(mpq.OSPath(getImagePath(archivePath, 0)))
+	return err == nil
+}
+
+// createOutputImages creates the output images of the archive, which are
+// written atomically once committed. Note: remember to close the writers while
+// done using them.
+func createOutputImages(archivePath string, imageCount int) (fws []*atomicfile.File, err error) {
+	if strings.LastIndex(archivePath, ".") == -1 {
 		return nil, fmt.Errorf("no extensions located for %q.", path.Base(archivePath))
 	}
 	for imageNum := 0; imageNum < imageCount; imageNum++ {
-		imgPath := fmt.Sprintf("%s%d%s", archivePath[:posExt], imageNum, archivePath[posExt:])
-		w, err := os.Create(imgPath)
+		imgPath := getImagePath(archivePath, imageNum)
+		w, err := atomicfile.Create(mpq.OSPath(imgPath))
 		if err != nil {
+			closeFiles(fws)
 			return nil, err
 		}
 		fws = append(fws, w)
@@ -64,8 +89,25 @@ func createOutputImages(archivePath string, imageCount int) (fws []*os.File, err
 	return fws, nil
 }
 
-// closeFiles ranges through the file slice and closes each file.
-func closeFiles(fws []*os.File) {
+// ImageName returns the name of an extracted image of the archive, which is the
+// archive name with imageNum inserted before the extension (e.g. "acida0.cl2").
+func ImageName(archiveName string, imageNum int) string {
+	return getImagePath(archiveName, imageNum)
+}
+
+// getImagePath returns the path of an extracted image of the archive, which is
+// the archive path with imageNum inserted before the extension.
+func getImagePath(archivePath string, imageNum int) string {
+	posExt := strings.LastIndex(archivePath, ".")
+	if posExt == -1 {
+		posExt = len(archivePath)
+	}
+	return fmt.Sprintf("%s%d%s", archivePath[:posExt], imageNum, archivePath[posExt:])
+}
+
+// closeFiles ranges through the file slice and closes each file; files which
+// have not been committed are removed.
+func closeFiles(fws []*atomicfile.File) {
 	for _, fw := range fws {
 		fw.Close()
 	}
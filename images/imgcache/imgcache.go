@@ -0,0 +1,131 @@
+// Package imgcache implements an on-disk cache of decoded frames and rendered
+// images.
+//
+// Each entry is keyed by a hash of the content it was decoded from (e.g. the
+// frames of a CEL image, the frame dimensions and the palette, or the blocks of
+// the pillars of a MIN file and the frames they are rendered from), which makes
+// the entries valid across runs; modified input files produce new keys. The
+// cache is disabled unless a cache directory is specified.
+package imgcache
+
+import (
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"image"
+	"image/draw"
+	"os"
+	"path"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+)
+
+// Dir is the path to the cache directory; the cache is disabled if empty. It
+// defaults to the BLIZZCONV_CACHE environment variable.
+var Dir = os.Getenv("BLIZZCONV_CACHE")
+
+// Enabled returns true if the cache is enabled.
+func Enabled() bool {
+	return len(Dir) > 0
+}
+
+// Key returns the cache key of an entry, based on a hash of the content it was
+// decoded from.
+func Key(data ...[]byte) string {
+	h := sha1.New()
+	for _, d := range data {
+		// Prefix each part with its length, to distinguish between e.g.
+		// ("ab", "c") and ("a", "bc").
+		var n [8]byte
+		for i := range n {
+			n[i] = byte(uint64(len(d)) >> (8 * uint(i)))
+		}
+		h.Write(n[:])
+		h.Write(d)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// frame is the encoded representation of a cached frame.
+type frame struct {
+	Rect image.Rectangle
+	Pix  []byte
+}
+
+// Load returns the frames of the cache entry with the given key. The frames
+// are only valid if ok is true.
+func Load(key string) (imgs []image.Image, ok bool) {
+	if !Enabled() {
+		return nil, false
+	}
+	f, err := os.Open(entryPath(key))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, false
+	}
+	var frames []frame
+	err = gob.NewDecoder(zr).Decode(&frames)
+	if err != nil {
+		return nil, false
+	}
+	for _, fr := range frames {
+		img := &image.RGBA{Pix: fr.Pix, Stride: 4 * fr.Rect.Dx(), Rect: fr.Rect}
+		if len(img.Pix) != img.Stride*fr.Rect.Dy() {
+			return nil, false
+		}
+		imgs = append(imgs, img)
+	}
+	return imgs, true
+}
+
+// Store stores the frames as the cache entry with the given key. It does
+// nothing if the cache is disabled.
+func Store(key string, imgs []image.Image) (err error) {
+	if !Enabled() {
+		return nil
+	}
+	var frames []frame
+	for _, img := range imgs {
+		bounds := img.Bounds()
+		rgba, ok := img.(*image.RGBA)
+		if !ok || rgba.Stride != 4*bounds.Dx() {
+			rgba = image.NewRGBA(bounds)
+			draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+		}
+		frames = append(frames, frame{Rect: bounds, Pix: rgba.Pix})
+	}
+	entry := entryPath(key)
+	err = os.MkdirAll(path.Dir(entry), 0755)
+	if err != nil {
+		return err
+	}
+	// Write atomically, so that concurrent or interrupted runs never observe
+	// partial entries.
+	f, err := atomicfile.Create(entry)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	err = gob.NewEncoder(zw).Encode(frames)
+	if err != nil {
+		return err
+	}
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// entryPath returns the path of the cache entry with the given key; entries
+// are spread over subdirectories named after the first two characters of the
+// key.
+func entryPath(key string) string {
+	return path.Join(Dir, key[:2], key+".gob.gz")
+}
@@ -58,6 +58,35 @@ func AllFunc(f func(string) error) (err error) {
 	return nil
 }
 
+// SetDefault sets the value of a key for the given image, unless the key is
+// already present in the ini file. It is used to add image information which
+// is derived rather than stored in the ini file.
+func SetDefault(imgName, key, val string) {
+	if _, found := dict.GetString(imgName, key); found {
+		return
+	}
+	if dict == nil {
+		dict = make(ini.Dict)
+	}
+	if dict[imgName] == nil {
+		dict[imgName] = make(map[string]string)
+	}
+	dict[imgName][key] = val
+}
+
+// Set sets the value of a key for the given image, replacing the value of the
+// ini file if present. It is used to apply the image information of other
+// versions of the game.
+func Set(imgName, key, val string) {
+	if dict == nil {
+		dict = make(ini.Dict)
+	}
+	if dict[imgName] == nil {
+		dict[imgName] = make(map[string]string)
+	}
+	dict[imgName][key] = val
+}
+
 // GetWidth returns the image width.
 func GetWidth(imgName string) (width int, err error) {
 	width, found := dict.GetInt(imgName, "width")
@@ -0,0 +1,60 @@
+// Package itemgfx implements functions for locating the graphics of items which
+// lie on the ground.
+//
+// When an item is dropped it plays a flip animation, the last frame of which is
+// displayed while the item lies on the ground. Each kind of item graphics is
+// stored as a CEL image in the items directory.
+package itemgfx
+
+// Drop contains information about the flip animation of items which share the
+// same drop graphics.
+type Drop struct {
+	// Name of the drop graphics (e.g. "sword").
+	Name string
+	// CelName is the name of the CEL image containing the flip animation.
+	CelName string
+	// FrameCount is the number of frames of the flip animation.
+	FrameCount int
+}
+
+// Drops contains the drop graphics of items, as specified by the drop graphics
+// index of the game.
+//
+// ref: ItemDropNames, ItemAnimLs
+var Drops = []Drop{
+	{"armor", "armor2.cel", 15},
+	{"axe", "axe.cel", 13},
+	{"potion", "fbttle.cel", 16},
+	{"bow", "bow.cel", 13},
+	{"gold", "goldflip.cel", 10},
+	{"helm", "helmut.cel", 13},
+	{"mace", "mace.cel", 13},
+	{"shield", "shield.cel", 13},
+	{"sword", "swrdflip.cel", 13},
+	{"rock", "rock.cel", 10},
+	{"cleaver", "cleaver.cel", 13},
+	{"staff", "staff.cel", 13},
+	{"ring", "ring.cel", 13},
+	{"crown", "crownf.cel", 13},
+	{"light_armor", "larmor.cel", 13},
+	{"war_shield", "wshield.cel", 13},
+	{"scroll", "scroll.cel", 13},
+	{"plate_armor", "fplatear.cel", 13},
+	{"book", "fbook.cel", 13},
+	{"food", "food.cel", 1},
+	{"potion_bb", "fbttlebb.cel", 16},
+	{"potion_dy", "fbttledy.cel", 16},
+	{"potion_or", "fbttleor.cel", 16},
+	{"potion_br", "fbttlebr.cel", 16},
+	{"potion_bl", "fbttlebl.cel", 16},
+	{"potion_by", "fbttleby.cel", 16},
+	{"potion_wh", "fbttlewh.cel", 16},
+	{"potion_db", "fbttledb.cel", 16},
+	{"ear", "fear.cel", 13},
+	{"brain", "fbrain.cel", 12},
+	{"mushroom", "fmush.cel", 12},
+	{"tavern_sign", "innsign.cel", 13},
+	{"blood_stone", "bldstn.cel", 13},
+	{"anvil", "fanvil.cel", 13},
+	{"staff_of_lazarus", "flazstaf.cel", 8},
+}
@@ -0,0 +1,62 @@
+// Package label implements functionality for drawing text labels onto images.
+//
+// The labels are drawn using a fixed size 7x13 font, surrounded by a dark
+// outline to keep them readable on top of any background.
+package label
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// face is the font face used for drawing labels.
+var face = basicfont.Face7x13
+
+// Outline is the color used for the outline of labels.
+var Outline color.Color = color.Black
+
+// Height returns the height of a line of text in pixels.
+func Height() int {
+	return face.Metrics().Height.Ceil()
+}
+
+// Width returns the width of the text in pixels.
+func Width(text string) int {
+	return font.MeasureString(face, text).Ceil()
+}
+
+// Draw draws the text onto dst using the color c. The top left corner of the
+// text is located at pt.
+func Draw(dst draw.Image, pt image.Point, text string, c color.Color) {
+	// The dot is located at the baseline of the text.
+	dot := fixed.P(pt.X, pt.Y+face.Metrics().Ascent.Ceil())
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(Outline),
+		Face: face,
+	}
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			d.Dot = dot.Add(fixed.P(dx, dy))
+			d.DrawString(text)
+		}
+	}
+	d.Src = image.NewUniform(c)
+	d.Dot = dot
+	d.DrawString(text)
+}
+
+// DrawCentered draws the text onto dst using the color c. The text is centered
+// at pt.
+func DrawCentered(dst draw.Image, pt image.Point, text string, c color.Color) {
+	topLeft := image.Pt(pt.X-Width(text)/2, pt.Y-Height()/2)
+	Draw(dst, topLeft, text, c)
+}
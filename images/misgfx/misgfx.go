@@ -0,0 +1,48 @@
+// Package misgfx implements functions for locating the graphics of missiles
+// (e.g. arrows and spells).
+//
+// The graphics of a missile are stored either as one CL2 image per direction,
+// named after the missile with the direction number (starting at 1) appended
+// (e.g. "fireba1.cl2"), or as a single CL2 image in which case each frame may
+// correspond to one direction (e.g. "arrows.cl2").
+package misgfx
+
+import "fmt"
+
+// Missile contains information about the graphics of a missile.
+type Missile struct {
+	// Name of the missile graphics (e.g. "fireba").
+	Name string
+	// DirCount is the number of directions of the missile.
+	DirCount int
+	// FrameCount is the number of frames of each direction.
+	FrameCount int
+	// AnimDelay is the number of additional game ticks each frame is displayed.
+	AnimDelay int
+	// FramePerDir specifies whether the missile is stored as a single CL2 image
+	// with one frame per direction.
+	FramePerDir bool
+}
+
+// Missiles contains the graphics of a curated set of missiles.
+//
+// ref: misfiledata
+var Missiles = []Missile{
+	{Name: "arrows", DirCount: 16, FrameCount: 1, AnimDelay: 0, FramePerDir: true},
+	{Name: "fireba", DirCount: 16, FrameCount: 14, AnimDelay: 0},
+	{Name: "lghning", DirCount: 1, FrameCount: 8, AnimDelay: 0},
+}
+
+// TicksPerFrame returns the number of game ticks each frame is displayed.
+func (mis Missile) TicksPerFrame() int {
+	return mis.AnimDelay + 1
+}
+
+// ImgName returns the name of the CL2 image containing the given direction of
+// the missile.
+func (mis Missile) ImgName(dir int) string {
+	if mis.DirCount == 1 || mis.FramePerDir {
+		return mis.Name + ".cl2"
+	}
+	return fmt.Sprintf("%s%d.cl2", mis.Name, dir+1)
+}
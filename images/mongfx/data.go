@@ -0,0 +1,507 @@
+package mongfx
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/images/imgconf"
+)
+
+// Info contains the image information of a monster's graphics, which is shared
+// by each direction of its animations.
+type Info struct {
+	// The animations of the monster.
+	Anims []Anim
+	// The width and height of each frame.
+	Width, Height int
+	// Frame widths and heights of specific animations, which override Width and
+	// Height.
+	AnimWidths, AnimHeights map[Anim]int
+	// The relative paths to the color transition files of the monster.
+	RelTrnPaths []string
+}
+
+// Data maps from monster to the image information of its graphics.
+//
+// ref: gaMonster_data
+var Data = map[Monster]Info{
+	"acid": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/acid/acidb.trn",
+			"monsters/acid/acidblk.trn",
+			"monsters/acid/acidr.trn",
+			"monsters/monsters/bfds.trn",
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"bat": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death},
+		Width:  96,
+		Height: 128,
+		RelTrnPaths: []string{
+			"monsters/bat/grey.trn",
+			"monsters/bat/orange.trn",
+			"monsters/bat/red.trn",
+			"monsters/monsters/db.trn",
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"black": {
+		Anims:       []Anim{Stand, Walk, Attack, Hit, Death},
+		Width:       160,
+		Height:      128,
+		AnimHeights: map[Anim]int{Death: 160},
+		RelTrnPaths: []string{
+			"monsters/black/blkkntbe.trn",
+			"monsters/black/blkkntbt.trn",
+			"monsters/black/blkkntrk.trn",
+			"monsters/black/blkkntrt.trn",
+			"monsters/monsters/bhka.trn",
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"demskl": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"diablo": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  160,
+		Height: 160,
+		RelTrnPaths: []string{
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"dmage": {
+		Anims:  []Anim{Stand, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"fall": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/falsword/blue.trn",
+			"monsters/falsword/dark.trn",
+			"monsters/falsword/fallent.trn",
+			"monsters/falsword/orange.trn",
+			"monsters/falsword/salam.trn",
+			"monsters/falsword/yellow.trn",
+			"monsters/monsters/bsts.trn",
+			"monsters/monsters/general.trn",
+			"monsters/monsters/gtq.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"fallg": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"fat": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/fat/blue.trn",
+			"monsters/fat/fat.trn",
+			"monsters/fat/fatb.trn",
+			"monsters/fat/fatf.trn",
+			"monsters/monsters/bftp.trn",
+			"monsters/monsters/bsm.trn",
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"fatc": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"monsters/monsters/genrl.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"firem": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 128,
+		// width should be 128 according to gaMonster_data.
+		AnimWidths:  map[Anim]int{Attack: 96},
+		AnimHeights: map[Anim]int{Hit: 160, Death: 160},
+		RelTrnPaths: []string{
+			"monsters/monsters/general.trn",
+			"monsters/monsters/wftd.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"gargo": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  160,
+		Height: 160,
+		RelTrnPaths: []string{
+			"monsters/gargoyle/gare.trn",
+			"monsters/gargoyle/gargb.trn",
+			"monsters/gargoyle/gargbr.trn",
+			"monsters/gargoyle/gargr.trn",
+			"monsters/gargoyle/gargy.trn",
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"goat": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"monsters/goatmace/beige.trn",
+			"monsters/goatmace/gray.trn",
+			"monsters/goatmace/red.trn",
+			"monsters/monsters/bgbl.trn",
+			"monsters/monsters/bhsm.trn",
+			"monsters/monsters/bsdb.trn",
+			"monsters/monsters/dsfm.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"goatb": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"monsters/goatbow/beige.trn",
+			"monsters/goatbow/gray.trn",
+			"monsters/goatbow/red.trn",
+			"monsters/monsters/blf.trn",
+			"monsters/monsters/bsdb.trn",
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"goatl": {
+		Anims:       []Anim{Stand, Walk, Attack, Hit, Death},
+		Width:       160,
+		Height:      128,
+		AnimHeights: map[Anim]int{Death: 160},
+		RelTrnPaths: []string{
+			"monsters/monsters/bhka.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"golem": {
+		Anims:  []Anim{Walk, Attack},
+		Width:  96,
+		Height: 96,
+		RelTrnPaths: []string{
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"mage": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"monsters/mage/cnselbk.trn",
+			"monsters/mage/cnselg.trn",
+			"monsters/mage/cnselgd.trn",
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"magma": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"monsters/magma/blue.trn",
+			"monsters/magma/wierd.trn",
+			"monsters/magma/yellow.trn",
+			"monsters/monsters/bhka.trn",
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"mega": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  160,
+		Height: 160,
+		RelTrnPaths: []string{
+			"monsters/mega/balr.trn",
+			"monsters/mega/guard.trn",
+			"monsters/mega/vtexl.trn",
+			"monsters/monsters/bhka.trn",
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"phall": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/falspear/blue.trn",
+			"monsters/falspear/dark.trn",
+			"monsters/falspear/fallent.trn",
+			"monsters/falspear/orange.trn",
+			"monsters/falspear/salam.trn",
+			"monsters/falspear/yellow.trn",
+			"monsters/monsters/bng.trn",
+			"monsters/monsters/ptu.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"rhino": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  160,
+		Height: 128,
+		RelTrnPaths: []string{
+			"monsters/monsters/general.trn",
+			"monsters/rhino/blue.trn",
+			"monsters/rhino/orange.trn",
+			"monsters/rhino/red.trn",
+			"monsters/rhino/rhinob.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"scav": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/monsters/bhka.trn",
+			"monsters/monsters/general.trn",
+			"monsters/monsters/shbt.trn",
+			"monsters/scav/scavbe.trn",
+			"monsters/scav/scavbr.trn",
+			"monsters/scav/scavw.trn",
+			"monsters/scav/scavy.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"scbs": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"monsters/monsters/blkjd.trn",
+			"monsters/monsters/general.trn",
+			"monsters/monsters/redv.trn",
+			"monsters/succ/blkjd.trn",
+			"monsters/succ/redv.trn",
+			"monsters/succ/succb.trn",
+			"monsters/succ/succbw.trn",
+			"monsters/succ/succrw.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"sking": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  160,
+		Height: 160,
+		RelTrnPaths: []string{
+			"monsters/monsters/genrl.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"sklax": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/monsters/bhka.trn",
+			"monsters/monsters/br.trn",
+			"monsters/monsters/mtd.trn",
+			"monsters/skelaxe/black.trn",
+			"monsters/skelaxe/blue.trn",
+			"monsters/skelaxe/red.trn",
+			"monsters/skelaxe/skelt.trn",
+			"monsters/skelaxe/white.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"sklbw": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/monsters/bashtb.trn",
+			"monsters/monsters/de.trn",
+			"monsters/monsters/skfr.trn",
+			"monsters/skelbow/black.trn",
+			"monsters/skelbow/blue.trn",
+			"monsters/skelbow/red.trn",
+			"monsters/skelbow/skelt.trn",
+			"monsters/skelbow/white.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"sklsr": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/monsters/bhbs.trn",
+			"monsters/monsters/shdr.trn",
+			"monsters/skelsd/black.trn",
+			"monsters/skelsd/blue.trn",
+			"monsters/skelsd/red.trn",
+			"monsters/skelsd/skelt.trn",
+			"monsters/skelsd/white.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"snake": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  160,
+		Height: 160,
+		RelTrnPaths: []string{
+			"monsters/monsters/bhka.trn",
+			"monsters/monsters/general.trn",
+			"monsters/snake/snakb.trn",
+			"monsters/snake/snakbl.trn",
+			"monsters/snake/snakg.trn",
+			"monsters/snake/snakr.trn",
+			"monsters/snake/snaky.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"sneak": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/monsters/bhka.trn",
+			"monsters/monsters/tspo.trn",
+			"monsters/sneak/sneakv1.trn",
+			"monsters/sneak/sneakv2.trn",
+			"monsters/sneak/sneakv3.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"thin": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  160,
+		Height: 128,
+		RelTrnPaths: []string{
+			"monsters/monsters/bhka.trn",
+			"monsters/monsters/general.trn",
+			"monsters/thin/thinv1.trn",
+			"monsters/thin/thinv2.trn",
+			"monsters/thin/thinv3.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"tsneak": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death},
+		Width:  128,
+		Height: 128,
+		RelTrnPaths: []string{
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"unrav": {
+		Anims:       []Anim{Stand, Attack, Hit, Death, Special},
+		Width:       96,
+		Height:      128,
+		AnimHeights: map[Anim]int{Attack: 96},
+		RelTrnPaths: []string{
+			"monsters/monsters/general.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+	"zombie": {
+		Anims:  []Anim{Stand, Walk, Attack, Hit, Death, Special},
+		Width:  128,
+		Height: 96,
+		RelTrnPaths: []string{
+			"monsters/monsters/eth.trn",
+			"monsters/monsters/general.trn",
+			"monsters/monsters/pmr.trn",
+			"monsters/monsters/rcrn.trn",
+			"monsters/zombie/bluered.trn",
+			"monsters/zombie/grey.trn",
+			"monsters/zombie/yellow.trn",
+			"plrgfx/infra.trn",
+			"plrgfx/stone.trn",
+		},
+	},
+}
+
+// InitConf adds the image information of each monster in Data to the loaded
+// ini file, as an archive entry for each animation and an image entry for each
+// direction. Entries present in the ini file take precedence.
+//
+// Note: imgconf.Init must be called with cl2.ini before calling InitConf.
+func InitConf() {
+	for mon, info := range Data {
+		for _, anim := range info.Anims {
+			imgconf.SetDefault(mon.ArchiveName(anim), "image_count", strconv.Itoa(DirCount))
+			width, ok := info.AnimWidths[anim]
+			if !ok {
+				width = info.Width
+			}
+			height, ok := info.AnimHeights[anim]
+			if !ok {
+				height = info.Height
+			}
+			for dir := 0; dir < DirCount; dir++ {
+				imgName := mon.ImgName(anim, dir)
+				imgconf.SetDefault(imgName, "width", strconv.Itoa(width))
+				imgconf.SetDefault(imgName, "height", strconv.Itoa(height))
+				imgconf.SetDefault(imgName, "header_size", "10")
+				if len(info.RelTrnPaths) > 0 {
+					imgconf.SetDefault(imgName, "trns", strings.Join(info.RelTrnPaths, ","))
+				}
+			}
+		}
+	}
+}
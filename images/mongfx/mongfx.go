@@ -0,0 +1,159 @@
+// Package mongfx implements functions for locating the graphics of monsters.
+//
+// The graphics of a monster are stored as CL2 archives, each containing the
+// eight directions of one animation. The name of each archive is derived from
+// the name of the monster and the animation, as illustrated below:
+//
+//    monsters/zombie/zombiea.cl2
+//
+//    zombie: monster (zombie)
+//    a:      anim    (attack)
+package mongfx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/images/anim"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// Anim specifies an animation of a monster.
+type Anim int
+
+// Monster animations.
+//
+// ref: MonstAnims
+const (
+	Stand Anim = iota
+	Walk
+	Attack
+	Hit
+	Death
+	Special
+)
+
+// animChars maps from animation to the character which identifies its archive.
+var animChars = map[Anim]string{
+	Stand:   "n",
+	Walk:    "w",
+	Attack:  "a",
+	Hit:     "h",
+	Death:   "d",
+	Special: "s",
+}
+
+// animNames maps from animation to its name.
+var animNames = map[Anim]string{
+	Stand:   "stand",
+	Walk:    "walk",
+	Attack:  "attack",
+	Hit:     "hit",
+	Death:   "death",
+	Special: "special",
+}
+
+func (anim Anim) String() string {
+	return animNames[anim]
+}
+
+// animEnds maps from animation to how it continues once its last frame has been
+// displayed.
+var animEnds = map[Anim]anim.End{
+	Stand:   anim.Loop,
+	Walk:    anim.Loop,
+	Attack:  anim.Return,
+	Hit:     anim.Return,
+	Death:   anim.Hold,
+	Special: anim.Return,
+}
+
+// End returns how the animation continues once its last frame has been
+// displayed.
+func (a Anim) End() anim.End {
+	return animEnds[a]
+}
+
+// Anims contains each monster animation, in the order used by the game.
+var Anims = []Anim{Stand, Walk, Attack, Hit, Death, Special}
+
+// DirCount is the number of directions of each animation.
+const DirCount = 8
+
+// Monster represents the graphics of a monster, as identified by the name
+// shared by its archives (e.g. "zombie").
+type Monster string
+
+// ArchiveName returns the name of the CL2 archive which contains the given
+// animation of the monster.
+func (mon Monster) ArchiveName(anim Anim) string {
+	return string(mon) + animChars[anim] + ".cl2"
+}
+
+// ImgName returns the name of the CL2 image which contains the given direction
+// of the monster's animation.
+func (mon Monster) ImgName(anim Anim, dir int) string {
+	return fmt.Sprintf("%s%s%d.cl2", mon, animChars[anim], dir)
+}
+
+// soundChars maps from animation to the character which identifies its sound,
+// for the animations which have sounds.
+//
+// ref: MonstSndChar
+var soundChars = map[Anim]string{
+	Attack:  "a",
+	Hit:     "h",
+	Death:   "d",
+	Special: "s",
+}
+
+// SoundName returns the name of the first variation of the sound played with
+// the given animation of the monster (e.g. "zombiea1.wav"), or an empty string
+// if the animation has no sound.
+//
+// Note: A few monsters use the sounds of other monsters, or abbreviated sound
+// file names, in which case no file of the returned name exists.
+func (mon Monster) SoundName(anim Anim) string {
+	c, ok := soundChars[anim]
+	if !ok {
+		return ""
+	}
+	return string(mon) + c + "1.wav"
+}
+
+// HasAnim returns true if the monster has an archive for the given animation.
+//
+// Note: imgconf.Init must be called with cl2.ini before calling HasAnim.
+func (mon Monster) HasAnim(anim Anim) bool {
+	_, found := imgconf.GetImageCount(mon.ArchiveName(anim))
+	return found
+}
+
+// Monsters returns each monster with a stand animation in the ini file, sorted
+// by name.
+//
+// Note: mpq.Init and imgconf.Init (with cl2.ini) must be called before calling
+// Monsters.
+func Monsters() (mons []Monster, err error) {
+	suffix := animChars[Stand] + ".cl2"
+	f := func(imgName string) error {
+		if !strings.HasSuffix(imgName, suffix) {
+			return nil
+		}
+		if _, found := imgconf.GetImageCount(imgName); !found {
+			return nil
+		}
+		relPath, err := mpq.GetRelPath(imgName)
+		if err != nil || !strings.HasPrefix(relPath, "monsters/") {
+			return nil
+		}
+		mons = append(mons, Monster(strings.TrimSuffix(imgName, suffix)))
+		return nil
+	}
+	err = imgconf.AllFunc(f)
+	if err != nil {
+		return nil, err
+	}
+	return mons, nil
+}
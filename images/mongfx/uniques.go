@@ -0,0 +1,58 @@
+package mongfx
+
+// Unique contains the graphics information of a unique monster.
+type Unique struct {
+	// The name of the unique monster.
+	Name string
+	// The monster whose graphics are shared by the unique monster.
+	Monster Monster
+	// The relative path to the color transition file of the unique monster.
+	RelTrnPath string
+}
+
+// Uniques lists the unique monsters which have color transitions of their own,
+// in the order of the game. Unique monsters sharing the color transitions of
+// monsters/monsters/general.trn or monsters/monsters/genrl.trn are not listed.
+//
+// ref: UniqMonst
+var Uniques = []Unique{
+	{Name: "Gharbad the Weak", Monster: "goat", RelTrnPath: "monsters/monsters/bsdb.trn"},
+	{Name: "Snotspill", Monster: "phall", RelTrnPath: "monsters/monsters/bng.trn"},
+	{Name: "Red Vex", Monster: "scbs", RelTrnPath: "monsters/monsters/redv.trn"},
+	{Name: "BlackJade", Monster: "scbs", RelTrnPath: "monsters/monsters/blkjd.trn"},
+	{Name: "Lachdanan", Monster: "black", RelTrnPath: "monsters/monsters/bhka.trn"},
+	{Name: "Bonehead Keenaxe", Monster: "sklax", RelTrnPath: "monsters/monsters/bhka.trn"},
+	{Name: "Bladeskin the Slasher", Monster: "fall", RelTrnPath: "monsters/monsters/bsts.trn"},
+	{Name: "Pukerat the Unclean", Monster: "phall", RelTrnPath: "monsters/monsters/ptu.trn"},
+	{Name: "Boneripper", Monster: "sklax", RelTrnPath: "monsters/monsters/br.trn"},
+	{Name: "Rotfeast the Hungry", Monster: "zombie", RelTrnPath: "monsters/monsters/eth.trn"},
+	{Name: "Gutshank the Quick", Monster: "fall", RelTrnPath: "monsters/monsters/gtq.trn"},
+	{Name: "Brokenhead Bangshield", Monster: "sklsr", RelTrnPath: "monsters/monsters/bhbs.trn"},
+	{Name: "Bongo", Monster: "phall", RelTrnPath: "monsters/monsters/bng.trn"},
+	{Name: "Rotcarnage", Monster: "zombie", RelTrnPath: "monsters/monsters/rcrn.trn"},
+	{Name: "Shadowbite", Monster: "scav", RelTrnPath: "monsters/monsters/shbt.trn"},
+	{Name: "Deadeye", Monster: "sklbw", RelTrnPath: "monsters/monsters/de.trn"},
+	{Name: "Madeye the Dead", Monster: "sklax", RelTrnPath: "monsters/monsters/mtd.trn"},
+	{Name: "Skullfire", Monster: "sklbw", RelTrnPath: "monsters/monsters/skfr.trn"},
+	{Name: "Warpskull", Monster: "sneak", RelTrnPath: "monsters/monsters/tspo.trn"},
+	{Name: "Goretongue", Monster: "zombie", RelTrnPath: "monsters/monsters/pmr.trn"},
+	{Name: "Pulsecrawler", Monster: "scav", RelTrnPath: "monsters/monsters/bhka.trn"},
+	{Name: "Blackash the Burning", Monster: "sklbw", RelTrnPath: "monsters/monsters/bashtb.trn"},
+	{Name: "Blightstone the Weak", Monster: "goatl", RelTrnPath: "monsters/monsters/bhka.trn"},
+	{Name: "Bilefroth the Pit Master", Monster: "fat", RelTrnPath: "monsters/monsters/bftp.trn"},
+	{Name: "Bloodskin Darkbow", Monster: "goatb", RelTrnPath: "monsters/monsters/bsdb.trn"},
+	{Name: "Foulwing", Monster: "bat", RelTrnPath: "monsters/monsters/db.trn"},
+	{Name: "Shadowdrinker", Monster: "sklsr", RelTrnPath: "monsters/monsters/shdr.trn"},
+	{Name: "Hazeshifter", Monster: "sneak", RelTrnPath: "monsters/monsters/bhka.trn"},
+	{Name: "Deathspit", Monster: "acid", RelTrnPath: "monsters/monsters/bfds.trn"},
+	{Name: "Bloodgutter", Monster: "goat", RelTrnPath: "monsters/monsters/bgbl.trn"},
+	{Name: "Deathshade Fleshmaul", Monster: "goat", RelTrnPath: "monsters/monsters/dsfm.trn"},
+	{Name: "Glasskull the Jagged", Monster: "thin", RelTrnPath: "monsters/monsters/bhka.trn"},
+	{Name: "Blightfire", Monster: "goatb", RelTrnPath: "monsters/monsters/blf.trn"},
+	{Name: "Wrathfire the Doomed", Monster: "firem", RelTrnPath: "monsters/monsters/wftd.trn"},
+	{Name: "Firewound the Grim", Monster: "magma", RelTrnPath: "monsters/monsters/bhka.trn"},
+	{Name: "Baron Sludge", Monster: "fat", RelTrnPath: "monsters/monsters/bsm.trn"},
+	{Name: "Blighthorn Steelmace", Monster: "goat", RelTrnPath: "monsters/monsters/bhsm.trn"},
+	{Name: "Fangskin", Monster: "snake", RelTrnPath: "monsters/monsters/bhka.trn"},
+	{Name: "Blackskull", Monster: "mega", RelTrnPath: "monsters/monsters/bhka.trn"},
+}
@@ -0,0 +1,25 @@
+package mongfx
+
+import "testing"
+
+func TestUniques(t *testing.T) {
+	// The color transition of each unique monster is listed among those of its
+	// monster.
+	for _, unique := range Uniques {
+		info, ok := Data[unique.Monster]
+		if !ok {
+			t.Errorf("%q: unknown monster %q", unique.Name, unique.Monster)
+			continue
+		}
+		found := false
+		for _, relTrnPath := range info.RelTrnPaths {
+			if relTrnPath == unique.RelTrnPath {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%q: color transition %q missing from monster %q", unique.Name, unique.RelTrnPath, unique.Monster)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package plrgfx implements functions for locating the player graphics of a
+// given character.
+//
+// The player graphics are stored as CL2 archives, each containing the eight
+// directions of one animation. The name of each archive is derived from the
+// class, armor, weapon and animation of the character, as illustrated below:
+//
+//    plrgfx/warrior/whs/whsat.cl2
+//
+//    w:  class  (warrior)
+//    h:  armor  (heavy)
+//    s:  weapon (sword)
+//    at: anim   (attack)
+package plrgfx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Class specifies the class of a character.
+type Class int
+
+// Character classes.
+const (
+	Warrior Class = iota
+	Rogue
+	Sorcerer
+)
+
+// classNames maps from class to the name of its graphics directory.
+var classNames = map[Class]string{
+	Warrior:  "warrior",
+	Rogue:    "rogue",
+	Sorcerer: "sorceror",
+}
+
+func (class Class) String() string {
+	return classNames[class]
+}
+
+// Armor specifies the armor tier of a character.
+type Armor byte
+
+// Armor tiers.
+const (
+	Light  Armor = 'l'
+	Medium Armor = 'm'
+	Heavy  Armor = 'h'
+)
+
+// Weapon specifies the weapon (and shield) of a character.
+type Weapon byte
+
+// Weapons.
+const (
+	NoWeapon    Weapon = 'n'
+	Shield      Weapon = 'u'
+	Sword       Weapon = 's'
+	SwordShield Weapon = 'd'
+	Bow         Weapon = 'b'
+	Axe         Weapon = 'a'
+	Mace        Weapon = 'm'
+	MaceShield  Weapon = 'h'
+	Staff       Weapon = 't'
+)
+
+// HasShield returns true if the weapon includes a shield.
+func (weapon Weapon) HasShield() bool {
+	switch weapon {
+	case Shield, SwordShield, MaceShield:
+		return true
+	}
+	return false
+}
+
+// Anim specifies an animation of a character.
+type Anim string
+
+// Animations.
+const (
+	TownStand      Anim = "as"
+	TownWalk       Anim = "aw"
+	Attack         Anim = "at"
+	Block          Anim = "bl"
+	Death          Anim = "dt"
+	FireMagic      Anim = "fm"
+	Hit            Anim = "ht"
+	LightningMagic Anim = "lm"
+	Magic          Anim = "qm"
+	Stand          Anim = "st"
+	Walk           Anim = "wl"
+)
+
+// Anims contains all animations.
+var Anims = []Anim{TownStand, TownWalk, Attack, Block, Death, FireMagic, Hit, LightningMagic, Magic, Stand, Walk}
+
+// DirCount is the number of directions of each animation.
+const DirCount = 8
+
+// A Character specifies the class, armor and weapon of a player character,
+// which are used to select the appropriate set of player graphics.
+type Character struct {
+	Class  Class
+	Armor  Armor
+	Weapon Weapon
+}
+
+// HasAnim returns true if there are player graphics for the given animation of
+// the character. Only characters with shields may block.
+func (char Character) HasAnim(anim Anim) bool {
+	if anim == Block {
+		return char.Weapon.HasShield()
+	}
+	return true
+}
+
+// ArchiveName returns the name of the CL2 archive containing the eight
+// directions of the given animation (e.g. "whsat.cl2").
+func (char Character) ArchiveName(anim Anim) string {
+	weapon := char.Weapon
+	if anim == Death {
+		// The death animation is only stored once for each class and armor, as
+		// the weapon is dropped.
+		weapon = NoWeapon
+	}
+	return fmt.Sprintf("%c%c%c%s.cl2", classNames[char.Class][0], char.Armor, weapon, anim)
+}
+
+// ImgName returns the name of the image containing the given direction of the
+// animation, once the CL2 archive has been extracted (e.g. "whsat0.cl2").
+func (char Character) ImgName(anim Anim, dir int) string {
+	archiveName := char.ArchiveName(anim)
+	return fmt.Sprintf("%s%d.cl2", strings.TrimSuffix(archiveName, ".cl2"), dir)
+}
+
+// ParseClass returns the class of the given name (e.g. "warrior").
+func ParseClass(s string) (class Class, err error) {
+	for class, name := range classNames {
+		if name == s {
+			return class, nil
+		}
+	}
+	if s == "sorcerer" {
+		return Sorcerer, nil
+	}
+	return 0, fmt.Errorf("plrgfx.ParseClass: invalid class %q.", s)
+}
+
+// ParseArmor returns the armor tier of the given name (e.g. "heavy").
+func ParseArmor(s string) (armor Armor, err error) {
+	switch s {
+	case "light":
+		return Light, nil
+	case "medium":
+		return Medium, nil
+	case "heavy":
+		return Heavy, nil
+	}
+	return 0, fmt.Errorf("plrgfx.ParseArmor: invalid armor %q.", s)
+}
+
+// weaponNames maps from weapon name to weapon.
+var weaponNames = map[string]Weapon{
+	"none":        NoWeapon,
+	"shield":      Shield,
+	"sword":       Sword,
+	"swordshield": SwordShield,
+	"bow":         Bow,
+	"axe":         Axe,
+	"mace":        Mace,
+	"maceshield":  MaceShield,
+	"staff":       Staff,
+}
+
+// ParseWeapon returns the weapon of the given name (e.g. "swordshield").
+func ParseWeapon(s string) (weapon Weapon, err error) {
+	weapon, ok := weaponNames[s]
+	if !ok {
+		return 0, fmt.Errorf("plrgfx.ParseWeapon: invalid weapon %q.", s)
+	}
+	return weapon, nil
+}
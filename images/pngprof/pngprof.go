@@ -0,0 +1,244 @@
+// Package pngprof implements functions for storing PNG images with a color
+// profile.
+//
+// The PNG images may be tagged as sRGB, using an sRGB chunk, and a gamma curve
+// may be applied to approximate how the colors of the game were presented on
+// CRT monitors. Below is a description of the sRGB tagging:
+//
+// sRGB tagging:
+//    1) Encode the image as a PNG image.
+//    2) Insert an sRGB chunk (rendering intent: perceptual) and the gAMA chunk
+//       recommended for sRGB images directly after the IHDR chunk.
+//
+// The provenance of each PNG image (e.g. its source asset and palette) is
+// embedded as tEXt chunks, along with the version of blizzconv which stored it,
+// so that generated asset trees remain traceable.
+//
+// The PNG images may furthermore be stored by content hash, in which case
+// identical images (e.g. the many identical frames of brazier-like assets) are
+// stored once. Below is a description of the manifest, which maps from the file
+// path of each image to its content hash:
+//
+// Manifest format (HashDir/manifest.txt):
+//    // one line per stored image, in the format of sha1sum.
+//    hash "  " filePath "\n"
+package pngprof
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// SRGB specifies if the stored PNG images should be tagged as sRGB or not.
+var SRGB bool
+
+// Gamma is the exponent of the gamma curve applied to the normalized color
+// channels of the stored PNG images; 1 leaves the colors as is, and values
+// above 1 darken the mid-tones (e.g. 1.14 approximates a CRT with a gamma of
+// 2.5 viewed on an sRGB display with a gamma of 2.2).
+var Gamma = 1.0
+
+// HashDir is the directory of content addressed PNG images; if set, the PNG
+// images are stored as HashDir/<sha1>.png rather than at their file paths, and
+// each file path is recorded in the manifest of HashDir.
+var HashDir string
+
+// Version is the version of blizzconv recorded in the stored PNG images. It may
+// be set at build time, e.g.
+//    go build -ldflags "-X github.com/mewrnd/blizzconv/images/pngprof.Version=v1.2"
+var Version = "devel"
+
+// Meta maps from keyword to text of the provenance metadata embedded in stored
+// PNG images.
+type Meta map[string]string
+
+// Keywords of the provenance metadata.
+const (
+	// KeySource is the relative MPQ path of the source asset.
+	KeySource = "Source asset"
+	// KeyPalette is the relative MPQ path of the palette (pal).
+	KeyPalette = "Palette"
+	// KeyTrn is the relative MPQ path of the color transition (trn).
+	KeyTrn = "Color transition"
+	// KeyFrame is the frame number (or pillar and square number) within the
+	// source asset.
+	KeyFrame = "Frame"
+	// keySoftware is the name and version of the converter.
+	keySoftware = "Software"
+)
+
+// With returns a copy of the metadata with the keyword set to the given text.
+func (meta Meta) With(key, text string) Meta {
+	m := make(Meta, len(meta)+1)
+	for k, v := range meta {
+		m[k] = v
+	}
+	m[key] = text
+	return m
+}
+
+// Source returns the provenance metadata of the given source asset (e.g.
+// "l1.cel") and palette, whose relative MPQ paths are recorded. The relPalPath
+// may be empty.
+func Source(name, relPalPath string) Meta {
+	meta := make(Meta)
+	if relPath, err := mpq.GetRelPath(name); err == nil {
+		meta[KeySource] = relPath
+	} else {
+		meta[KeySource] = name
+	}
+	if len(relPalPath) > 0 {
+		meta[KeyPalette] = relPalPath
+	}
+	return meta
+}
+
+// WriteFile stores the image as a PNG image, using the color profile specified
+// by SRGB and Gamma. The image is stored by content hash if HashDir is set.
+func WriteFile(filePath string, img image.Image) (err error) {
+	return WriteFileMeta(filePath, img, nil)
+}
+
+// WriteFileMeta stores the image as a PNG image, like WriteFile, and embeds
+// the provenance metadata as tEXt chunks.
+//
+// Note: When stored by content hash, the hash is computed without the tEXt
+// chunks, so that identical images of different assets are still stored once,
+// using the metadata of the first one; the manifest records each file path.
+func WriteFileMeta(filePath string, img image.Image, meta Meta) (err error) {
+	if Gamma != 1 {
+		img = applyGamma(img, Gamma)
+	}
+	buf := new(bytes.Buffer)
+	err = png.Encode(buf, img)
+	if err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if SRGB {
+		data = tagSRGB(data)
+	}
+	text := addText(data, meta.With(keySoftware, "blizzconv "+Version))
+	if len(HashDir) > 0 {
+		return writeHashed(filePath, data, text)
+	}
+	return atomicfile.WriteFile(filePath, text)
+}
+
+// addText returns the encoded PNG image with a tEXt chunk inserted for each
+// keyword of the metadata, sorted by keyword, before its IEND chunk.
+func addText(data []byte, meta Meta) []byte {
+	var keys []string
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	// The IEND chunk (length, type and CRC) is the last chunk of a PNG image.
+	iend := len(data) - (4 + 4 + 4)
+	buf := new(bytes.Buffer)
+	buf.Write(data[:iend])
+	for _, key := range keys {
+		writeChunk(buf, "tEXt", []byte(key+"\x00"+meta[key]))
+	}
+	buf.Write(data[iend:])
+	return buf.Bytes()
+}
+
+// manifestMutex serializes the writes to the manifest.
+var manifestMutex sync.Mutex
+
+// writeHashed stores the encoded PNG image with tEXt chunks as
+// HashDir/<sha1>.png, unless an identical image has already been stored, and
+// records filePath in the manifest. The hash is computed from the encoded PNG
+// image without tEXt chunks.
+func writeHashed(filePath string, data, text []byte) (err error) {
+	sum := sha1.Sum(data)
+	hash := hex.EncodeToString(sum[:])
+	err = os.MkdirAll(HashDir, 0755)
+	if err != nil {
+		return err
+	}
+	hashPath := path.Join(HashDir, hash+".png")
+	if _, err := os.Stat(hashPath); os.IsNotExist(err) {
+		err = atomicfile.WriteFile(hashPath, text)
+		if err != nil {
+			return err
+		}
+	}
+	manifestMutex.Lock()
+	defer manifestMutex.Unlock()
+	f, err := os.OpenFile(path.Join(HashDir, "manifest.txt"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%s  %s\n", hash, filePath)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// applyGamma returns a copy of the image with the gamma curve applied to its
+// color channels.
+func applyGamma(src image.Image, gamma float64) image.Image {
+	// lut maps from 8-bit channel value to corrected channel value.
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8(math.Floor(255*math.Pow(float64(i)/255, gamma) + 0.5))
+	}
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			c.R, c.G, c.B = lut[c.R], lut[c.G], lut[c.B]
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
+
+// ihdrEnd is the offset directly after the IHDR chunk of a PNG image; the PNG
+// signature (8 bytes) followed by the IHDR chunk (length, type, 13 bytes of
+// data and CRC).
+const ihdrEnd = 8 + 4 + 4 + 13 + 4
+
+// tagSRGB returns the encoded PNG image with an sRGB and a gAMA chunk inserted
+// after its IHDR chunk.
+func tagSRGB(data []byte) []byte {
+	var gama [4]byte
+	// 1/2.2 scaled by 100000, as specified for sRGB images.
+	binary.BigEndian.PutUint32(gama[:], 45455)
+	buf := new(bytes.Buffer)
+	buf.Write(data[:ihdrEnd])
+	writeChunk(buf, "sRGB", []byte{0})
+	writeChunk(buf, "gAMA", gama[:])
+	buf.Write(data[ihdrEnd:])
+	return buf.Bytes()
+}
+
+// writeChunk writes a PNG chunk of the given type and data.
+func writeChunk(buf *bytes.Buffer, typ string, data []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	buf.WriteString(typ)
+	buf.Write(data)
+	binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
@@ -0,0 +1,70 @@
+// Package spells implements functions for locating the icon graphics of spells.
+//
+// The spell icons are stored in two CEL images; spelicon.cel contains the large
+// icons of the control panel and the speedbook, and spelli2.cel contains the
+// small icons of the spellbook. The icons are arranged in the same order in
+// both images.
+package spells
+
+import "strings"
+
+// IconCelNames contains the names of the CEL images which contain spell icons.
+var IconCelNames = []string{"spelicon.cel", "spelli2.cel"}
+
+// Spell contains the name of a spell and the frame of its icon.
+type Spell struct {
+	// Name of the spell (e.g. "Firebolt").
+	Name string
+	// IconNum is the frameNum of the spell icon.
+	IconNum int
+}
+
+// Spells contains the spells of the game, as specified by spell ID. The first
+// entry corresponds to the absence of a spell.
+//
+// ref: spelldata, SpellITbl
+var Spells = []Spell{
+	{"None", 0},
+	{"Firebolt", 0},
+	{"Healing", 1},
+	{"Lightning", 2},
+	{"Flash", 3},
+	{"Identify", 4},
+	{"Fire Wall", 5},
+	{"Town Portal", 6},
+	{"Stone Curse", 7},
+	{"Infravision", 8},
+	{"Phasing", 27},
+	{"Mana Shield", 12},
+	{"Fireball", 11},
+	{"Guardian", 17},
+	{"Chain Lightning", 15},
+	{"Flame Wave", 13},
+	{"Doom Serpents", 17},
+	{"Blood Ritual", 18},
+	{"Nova", 10},
+	{"Invisibility", 19},
+	{"Inferno", 14},
+	{"Golem", 20},
+	{"Blood Boil", 22},
+	{"Teleport", 23},
+	{"Apocalypse", 24},
+	{"Etherealize", 21},
+	{"Item Repair", 25},
+	{"Staff Recharge", 28},
+	{"Trap Disarm", 36},
+	{"Elemental", 37},
+	{"Charged Bolt", 38},
+	{"Holy Bolt", 41},
+	{"Resurrect", 40},
+	{"Telekinesis", 39},
+	{"Heal Other", 9},
+	{"Blood Star", 35},
+	{"Bone Spirit", 29},
+}
+
+// FileName returns a file name friendly version of the spell name (e.g.
+// "fire_wall").
+func (spell Spell) FileName() string {
+	return strings.Replace(strings.ToLower(spell.Name), " ", "_", -1)
+}
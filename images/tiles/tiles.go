@@ -0,0 +1,137 @@
+// Package tiles implements functionality for exporting large images as tile
+// pyramids, which may be browsed in web pages using Leaflet or OpenSeadragon.
+//
+// The tile pyramid is stored using the z/x/y layout of Leaflet, where zoom
+// level 0 is the most zoomed-out level and the highest zoom level contains the
+// image at full size. Below is a description of the layout:
+//
+// Tile pyramid layout:
+//    tiles.json   // width, height, tile size and max zoom level.
+//    0/0/0.png    // the image, halved until it fits within a single tile.
+//    1/0/0.png
+//    1/1/0.png
+//    ...
+//    z/x/y.png    // tile at column x and row y of zoom level z.
+package tiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+	"github.com/mewrnd/blizzconv/images/pngprof"
+)
+
+// Size is the width and height of each tile in pixels.
+const Size = 256
+
+// Info describes a tile pyramid.
+type Info struct {
+	// The width and height of the image at full size.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// The width and height of each tile.
+	TileSize int `json:"tileSize"`
+	// The zoom level which contains the image at full size.
+	MaxZoom int `json:"maxZoom"`
+}
+
+// WritePyramid stores the image as a tile pyramid in the given directory. Each
+// zoom level below the full size level is constructed by halving the width and
+// height of the level above it.
+func WritePyramid(dir string, img image.Image) (info Info, err error) {
+	bounds := img.Bounds()
+	info = Info{Width: bounds.Dx(), Height: bounds.Dy(), TileSize: Size}
+	size := info.Width
+	if info.Height > size {
+		size = info.Height
+	}
+	for ; size > Size; size = (size + 1) / 2 {
+		info.MaxZoom++
+	}
+	for zoom := info.MaxZoom; zoom >= 0; zoom-- {
+		err = writeLevel(path.Join(dir, fmt.Sprint(zoom)), img)
+		if err != nil {
+			return Info{}, err
+		}
+		if zoom > 0 {
+			img = halve(img)
+		}
+	}
+	buf, err := json.MarshalIndent(info, "", "\t")
+	if err != nil {
+		return Info{}, err
+	}
+	err = atomicfile.WriteFile(path.Join(dir, "tiles.json"), append(buf, '\n'))
+	if err != nil {
+		return Info{}, err
+	}
+	return info, nil
+}
+
+// writeLevel stores the tiles of one zoom level in the given directory, as
+// x/y.png. Tiles at the right and bottom edges are padded with transparent
+// pixels, and fully transparent tiles are skipped.
+func writeLevel(dir string, img image.Image) (err error) {
+	bounds := img.Bounds()
+	for x := 0; x*Size < bounds.Dx(); x++ {
+		colDir := path.Join(dir, fmt.Sprint(x))
+		for y := 0; y*Size < bounds.Dy(); y++ {
+			tile := image.NewRGBA(image.Rect(0, 0, Size, Size))
+			sp := bounds.Min.Add(image.Pt(x*Size, y*Size))
+			draw.Draw(tile, tile.Bounds(), img, sp, draw.Src)
+			if isTransparent(tile) {
+				continue
+			}
+			err = os.MkdirAll(colDir, 0755)
+			if err != nil {
+				return err
+			}
+			err = pngprof.WriteFile(path.Join(colDir, fmt.Sprintf("%d.png", y)), tile)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTransparent returns true if each pixel of the tile is fully transparent.
+func isTransparent(tile *image.RGBA) bool {
+	for i := 3; i < len(tile.Pix); i += 4 {
+		if tile.Pix[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// halve returns the image scaled to half of its width and height (rounded up),
+// by averaging each 2x2 box of pixels.
+func halve(src image.Image) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, (bounds.Dx()+1)/2, (bounds.Dy()+1)/2))
+	for y := 0; y < dst.Bounds().Dy(); y++ {
+		for x := 0; x < dst.Bounds().Dx(); x++ {
+			var r, g, b, a uint32
+			for dy := 0; dy < 2; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					// Pixels outside of the image are transparent.
+					sr, sg, sb, sa := src.At(bounds.Min.X+2*x+dx, bounds.Min.Y+2*y+dy).RGBA()
+					r += sr
+					g += sg
+					b += sb
+					a += sa
+				}
+			}
+			c := color.RGBA64{R: uint16(r / 4), G: uint16(g / 4), B: uint16(b / 4), A: uint16(a / 4)}
+			dst.Set(x, y, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,150 @@
+// Package towngfx implements functions for locating the graphics of the towners
+// (the non-player characters of Tristram).
+//
+// Most towners are stored as a single CEL image, containing the frames of their
+// idle animation facing south. The cows are stored as a CEL archive containing
+// one CEL image per direction (e.g. "cow0.cel").
+//
+// The game plays the frames of most shopkeepers in a non-sequential order
+// (e.g. pausing on and repeating some frames), as specified by a row of its
+// frame order table (AnimOrder). The table is not included, but may be parsed
+// from a text file by ParseAnimOrders.
+package towngfx
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Towner contains information about the graphics of a towner.
+type Towner struct {
+	// Name of the towner (e.g. "griswold").
+	Name string
+	// CelName is the name of the CEL image, or CEL archive, of the towner.
+	CelName string
+	// DirCount is the number of directions of the towner; if greater than one
+	// CelName refers to a CEL archive.
+	DirCount int
+	// FrameCount is the number of frames of each direction.
+	FrameCount int
+	// TicksPerFrame is the number of game ticks each frame is displayed.
+	TicksPerFrame int
+	// AnimOrder is the row of the frame order table of the game which specifies
+	// the order of the frames, or -1 if the frames are played in order.
+	AnimOrder int
+	// FrameOrder is the sequence of frame numbers played by the game, as set by
+	// ParseAnimOrders; the frames are played in order if nil.
+	FrameOrder []int
+}
+
+// Towners contains the graphics of each towner.
+//
+// ref: InitTowners
+var Towners = []Towner{
+	{Name: "griswold", CelName: "smithn.cel", DirCount: 1, FrameCount: 16, TicksPerFrame: 3, AnimOrder: 0},
+	{Name: "ogden", CelName: "twnfn.cel", DirCount: 1, FrameCount: 16, TicksPerFrame: 3, AnimOrder: 3},
+	{Name: "wounded_townsman", CelName: "deadguy.cel", DirCount: 1, FrameCount: 8, TicksPerFrame: 6, AnimOrder: -1},
+	{Name: "adria", CelName: "witch.cel", DirCount: 1, FrameCount: 19, TicksPerFrame: 6, AnimOrder: 5},
+	{Name: "gillian", CelName: "wmnn.cel", DirCount: 1, FrameCount: 18, TicksPerFrame: 6, AnimOrder: -1},
+	{Name: "wirt", CelName: "pegkid1.cel", DirCount: 1, FrameCount: 20, TicksPerFrame: 6, AnimOrder: -1},
+	{Name: "pepin", CelName: "healer.cel", DirCount: 1, FrameCount: 20, TicksPerFrame: 6, AnimOrder: 1},
+	{Name: "cain", CelName: "strytell.cel", DirCount: 1, FrameCount: 25, TicksPerFrame: 3, AnimOrder: 2},
+	{Name: "farnham", CelName: "twndrunk.cel", DirCount: 1, FrameCount: 18, TicksPerFrame: 3, AnimOrder: 4},
+	{Name: "cow", CelName: "cow.cel", DirCount: 8, FrameCount: 12, TicksPerFrame: 3, AnimOrder: -1},
+}
+
+// AnimOrderCount is the number of rows of the frame order table of the game.
+const AnimOrderCount = 6
+
+// ParseAnimOrders parses the frame order table of the game (AnimOrder) from r,
+// and sets the FrameOrder of each towner to its row. The table contains one row
+// per line, each a comma-separated list of 1-based frame numbers terminated by
+// -1, as stored by the game; blank lines and lines starting with '#' are
+// skipped. The frame numbers are stored 0-based in FrameOrder.
+//
+// ref: AnimOrder
+func ParseAnimOrders(r io.Reader) (err error) {
+	var rows [][]int
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var row []int
+		for _, field := range strings.Split(line, ",") {
+			field = strings.TrimSpace(field)
+			if len(field) == 0 {
+				continue
+			}
+			frameNum, err := strconv.Atoi(field)
+			if err != nil {
+				return fmt.Errorf("towngfx.ParseAnimOrders: invalid frame number in row %d; %v", len(rows), err)
+			}
+			if frameNum == -1 {
+				break
+			}
+			row = append(row, frameNum-1)
+		}
+		rows = append(rows, row)
+	}
+	err = s.Err()
+	if err != nil {
+		return err
+	}
+	if len(rows) != AnimOrderCount {
+		return fmt.Errorf("towngfx.ParseAnimOrders: invalid row count (%d); expected %d.", len(rows), AnimOrderCount)
+	}
+	// Validate each row before setting any frame order.
+	for _, towner := range Towners {
+		if towner.AnimOrder < 0 {
+			continue
+		}
+		row := rows[towner.AnimOrder]
+		if len(row) == 0 {
+			return fmt.Errorf("towngfx.ParseAnimOrders: empty row %d of towner %s.", towner.AnimOrder, towner.Name)
+		}
+		for _, frameNum := range row {
+			if frameNum < 0 || frameNum >= towner.FrameCount {
+				return fmt.Errorf("towngfx.ParseAnimOrders: invalid frame number (%d) in row %d of towner %s; expected 1 to %d.", frameNum+1, towner.AnimOrder, towner.Name, towner.FrameCount)
+			}
+		}
+	}
+	for i, towner := range Towners {
+		if towner.AnimOrder >= 0 {
+			Towners[i].FrameOrder = rows[towner.AnimOrder]
+		}
+	}
+	return nil
+}
+
+// Frames returns the frames of one direction of the towner in the order played
+// by the game, based on its FrameOrder.
+func (towner Towner) Frames(imgs []image.Image) (frames []image.Image) {
+	if towner.FrameOrder == nil {
+		return imgs
+	}
+	for _, frameNum := range towner.FrameOrder {
+		frames = append(frames, imgs[frameNum])
+	}
+	return frames
+}
+
+// IsArchive returns true if the graphics of the towner are stored as a CEL
+// archive.
+func (towner Towner) IsArchive() bool {
+	return towner.DirCount > 1
+}
+
+// ImgName returns the name of the CEL image containing the given direction of
+// the towner.
+func (towner Towner) ImgName(dir int) string {
+	if !towner.IsArchive() {
+		return towner.CelName
+	}
+	return fmt.Sprintf("%s%d.cel", strings.TrimSuffix(towner.CelName, ".cel"), dir)
+}
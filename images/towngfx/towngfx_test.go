@@ -0,0 +1,63 @@
+package towngfx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAnimOrders(t *testing.T) {
+	orig := make([]Towner, len(Towners))
+	copy(orig, Towners)
+	defer copy(Towners, orig)
+
+	// A synthetic table; each row i starts with frame number i+1.
+	table := `# AnimOrder
+1, 2, 2, 3, -1
+2, 1, -1
+3, 3, 3, -1, 0, 0
+
+4, 1, -1
+5, -1
+6, 5, 4, -1
+`
+	err := ParseAnimOrders(strings.NewReader(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := map[string][]int{
+		"griswold":         {0, 1, 1, 2},
+		"pepin":            {1, 0},
+		"cain":             {2, 2, 2},
+		"ogden":            {3, 0},
+		"farnham":          {4},
+		"adria":            {5, 4, 3},
+		"wounded_townsman": nil,
+		"cow":              nil,
+	}
+	for _, towner := range Towners {
+		want, ok := golden[towner.Name]
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(towner.FrameOrder, want) {
+			t.Errorf("%s: frame order mismatch; expected %v, got %v", towner.Name, want, towner.FrameOrder)
+		}
+	}
+
+	// Invalid tables.
+	invalid := []string{
+		// too few rows.
+		"1, -1\n1, -1\n",
+		// frame number 17 of griswold, who has 16 frames.
+		"17, -1\n1, -1\n1, -1\n1, -1\n1, -1\n1, -1\n",
+		// empty row of griswold.
+		"-1\n1, -1\n1, -1\n1, -1\n1, -1\n1, -1\n",
+		"a, -1\n1, -1\n1, -1\n1, -1\n1, -1\n1, -1\n",
+	}
+	for _, table := range invalid {
+		if err := ParseAnimOrders(strings.NewReader(table)); err == nil {
+			t.Errorf("%q: expected error", table)
+		}
+	}
+}
@@ -12,7 +12,6 @@ package trn
 import (
 	"fmt"
 	"image/color"
-	"io/ioutil"
 
 	"github.com/mewrnd/blizzconv/mpq"
 )
@@ -20,10 +19,15 @@ import (
 // ConvertPal converts the src palette based on the provided TRN file and
 // returns it as a color.Palette.
 //
-// Note: The absolute path of relTrnPath is relative to mpq.ExtractPath.
+// Note: relTrnPath is relative to the extracted MPQ archive.
 func ConvertPal(src color.Palette, relTrnPath string) (dst color.Palette, err error) {
-	trnPath := mpq.AbsPath(relTrnPath)
-	trn, err := ioutil.ReadFile(trnPath)
+	return ConvertPalFrom(mpq.Default(), src, relTrnPath)
+}
+
+// ConvertPalFrom converts the src palette based on the given TRN file of the
+// store s, as described by ConvertPal.
+func ConvertPalFrom(s *mpq.Store, src color.Palette, relTrnPath string) (dst color.Palette, err error) {
+	trn, err := s.ReadFile(relTrnPath)
 	if err != nil {
 		return nil, err
 	}
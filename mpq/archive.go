@@ -0,0 +1,405 @@
+package mpq
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// An Archive is a Source which provides the files of an MPQ archive (e.g.
+// DIABDAT.MPQ) directly, without extracting it first. Below is a description
+// of the MPQ format:
+//
+// Header:
+//    magic             [4]byte // "MPQ\x1A"
+//    headerSize        uint32
+//    archiveSize       uint32
+//    formatVersion     uint16
+//    sectorSizeShift   uint16  // sector size: 512 << sectorSizeShift
+//    hashTableOffset   uint32
+//    blockTableOffset  uint32
+//    hashTableEntries  uint32
+//    blockTableEntries uint32
+//
+// The hash table locates the block of a file using hashes of its name, and the
+// block table locates the (compressed) contents of each file. Both tables are
+// encrypted. The contents of each file are split into sectors, which are
+// compressed and encrypted individually.
+//
+// Note: The header is located at an offset which is a multiple of 512 bytes.
+//
+// ref: http://www.zezula.net/en/mpq/mpqformat.html
+type Archive struct {
+	f *os.File
+	// offset is the position of the header within the file.
+	offset     int64
+	sectorSize int
+	hashTable  []hashEntry
+	blockTable []blockEntry
+	// iniPaths returns the relative paths of the ini file, which are listed in
+	// the directory tree along with the files of the listfile; nil if none.
+	iniPaths func() []string
+	// dirTree is the directory tree of the archive, which is built once by
+	// dirs.
+	dirsOnce sync.Once
+	dirTree  map[string][]fs.DirEntry
+}
+
+// A hashEntry is an entry of the hash table.
+type hashEntry struct {
+	NameA      uint32
+	NameB      uint32
+	Locale     uint16
+	Platform   uint16
+	BlockIndex uint32
+}
+
+// Block indices of unused hash table entries.
+const (
+	// blockIndexEmpty marks an entry which has never been used, and ends the
+	// search for a file.
+	blockIndexEmpty = 0xFFFFFFFF
+	// blockIndexDeleted marks an entry of a deleted file.
+	blockIndexDeleted = 0xFFFFFFFE
+)
+
+// A blockEntry is an entry of the block table.
+type blockEntry struct {
+	FilePos        uint32
+	CompressedSize uint32
+	FileSize       uint32
+	Flags          uint32
+}
+
+// Flags of block table entries.
+const (
+	flagImplode    = 0x00000100
+	flagCompress   = 0x00000200
+	flagEncrypted  = 0x00010000
+	flagFixKey     = 0x00020000
+	flagSingleUnit = 0x01000000
+	flagSectorCRC  = 0x04000000
+	flagExists     = 0x80000000
+)
+
+// Compression types of sectors of files with the compress flag.
+const (
+	compressZlib    = 0x02
+	compressImplode = 0x08
+	compressBzip2   = 0x10
+)
+
+// unsupportedCompressions contains the compression types which are not used by
+// the MPQ archives of Diablo, and are therefore not supported.
+var unsupportedCompressions = []struct {
+	mask byte
+	name string
+}{
+	{0x01, "Huffman"},
+	{0x20, "sparse"},
+	{0x40, "ADPCM mono"},
+	{0x80, "ADPCM stereo"},
+}
+
+// compressLZMA is the compression type mask of LZMA, which is not a combination
+// of other compression types despite overlapping their bits.
+const compressLZMA = 0x12
+
+// archiveMagic is the magic of the MPQ header ("MPQ\x1A").
+const archiveMagic = 0x1A51504D
+
+// OpenArchive opens the MPQ archive at archivePath, and reads its hash and
+// block tables.
+func OpenArchive(archivePath string) (a *Archive, err error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	a = &Archive{f: f}
+	err = a.readTables()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("mpq.OpenArchive: unable to read %q: %v", archivePath, err)
+	}
+	return a, nil
+}
+
+// readTables locates the header of the archive and reads its hash and block
+// tables.
+func (a *Archive) readTables() (err error) {
+	fi, err := a.f.Stat()
+	if err != nil {
+		return err
+	}
+	var hdr struct {
+		Magic             uint32
+		HeaderSize        uint32
+		ArchiveSize       uint32
+		FormatVersion     uint16
+		SectorSizeShift   uint16
+		HashTableOffset   uint32
+		BlockTableOffset  uint32
+		HashTableEntries  uint32
+		BlockTableEntries uint32
+	}
+	for a.offset = 0; ; a.offset += 512 {
+		if a.offset+32 > fi.Size() {
+			return errors.New("no MPQ header found.")
+		}
+		err = binary.Read(io.NewSectionReader(a.f, a.offset, 32), binary.LittleEndian, &hdr)
+		if err != nil {
+			return err
+		}
+		if hdr.Magic == archiveMagic {
+			break
+		}
+	}
+	a.sectorSize = 512 << hdr.SectorSizeShift
+	a.hashTable = make([]hashEntry, hdr.HashTableEntries)
+	err = a.readTable(a.hashTable, hdr.HashTableOffset, "(hash table)")
+	if err != nil {
+		return err
+	}
+	a.blockTable = make([]blockEntry, hdr.BlockTableEntries)
+	return a.readTable(a.blockTable, hdr.BlockTableOffset, "(block table)")
+}
+
+// readTable reads and decrypts the table at the given offset, relative to the
+// header, into table.
+func (a *Archive) readTable(table interface{}, offset uint32, keyName string) (err error) {
+	buf := make([]byte, binary.Size(table))
+	_, err = a.f.ReadAt(buf, a.offset+int64(offset))
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %v", keyName, err)
+	}
+	decrypt(buf, hashString(keyName, hashFileKey))
+	return binary.Read(bytes.NewReader(buf), binary.LittleEndian, table)
+}
+
+// Close closes the MPQ archive.
+func (a *Archive) Close() error {
+	return a.f.Close()
+}
+
+// ReadFile returns the decompressed contents of the file at relPath within the
+// MPQ archive.
+func (a *Archive) ReadFile(relPath string) (buf []byte, err error) {
+	if !validPath(relPath) {
+		return nil, &os.PathError{Op: "open", Path: relPath, Err: os.ErrInvalid}
+	}
+	block, ok := a.lookup(relPath)
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: relPath, Err: os.ErrNotExist}
+	}
+	buf, err = a.readBlock(block, relPath)
+	if err != nil {
+		return nil, fmt.Errorf("mpq.Archive.ReadFile: unable to read %q: %v", relPath, err)
+	}
+	return buf, nil
+}
+
+// lookup returns the block table entry of the file at relPath.
+func (a *Archive) lookup(relPath string) (block blockEntry, ok bool) {
+	blockIndex, ok := a.lookupIndex(relPath)
+	if !ok {
+		return blockEntry{}, false
+	}
+	return a.blockTable[blockIndex], true
+}
+
+// lookupIndex returns the index of the block table entry of the file at
+// relPath.
+func (a *Archive) lookupIndex(relPath string) (blockIndex uint32, ok bool) {
+	entry, ok := a.lookupEntry(relPath)
+	if !ok {
+		return 0, false
+	}
+	return entry.BlockIndex, true
+}
+
+// lookupEntry returns the hash table entry of the file at relPath.
+func (a *Archive) lookupEntry(relPath string) (entry hashEntry, ok bool) {
+	n := uint32(len(a.hashTable))
+	if n == 0 {
+		return hashEntry{}, false
+	}
+	start := hashString(relPath, hashTableOffset) % n
+	// The name hashes are computed once needed, as most lookups of missing
+	// files (e.g. by Recover) end at an empty entry.
+	var nameA, nameB uint32
+	hashed := false
+	for i := start; ; {
+		entry = a.hashTable[i]
+		if entry.BlockIndex == blockIndexEmpty {
+			return hashEntry{}, false
+		}
+		if !hashed {
+			nameA = hashString(relPath, hashNameA)
+			nameB = hashString(relPath, hashNameB)
+			hashed = true
+		}
+		if entry.NameA == nameA && entry.NameB == nameB && entry.BlockIndex != blockIndexDeleted && entry.BlockIndex < uint32(len(a.blockTable)) {
+			if a.blockTable[entry.BlockIndex].Flags&flagExists != 0 {
+				return entry, true
+			}
+		}
+		i = (i + 1) % n
+		if i == start {
+			return hashEntry{}, false
+		}
+	}
+}
+
+// ReadFileChecked returns the decompressed contents of the file at relPath
+// within the MPQ archive, after verifying the checksums of its sectors. checked
+// reports whether the file has sector checksums; files without sector checksums
+// (e.g. the files of DIABDAT.MPQ) are read without being verified.
+func (a *Archive) ReadFileChecked(relPath string) (buf []byte, checked bool, err error) {
+	if !validPath(relPath) {
+		return nil, false, &os.PathError{Op: "open", Path: relPath, Err: os.ErrInvalid}
+	}
+	block, ok := a.lookup(relPath)
+	if !ok {
+		return nil, false, &os.PathError{Op: "open", Path: relPath, Err: os.ErrNotExist}
+	}
+	buf, checked, err = a.readSectors(block, relPath, true)
+	if err != nil {
+		return nil, false, fmt.Errorf("mpq.Archive.ReadFileChecked: unable to read %q: %v", relPath, err)
+	}
+	return buf, checked, nil
+}
+
+// readBlock returns the decompressed contents of the given block, which stores
+// the file at relPath.
+func (a *Archive) readBlock(block blockEntry, relPath string) (buf []byte, err error) {
+	buf, _, err = a.readSectors(block, relPath, false)
+	return buf, err
+}
+
+// readSectors returns the decompressed contents of the given block, which
+// stores the file at relPath. The sectors are verified against the sector
+// checksums of the block if verify is set, in which case checked reports
+// whether the block has sector checksums.
+func (a *Archive) readSectors(block blockEntry, relPath string, verify bool) (buf []byte, checked bool, err error) {
+	r, err := a.newSectorReader(block, relPath, verify)
+	if err != nil {
+		return nil, false, err
+	}
+	buf = make([]byte, 0, block.FileSize)
+	for r.sector < r.sectorCount() {
+		err = r.next()
+		if err != nil {
+			return nil, false, err
+		}
+		buf = append(buf, r.buf...)
+	}
+	return buf, r.checksums != nil, nil
+}
+
+// readChecksums returns the sector checksums of a block with the sector CRC
+// flag, decompressed from data; one for each of the sectorCount sectors. The
+// checksums are not encrypted, but compressed like the sectors if they would
+// shrink.
+func readChecksums(data []byte, sectorCount int, flags uint32) (checksums []byte, err error) {
+	checksums, err = decompress(data, 4*sectorCount, flags)
+	if err != nil {
+		return nil, fmt.Errorf("sector checksums: %v", err)
+	}
+	return checksums, nil
+}
+
+// sectorChecksum returns the checksum of the given sector, as stored in the
+// sector checksums; the Adler-32 checksum of the (compressed) sector, starting
+// from 0 rather than 1.
+//
+// ref: http://www.zezula.net/en/mpq/mpqformat.html (sector CRC)
+func sectorChecksum(sector []byte) uint32 {
+	const mod = 65521
+	var s1, s2 uint32
+	for _, b := range sector {
+		s1 = (s1 + uint32(b)) % mod
+		s2 = (s2 + s1) % mod
+	}
+	return s2<<16 | s1
+}
+
+// decompress returns the decompressed contents of the given sector, whose
+// decompressed size is size. Sectors which would not shrink when compressed are
+// stored as is.
+func decompress(sector []byte, size int, flags uint32) (buf []byte, err error) {
+	buf, err = decompressMethods(sector, size, flags)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) != size {
+		return nil, fmt.Errorf("decompressed size mismatch; expected %d, got %d.", size, len(buf))
+	}
+	return buf, nil
+}
+
+// decompressMethods dispatches the sector to the decompression method of the
+// file flags, or to each method of the compression type mask stored in the
+// first byte of sectors of files with the compress flag.
+func decompressMethods(sector []byte, size int, flags uint32) (buf []byte, err error) {
+	if len(sector) >= size {
+		return sector[:size], nil
+	}
+	if flags&flagImplode != 0 {
+		return explode(sector, size)
+	}
+	if flags&flagCompress == 0 {
+		return sector, nil
+	}
+	if len(sector) < 1 {
+		return nil, errors.New("missing compression type.")
+	}
+	mask, buf := sector[0], sector[1:]
+	if mask == compressLZMA {
+		return nil, errors.New("unsupported compression type 0x12 (LZMA).")
+	}
+	if unsupported := mask &^ (compressZlib | compressImplode | compressBzip2); unsupported != 0 {
+		var names []string
+		for _, c := range unsupportedCompressions {
+			if unsupported&c.mask != 0 {
+				names = append(names, c.name)
+			}
+		}
+		if len(names) == 0 {
+			names = append(names, "unknown")
+		}
+		return nil, fmt.Errorf("unsupported compression type 0x%02X (%s).", mask, strings.Join(names, ", "))
+	}
+	// Decompress in the reverse order of compression.
+	if mask&compressBzip2 != 0 {
+		buf, err = ioutil.ReadAll(bzip2.NewReader(bytes.NewReader(buf)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if mask&compressImplode != 0 {
+		buf, err = explode(buf, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if mask&compressZlib != 0 {
+		r, err := zlib.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return nil, err
+		}
+		buf, err = ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
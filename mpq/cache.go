@@ -0,0 +1,49 @@
+package mpq
+
+import (
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+)
+
+// A Cache is a Source which extracts the files of an MPQ archive lazily. Each
+// file is extracted to the directory the first time it is opened, and is
+// opened from the directory from then on. Only the files needed by a command
+// are therefore extracted, instead of the entire archive.
+//
+// Files which are not part of the archive (e.g. the extracted images of image
+// archives) are opened from the directory as well.
+type Cache struct {
+	// Archive is the MPQ archive (e.g. an *Archive or a Chain of archives) to
+	// extract files from.
+	Archive fs.ReadFileFS
+	// Dir is the directory to which the files are extracted.
+	Dir Dir
+}
+
+// Open opens the file at relPath from the directory, after extracting it from
+// the MPQ archive if it has not been extracted yet.
+func (c *Cache) Open(relPath string) (fs.File, error) {
+	f, err := c.Dir.Open(relPath)
+	if err == nil || !os.IsNotExist(err) {
+		return f, err
+	}
+	buf, err := c.Archive.ReadFile(relPath)
+	if err != nil {
+		return nil, err
+	}
+	filePath := path.Join(string(c.Dir), relPath)
+	err = os.MkdirAll(OSPath(path.Dir(filePath)), 0755)
+	if err != nil {
+		return nil, err
+	}
+	// The file is written atomically, as concurrent readers may extract the
+	// same file.
+	err = atomicfile.WriteFile(OSPath(filePath), buf)
+	if err != nil {
+		return nil, err
+	}
+	return c.Dir.Open(relPath)
+}
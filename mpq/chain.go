@@ -0,0 +1,188 @@
+package mpq
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+)
+
+// A Chain is a Source which provides the files of several MPQ archives, e.g.
+// DIABDAT.MPQ followed by the archives of Hellfire (hellfire.mpq, hfmonk.mpq,
+// hfmusic.mpq and hfvoice.mpq). The files of later archives shadow the files of
+// earlier archives with the same relative path, as in the game.
+//
+// The directories of the archives are merged, and list the files of each
+// archive.
+type Chain []*Archive
+
+var (
+	_ fs.FS         = Chain(nil)
+	_ fs.ReadDirFS  = Chain(nil)
+	_ fs.ReadFileFS = Chain(nil)
+	_ fs.StatFS     = Chain(nil)
+)
+
+// OpenChain opens the MPQ archives at the given paths, in order of increasing
+// precedence.
+func OpenChain(archivePaths []string) (chain Chain, err error) {
+	for _, archivePath := range archivePaths {
+		a, err := OpenArchive(archivePath)
+		if err != nil {
+			chain.Close()
+			return nil, err
+		}
+		chain = append(chain, a)
+	}
+	return chain, nil
+}
+
+// SplitArchivePaths splits the given list of MPQ archive paths, which are
+// separated by the path list separator of the OS (e.g. ':' on Unix), as used
+// by ArchivePath.
+func SplitArchivePaths(list string) (archivePaths []string) {
+	return filepath.SplitList(list)
+}
+
+// Close closes each MPQ archive of the chain.
+func (chain Chain) Close() (err error) {
+	for _, a := range chain {
+		if cerr := a.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// setIniPaths sets the function which returns the relative paths of the ini
+// file, listed in the directory tree of each archive of the chain.
+func (chain Chain) setIniPaths(iniPaths func() []string) {
+	for _, a := range chain {
+		a.iniPaths = iniPaths
+	}
+}
+
+// Names returns the named files of each archive of the chain, as described by
+// Archive.Names, sorted by path.
+func (chain Chain) Names() (relPaths []string) {
+	found := make(map[string]bool)
+	for _, a := range chain {
+		for _, relPath := range a.Names() {
+			if !found[relPath] {
+				found[relPath] = true
+				relPaths = append(relPaths, relPath)
+			}
+		}
+	}
+	sort.Strings(relPaths)
+	return relPaths
+}
+
+// archive returns the archive of the chain which provides the file at relPath,
+// i.e. the last archive which contains it.
+func (chain Chain) archive(relPath string) (a *Archive, ok bool) {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if _, ok := chain[i].lookup(relPath); ok {
+			return chain[i], true
+		}
+	}
+	return nil, false
+}
+
+// Open opens the file at relPath, from the last archive of the chain which
+// contains it.
+func (chain Chain) Open(relPath string) (fs.File, error) {
+	if !validPath(relPath) {
+		return nil, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrInvalid}
+	}
+	if a, ok := chain.archive(relPath); ok {
+		return a.Open(relPath)
+	}
+	entries, ok := chain.readDir(relPath)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrNotExist}
+	}
+	return &dirFile{info: dirInfo(relPath), entries: entries}, nil
+}
+
+// ReadFile returns the decompressed contents of the file at relPath, from the
+// last archive of the chain which contains it.
+func (chain Chain) ReadFile(relPath string) (buf []byte, err error) {
+	if !validPath(relPath) {
+		return nil, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrInvalid}
+	}
+	a, ok := chain.archive(relPath)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrNotExist}
+	}
+	return a.ReadFile(relPath)
+}
+
+// ReadFileChecked returns the decompressed contents of the file at relPath,
+// from the last archive of the chain which contains it, after verifying the
+// checksums of its sectors as described by Archive.ReadFileChecked.
+func (chain Chain) ReadFileChecked(relPath string) (buf []byte, checked bool, err error) {
+	if !validPath(relPath) {
+		return nil, false, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrInvalid}
+	}
+	a, ok := chain.archive(relPath)
+	if !ok {
+		return nil, false, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrNotExist}
+	}
+	return a.ReadFileChecked(relPath)
+}
+
+// Stat returns the file info of the file at relPath, from the last archive of
+// the chain which contains it.
+func (chain Chain) Stat(relPath string) (fs.FileInfo, error) {
+	if !validPath(relPath) {
+		return nil, &fs.PathError{Op: "stat", Path: relPath, Err: fs.ErrInvalid}
+	}
+	if a, ok := chain.archive(relPath); ok {
+		return a.Stat(relPath)
+	}
+	if _, ok := chain.readDir(relPath); ok {
+		return dirInfo(relPath), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: relPath, Err: fs.ErrNotExist}
+}
+
+// ReadDir returns the merged entries of the directory at relPath of each
+// archive of the chain, sorted by name.
+func (chain Chain) ReadDir(relPath string) ([]fs.DirEntry, error) {
+	if !validPath(relPath) {
+		return nil, &fs.PathError{Op: "readdir", Path: relPath, Err: fs.ErrInvalid}
+	}
+	entries, ok := chain.readDir(relPath)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: relPath, Err: fs.ErrNotExist}
+	}
+	return entries, nil
+}
+
+// readDir returns the merged entries of the directory at relPath, in which the
+// entries of later archives replace those of earlier archives with the same
+// name.
+func (chain Chain) readDir(relPath string) (entries []fs.DirEntry, ok bool) {
+	merged := make(map[string]fs.DirEntry)
+	for _, a := range chain {
+		dirEntries, found := a.dirs()[relPath]
+		if !found {
+			continue
+		}
+		ok = true
+		for _, entry := range dirEntries {
+			merged[entry.Name()] = entry
+		}
+	}
+	if !ok {
+		return nil, false
+	}
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, true
+}
+
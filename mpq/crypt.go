@@ -0,0 +1,88 @@
+package mpq
+
+// Hash types of hashString.
+const (
+	// hashTableOffset locates the first hash table entry to examine.
+	hashTableOffset = 0
+	// hashNameA and hashNameB verify the file name of hash table entries.
+	hashNameA = 1
+	hashNameB = 2
+	// hashFileKey is used to calculate encryption keys.
+	hashFileKey = 3
+)
+
+// cryptTable contains the values used when hashing file names and decrypting
+// data.
+var cryptTable [0x500]uint32
+
+func init() {
+	seed := uint32(0x00100001)
+	for i := 0; i < 0x100; i++ {
+		for j := i; j < len(cryptTable); j += 0x100 {
+			seed = (seed*125 + 3) % 0x2AAAAB
+			hi := (seed & 0xFFFF) << 16
+			seed = (seed*125 + 3) % 0x2AAAAB
+			lo := seed & 0xFFFF
+			cryptTable[j] = hi | lo
+		}
+	}
+}
+
+// hashString returns the hash of the given file name, using the given hash
+// type. File names are case-insensitive and both '/' and '\' are treated as
+// path separators. As in the game, the name is upper-cased byte by byte and
+// only the ASCII letters are mapped; other bytes (e.g. of names encoded in a
+// legacy code page) are hashed as is.
+//
+// ref: HashString (Storm)
+func hashString(name string, hashType uint32) uint32 {
+	seed1 := uint32(0x7FED7FED)
+	seed2 := uint32(0xEEEEEEEE)
+	for i := 0; i < len(name); i++ {
+		b := name[i]
+		switch {
+		case b == '/':
+			b = '\\'
+		case 'a' <= b && b <= 'z':
+			b -= 'a' - 'A'
+		}
+		c := uint32(b)
+		seed1 = cryptTable[hashType<<8+c] ^ (seed1 + seed2)
+		seed2 = c + seed1 + seed2 + seed2<<5 + 3
+	}
+	return seed1
+}
+
+// decrypt decrypts the given data in place, using the given key. The data is
+// decrypted in units of 4 bytes, and any trailing bytes are left as is.
+func decrypt(data []byte, key uint32) {
+	seed := uint32(0xEEEEEEEE)
+	for i := 0; i+4 <= len(data); i += 4 {
+		seed += cryptTable[0x400+key&0xFF]
+		v := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		v ^= key + seed
+		key = (^key<<21 + 0x11111111) | key>>11
+		seed = v + seed + seed<<5 + 3
+		data[i] = byte(v)
+		data[i+1] = byte(v >> 8)
+		data[i+2] = byte(v >> 16)
+		data[i+3] = byte(v >> 24)
+	}
+}
+
+// encrypt encrypts the given data in place, using the given key. It is the
+// inverse of decrypt.
+func encrypt(data []byte, key uint32) {
+	seed := uint32(0xEEEEEEEE)
+	for i := 0; i+4 <= len(data); i += 4 {
+		seed += cryptTable[0x400+key&0xFF]
+		v := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		enc := v ^ (key + seed)
+		key = (^key<<21 + 0x11111111) | key>>11
+		seed = v + seed + seed<<5 + 3
+		data[i] = byte(enc)
+		data[i+1] = byte(enc >> 8)
+		data[i+2] = byte(enc >> 16)
+		data[i+3] = byte(enc >> 24)
+	}
+}
@@ -0,0 +1,49 @@
+package mpq
+
+import "testing"
+
+func TestHashString(t *testing.T) {
+	// The keys of the hash and block tables are known constants of the MPQ
+	// format.
+	golden := []struct {
+		name     string
+		hashType uint32
+		want     uint32
+	}{
+		{name: "(hash table)", hashType: hashFileKey, want: 0xC3AF3770},
+		{name: "(block table)", hashType: hashFileKey, want: 0xEC83B3A3},
+	}
+	for _, g := range golden {
+		if got := hashString(g.name, g.hashType); got != g.want {
+			t.Errorf("%q: hash mismatch; expected 0x%08X, got 0x%08X", g.name, g.want, got)
+		}
+	}
+
+	// Names which are hashed alike.
+	same := [][2]string{
+		{`levels\l1data\l1.min`, "LEVELS/L1DATA/L1.MIN"},
+		// Latin-1 (Windows-1252) bytes are hashed as is.
+		{"items\\\xe9p\xe9e.cel", "ITEMS/\xe9P\xe9E.CEL"},
+	}
+	for _, names := range same {
+		for hashType := uint32(hashTableOffset); hashType <= hashFileKey; hashType++ {
+			a, b := hashString(names[0], hashType), hashString(names[1], hashType)
+			if a != b {
+				t.Errorf("%q and %q: hash mismatch of type %d; 0x%08X != 0x%08X", names[0], names[1], hashType, a, b)
+			}
+		}
+	}
+
+	// Only ASCII letters are case-mapped; the Latin-1 letters 'é' (0xE9) and
+	// 'É' (0xC9) are distinct, and UTF-8 encoded names are not equivalent to
+	// Latin-1 encoded names.
+	differ := [][2]string{
+		{"\xe9.cel", "\xc9.cel"},
+		{"é.cel", "\xe9.cel"},
+	}
+	for _, names := range differ {
+		if hashString(names[0], hashNameA) == hashString(names[1], hashNameA) {
+			t.Errorf("%q and %q: expected hash mismatch", names[0], names[1])
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package mpq
+
+import (
+	"errors"
+	"fmt"
+)
+
+// explode decompresses data which has been compressed using the PKWARE Data
+// Compression Library (implode), and returns at most size bytes. Below is a
+// description of the compressed format:
+//
+// Header:
+//    lit   byte // 0 if literals are stored as is, 1 if they are Huffman coded.
+//    dict  byte // dictionary size in bits; 4, 5 or 6 (1024, 2048 or 4096 bytes).
+//
+// The header is followed by a bit stream, read from the least significant bit
+// of each byte. Each entry of the stream is either a literal (0 bit) or a
+// length/distance pair (1 bit) which repeats previously decompressed data. The
+// Huffman codes are stored bit-inverted, and the length 519 marks the end of
+// the stream.
+//
+// ref: blast.c of zlib/contrib/blast (Mark Adler)
+func explode(data []byte, size int) (buf []byte, err error) {
+	if len(data) < 2 {
+		return nil, errors.New("mpq.explode: missing header.")
+	}
+	lit, dict := data[0], uint(data[1])
+	if lit > 1 {
+		return nil, fmt.Errorf("mpq.explode: invalid literal mode %d.", lit)
+	}
+	if dict < 4 || dict > 6 {
+		return nil, fmt.Errorf("mpq.explode: invalid dictionary size %d.", dict)
+	}
+	br := &bitReader{data: data[2:]}
+	buf = make([]byte, 0, size)
+	for len(buf) < size {
+		flag, err := br.bits(1)
+		if err != nil {
+			return nil, err
+		}
+		if flag == 0 {
+			// Literal.
+			var b int
+			if lit == 1 {
+				b, err = br.decode(litCode)
+			} else {
+				b, err = br.bits(8)
+			}
+			if err != nil {
+				return nil, err
+			}
+			buf = append(buf, byte(b))
+			continue
+		}
+		// Length/distance pair.
+		sym, err := br.decode(lenCode)
+		if err != nil {
+			return nil, err
+		}
+		n, err := br.bits(lenExtra[sym])
+		if err != nil {
+			return nil, err
+		}
+		length := lenBase[sym] + n
+		if length == 519 {
+			break
+		}
+		shift := dict
+		if length == 2 {
+			shift = 2
+		}
+		sym, err = br.decode(distCode)
+		if err != nil {
+			return nil, err
+		}
+		n, err = br.bits(shift)
+		if err != nil {
+			return nil, err
+		}
+		dist := sym<<shift + n + 1
+		if dist > len(buf) {
+			return nil, fmt.Errorf("mpq.explode: distance %d too far back.", dist)
+		}
+		// The copied data may overlap the data being written.
+		for i := 0; i < length && len(buf) < size; i++ {
+			buf = append(buf, buf[len(buf)-dist])
+		}
+	}
+	return buf, nil
+}
+
+// A bitReader reads the bits of data, starting with the least significant bit
+// of each byte.
+type bitReader struct {
+	data []byte
+	// bitBuf contains bitCount unread bits.
+	bitBuf   uint32
+	bitCount uint
+}
+
+// bits returns the next n bits.
+func (br *bitReader) bits(n uint) (v int, err error) {
+	for br.bitCount < n {
+		if len(br.data) == 0 {
+			return 0, errors.New("mpq.explode: unexpected end of data.")
+		}
+		br.bitBuf |= uint32(br.data[0]) << br.bitCount
+		br.data = br.data[1:]
+		br.bitCount += 8
+	}
+	v = int(br.bitBuf & (1<<n - 1))
+	br.bitBuf >>= n
+	br.bitCount -= n
+	return v, nil
+}
+
+// decode returns the next symbol of the given Huffman code, whose code bits
+// are stored inverted.
+func (br *bitReader) decode(h *huffman) (sym int, err error) {
+	code, first, index := 0, 0, 0
+	for length := 1; length < len(h.counts); length++ {
+		bit, err := br.bits(1)
+		if err != nil {
+			return 0, err
+		}
+		code |= bit ^ 1
+		count := h.counts[length]
+		if code < first+count {
+			return h.syms[index+code-first], nil
+		}
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+	return 0, errors.New("mpq.explode: invalid Huffman code.")
+}
+
+// A huffman is a canonical Huffman code.
+type huffman struct {
+	// counts contains the number of codes of each length.
+	counts [14]int
+	// syms contains the symbols, ordered by code.
+	syms []int
+}
+
+// newHuffman returns the canonical Huffman code of the given compact code
+// lengths. The high nibble of each byte contains the number of consecutive
+// symbols minus one, and the low nibble contains their code length.
+func newHuffman(compact []byte) *huffman {
+	var lengths []int
+	for _, b := range compact {
+		for i := 0; i <= int(b>>4); i++ {
+			lengths = append(lengths, int(b&0x0F))
+		}
+	}
+	h := &huffman{syms: make([]int, len(lengths))}
+	for _, length := range lengths {
+		h.counts[length]++
+	}
+	var offsets [len(h.counts)]int
+	for length := 1; length < len(h.counts)-1; length++ {
+		offsets[length+1] = offsets[length] + h.counts[length]
+	}
+	for sym, length := range lengths {
+		h.syms[offsets[length]] = sym
+		offsets[length]++
+	}
+	return h
+}
+
+// Huffman codes of the literals, lengths and distances.
+var (
+	litCode = newHuffman([]byte{
+		11, 124, 8, 7, 28, 7, 188, 13, 76, 4, 10, 8, 12, 10, 12, 10, 8, 23, 8,
+		9, 7, 6, 7, 8, 7, 6, 55, 8, 23, 24, 12, 11, 7, 9, 11, 12, 6, 7, 22, 5,
+		7, 24, 6, 11, 9, 6, 7, 22, 7, 11, 38, 7, 9, 8, 25, 11, 8, 11, 9, 12,
+		8, 12, 5, 38, 5, 38, 5, 11, 7, 5, 6, 21, 6, 10, 53, 8, 7, 24, 10, 27,
+		44, 253, 253, 253, 252, 252, 252, 13, 12, 45, 12, 45, 12, 61, 12, 45,
+		44, 173,
+	})
+	lenCode  = newHuffman([]byte{2, 35, 36, 53, 38, 23})
+	distCode = newHuffman([]byte{2, 20, 53, 230, 247, 151, 248})
+)
+
+// lenBase and lenExtra contain the base length and the number of extra bits of
+// each length symbol.
+var (
+	lenBase  = []int{3, 2, 4, 5, 6, 7, 8, 9, 10, 12, 16, 24, 40, 72, 136, 264}
+	lenExtra = []uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8}
+)
@@ -0,0 +1,225 @@
+package mpq
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The Archive implements the fs.FS, fs.ReadDirFS, fs.ReadFileFS and fs.StatFS
+// interfaces, which allows it to be used interchangeably with other file
+// systems (e.g. os.DirFS or fstest.MapFS).
+//
+// MPQ archives only store hashes of file names, so the directory tree of the
+// archive is based on the file names of its listfile ("(listfile)") if present,
+// the relative paths of the ini file of the Store which opened the archive, and
+// the known files of KnownFiles. Files which are part of none are accessible
+// but not listed.
+var (
+	_ fs.FS         = (*Archive)(nil)
+	_ fs.ReadDirFS  = (*Archive)(nil)
+	_ fs.ReadFileFS = (*Archive)(nil)
+	_ fs.StatFS     = (*Archive)(nil)
+)
+
+// Open opens the file at relPath within the MPQ archive. The contents of files
+// are decompressed when opened.
+func (a *Archive) Open(relPath string) (fs.File, error) {
+	if !validPath(relPath) {
+		return nil, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrInvalid}
+	}
+	if block, ok := a.lookup(relPath); ok {
+		buf, err := a.readBlock(block, relPath)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: relPath, Err: err}
+		}
+		return memFile{Reader: bytes.NewReader(buf), info: fileInfo{name: path.Base(relPath), size: int64(len(buf))}}, nil
+	}
+	entries, ok := a.dirs()[relPath]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrNotExist}
+	}
+	return &dirFile{info: dirInfo(relPath), entries: entries}, nil
+}
+
+// Stat returns the file info of the file at relPath within the MPQ archive.
+func (a *Archive) Stat(relPath string) (fs.FileInfo, error) {
+	if !validPath(relPath) {
+		return nil, &fs.PathError{Op: "stat", Path: relPath, Err: fs.ErrInvalid}
+	}
+	if meta, err := a.Meta(relPath); err == nil {
+		return fileInfo{name: path.Base(relPath), size: meta.Size, meta: meta}, nil
+	}
+	if _, ok := a.dirs()[relPath]; ok {
+		return dirInfo(relPath), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: relPath, Err: fs.ErrNotExist}
+}
+
+// ReadDir returns the entries of the directory at relPath within the MPQ
+// archive, sorted by name.
+func (a *Archive) ReadDir(relPath string) ([]fs.DirEntry, error) {
+	if !validPath(relPath) {
+		return nil, &fs.PathError{Op: "readdir", Path: relPath, Err: fs.ErrInvalid}
+	}
+	entries, ok := a.dirs()[relPath]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: relPath, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+// validPath reports whether relPath is a valid path name of the fs.FS
+// interface. Unlike ReadFile, backslashes are not accepted as path separators.
+func validPath(relPath string) bool {
+	return fs.ValidPath(relPath) && !strings.Contains(relPath, `\`)
+}
+
+// dirs returns a map from the relative path of each directory of the MPQ
+// archive (e.g. "levels/l1data") to its entries. The root directory is ".".
+//
+// Note: The directory tree is built the first time it is needed.
+func (a *Archive) dirs() map[string][]fs.DirEntry {
+	a.dirsOnce.Do(func() {
+		a.dirTree = a.buildDirs()
+	})
+	return a.dirTree
+}
+
+// buildDirs returns the directory tree of the files of the MPQ archive which
+// are listed in its listfile, the ini file or the known files.
+func (a *Archive) buildDirs() map[string][]fs.DirEntry {
+	relPaths := a.listedPaths()
+	tree := map[string][]fs.DirEntry{".": nil}
+	// added tracks the entries which have been added to each directory.
+	added := make(map[string]bool)
+	for relPath := range relPaths {
+		if !validPath(relPath) || relPath == "." {
+			continue
+		}
+		block, ok := a.lookup(relPath)
+		if !ok {
+			continue
+		}
+		var entry fs.DirEntry = fs.FileInfoToDirEntry(fileInfo{name: path.Base(relPath), size: int64(block.FileSize)})
+		for p := relPath; p != "."; p = path.Dir(p) {
+			dir := path.Dir(p)
+			if !added[p] {
+				added[p] = true
+				tree[dir] = append(tree[dir], entry)
+			}
+			entry = fs.FileInfoToDirEntry(dirInfo(dir))
+		}
+	}
+	for _, entries := range tree {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+	}
+	return tree
+}
+
+// A fileInfo describes a file or directory of an MPQ archive.
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+	// meta is the metadata of the file, if known.
+	meta *FileMeta
+}
+
+// dirInfo returns the file info of the directory at relPath.
+func dirInfo(relPath string) fileInfo {
+	return fileInfo{name: path.Base(relPath), dir: true}
+}
+
+// Name returns the base name of the file.
+func (fi fileInfo) Name() string { return fi.name }
+
+// Size returns the decompressed size of the file.
+func (fi fileInfo) Size() int64 { return fi.size }
+
+// Mode returns the file mode bits, which are read-only.
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// ModTime returns the zero time, as MPQ archives store no modification times.
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+
+// IsDir reports whether the file info describes a directory.
+func (fi fileInfo) IsDir() bool { return fi.dir }
+
+// Sys returns the metadata of the file (a *FileMeta) as returned by Stat, or
+// nil for directories.
+func (fi fileInfo) Sys() interface{} {
+	if fi.meta == nil {
+		return nil
+	}
+	return fi.meta
+}
+
+// A memFile is an open file whose contents are held in memory.
+type memFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+// Stat returns the file info of the file.
+func (f memFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close closes the file.
+func (memFile) Close() error {
+	return nil
+}
+
+// A dirFile is an open directory of an MPQ archive.
+type dirFile struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	// offset is the number of entries which have been read.
+	offset int
+}
+
+// Stat returns the file info of the directory.
+func (d *dirFile) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read returns an error, as directories can't be read.
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// Close closes the directory.
+func (d *dirFile) Close() error {
+	return nil
+}
+
+// ReadDir returns the next n entries of the directory, or all remaining entries
+// if n <= 0.
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return append([]fs.DirEntry(nil), rest...), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return append([]fs.DirEntry(nil), rest[:n]...), nil
+}
@@ -0,0 +1,85 @@
+package mpq
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Glob returns the relative paths of the files of the default store which
+// match pattern, as described by Store.Glob.
+func Glob(pattern string) (relPaths []string, err error) {
+	return Default().Glob(pattern)
+}
+
+// Glob returns the relative paths of the files of the store which match
+// pattern (e.g. "monsters/**/*.cl2"), sorted by path. The files are those of
+// the ini file, and the named files of the MPQ archives of the store (see
+// Archive.Names). Patterns are matched as described by MatchPath.
+func (s *Store) Glob(pattern string) (relPaths []string, err error) {
+	// Report malformed patterns even if there are no files.
+	_, err = MatchPath(pattern, "")
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool)
+	candidates := s.relPaths()
+	if s.chain != nil {
+		candidates = append(candidates, s.chain.Names()...)
+	}
+	for _, relPath := range candidates {
+		if found[relPath] {
+			continue
+		}
+		if ok, _ := MatchPath(pattern, relPath); ok {
+			found[relPath] = true
+			relPaths = append(relPaths, relPath)
+		}
+	}
+	sort.Strings(relPaths)
+	return relPaths, nil
+}
+
+// MatchPath reports whether the slash-separated relPath matches pattern. Each
+// element of pattern is matched against an element of relPath using
+// path.Match, except for "**" which matches zero or more elements. Matching is
+// case-insensitive, as are the file names of MPQ archives.
+func MatchPath(pattern, relPath string) (matched bool, err error) {
+	patterns := strings.Split(strings.ToLower(pattern), "/")
+	var elems []string
+	if len(relPath) > 0 {
+		elems = strings.Split(strings.ToLower(relPath), "/")
+	}
+	// Validate each element of the pattern, as path.Match only reports
+	// malformed patterns once they are reached.
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return false, err
+		}
+	}
+	return matchElems(patterns, elems), nil
+}
+
+// matchElems reports whether the path elements match the pattern elements.
+func matchElems(patterns, elems []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			// Try to match the remaining pattern against each suffix of the
+			// path elements.
+			for i := 0; i <= len(elems); i++ {
+				if matchElems(patterns[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], elems[0]); !ok {
+			return false
+		}
+		patterns, elems = patterns[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
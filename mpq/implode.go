@@ -0,0 +1,155 @@
+package mpq
+
+// implodeDictBits is the dictionary size in bits used by implode (4096 bytes).
+const implodeDictBits = 6
+
+// Bounds of the lengths of length/distance pairs used by implode.
+const (
+	implodeMinLength = 3
+	implodeMaxLength = 518
+	// implodeEnd is the length which marks the end of the stream.
+	implodeEnd = 519
+)
+
+// implodeMaxChain is the maximum number of earlier positions examined when
+// searching for the longest match.
+const implodeMaxChain = 64
+
+// implode compresses data using the PKWARE Data Compression Library format, as
+// decompressed by explode. Literals are stored as is, and repeated data is
+// stored as length/distance pairs using the fixed Huffman codes of the format.
+//
+// ref: explode (description of the compressed format)
+func implode(data []byte) (buf []byte) {
+	bw := &bitWriter{buf: []byte{0, implodeDictBits}}
+	// head maps from the hash of the three bytes at a position to the last
+	// position with the same hash, and prev links each position to the
+	// previous position with the same hash; -1 if none.
+	head := make([]int, 1<<implodeHashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int, len(data))
+	insert := func(pos int) {
+		if pos+implodeMinLength > len(data) {
+			return
+		}
+		h := implodeHash(data[pos:])
+		prev[pos] = head[h]
+		head[h] = pos
+	}
+	maxDist := 64 << implodeDictBits
+	for pos := 0; pos < len(data); {
+		length, dist := 0, 0
+		if pos+implodeMinLength <= len(data) {
+			p := head[implodeHash(data[pos:])]
+			for chain := 0; p >= 0 && pos-p <= maxDist && chain < implodeMaxChain; chain++ {
+				n := 0
+				for n < implodeMaxLength && pos+n < len(data) && data[p+n] == data[pos+n] {
+					n++
+				}
+				if n > length {
+					length, dist = n, pos-p
+				}
+				p = prev[p]
+			}
+		}
+		if length < implodeMinLength {
+			// Literal.
+			bw.bits(0, 1)
+			bw.bits(int(data[pos]), 8)
+			insert(pos)
+			pos++
+			continue
+		}
+		// Length/distance pair.
+		bw.bits(1, 1)
+		bw.length(length)
+		d := dist - 1
+		bw.encode(distCode, d>>implodeDictBits)
+		bw.bits(d&(1<<implodeDictBits-1), implodeDictBits)
+		for i := 0; i < length; i++ {
+			insert(pos + i)
+		}
+		pos += length
+	}
+	bw.bits(1, 1)
+	bw.length(implodeEnd)
+	return bw.flush()
+}
+
+// implodeHashBits is the size in bits of the hashes of implodeHash.
+const implodeHashBits = 15
+
+// implodeHash returns the hash of the first three bytes of data.
+func implodeHash(data []byte) int {
+	return (int(data[0])<<10 ^ int(data[1])<<5 ^ int(data[2])) & (1<<implodeHashBits - 1)
+}
+
+// A bitWriter writes bits, starting with the least significant bit of each
+// byte.
+type bitWriter struct {
+	buf []byte
+	// bitBuf contains bitCount unwritten bits.
+	bitBuf   uint32
+	bitCount uint
+}
+
+// bits writes the n least significant bits of v.
+func (bw *bitWriter) bits(v int, n uint) {
+	bw.bitBuf |= uint32(v) & (1<<n - 1) << bw.bitCount
+	bw.bitCount += n
+	for bw.bitCount >= 8 {
+		bw.buf = append(bw.buf, byte(bw.bitBuf))
+		bw.bitBuf >>= 8
+		bw.bitCount -= 8
+	}
+}
+
+// length writes the given length of a length/distance pair, using its length
+// symbol and extra bits.
+func (bw *bitWriter) length(length int) {
+	for sym, base := range lenBase {
+		if length >= base && length < base+1<<lenExtra[sym] {
+			bw.encode(lenCode, sym)
+			bw.bits(length-base, lenExtra[sym])
+			return
+		}
+	}
+	panic("mpq.implode: invalid length")
+}
+
+// encode writes the code of sym using the given Huffman code, whose code bits
+// are stored inverted.
+func (bw *bitWriter) encode(h *huffman, sym int) {
+	code, length := h.code(sym)
+	for i := length - 1; i >= 0; i-- {
+		bw.bits(code>>uint(i)&1^1, 1)
+	}
+}
+
+// flush writes any remaining bits, padded with zeros, and returns the written
+// bytes.
+func (bw *bitWriter) flush() []byte {
+	if bw.bitCount > 0 {
+		bw.buf = append(bw.buf, byte(bw.bitBuf))
+		bw.bitBuf, bw.bitCount = 0, 0
+	}
+	return bw.buf
+}
+
+// code returns the code and code length of sym, as decoded by decode.
+func (h *huffman) code(sym int) (code, length int) {
+	first, index := 0, 0
+	for length := 1; length < len(h.counts); length++ {
+		count := h.counts[length]
+		for i := 0; i < count; i++ {
+			if h.syms[index+i] == sym {
+				return first + i, length
+			}
+		}
+		index += count
+		first = (first + count) << 1
+	}
+	panic("mpq.implode: invalid symbol")
+}
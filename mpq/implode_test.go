@@ -0,0 +1,37 @@
+package mpq
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestImplode(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+	golden := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "short", data: []byte("l1")},
+		{name: "text", data: bytes.Repeat([]byte("levels\\l1data\\l1.min\r\n"), 100)},
+		{name: "zeros", data: make([]byte, 4096)},
+		// runs longer than the maximum length of a length/distance pair.
+		{name: "long run", data: bytes.Repeat([]byte{0xAB}, 2*implodeMaxLength+7)},
+		// matches at the far end of the dictionary.
+		{name: "distant", data: append(append([]byte(nil), random[:4000]...), random[:100]...)},
+		{name: "random", data: random},
+	}
+	for _, g := range golden {
+		compressed := implode(g.data)
+		buf, err := explode(compressed, len(g.data))
+		if err != nil {
+			t.Errorf("%s: unable to explode imploded data; %v", g.name, err)
+			continue
+		}
+		if !bytes.Equal(buf, g.data) {
+			t.Errorf("%s: round trip mismatch; expected %d bytes, got %d bytes", g.name, len(g.data), len(buf))
+		}
+	}
+}
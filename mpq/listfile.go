@@ -0,0 +1,110 @@
+package mpq
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"path"
+	"sort"
+	"strings"
+)
+
+// listfile is the embedded listfile of the files of the MPQ archives of Diablo
+// and Hellfire, with one relative path per line. It contains the relative
+// paths of mpq.ini, excluding the images extracted from image archives (e.g.
+// "plrgfx/rogue/rha/rhaas0.cl2"), which are not part of the archives.
+//
+//go:embed listfile.txt
+var listfile string
+
+// KnownFiles returns the relative paths of the known files of the MPQ archives
+// of Diablo and Hellfire (e.g. "levels/l1data/l1.min"), sorted by path. Each
+// archive contains a subset of the known files.
+func KnownFiles() (relPaths []string) {
+	return strings.Fields(listfile)
+}
+
+// Names returns the relative paths of the files of the MPQ archive which are
+// listed in its listfile ("(listfile)"), the ini file or the known files,
+// sorted by path.
+func (a *Archive) Names() (relPaths []string) {
+	for relPath := range a.listedPaths() {
+		if _, ok := a.lookup(relPath); ok {
+			relPaths = append(relPaths, relPath)
+		}
+	}
+	sort.Strings(relPaths)
+	return relPaths
+}
+
+// listedPaths returns the set of relative paths listed in the listfile of the
+// MPQ archive, the ini file and the known files, which may or may not be part
+// of the archive.
+func (a *Archive) listedPaths() map[string]bool {
+	relPaths := make(map[string]bool)
+	if buf, err := a.ReadFile("(listfile)"); err == nil {
+		s := bufio.NewScanner(bytes.NewReader(buf))
+		for s.Scan() {
+			relPath := strings.ToLower(strings.TrimSpace(s.Text()))
+			relPaths[strings.Replace(relPath, `\`, "/", -1)] = true
+		}
+	}
+	if a.iniPaths != nil {
+		for _, relPath := range a.iniPaths() {
+			relPaths[relPath] = true
+		}
+	}
+	for _, relPath := range KnownFiles() {
+		relPaths[relPath] = true
+	}
+	return relPaths
+}
+
+// specialNames contains the names of the special files of MPQ archives, which
+// are named even though they are not listed.
+var specialNames = []string{"(listfile)", "(attributes)", "(signature)"}
+
+// Unnamed returns the number of files of the MPQ archive whose names are not
+// among the given relative paths, e.g. the files which remain unnamed after
+// looking up the names of Names and Recover. The special files (e.g.
+// "(listfile)") are named.
+func (a *Archive) Unnamed(relPaths []string) (n int) {
+	named := make(map[uint32]bool)
+	for _, names := range [][]string{relPaths, specialNames} {
+		for _, relPath := range names {
+			if blockIndex, ok := a.lookupIndex(relPath); ok {
+				named[blockIndex] = true
+			}
+		}
+	}
+	for blockIndex, block := range a.blockTable {
+		if block.Flags&flagExists != 0 && !named[uint32(blockIndex)] {
+			n++
+		}
+	}
+	return n
+}
+
+// Recover recovers the names of unnamed files of the MPQ archive by brute
+// force; each candidate name dir/base+ext, for every combination of the given
+// directories (e.g. "levels/l1data"), base names (e.g. "banner1") and
+// extensions (e.g. ".dun"), is looked up using the hashes of the hash table.
+// The candidates present in the archive are returned, sorted by path.
+//
+// Note: Two different names may share the same hashes, but the chance of a
+// false positive is negligible as each name is verified using two 32-bit hashes
+// in addition to its position in the hash table.
+func (a *Archive) Recover(dirs, bases, exts []string) (relPaths []string) {
+	for _, dir := range dirs {
+		for _, base := range bases {
+			for _, ext := range exts {
+				relPath := path.Join(dir, base+ext)
+				if _, ok := a.lookup(relPath); ok {
+					relPaths = append(relPaths, relPath)
+				}
+			}
+		}
+	}
+	sort.Strings(relPaths)
+	return relPaths
+}
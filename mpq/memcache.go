@@ -0,0 +1,132 @@
+package mpq
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"sync"
+)
+
+// A MemCache is a Source which keeps the contents of the files of another
+// source in memory, so that the files read over and over again by parallel
+// conversions (e.g. the MIN, TIL and PAL files of a level) are read and
+// decompressed only once. The files are keyed by relative path, and the least
+// recently used files are evicted once the contents of the cached files exceed
+// MaxSize bytes.
+//
+// The methods of a MemCache may be called concurrently. Concurrent opens of a
+// file which is not cached yet wait for a single read of the file. The files
+// of the source are assumed not to change while they are cached; errors (e.g.
+// missing files) are not cached.
+type MemCache struct {
+	// Src is the source of the cached files.
+	Src Source
+	// MaxSize is the memory limit of the cached files, in bytes; files larger
+	// than MaxSize are read from the source each time they are opened.
+	MaxSize int64
+	// mu protects the fields below.
+	mu sync.Mutex
+	// entries maps from relative path to the cache entry of the file.
+	entries map[string]*memEntry
+	// lru holds the cached entries, the most recently used first.
+	lru *list.List
+	// size is the total size of the contents of the cached entries.
+	size int64
+}
+
+// A memEntry is a file of a MemCache, which is being read or cached.
+type memEntry struct {
+	relPath string
+	// ready is closed once the file has been read, after which buf, info and
+	// err are set.
+	ready chan struct{}
+	buf   []byte
+	info  fs.FileInfo
+	err   error
+	// elem is the element of the entry in the LRU list, or nil if the entry is
+	// still being read.
+	elem *list.Element
+}
+
+// Open opens the file at relPath from memory, after reading it from the source
+// if it is not cached yet.
+func (c *MemCache) Open(relPath string) (fs.File, error) {
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*memEntry)
+		c.lru = list.New()
+	}
+	e, ok := c.entries[relPath]
+	if ok {
+		if e.elem != nil {
+			c.lru.MoveToFront(e.elem)
+		}
+		c.mu.Unlock()
+		<-e.ready
+	} else {
+		e = &memEntry{relPath: relPath, ready: make(chan struct{})}
+		c.entries[relPath] = e
+		c.mu.Unlock()
+		c.load(e)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return memFile{Reader: bytes.NewReader(e.buf), info: e.info}, nil
+}
+
+// load reads the file of the entry from the source, and caches it within the
+// memory limit.
+func (c *MemCache) load(e *memEntry) {
+	e.buf, e.info, e.err = readAll(c.Src, e.relPath)
+	close(e.ready)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e.err != nil || int64(len(e.buf)) > c.MaxSize {
+		delete(c.entries, e.relPath)
+		return
+	}
+	e.elem = c.lru.PushFront(e)
+	c.size += int64(len(e.buf))
+	for c.size > c.MaxSize {
+		old := c.lru.Remove(c.lru.Back()).(*memEntry)
+		delete(c.entries, old.relPath)
+		c.size -= int64(len(old.buf))
+	}
+}
+
+// OpenFile opens the file at relPath for streaming, from memory if it is
+// cached, or else from the source without caching it.
+func (c *MemCache) OpenFile(relPath string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	e, ok := c.entries[relPath]
+	c.mu.Unlock()
+	if ok {
+		<-e.ready
+		if e.err == nil {
+			return memFile{Reader: bytes.NewReader(e.buf), info: e.info}, nil
+		}
+	}
+	return openFile(c.Src, relPath)
+}
+
+// readAll returns the contents and file info of the file at relPath of the
+// source.
+func readAll(src Source, relPath string) (buf []byte, info fs.FileInfo, err error) {
+	f, err := src.Open(relPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	info, err = f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	buf, err = ioutil.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf, info, nil
+}
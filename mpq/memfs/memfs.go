@@ -0,0 +1,72 @@
+// Package memfs implements an extracted MPQ archive held in memory.
+//
+// The files of the archive are populated from byte slices, which allows the
+// parsers and renderers to be used without an extracted MPQ archive on disk,
+// e.g. in tests or when embedding a few assets in a program.
+package memfs
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// An FS is an extracted MPQ archive which maps from the relative path of each
+// file (e.g. "levels/l1data/l1.min") to its contents.
+type FS map[string][]byte
+
+// Open opens the file at relPath.
+func (files FS) Open(relPath string) (fs.File, error) {
+	name := path.Clean(relPath)
+	buf, ok := files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: relPath, Err: os.ErrNotExist}
+	}
+	return file{Reader: bytes.NewReader(buf), name: path.Base(name)}, nil
+}
+
+// A file is an open file of an FS.
+type file struct {
+	*bytes.Reader
+	name string
+}
+
+// Stat returns the file info of the file, which is the file itself; its size is
+// provided by the embedded bytes.Reader.
+func (f file) Stat() (fs.FileInfo, error) {
+	return f, nil
+}
+
+// Close closes the file.
+func (file) Close() error {
+	return nil
+}
+
+// Name returns the base name of the file.
+func (f file) Name() string { return f.name }
+
+// Mode returns the file mode bits of the file.
+func (file) Mode() fs.FileMode { return 0444 }
+
+// ModTime returns the zero time.
+func (file) ModTime() time.Time { return time.Time{} }
+
+// IsDir returns false.
+func (file) IsDir() bool { return false }
+
+// Sys returns nil.
+func (file) Sys() interface{} { return nil }
+
+// Mount makes fs the source of the files of the mpq package, and registers the
+// relative path of each file by its base name (e.g. "l1.min"), as done by the
+// mpq.ini file.
+func Mount(fs FS) {
+	mpq.Src = fs
+	for relPath := range fs {
+		mpq.SetRelPath(path.Base(relPath), relPath)
+	}
+}
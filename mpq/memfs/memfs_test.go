@@ -0,0 +1,91 @@
+package memfs_test
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/mpq"
+	"github.com/mewrnd/blizzconv/mpq/memfs"
+	"github.com/mewrnd/blizzconv/mpq/memfs/synth"
+)
+
+func TestStore(t *testing.T) {
+	files := memfs.FS{
+		"levels/l1data/l1.pal": synth.PAL(),
+		"levels/l1data/l1.cel": synth.CEL(synth.PlainFrame(0x10), synth.PlainFrame(0x20)),
+		// frameNumPlus1 2 and 1 of plain (type 0) blocks, and one empty block.
+		"levels/l1data/l1.min": synth.MIN(
+			[]uint16{2, 1, 0, 1, 1, 1, 1, 1, 1, 1},
+			[]uint16{1, 1, 1, 1, 1, 1, 1, 1, 1, 0x1002},
+		),
+	}
+	s, err := mpq.OpenStore(mpq.Options{Src: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetRelPath("l1.cel", "levels/l1data/l1.cel")
+	s.SetRelPath("l1.min", "levels/l1data/l1.min")
+	imgconf.SetDefault("l1.cel", "width", "32")
+	imgconf.SetDefault("l1.cel", "height", "32")
+
+	// CEL image.
+	conf, err := cel.GetConfFrom(s, "l1.cel", "levels/l1data/l1.pal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imgs, err := cel.DecodeAllFrom(s, "l1.cel", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := []color.Gray{{Y: 0x10}, {Y: 0x20}}
+	if len(imgs) != len(golden) {
+		t.Fatalf("frame count mismatch; expected %d, got %d", len(golden), len(imgs))
+	}
+	for frameNum, want := range golden {
+		img := imgs[frameNum]
+		if got := img.Bounds().Size(); got.X != 32 || got.Y != 32 {
+			t.Errorf("frame %d: size mismatch; expected 32x32, got %dx%d", frameNum, got.X, got.Y)
+			continue
+		}
+		wantR, wantG, wantB, wantA := want.RGBA()
+		for _, p := range [][2]int{{0, 0}, {31, 31}} {
+			r, g, b, a := img.At(p[0], p[1]).RGBA()
+			if r != wantR || g != wantG || b != wantB || a != wantA {
+				t.Errorf("frame %d: color mismatch at %v; expected %v, got %v", frameNum, p, want, img.At(p[0], p[1]))
+			}
+		}
+	}
+
+	// MIN file.
+	pillars, err := min.ParseFrom(s, "l1.min")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pillars) != 2 {
+		t.Fatalf("pillar count mismatch; expected 2, got %d", len(pillars))
+	}
+	blocks := []struct {
+		pillarNum, blockNum int
+		want                min.Block
+	}{
+		{pillarNum: 0, blockNum: 0, want: min.Block{IsValid: true, FrameNum: 1}},
+		{pillarNum: 0, blockNum: 1, want: min.Block{IsValid: true, FrameNum: 0}},
+		{pillarNum: 0, blockNum: 2, want: min.Block{}},
+		{pillarNum: 1, blockNum: 9, want: min.Block{IsValid: true, FrameNum: 1, Type: 1}},
+	}
+	for _, g := range blocks {
+		got := pillars[g.pillarNum].Blocks[g.blockNum]
+		if got != g.want {
+			t.Errorf("pillar %d, block %d: block mismatch; expected %+v, got %+v", g.pillarNum, g.blockNum, g.want, got)
+		}
+	}
+
+	// Files missing from the store.
+	if _, err := min.ParseFrom(s, "l2.min"); err == nil {
+		t.Errorf("expected error for missing %q", "l2.min")
+	}
+}
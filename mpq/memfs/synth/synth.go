@@ -0,0 +1,130 @@
+// Package synth implements generators for tiny, yet valid, game files.
+//
+// The generated files contain no game assets, which makes them suitable for
+// self-contained parser and renderer tests that may be freely distributed.
+// Level returns a complete level, whose files are described below:
+//
+// Synthetic level (l1):
+//    levels/l1data/l1.pal    // grayscale palette.
+//    levels/l1data/l1.cel    // 4 plain (type 0) 32x32 frames.
+//    levels/l1data/l1.min    // 4 pillars; pillar n uses frame n for each block.
+//    levels/l1data/l1.til    // 1 square of the pillars 0, 1, 2 and 3.
+//    levels/l1data/synth.dun // 1x1 squares (2x2 cells) of square 0.
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/mpq/memfs"
+)
+
+// FrameSize is the size in bytes of a plain (type 0) 32x32 level frame.
+const FrameSize = 32 * 32
+
+// PAL returns a PAL file of a grayscale palette, where color index i has the
+// red, green and blue components i.
+func PAL() []byte {
+	buf := make([]byte, 0, 256*3)
+	for i := 0; i < 256; i++ {
+		buf = append(buf, byte(i), byte(i), byte(i))
+	}
+	return buf
+}
+
+// CEL returns a CEL image containing the given frames, without frame headers.
+func CEL(frames ...[]byte) []byte {
+	offset := 4 + 4*(len(frames)+1)
+	offsets := []uint32{uint32(offset)}
+	for _, frame := range frames {
+		offset += len(frame)
+		offsets = append(offsets, uint32(offset))
+	}
+	buf := new(bytes.Buffer)
+	write(buf, uint32(len(frames)))
+	write(buf, offsets)
+	for _, frame := range frames {
+		buf.Write(frame)
+	}
+	return buf.Bytes()
+}
+
+// PlainFrame returns a plain (type 0) 32x32 level frame, filled with the given
+// color index.
+func PlainFrame(colorIndex byte) []byte {
+	return bytes.Repeat([]byte{colorIndex}, FrameSize)
+}
+
+// MIN returns a MIN file containing the given pillars. Each block of a pillar
+// stores frameNumPlus1 in its lower 12 bits and the block type in the bits
+// 12-14.
+//
+// Note: l1, l2, l3, l5 and l6 pillars contain 10 blocks, and l4 and town
+// pillars 16.
+func MIN(pillars ...[]uint16) []byte {
+	buf := new(bytes.Buffer)
+	for _, blocks := range pillars {
+		write(buf, blocks)
+	}
+	return buf.Bytes()
+}
+
+// TIL returns a TIL file containing the given squares, each of which stores the
+// top, right, left and bottom pillarNum.
+func TIL(squares ...[4]uint16) []byte {
+	buf := new(bytes.Buffer)
+	write(buf, squares)
+	return buf.Bytes()
+}
+
+// DUN returns a DUN file of dunQWidth x dunQHeight squares, which contains the
+// given squareNumsPlus1 (row major) and no additional cell data.
+func DUN(dunQWidth, dunQHeight int, squareNumsPlus1 ...uint16) []byte {
+	buf := new(bytes.Buffer)
+	write(buf, []uint16{uint16(dunQWidth), uint16(dunQHeight)})
+	write(buf, squareNumsPlus1)
+	return buf.Bytes()
+}
+
+// Level returns the files of the synthetic level described above.
+func Level() memfs.FS {
+	var frames [][]byte
+	var pillars [][]uint16
+	for i := 0; i < 4; i++ {
+		frames = append(frames, PlainFrame(byte(64*i+63)))
+		blocks := make([]uint16, 10)
+		for blockNum := range blocks {
+			// frameNumPlus1 of a plain (type 0) block.
+			blocks[blockNum] = uint16(i + 1)
+		}
+		pillars = append(pillars, blocks)
+	}
+	return memfs.FS{
+		"levels/l1data/l1.pal":    PAL(),
+		"levels/l1data/l1.cel":    CEL(frames...),
+		"levels/l1data/l1.min":    MIN(pillars...),
+		"levels/l1data/l1.til":    TIL([4]uint16{0, 1, 2, 3}),
+		"levels/l1data/synth.dun": DUN(1, 1, 1),
+	}
+}
+
+// Mount mounts the files of the synthetic level, and sets the image
+// information of l1.cel (32x32 frames using l1.pal).
+func Mount() {
+	memfs.Mount(Level())
+	imgconf.SetDefault("l1.cel", "width", strconv.Itoa(32))
+	imgconf.SetDefault("l1.cel", "height", strconv.Itoa(32))
+	imgconf.SetDefault("l1.cel", "pals", "levels/l1data/l1.pal")
+	// The special CEL image is not part of the level, but its image
+	// information is required by cel.GetLevelConf.
+	imgconf.SetDefault("l1s.cel", "width", strconv.Itoa(64))
+	imgconf.SetDefault("l1s.cel", "height", strconv.Itoa(160))
+}
+
+// write writes the little endian representation of data to buf.
+func write(buf *bytes.Buffer, data interface{}) {
+	// writes to a bytes.Buffer never fail.
+	binary.Write(buf, binary.LittleEndian, data)
+}
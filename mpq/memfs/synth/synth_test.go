@@ -0,0 +1,106 @@
+package synth_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/mewrnd/blizzconv/configs/dun"
+	"github.com/mewrnd/blizzconv/configs/min"
+	"github.com/mewrnd/blizzconv/configs/til"
+	"github.com/mewrnd/blizzconv/images/cel"
+	"github.com/mewrnd/blizzconv/images/imgcache"
+	"github.com/mewrnd/blizzconv/mpq/memfs/synth"
+)
+
+func TestLevel(t *testing.T) {
+	synth.Mount()
+
+	// Parse the level.
+	conf, err := cel.GetConf("l1.cel", "levels/l1data/l1.pal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	levelFrames, err := cel.DecodeAll("l1.cel", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levelFrames) != 4 {
+		t.Fatalf("frame count mismatch; expected 4, got %d", len(levelFrames))
+	}
+	pillars, err := min.Parse("l1.min")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pillars) != 4 {
+		t.Fatalf("pillar count mismatch; expected 4, got %d", len(pillars))
+	}
+	squares, err := til.Parse("l1.til")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSquare := til.Square{PillarNumTop: 0, PillarNumRight: 1, PillarNumLeft: 2, PillarNumBottom: 3}
+	if len(squares) != 1 || squares[0] != wantSquare {
+		t.Fatalf("square mismatch; expected [%+v], got %+v", wantSquare, squares)
+	}
+	dungeon := dun.New()
+	err = dungeon.ParseAt("synth.dun", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colCount, rowCount, err := dun.GetSize("synth.dun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if colCount != 2 || rowCount != 2 {
+		t.Fatalf("dungeon size mismatch; expected 2x2, got %dx%d", colCount, rowCount)
+	}
+
+	// Render the level; the pillars are 64x160, and their cells are laid out
+	// as follows:
+	//
+	//          (0, 0)
+	//    (0, 1)      (1, 0)
+	//          (1, 1)
+	img := dungeon.Image(colCount, rowCount, pillars, levelFrames, 1)
+	if got := img.Bounds().Size(); got.X != 128 || got.Y != 192 {
+		t.Fatalf("image size mismatch; expected 128x192, got %dx%d", got.X, got.Y)
+	}
+	golden := []struct {
+		x, y int
+		// pillarNum n is drawn using the color index 64*n+63.
+		want color.Gray
+	}{
+		{x: 64, y: 8, want: color.Gray{Y: 63}},
+		{x: 100, y: 40, want: color.Gray{Y: 127}},
+		{x: 10, y: 40, want: color.Gray{Y: 191}},
+		{x: 64, y: 185, want: color.Gray{Y: 255}},
+	}
+	for _, g := range golden {
+		wantR, wantG, wantB, wantA := g.want.RGBA()
+		r, gr, b, a := img.At(g.x, g.y).RGBA()
+		if r != wantR || gr != wantG || b != wantB || a != wantA {
+			t.Errorf("(%d, %d): color mismatch; expected %v, got %v", g.x, g.y, g.want, img.At(g.x, g.y))
+		}
+	}
+
+	// Render the level using pillar images cached on disk; the second call
+	// loads the images stored by the first.
+	imgcache.Dir = t.TempDir()
+	defer func() { imgcache.Dir = "" }()
+	levelKey, err := cel.ContentKey("l1.cel", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		pillarImgs, err := min.CachedImages(pillars, levelFrames, levelKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cached := dungeon.ImageWithPillarImages(colCount, rowCount, pillars, pillarImgs, nil, 1)
+		if !bytes.Equal(cached.(*image.RGBA).Pix, img.(*image.RGBA).Pix) {
+			t.Errorf("call %d: image mismatch of cached pillar images", i)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package mpq
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// A FileMeta describes how a file is stored in an MPQ archive, as recorded by
+// its hash table and block table entries.
+type FileMeta struct {
+	// RelPath is the relative path of the file.
+	RelPath string
+	// Size is the decompressed size of the file.
+	Size int64
+	// CompressedSize is the size of the file as stored in the archive,
+	// including its sector offset table and sector checksums, if any.
+	CompressedSize int64
+	// Flags contains the flags of the block table entry of the file.
+	Flags uint32
+	// Locale is the Windows language ID of the file (e.g. 0x0409 for English),
+	// or 0 for files which are language neutral.
+	Locale uint16
+	// Platform is the platform of the file; always 0.
+	Platform uint16
+}
+
+// Compression returns the compression of the file; "implode" for PKWARE
+// implode, "compress" for sectors compressed using the methods stored in the
+// first byte of each sector (e.g. zlib or bzip2), or "none".
+func (meta *FileMeta) Compression() string {
+	switch {
+	case meta.Flags&flagImplode != 0:
+		return "implode"
+	case meta.Flags&flagCompress != 0:
+		return "compress"
+	}
+	return "none"
+}
+
+// Encrypted reports whether the file is encrypted.
+func (meta *FileMeta) Encrypted() bool {
+	return meta.Flags&flagEncrypted != 0
+}
+
+// FixKey reports whether the encryption key of the file is adjusted by the
+// position and size of the file.
+func (meta *FileMeta) FixKey() bool {
+	return meta.Flags&flagFixKey != 0
+}
+
+// SingleUnit reports whether the file is stored as a single unit, rather than
+// being split into sectors.
+func (meta *FileMeta) SingleUnit() bool {
+	return meta.Flags&flagSingleUnit != 0
+}
+
+// SectorCRC reports whether the file has sector checksums.
+func (meta *FileMeta) SectorCRC() bool {
+	return meta.Flags&flagSectorCRC != 0
+}
+
+// Stat returns the metadata of the file of the given name of the default
+// store, as described by Store.Stat.
+func Stat(name string) (meta *FileMeta, err error) {
+	return Default().Stat(name)
+}
+
+// Stat returns the metadata of the file of the given name, whose relative path
+// is located using the ini file, from the last MPQ archive of the store which
+// contains it. Stores without MPQ archives have no metadata to report.
+func (s *Store) Stat(name string) (meta *FileMeta, err error) {
+	relPath, err := s.GetRelPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.chain == nil {
+		return nil, fmt.Errorf("mpq.Store.Stat: unable to locate the metadata of %q; no MPQ archive opened.", name)
+	}
+	return s.chain.Meta(relPath)
+}
+
+// Meta returns the metadata of the file at relPath, from the last archive of
+// the chain which contains it.
+func (chain Chain) Meta(relPath string) (meta *FileMeta, err error) {
+	if !validPath(relPath) {
+		return nil, &fs.PathError{Op: "stat", Path: relPath, Err: fs.ErrInvalid}
+	}
+	a, ok := chain.archive(relPath)
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: relPath, Err: fs.ErrNotExist}
+	}
+	return a.Meta(relPath)
+}
+
+// Meta returns the metadata of the file at relPath within the MPQ archive.
+func (a *Archive) Meta(relPath string) (meta *FileMeta, err error) {
+	if !validPath(relPath) {
+		return nil, &os.PathError{Op: "stat", Path: relPath, Err: os.ErrInvalid}
+	}
+	entry, ok := a.lookupEntry(relPath)
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: relPath, Err: os.ErrNotExist}
+	}
+	block := a.blockTable[entry.BlockIndex]
+	meta = &FileMeta{
+		RelPath:        relPath,
+		Size:           int64(block.FileSize),
+		CompressedSize: int64(block.CompressedSize),
+		Flags:          block.Flags,
+		Locale:         entry.Locale,
+		Platform:       entry.Platform,
+	}
+	return meta, nil
+}
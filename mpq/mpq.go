@@ -1,52 +1,122 @@
-// Package mpq provides access to an extracted MPQ archive.
+// Package mpq provides access to the files of an MPQ archive, either extracted
+// or read directly from the archive.
+//
+// The files of a game version are provided by a Store. The package-level
+// functions and variables are deprecated wrappers of the default store, which
+// is described by ExtractPath, IniPath, ArchivePath and Src.
 package mpq
 
 import (
-	"fmt"
-	"path"
-
 	"github.com/mewbak/goini"
 )
 
-var dict ini.Dict
+// dict provides the relative paths of the files of the default store, and
+// folded indexes its names case-insensitively.
+var (
+	dict   = make(ini.Dict)
+	folded = make(map[string]string)
+)
 
 // IniPath is the path to an ini file which provides relative path information
 // for files in an extracted MPQ archive.
+//
+// Deprecated: Use Options.IniPath of a Store instead.
 var IniPath string
 
+// ArchivePath is the path to an MPQ archive (e.g. DIABDAT.MPQ). If set, the
+// files are read from the archive instead of from ExtractPath, and each file is
+// extracted to ExtractPath the first time it is opened. Files are read directly
+// from the archive, without being extracted, if ExtractPath is empty.
+//
+// ArchivePath may contain several paths, separated by the path list separator
+// of the OS (e.g. "DIABDAT.MPQ:hellfire.mpq" on Unix), in which case the
+// archives are opened as a Chain; the files of later archives shadow those of
+// earlier archives.
+//
+// Deprecated: Use Options.ArchivePath of a Store instead.
+var ArchivePath string
+
+// CacheSize is the memory limit, in bytes, of the files of the default store
+// kept in memory by a MemCache; the files are not kept in memory if 0. It is
+// applied by Init.
+var CacheSize int64
+
 // Init loads an ini file which provides relative path information for files in
-// an extracted MPQ archive.
+// an extracted MPQ archive, and opens the MPQ archives of ArchivePath if set.
+// The files are kept in memory if CacheSize is set.
+//
+// Deprecated: Use OpenStore instead.
 func Init() (err error) {
 	dict, err = ini.Load(IniPath)
 	if err != nil {
 		return err
 	}
+	folded = foldNames(dict)
+	if len(ArchivePath) > 0 {
+		chain, err := OpenChain(SplitArchivePaths(ArchivePath))
+		if err != nil {
+			return err
+		}
+		chain.setIniPaths(func() []string {
+			return Default().relPaths()
+		})
+		Src = chain
+		if len(ExtractPath) > 0 {
+			Src = &Cache{Archive: chain, Dir: Dir(ExtractPath)}
+		}
+	}
+	if CacheSize > 0 {
+		Src = &MemCache{Src: Default().source(), MaxSize: CacheSize}
+	}
 	return nil
 }
 
 // ExtractPath is the path to an extracted MPQ file.
+//
+// Deprecated: Use Options.ExtractPath of a Store instead.
 var ExtractPath string
 
+// Default returns the default store, which is described by the current values
+// of ExtractPath and Src, and the ini file loaded by Init.
+func Default() *Store {
+	return &Store{dict: dict, folded: folded, extractPath: ExtractPath, src: Src}
+}
+
 // AbsPath returns the absolute path of relPath. The absolute path of relPath is
 // relative to mpq.ExtractPath.
+//
+// Deprecated: Use Store.AbsPath instead.
 func AbsPath(relPath string) (absPath string) {
-	return path.Join(ExtractPath, relPath)
+	return Default().AbsPath(relPath)
 }
 
 // GetPath returns the full path of name.
+//
+// Deprecated: Use Store.GetPath instead.
 func GetPath(name string) (path string, err error) {
-	relPath, err := GetRelPath(name)
-	if err != nil {
-		return "", err
-	}
-	return AbsPath(relPath), nil
+	return Default().GetPath(name)
 }
 
 // GetRelPath returns the relative path of name.
+//
+// Deprecated: Use Store.GetRelPath instead.
 func GetRelPath(name string) (relPath string, err error) {
-	relPath, found := dict.GetString(name, "path")
-	if !found {
-		return "", fmt.Errorf("mpq.GetRelPath: path not found for %q", name)
-	}
-	return relPath, nil
+	return Default().GetRelPath(name)
+}
+
+// SetRelPath sets the relative path of name, as if it had been present in the
+// ini file. It is used to register files which are not listed in the ini file,
+// e.g. the files of a Source populated in memory.
+//
+// Deprecated: Use Store.SetRelPath instead.
+func SetRelPath(name, relPath string) {
+	Default().SetRelPath(name, relPath)
+}
+
+// AllFunc calls the function f with the parameter name once for each file in
+// the ini file, sorted by name.
+//
+// Deprecated: Use Store.AllFunc instead.
+func AllFunc(f func(string) error) (err error) {
+	return Default().AllFunc(f)
 }
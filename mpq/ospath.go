@@ -0,0 +1,83 @@
+package mpq
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxPath is the length at which paths are converted to extended-length paths
+// on Windows. Directories are limited to MAX_PATH - 12 (248) characters, to
+// leave room for an 8.3 file name.
+const maxPath = 248
+
+// OSPath returns the path of the operating system for the slash-separated path
+// p. On Windows, paths of at least 248 characters are converted to absolute
+// extended-length paths (e.g. `\\?\C:\mpqdump\...`), which are not limited to
+// MAX_PATH (260) characters; as produced by extraction tools nesting the files
+// of the MPQ archive deep below the extract path.
+func OSPath(p string) string {
+	p = filepath.FromSlash(p)
+	if runtime.GOOS != "windows" || len(p) < maxPath || strings.HasPrefix(p, `\\?\`) {
+		return p
+	}
+	// Extended-length paths are passed to the file system as is, so they must
+	// be absolute and clean.
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if strings.HasPrefix(absPath, `\\`) {
+		// UNC path (e.g. `\\server\share\...`).
+		return `\\?\UNC\` + absPath[2:]
+	}
+	return `\\?\` + absPath
+}
+
+// lookup locates the file at relPath, relative to the directory, whose name
+// differs from relPath in case or in the encoding of non-ASCII characters, and
+// returns its path. Each element of relPath is matched against the entries of
+// its directory, as compared by foldName.
+func (dir Dir) lookup(relPath string) (filePath string, ok bool) {
+	filePath = string(dir)
+	for _, elem := range strings.Split(path.Clean(relPath), "/") {
+		dirPath := filePath
+		if len(dirPath) == 0 {
+			dirPath = "."
+		}
+		entries, err := os.ReadDir(OSPath(dirPath))
+		if err != nil {
+			return "", false
+		}
+		found := false
+		for _, entry := range entries {
+			if foldName(entry.Name()) == foldName(elem) {
+				filePath = path.Join(filePath, entry.Name())
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return filePath, true
+}
+
+// foldName returns name folded to lower case, for case-insensitive comparison
+// of file names. Names which are not valid UTF-8 (e.g. names extracted by tools
+// using a legacy code page such as Windows-1252) are decoded as Latin-1 first,
+// so they compare equal to their UTF-8 encoded counterparts.
+func foldName(name string) string {
+	if !utf8.ValidString(name) {
+		runes := make([]rune, len(name))
+		for i := 0; i < len(name); i++ {
+			runes[i] = rune(name[i])
+		}
+		name = string(runes)
+	}
+	return strings.ToLower(name)
+}
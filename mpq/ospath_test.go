@@ -0,0 +1,144 @@
+package mpq
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGetRelPath(t *testing.T) {
+	s := &Store{}
+	s.SetRelPath("l1.min", `levels\l1data\l1.min`)
+	s.SetRelPath("Town.CEL", "levels/towndata/town.cel")
+	s.SetRelPath("épée.cel", "items/épée.cel")
+	golden := []struct {
+		name    string
+		relPath string
+		found   bool
+	}{
+		{name: "l1.min", relPath: "levels/l1data/l1.min", found: true},
+		{name: "L1.MIN", relPath: "levels/l1data/l1.min", found: true},
+		{name: "town.cel", relPath: "levels/towndata/town.cel", found: true},
+		{name: "TOWN.CEL", relPath: "levels/towndata/town.cel", found: true},
+		{name: "épée.cel", relPath: "items/épée.cel", found: true},
+		{name: "ÉPÉE.CEL", relPath: "items/épée.cel", found: true},
+		{name: "l2.min", found: false},
+	}
+	for _, g := range golden {
+		relPath, err := s.GetRelPath(g.name)
+		if !g.found {
+			if err == nil {
+				t.Errorf("%q: expected error, got relative path %q", g.name, relPath)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", g.name, err)
+			continue
+		}
+		if relPath != g.relPath {
+			t.Errorf("%q: relative path mismatch; expected %q, got %q", g.name, g.relPath, relPath)
+		}
+	}
+}
+
+func TestFoldName(t *testing.T) {
+	golden := []struct {
+		name string
+		want string
+	}{
+		{name: "L1.MIN", want: "l1.min"},
+		{name: "épée.cel", want: "épée.cel"},
+		{name: "ÉPÉE.CEL", want: "épée.cel"},
+		// "épée.cel" encoded as Latin-1 (Windows-1252).
+		{name: "\xe9p\xe9e.cel", want: "épée.cel"},
+		{name: "\xc9P\xc9E.CEL", want: "épée.cel"},
+	}
+	for _, g := range golden {
+		if got := foldName(g.name); got != g.want {
+			t.Errorf("%q: folded name mismatch; expected %q, got %q", g.name, g.want, got)
+		}
+	}
+}
+
+func TestDirOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// longDir is nested deep enough for the paths below it to exceed MAX_PATH
+	// (260) characters.
+	longDir := ""
+	for len(dir)+len(longDir) <= 260 {
+		longDir = path.Join(longDir, strings.Repeat("d", 50))
+	}
+	// The differently cased paths are placed below their own directory, so that
+	// they don't shadow the others on case-insensitive file systems.
+	golden := []struct {
+		// diskPath is the path of the file on disk, relative to dir.
+		diskPath string
+		// relPath is the path used to open the file.
+		relPath string
+	}{
+		{diskPath: "levels/l1data/l1.min", relPath: "levels/l1data/l1.min"},
+		{diskPath: "upper/Levels/L1Data/L1.MIN", relPath: "upper/levels/l1data/l1.min"},
+		{diskPath: "items/épée.cel", relPath: "items/épée.cel"},
+		{diskPath: "upper/items/ÉPÉE.CEL", relPath: "upper/items/épée.cel"},
+		{diskPath: "latin1/\xe9p\xe9e.cel", relPath: "latin1/épée.cel"},
+		{diskPath: path.Join(longDir, "l1.min"), relPath: path.Join(longDir, "l1.min")},
+		{diskPath: path.Join("upper", strings.ToUpper(longDir), "L1.MIN"), relPath: path.Join("upper", longDir, "l1.min")},
+	}
+	for i, g := range golden {
+		diskPath := filepath.Join(dir, filepath.FromSlash(g.diskPath))
+		err := os.MkdirAll(OSPath(filepath.Dir(diskPath)), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := []byte{byte(i)}
+		err = ioutil.WriteFile(OSPath(diskPath), content, 0644)
+		if err != nil {
+			// e.g. file systems which reject names which are not valid UTF-8.
+			t.Logf("%q: skipped; %v", g.diskPath, err)
+			continue
+		}
+		f, err := Dir(dir).Open(g.relPath)
+		if err != nil {
+			t.Errorf("%q: unable to open %q: %v", g.diskPath, g.relPath, err)
+			continue
+		}
+		buf, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%q: unable to read %q: %v", g.diskPath, g.relPath, err)
+			continue
+		}
+		if string(buf) != string(content) {
+			t.Errorf("%q: content mismatch of %q; expected %v, got %v", g.diskPath, g.relPath, content, buf)
+		}
+	}
+}
+
+func TestOSPath(t *testing.T) {
+	longPath := strings.Repeat("a/", 150) + "l1.min"
+	golden := []string{
+		"levels/l1data/l1.min",
+		longPath,
+	}
+	for _, p := range golden {
+		got := OSPath(p)
+		if runtime.GOOS != "windows" {
+			if got != filepath.FromSlash(p) {
+				t.Errorf("%q: OS path mismatch; expected %q, got %q", p, filepath.FromSlash(p), got)
+			}
+			continue
+		}
+		if long := len(filepath.FromSlash(p)) >= maxPath; long != strings.HasPrefix(got, `\\?\`) {
+			t.Errorf("%q: extended-length prefix mismatch of %q", p, got)
+		}
+	}
+}
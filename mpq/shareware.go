@@ -0,0 +1,64 @@
+package mpq
+
+import (
+	"io/fs"
+	"os"
+)
+
+// Relative paths used to detect shareware archives (e.g. spawn.mpq), which
+// contain the first dungeon level type (cathedral) but none of the others.
+const (
+	sharewareRelPath = "levels/l1data/l1.cel"
+	fullRelPath      = "levels/l2data/l2.cel"
+)
+
+// IsShareware reports whether the default store contains the assets of the
+// shareware version, as described by Store.IsShareware.
+func IsShareware() bool {
+	return Default().IsShareware()
+}
+
+// IsShareware reports whether the store contains the assets of the shareware
+// version (spawn.mpq) rather than those of the full game. The shareware
+// version only contains the cathedral levels, and lacks many of the monsters,
+// items and sounds listed by the ini files.
+func (s *Store) IsShareware() bool {
+	return exists(s.source(), sharewareRelPath) && !exists(s.source(), fullRelPath)
+}
+
+// Missing reports whether the file of the given name of the default store is
+// missing, as described by Store.Missing.
+func Missing(name string) bool {
+	return Default().Missing(name)
+}
+
+// Missing reports whether the file of the given name is listed in the ini file
+// but is provided by none of the sources of the store, e.g. the files of the
+// full game which are missing from the shareware version. Files which are not
+// listed in the ini file (e.g. the extracted images of image archives) are
+// never reported as missing. No files are extracted.
+func (s *Store) Missing(name string) bool {
+	relPath, err := s.GetRelPath(name)
+	if err != nil {
+		return false
+	}
+	return !exists(s.source(), relPath)
+}
+
+// exists reports whether the file at relPath is provided by the source, without
+// extracting it.
+func exists(src Source, relPath string) bool {
+	switch src := src.(type) {
+	case *MemCache:
+		return exists(src.Src, relPath)
+	case *Cache:
+		if exists(src.Dir, relPath) {
+			return true
+		}
+		return exists(src.Archive, relPath)
+	}
+	// Files which fail to open for other reasons (e.g. permissions) are
+	// reported as present, so that the error surfaces when they are opened.
+	_, err := fs.Stat(src, relPath)
+	return err == nil || !os.IsNotExist(err)
+}
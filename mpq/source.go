@@ -0,0 +1,73 @@
+package mpq
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// A File is an open file of an extracted MPQ archive, which supports random
+// access.
+type File interface {
+	fs.File
+	io.ReaderAt
+	io.Seeker
+}
+
+// A Source provides the files of an extracted MPQ archive, which are located
+// by their relative paths. Any file system (e.g. os.DirFS or fstest.MapFS) may
+// be used as a Source.
+type Source interface {
+	fs.FS
+}
+
+// Dir is a Source which provides the files of an MPQ archive extracted to the
+// given directory.
+type Dir string
+
+// Open opens the file at relPath, relative to the directory. Files whose names
+// differ from relPath in case or in the encoding of non-ASCII characters (e.g.
+// files extracted using a legacy code page) are opened as well.
+func (dir Dir) Open(relPath string) (fs.File, error) {
+	f, err := os.Open(OSPath(path.Join(string(dir), relPath)))
+	if os.IsNotExist(err) {
+		if filePath, ok := dir.lookup(relPath); ok {
+			f, err = os.Open(OSPath(filePath))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Src is the source of the files of the extracted MPQ archive. The files are
+// provided by Dir(ExtractPath) if Src is nil.
+//
+// Deprecated: Use Options.Src of a Store instead.
+var Src Source
+
+// Open opens the file of the given name, whose relative path is located using
+// the ini file.
+//
+// Deprecated: Use Store.Open instead.
+func Open(name string) (f File, err error) {
+	return Default().Open(name)
+}
+
+// OpenRel opens the file at relPath, relative to the extracted MPQ archive.
+// The contents of files which don't support random access are read into memory.
+//
+// Deprecated: Use Store.OpenRel instead.
+func OpenRel(relPath string) (f File, err error) {
+	return Default().OpenRel(relPath)
+}
+
+// ReadFile returns the contents of the file at relPath, relative to the
+// extracted MPQ archive.
+//
+// Deprecated: Use Store.ReadFile instead.
+func ReadFile(relPath string) (buf []byte, err error) {
+	return Default().ReadFile(relPath)
+}
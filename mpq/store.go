@@ -0,0 +1,242 @@
+package mpq
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mewbak/goini"
+)
+
+// Options specifies the files of a game version provided by a Store.
+type Options struct {
+	// IniPath is the path to an ini file which provides relative path
+	// information for the files of the MPQ archive; no ini file is loaded if
+	// empty.
+	IniPath string
+	// ExtractPath is the path to an extracted MPQ archive.
+	ExtractPath string
+	// ArchivePath is the path to an MPQ archive (e.g. DIABDAT.MPQ), or several
+	// paths separated by the path list separator of the OS which are opened as
+	// a Chain. If set, the files are read from the archives and extracted to
+	// ExtractPath the first time they are opened, or read directly from the
+	// archives if ExtractPath is empty.
+	ArchivePath string
+	// Src is the source of the files, which takes precedence over both
+	// ExtractPath and ArchivePath if non-nil.
+	Src Source
+	// CacheSize is the memory limit, in bytes, of the files kept in memory by
+	// a MemCache, so that the files read repeatedly are read and decompressed
+	// only once; the files are not kept in memory if 0.
+	CacheSize int64
+}
+
+// A Store provides the files of one version of the game (e.g. an extracted
+// DIABDAT.MPQ and the relative paths of its ini file). Stores are independent
+// of each other, so several game versions may be used at the same time, e.g.
+// to compare their assets.
+//
+// The methods of a Store may be called concurrently, except for SetRelPath.
+type Store struct {
+	// dict provides the relative paths of the files.
+	dict ini.Dict
+	// folded maps from the lower case names of dict to the names, for
+	// case-insensitive lookups.
+	folded map[string]string
+	// extractPath is the path to the extracted MPQ archive.
+	extractPath string
+	// src is the source of the files, or nil if the files are provided by
+	// Dir(extractPath).
+	src Source
+	// chain holds the MPQ archives opened by the store, if any.
+	chain Chain
+}
+
+// OpenStore returns a store which provides the files specified by opts. The
+// store should be closed once it is no longer needed, to close its MPQ
+// archives.
+func OpenStore(opts Options) (s *Store, err error) {
+	s = &Store{extractPath: opts.ExtractPath, src: opts.Src}
+	if len(opts.IniPath) > 0 {
+		s.dict, err = ini.Load(opts.IniPath)
+		if err != nil {
+			return nil, err
+		}
+		s.folded = foldNames(s.dict)
+	}
+	if s.src == nil && len(opts.ArchivePath) > 0 {
+		s.chain, err = OpenChain(SplitArchivePaths(opts.ArchivePath))
+		if err != nil {
+			return nil, err
+		}
+		s.chain.setIniPaths(s.relPaths)
+		s.src = s.chain
+		if len(opts.ExtractPath) > 0 {
+			s.src = &Cache{Archive: s.chain, Dir: Dir(opts.ExtractPath)}
+		}
+	}
+	if opts.CacheSize > 0 {
+		s.src = &MemCache{Src: s.source(), MaxSize: opts.CacheSize}
+	}
+	return s, nil
+}
+
+// Close closes the MPQ archives opened by the store, if any.
+func (s *Store) Close() error {
+	return s.chain.Close()
+}
+
+// Chain returns the MPQ archives opened by the store, which list the relative
+// paths of the ini file along with the files of their listfiles; nil if the
+// store has no archives.
+func (s *Store) Chain() Chain {
+	return s.chain
+}
+
+// source returns the source of the files of the store.
+func (s *Store) source() Source {
+	if s.src != nil {
+		return s.src
+	}
+	return Dir(s.extractPath)
+}
+
+// AbsPath returns the absolute path of relPath, relative to the extracted MPQ
+// archive of the store.
+func (s *Store) AbsPath(relPath string) (absPath string) {
+	return path.Join(s.extractPath, relPath)
+}
+
+// GetPath returns the full path of name.
+func (s *Store) GetPath(name string) (path string, err error) {
+	relPath, err := s.GetRelPath(name)
+	if err != nil {
+		return "", err
+	}
+	return s.AbsPath(relPath), nil
+}
+
+// GetRelPath returns the relative path of name. Names are case-insensitive
+// (e.g. "L1.MIN" is resolved as "l1.min"), as are the file names of MPQ
+// archives. Backslashes of the ini file (e.g. `levels\l1data\l1.min`, as used
+// by the MPQ archive) are converted to slashes.
+func (s *Store) GetRelPath(name string) (relPath string, err error) {
+	relPath, found := s.dict.GetString(name, "path")
+	if !found {
+		// Fall back to a case-insensitive lookup of the name.
+		if other, ok := s.folded[strings.ToLower(name)]; ok {
+			relPath, found = s.dict.GetString(other, "path")
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("mpq.GetRelPath: path not found for %q", name)
+	}
+	return strings.Replace(relPath, `\`, "/", -1), nil
+}
+
+// SetRelPath sets the relative path of name, as if it had been present in the
+// ini file. It is used to register files which are not listed in the ini file,
+// e.g. the files of a Source populated in memory.
+func (s *Store) SetRelPath(name, relPath string) {
+	if s.dict == nil {
+		s.dict = make(ini.Dict)
+	}
+	if s.folded == nil {
+		s.folded = make(map[string]string)
+	}
+	if s.dict[name] == nil {
+		s.dict[name] = make(map[string]string)
+	}
+	s.dict[name]["path"] = relPath
+	s.folded[strings.ToLower(name)] = name
+}
+
+// foldNames returns a map from the lower case names of dict to the names. Of
+// names which only differ in case, the smallest one is indexed, so that the
+// lookups are deterministic.
+func foldNames(dict ini.Dict) (folded map[string]string) {
+	folded = make(map[string]string)
+	for name := range dict {
+		key := strings.ToLower(name)
+		if other, ok := folded[key]; !ok || name < other {
+			folded[key] = name
+		}
+	}
+	return folded
+}
+
+// relPaths returns the relative paths of the files of the ini file.
+func (s *Store) relPaths() (relPaths []string) {
+	for name := range s.dict {
+		if relPath, found := s.dict.GetString(name, "path"); found {
+			relPaths = append(relPaths, strings.Replace(relPath, `\`, "/", -1))
+		}
+	}
+	return relPaths
+}
+
+// AllFunc calls the function f with the parameter name once for each file in
+// the ini file, sorted by name.
+func (s *Store) AllFunc(f func(string) error) (err error) {
+	var names []string
+	for name := range s.dict {
+		if name == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err = f(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open opens the file of the given name, whose relative path is located using
+// the ini file.
+func (s *Store) Open(name string) (f File, err error) {
+	relPath, err := s.GetRelPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.OpenRel(relPath)
+}
+
+// OpenRel opens the file at relPath, relative to the extracted MPQ archive.
+// The contents of files which don't support random access are read into memory.
+func (s *Store) OpenRel(relPath string) (f File, err error) {
+	sf, err := s.source().Open(relPath)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := sf.(File); ok {
+		return f, nil
+	}
+	defer sf.Close()
+	info, err := sf.Stat()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := ioutil.ReadAll(sf)
+	if err != nil {
+		return nil, err
+	}
+	return memFile{Reader: bytes.NewReader(buf), info: info}, nil
+}
+
+// ReadFile returns the contents of the file at relPath, relative to the
+// extracted MPQ archive.
+func (s *Store) ReadFile(relPath string) (buf []byte, err error) {
+	f, err := s.OpenRel(relPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
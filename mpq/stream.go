@@ -0,0 +1,280 @@
+package mpq
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// OpenFile opens the file of the given name of the default store for
+// streaming, as described by Store.OpenFile.
+func OpenFile(name string) (rc io.ReadCloser, err error) {
+	return Default().OpenFile(name)
+}
+
+// OpenFile opens the file of the given name, whose relative path is located
+// using the ini file, for streaming. Unlike Open, the contents of files read
+// from MPQ archives are decompressed one sector at a time while being read,
+// rather than being read into memory when opened, so large files (e.g. SMK
+// videos) may be processed in constant memory. Files which have already been
+// extracted are read from the extracted MPQ archive.
+func (s *Store) OpenFile(name string) (rc io.ReadCloser, err error) {
+	relPath, err := s.GetRelPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return openFile(s.source(), relPath)
+}
+
+// fileOpener is implemented by the sources which support streaming.
+type fileOpener interface {
+	OpenFile(relPath string) (io.ReadCloser, error)
+}
+
+// openFile opens the file at relPath of the source for streaming; files of
+// sources which don't support streaming are opened using Open, which streams
+// the files of most file systems (e.g. Dir) anyway.
+func openFile(src Source, relPath string) (rc io.ReadCloser, err error) {
+	if o, ok := src.(fileOpener); ok {
+		return o.OpenFile(relPath)
+	}
+	return src.Open(relPath)
+}
+
+// OpenFile opens the file at relPath for streaming, from the directory if it
+// has been extracted, or else directly from the MPQ archive without extracting
+// it.
+func (c *Cache) OpenFile(relPath string) (io.ReadCloser, error) {
+	f, err := c.Dir.Open(relPath)
+	if err == nil || !os.IsNotExist(err) {
+		return f, err
+	}
+	if o, ok := c.Archive.(fileOpener); ok {
+		return o.OpenFile(relPath)
+	}
+	return c.Open(relPath)
+}
+
+// OpenFile opens the file at relPath, from the last archive of the chain which
+// contains it, for streaming.
+func (chain Chain) OpenFile(relPath string) (io.ReadCloser, error) {
+	if !validPath(relPath) {
+		return nil, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrInvalid}
+	}
+	a, ok := chain.archive(relPath)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: relPath, Err: fs.ErrNotExist}
+	}
+	return a.OpenFile(relPath)
+}
+
+// OpenFile opens the file at relPath within the MPQ archive for streaming. The
+// sectors of the file are read and decompressed as the file is read; files
+// stored as a single unit are decompressed at once, as their sector spans the
+// entire file.
+func (a *Archive) OpenFile(relPath string) (io.ReadCloser, error) {
+	if !validPath(relPath) {
+		return nil, &os.PathError{Op: "open", Path: relPath, Err: os.ErrInvalid}
+	}
+	block, ok := a.lookup(relPath)
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: relPath, Err: os.ErrNotExist}
+	}
+	r, err := a.newSectorReader(block, relPath, false)
+	if err != nil {
+		return nil, fmt.Errorf("mpq.Archive.OpenFile: unable to read %q: %v", relPath, err)
+	}
+	return r, nil
+}
+
+// A sectorReader reads the sectors of a block on demand, and decompresses them
+// one at a time.
+type sectorReader struct {
+	a     *Archive
+	block blockEntry
+	// relPath is the path of the file stored by the block.
+	relPath string
+	// key is the encryption key of the block, if encrypted.
+	key uint32
+	// sectorSize is the decompressed size of each sector but the last; the
+	// size of the file for blocks stored as a single unit.
+	sectorSize int
+	// offsets contains the start of each sector and the end of the last
+	// sector, relative to the start of the block.
+	offsets []uint32
+	// checksums contains the sector checksums of the block, if present and
+	// verified.
+	checksums []byte
+	// sector is the index of the next sector to read.
+	sector int
+	// raw holds the compressed contents of the current sector.
+	raw []byte
+	// buf holds the unread decompressed contents of the current sector.
+	buf []byte
+	// done is the number of decompressed bytes of the sectors read so far.
+	done int
+	// closed specifies if the reader has been closed.
+	closed bool
+}
+
+// newSectorReader returns a reader of the sectors of the given block, which
+// stores the file at relPath. The sector offset table (and the sector
+// checksums, if verify is set) are read up front.
+func (a *Archive) newSectorReader(block blockEntry, relPath string, verify bool) (r *sectorReader, err error) {
+	r = &sectorReader{a: a, block: block, relPath: relPath, sectorSize: a.sectorSize}
+	if block.Flags&flagEncrypted != 0 {
+		// The key is based on the base name of the file.
+		name := relPath[strings.LastIndexAny(relPath, `/\`)+1:]
+		r.key = hashString(name, hashFileKey)
+		if block.Flags&flagFixKey != 0 {
+			r.key = (r.key + block.FilePos) ^ block.FileSize
+		}
+	}
+	fileSize := int(block.FileSize)
+	if block.Flags&flagSingleUnit != 0 {
+		r.sectorSize = fileSize
+		r.offsets = []uint32{0, block.CompressedSize}
+		return r, nil
+	}
+	sectorCount := (fileSize + a.sectorSize - 1) / a.sectorSize
+	r.offsets = make([]uint32, sectorCount+1)
+	if block.Flags&(flagImplode|flagCompress) == 0 {
+		for i := range r.offsets {
+			r.offsets[i] = uint32(i * a.sectorSize)
+		}
+		r.offsets[sectorCount] = block.FileSize
+		return r, nil
+	}
+	// The sector offset table is followed by the end of the sector checksums,
+	// which are stored after the last sector, if present.
+	entryCount := len(r.offsets)
+	if block.Flags&flagSectorCRC != 0 {
+		entryCount++
+	}
+	tableSize := 4 * entryCount
+	if int(block.CompressedSize) < tableSize {
+		return nil, errors.New("sector offset table out of bounds.")
+	}
+	table, err := r.readAt(0, uint32(tableSize))
+	if err != nil {
+		return nil, err
+	}
+	if block.Flags&flagEncrypted != 0 {
+		decrypt(table, r.key-1)
+	}
+	for i := range r.offsets {
+		r.offsets[i] = binary.LittleEndian.Uint32(table[4*i:])
+	}
+	// The first sector starts directly after the sector offset table. Any
+	// other value indicates that the table was decrypted using the wrong key.
+	if r.offsets[0] != uint32(tableSize) {
+		if block.Flags&flagEncrypted != 0 {
+			return nil, fmt.Errorf("invalid sector offset table; unable to decrypt using the key of %q.", relPath)
+		}
+		return nil, errors.New("invalid sector offset table.")
+	}
+	if verify && block.Flags&flagSectorCRC != 0 {
+		start, end := r.offsets[sectorCount], binary.LittleEndian.Uint32(table[4*sectorCount+4:])
+		if start > end || end > block.CompressedSize {
+			return nil, errors.New("sector checksums out of bounds.")
+		}
+		data, err := r.readAt(start, end)
+		if err != nil {
+			return nil, err
+		}
+		r.checksums, err = readChecksums(data, sectorCount, block.Flags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// sectorCount returns the number of sectors of the block.
+func (r *sectorReader) sectorCount() int {
+	return len(r.offsets) - 1
+}
+
+// readAt returns the contents of the block between start and end, relative to
+// the start of the block.
+func (r *sectorReader) readAt(start, end uint32) (data []byte, err error) {
+	data = make([]byte, end-start)
+	_, err = r.a.f.ReadAt(data, r.a.offset+int64(r.block.FilePos)+int64(start))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// next reads and decompresses the next sector into buf.
+func (r *sectorReader) next() (err error) {
+	i := r.sector
+	start, end := r.offsets[i], r.offsets[i+1]
+	if start > end || end > r.block.CompressedSize {
+		return fmt.Errorf("sector %d out of bounds.", i)
+	}
+	if n := int(end - start); cap(r.raw) < n {
+		r.raw = make([]byte, n)
+	}
+	sector := r.raw[:end-start]
+	_, err = r.a.f.ReadAt(sector, r.a.offset+int64(r.block.FilePos)+int64(start))
+	if err != nil {
+		return err
+	}
+	if r.block.Flags&flagEncrypted != 0 {
+		decrypt(sector, r.key+uint32(i))
+	}
+	if r.checksums != nil {
+		// A checksum of 0 marks a sector without checksum.
+		want := binary.LittleEndian.Uint32(r.checksums[4*i:])
+		if got := sectorChecksum(sector); want != 0 && got != want {
+			return fmt.Errorf("sector %d checksum mismatch; expected 0x%08X, got 0x%08X.", i, want, got)
+		}
+	}
+	size := r.sectorSize
+	if rest := int(r.block.FileSize) - r.done; rest < size {
+		size = rest
+	}
+	r.buf, err = decompress(sector, size, r.block.Flags)
+	if err != nil {
+		if r.block.Flags&flagSingleUnit != 0 {
+			return err
+		}
+		return fmt.Errorf("sector %d: %v", i, err)
+	}
+	r.sector++
+	r.done += size
+	return nil
+}
+
+// Read reads the decompressed contents of the file, reading the next sector
+// once the current sector has been read.
+func (r *sectorReader) Read(p []byte) (n int, err error) {
+	if r.closed {
+		return 0, &os.PathError{Op: "read", Path: r.relPath, Err: os.ErrClosed}
+	}
+	for len(r.buf) == 0 {
+		if r.sector >= r.sectorCount() {
+			return 0, io.EOF
+		}
+		err = r.next()
+		if err != nil {
+			return 0, fmt.Errorf("mpq: unable to read %q: %v", r.relPath, err)
+		}
+	}
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close closes the reader. The MPQ archive remains open.
+func (r *sectorReader) Close() error {
+	r.closed = true
+	r.buf = nil
+	r.raw = nil
+	return nil
+}
@@ -0,0 +1,57 @@
+package mpq
+
+import "fmt"
+
+// A BlockResult reports the integrity of a file (block) of an MPQ archive.
+type BlockResult struct {
+	// BlockIndex is the index of the block table entry of the file.
+	BlockIndex int
+	// RelPath is the relative path of the file, or an empty string if the
+	// file is unnamed.
+	RelPath string
+	// Checked specifies if the sector checksums of the file were verified.
+	Checked bool
+	// Skipped specifies if the file was skipped, as it is both encrypted and
+	// unnamed; the encryption key of a file is based on its name.
+	Skipped bool
+	// Err is the reason why the file is corrupt, or nil if the file is intact.
+	Err error
+}
+
+// Verify verifies the integrity of each file (block) of the MPQ archive, by
+// decompressing it and verifying its sector checksums, if present. Files which
+// extend past the end of the archive are reported as truncated. The names of
+// the files are located as described by Names; the encrypted files which
+// remain unnamed are skipped, after checking that they are not truncated. The
+// results are sorted by block index.
+func (a *Archive) Verify() (results []BlockResult, err error) {
+	fi, err := a.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	archiveSize := fi.Size()
+	names := make(map[uint32]string)
+	for _, relPath := range append(a.Names(), specialNames...) {
+		if blockIndex, ok := a.lookupIndex(relPath); ok {
+			names[blockIndex] = relPath
+		}
+	}
+	for blockIndex, block := range a.blockTable {
+		if block.Flags&flagExists == 0 {
+			continue
+		}
+		result := BlockResult{BlockIndex: blockIndex}
+		relPath, named := names[uint32(blockIndex)]
+		result.RelPath = relPath
+		switch {
+		case a.offset+int64(block.FilePos)+int64(block.CompressedSize) > archiveSize:
+			result.Err = fmt.Errorf("truncated; the file extends %d bytes past the end of the archive.", a.offset+int64(block.FilePos)+int64(block.CompressedSize)-archiveSize)
+		case !named && block.Flags&flagEncrypted != 0:
+			result.Skipped = true
+		default:
+			_, result.Checked, result.Err = a.readSectors(block, relPath, true)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,209 @@
+package mpq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mewrnd/blizzconv/atomicfile"
+)
+
+// writeSectorSizeShift is the sector size shift of the MPQ archives created by
+// CreateArchive (4096 byte sectors), as used by DIABDAT.MPQ.
+const writeSectorSizeShift = 3
+
+// headerSize is the size of the header of MPQ archives of format version 0.
+const headerSize = 32
+
+// CreateArchive creates an MPQ archive at archivePath which contains each file
+// of fsys (e.g. an extracted and modified MPQ archive), stored using their
+// relative paths. A listfile ("(listfile)") is added to the archive, replacing
+// any listfile of fsys.
+//
+// The sectors of each file are compressed using PKWARE implode if compress is
+// set, which is the only compression supported by the game; sectors which
+// would not shrink are stored as is. The files are not encrypted.
+//
+// ref: Archive (description of the MPQ format)
+func CreateArchive(archivePath string, fsys fs.FS, compress bool) (err error) {
+	var relPaths []string
+	// names tracks the case-insensitive names of the files, as MPQ archives
+	// can't hold files whose names only differ in case.
+	names := make(map[string]string)
+	err = fs.WalkDir(fsys, ".", func(relPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || relPath == "(listfile)" {
+			return nil
+		}
+		name := strings.ToUpper(relPath)
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("file names %q and %q only differ in case.", other, relPath)
+		}
+		names[name] = relPath
+		relPaths = append(relPaths, relPath)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("mpq.CreateArchive: unable to list files; %v", err)
+	}
+	sort.Strings(relPaths)
+	f, err := atomicfile.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := &archiveWriter{buf: make([]byte, headerSize), compress: compress, sectorSize: 512 << writeSectorSizeShift}
+	var listfile bytes.Buffer
+	for _, relPath := range relPaths {
+		buf, err := fs.ReadFile(fsys, relPath)
+		if err != nil {
+			return fmt.Errorf("mpq.CreateArchive: unable to read %q; %v", relPath, err)
+		}
+		w.addFile(relPath, buf)
+		fmt.Fprintf(&listfile, "%s\r\n", strings.Replace(relPath, "/", `\`, -1))
+	}
+	w.addFile("(listfile)", listfile.Bytes())
+	buf, err := w.finish()
+	if err != nil {
+		return fmt.Errorf("mpq.CreateArchive: unable to create %q; %v", archivePath, err)
+	}
+	_, err = f.Write(buf)
+	if err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// An archiveWriter assembles the contents of an MPQ archive; the header, the
+// blocks of each file and the hash and block tables.
+type archiveWriter struct {
+	// buf contains the header, followed by the blocks which have been added.
+	buf        []byte
+	compress   bool
+	sectorSize int
+	relPaths   []string
+	blockTable []blockEntry
+}
+
+// addFile adds the block of a file to the archive.
+func (w *archiveWriter) addFile(relPath string, data []byte) {
+	block := blockEntry{
+		FilePos:  uint32(len(w.buf)),
+		FileSize: uint32(len(data)),
+		Flags:    flagExists,
+	}
+	start := len(w.buf)
+	if w.compress {
+		block.Flags |= flagImplode
+		w.buf = appendSectors(w.buf, data, w.sectorSize)
+	} else {
+		w.buf = append(w.buf, data...)
+	}
+	block.CompressedSize = uint32(len(w.buf) - start)
+	w.relPaths = append(w.relPaths, relPath)
+	w.blockTable = append(w.blockTable, block)
+}
+
+// appendSectors appends the sector offset table of data, followed by each of
+// its sectors compressed using implode, to buf.
+func appendSectors(buf, data []byte, sectorSize int) []byte {
+	sectorCount := (len(data) + sectorSize - 1) / sectorSize
+	tableStart := len(buf)
+	buf = append(buf, make([]byte, 4*(sectorCount+1))...)
+	offset := 4 * (sectorCount + 1)
+	for i := 0; i <= sectorCount; i++ {
+		binary.LittleEndian.PutUint32(buf[tableStart+4*i:], uint32(offset))
+		if i == sectorCount {
+			break
+		}
+		end := (i + 1) * sectorSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sector := data[i*sectorSize : end]
+		if c := implode(sector); len(c) < len(sector) {
+			sector = c
+		}
+		buf = append(buf, sector...)
+		offset += len(sector)
+	}
+	return buf
+}
+
+// finish appends the encrypted hash and block tables to the archive, and
+// returns its contents after updating the header.
+func (w *archiveWriter) finish() (buf []byte, err error) {
+	// The hash table holds at least twice as many entries as there are files,
+	// which keeps the probe sequences of lookups short. Its number of entries
+	// is a power of two.
+	hashTableEntries := 16
+	for hashTableEntries < 2*len(w.relPaths) {
+		hashTableEntries <<= 1
+	}
+	hashTable := make([]hashEntry, hashTableEntries)
+	for i := range hashTable {
+		hashTable[i] = hashEntry{NameA: 0xFFFFFFFF, NameB: 0xFFFFFFFF, Locale: 0xFFFF, Platform: 0xFFFF, BlockIndex: blockIndexEmpty}
+	}
+	for blockIndex, relPath := range w.relPaths {
+		i := hashString(relPath, hashTableOffset) % uint32(hashTableEntries)
+		for hashTable[i].BlockIndex != blockIndexEmpty {
+			i = (i + 1) % uint32(hashTableEntries)
+		}
+		hashTable[i] = hashEntry{
+			NameA:      hashString(relPath, hashNameA),
+			NameB:      hashString(relPath, hashNameB),
+			BlockIndex: uint32(blockIndex),
+		}
+	}
+	hashTableOffset := len(w.buf)
+	w.buf = appendTable(w.buf, hashTable, "(hash table)")
+	blockTableOffset := len(w.buf)
+	w.buf = appendTable(w.buf, w.blockTable, "(block table)")
+	if len(w.buf) > math.MaxUint32 {
+		return nil, fmt.Errorf("archive size (%d) exceeds 4 GiB.", len(w.buf))
+	}
+	hdr := struct {
+		Magic             uint32
+		HeaderSize        uint32
+		ArchiveSize       uint32
+		FormatVersion     uint16
+		SectorSizeShift   uint16
+		HashTableOffset   uint32
+		BlockTableOffset  uint32
+		HashTableEntries  uint32
+		BlockTableEntries uint32
+	}{
+		Magic:             archiveMagic,
+		HeaderSize:        headerSize,
+		ArchiveSize:       uint32(len(w.buf)),
+		SectorSizeShift:   writeSectorSizeShift,
+		HashTableOffset:   uint32(hashTableOffset),
+		BlockTableOffset:  uint32(blockTableOffset),
+		HashTableEntries:  uint32(len(hashTable)),
+		BlockTableEntries: uint32(len(w.blockTable)),
+	}
+	var header bytes.Buffer
+	err = binary.Write(&header, binary.LittleEndian, hdr)
+	if err != nil {
+		return nil, err
+	}
+	copy(w.buf, header.Bytes())
+	return w.buf, nil
+}
+
+// appendTable appends the table, encrypted using the key of the given name, to
+// buf.
+func appendTable(buf []byte, table interface{}, keyName string) []byte {
+	var b bytes.Buffer
+	// Writing a slice of fixed size structs to a bytes.Buffer can't fail.
+	binary.Write(&b, binary.LittleEndian, table)
+	data := b.Bytes()
+	encrypt(data, hashString(keyName, hashFileKey))
+	return append(buf, data...)
+}
@@ -0,0 +1,57 @@
+package mpq
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCreateArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	random := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(random)
+	fsys := fstest.MapFS{
+		"levels/l1data/l1.min": {Data: bytes.Repeat([]byte{1, 0, 2, 0, 0, 0}, 2000)},
+		"levels/l1data/l1.pal": {Data: make([]byte, 256*3)},
+		"ui_art/empty.pcx":     {Data: []byte{}},
+		// spans several sectors, none of which shrink when compressed.
+		"data/random.bin": {Data: random},
+	}
+	for _, compress := range []bool{false, true} {
+		archivePath := filepath.Join(dir, "test.mpq")
+		err = CreateArchive(archivePath, fsys, compress)
+		if err != nil {
+			t.Fatalf("compress=%v: %v", compress, err)
+		}
+		a, err := OpenArchive(archivePath)
+		if err != nil {
+			t.Fatalf("compress=%v: %v", compress, err)
+		}
+		for relPath, file := range fsys {
+			buf, err := a.ReadFile(relPath)
+			if err != nil {
+				t.Errorf("compress=%v: unable to read %q; %v", compress, relPath, err)
+				continue
+			}
+			if !bytes.Equal(buf, file.Data) {
+				t.Errorf("compress=%v: content mismatch of %q; expected %d bytes, got %d bytes", compress, relPath, len(file.Data), len(buf))
+			}
+		}
+		// The listfile holds the relative paths of the files.
+		listfile, err := a.ReadFile("(listfile)")
+		if err != nil {
+			t.Errorf("compress=%v: unable to read listfile; %v", compress, err)
+		} else if !bytes.Contains(listfile, []byte(`levels\l1data\l1.min`)) {
+			t.Errorf("compress=%v: %q missing from listfile %q", compress, `levels\l1data\l1.min`, listfile)
+		}
+		a.Close()
+	}
+}
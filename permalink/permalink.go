@@ -0,0 +1,93 @@
+// Package permalink implements stable URLs of views of assets and dungeon maps,
+// which encode the asset name, frame, palette, zoom and map coordinates of the
+// view, so that exact views may be shared.
+//
+// A permalink consists of the name of the asset, following Path, and the
+// state of the view as query parameters; parameters which hold their default
+// value are omitted, and the remaining ones are sorted by key. For instance:
+//
+//    /view/l1.cel?frame=3&pal=l1_2.pal&zoom=2
+//    /view/cathedral?col=40&row=32&zoom=4
+package permalink
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Path is the path prefix of permalinks, which is followed by the name of the
+// asset.
+const Path = "/view/"
+
+// A View describes the state of a view of an asset (e.g. "l1.cel") or a
+// dungeon map (e.g. "cathedral" or "l1.dun").
+type View struct {
+	// Asset is the name of the viewed asset.
+	Asset string
+	// Frame is the frame number of the viewed image.
+	Frame int
+	// Pal is the name of the palette (e.g. "l1_2.pal") used to decode the
+	// asset, or an empty string for its default palette.
+	Pal string
+	// Zoom is the zoom factor of the view; 0 is equivalent to 1.
+	Zoom int
+	// Col and Row of the cell of the dungeon map on which the view is centered.
+	Col, Row int
+}
+
+// URL returns the permalink of the view, relative to the root of the viewer.
+func (v View) URL() string {
+	query := make(url.Values)
+	set := func(key string, x int) {
+		if x != 0 {
+			query.Set(key, strconv.Itoa(x))
+		}
+	}
+	set("frame", v.Frame)
+	set("col", v.Col)
+	set("row", v.Row)
+	if v.Zoom > 1 {
+		query.Set("zoom", strconv.Itoa(v.Zoom))
+	}
+	if len(v.Pal) > 0 {
+		query.Set("pal", v.Pal)
+	}
+	u := url.URL{Path: Path + v.Asset, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// Parse parses the permalink of a view, either relative to the root of the
+// viewer or absolute (e.g. "http://localhost:8080/view/l1.cel?frame=3").
+// Unknown query parameters are ignored, so that permalinks remain valid as
+// views gain state.
+func Parse(rawURL string) (v View, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return View{}, err
+	}
+	if !strings.HasPrefix(u.Path, Path) {
+		return View{}, fmt.Errorf("permalink.Parse: invalid path %q; expected prefix %q.", u.Path, Path)
+	}
+	v.Asset = u.Path[len(Path):]
+	if len(v.Asset) == 0 || strings.Contains(v.Asset, "/") {
+		return View{}, fmt.Errorf("permalink.Parse: invalid asset name %q.", v.Asset)
+	}
+	query := u.Query()
+	for _, f := range []struct {
+		key string
+		x   *int
+	}{{"frame", &v.Frame}, {"zoom", &v.Zoom}, {"col", &v.Col}, {"row", &v.Row}} {
+		rawX := query.Get(f.key)
+		if len(rawX) == 0 {
+			continue
+		}
+		*f.x, err = strconv.Atoi(rawX)
+		if err != nil || *f.x < 0 {
+			return View{}, fmt.Errorf("permalink.Parse: invalid %s %q.", f.key, rawX)
+		}
+	}
+	v.Pal = query.Get("pal")
+	return v, nil
+}
@@ -0,0 +1,127 @@
+// Package quests implements the quest selection of single player games.
+//
+// Each single player game deactivates a subset of the quests, based on the
+// seed of the game. The remaining quests are available, each on the dungeon
+// level of the quest. Below is a description of the quest selection:
+//
+// Quest selection:
+//    1) Seed the random number generator with the seed of level 15.
+//    2) Deactivate either Poisoned Water Supply or The Curse of King Leoric.
+//    3) Deactivate one quest of each quest group.
+//
+// ref: InitQuests
+package quests
+
+import "github.com/mewrnd/blizzconv/rng"
+
+// Quest specifies a quest of the game.
+type Quest int
+
+// Quests, in the order used by the game.
+//
+// ref: questlist
+const (
+	Rock Quest = iota
+	Mushroom
+	Garbud
+	Zhar
+	Veil
+	Diablo
+	Butcher
+	LtBanner
+	Blind
+	Blood
+	Anvil
+	Warlord
+	SkelKing
+	PWater
+	SChamb
+	Betrayer
+)
+
+// QuestCount is the number of quests.
+const QuestCount = 16
+
+// Info contains information about a quest.
+type Info struct {
+	// The name of the quest.
+	Name string
+	// The dungeon level of the quest.
+	Level int
+}
+
+// Infos maps from quest to quest information.
+//
+// ref: questlist
+var Infos = [QuestCount]Info{
+	Rock:     {"The Magic Rock", 5},
+	Mushroom: {"Black Mushroom", 9},
+	Garbud:   {"Gharbad The Weak", 4},
+	Zhar:     {"Zhar the Mad", 8},
+	Veil:     {"Lachdanan", 14},
+	Diablo:   {"Diablo", 15},
+	Butcher:  {"The Butcher", 2},
+	LtBanner: {"Ogden's Sign", 4},
+	Blind:    {"Halls of the Blind", 7},
+	Blood:    {"Valor", 5},
+	Anvil:    {"Anvil of Fury", 10},
+	Warlord:  {"Warlord of Blood", 13},
+	SkelKing: {"The Curse of King Leoric", 3},
+	PWater:   {"Poisoned Water Supply", 2},
+	SChamb:   {"The Chamber of Bone", 6},
+	Betrayer: {"Archbishop Lazarus", 15},
+}
+
+func (quest Quest) String() string {
+	if quest < 0 || quest >= QuestCount {
+		return "unknown quest"
+	}
+	return Infos[quest].Name
+}
+
+// groups contains the quest groups, of which one quest each is deactivated.
+//
+// ref: QuestGroup1, QuestGroup2, QuestGroup3 and QuestGroup4
+var groups = [][]Quest{
+	{Butcher, LtBanner, Garbud},
+	{Blind, Rock, Blood},
+	{Mushroom, Zhar, Anvil},
+	{Veil, Warlord},
+}
+
+// Select returns the quests which are available in a single player game, based
+// on the seed of level 15 of the game.
+//
+// ref: InitQuests
+func Select(level15Seed int32) (quests []Quest) {
+	var active [QuestCount]bool
+	for i := range active {
+		active[i] = true
+	}
+	r := rng.New(level15Seed)
+	if r.Random(2) != 0 {
+		active[PWater] = false
+	} else {
+		active[SkelKing] = false
+	}
+	for _, group := range groups {
+		active[group[r.Random(len(group))]] = false
+	}
+	for quest, ok := range active {
+		if ok {
+			quests = append(quests, Quest(quest))
+		}
+	}
+	return quests
+}
+
+// LevelQuests returns the available quests of each dungeon level, as a map
+// from level to quests.
+func LevelQuests(quests []Quest) (levelQuests map[int][]Quest) {
+	levelQuests = make(map[int][]Quest)
+	for _, quest := range quests {
+		level := Infos[quest].Level
+		levelQuests[level] = append(levelQuests[level], quest)
+	}
+	return levelQuests
+}
@@ -0,0 +1,146 @@
+// Package quirks implements support for the differences between the versions
+// of the game (e.g. 1.00 and 1.02), as described by an ini file.
+//
+// Early retail versions store some files differently, or at other paths, than
+// the version described by the cel.ini, cl2.ini and mpq.ini files. Each version
+// is identified by the fingerprint of its extracted MPQ archive; the SHA-1
+// hashes of a few of its files. The image information and relative paths of
+// the identified version override those of the other ini files.
+//
+// Quirks ini format:
+//    // version section; selected if each fingerprint file has the given hash.
+//    [version]
+//    fingerprint=relPath:sha1,...
+//
+//    // override section of a file of the version.
+//    [version/name]
+//    path=relPath  // overrides mpq.ini.
+//    key=val       // overrides the image information of cel.ini and cl2.ini.
+package quirks
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mewbak/goini"
+	"github.com/mewrnd/blizzconv/images/imgconf"
+	"github.com/mewrnd/blizzconv/mpq"
+)
+
+// IniPath is the path to an ini file which describes the quirks of each
+// version. Quirks are disabled if IniPath is empty.
+var IniPath string
+
+var dict ini.Dict
+
+// Init loads the ini file which describes the quirks of each version.
+func Init() (err error) {
+	if len(IniPath) == 0 {
+		return nil
+	}
+	dict, err = ini.Load(IniPath)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Versions returns the versions of the ini file, sorted by name.
+func Versions() (versions []string) {
+	for section := range dict {
+		if section == "" || strings.Contains(section, "/") {
+			continue
+		}
+		versions = append(versions, section)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Detect returns the version whose fingerprint matches the extracted MPQ
+// archive, or an empty string if no version matches.
+//
+// Note: mpq.Init must be called before calling Detect.
+func Detect() (version string, err error) {
+	for _, version := range Versions() {
+		ok, err := match(version)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return version, nil
+		}
+	}
+	return "", nil
+}
+
+// match returns true if each file of the fingerprint of the version has the
+// given hash. Missing files do not match.
+func match(version string) (ok bool, err error) {
+	rawFingerprint, found := dict.GetString(version, "fingerprint")
+	if !found {
+		return false, fmt.Errorf("quirks.match: fingerprint not found for %q.", version)
+	}
+	for _, entry := range strings.Split(rawFingerprint, ",") {
+		entry = strings.TrimSpace(entry)
+		pos := strings.LastIndex(entry, ":")
+		if pos == -1 {
+			return false, fmt.Errorf("quirks.match: no delim ':' found for %q.", entry)
+		}
+		buf, err := mpq.ReadFile(entry[:pos])
+		if err != nil {
+			return false, nil
+		}
+		if fmt.Sprintf("%x", sha1.Sum(buf)) != strings.ToLower(entry[pos+1:]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Apply overrides the relative paths and image information of the files of the
+// given version.
+//
+// Note: mpq.Init and imgconf.Init must be called before calling Apply.
+func Apply(version string) {
+	prefix := version + "/"
+	for section, keys := range dict {
+		if !strings.HasPrefix(section, prefix) {
+			continue
+		}
+		name := section[len(prefix):]
+		for key, val := range keys {
+			if key == "path" {
+				mpq.SetRelPath(name, val)
+				continue
+			}
+			imgconf.Set(name, key, val)
+		}
+	}
+}
+
+// DetectAndApply detects the version of the extracted MPQ archive and applies
+// its quirks. It returns the detected version, or an empty string if no version
+// matches or quirks are disabled.
+//
+// Note: mpq.Init and imgconf.Init must be called before calling
+// DetectAndApply.
+func DetectAndApply() (version string, err error) {
+	if len(IniPath) == 0 {
+		return "", nil
+	}
+	err = Init()
+	if err != nil {
+		return "", err
+	}
+	version, err = Detect()
+	if err != nil {
+		return "", err
+	}
+	if len(version) > 0 {
+		Apply(version)
+	}
+	return version, nil
+}
@@ -0,0 +1,99 @@
+// Package rng implements the random number generator of the game.
+//
+// The game uses a linear congruential generator, whose state is a signed 32-bit
+// integer. Below is a description of how random numbers are generated:
+//
+// Random number generation:
+//    1) state = 0x015A4E35*state + 1 (overflows wrap around).
+//    2) x = abs(state)
+//       - abs(-2147483648) is -2147483648, as the game uses a 32-bit abs.
+//    3) For v < 0xFFFF, the random number is (x >> 16) % v; otherwise x % v.
+//       - The shift is arithmetic and the remainder keeps the sign of x, so
+//         the quirk of 2) yields a negative random number.
+//
+// ref: SetRndSeed, GetRndSeed and random
+package rng
+
+// Multiplier and increment of the linear congruential generator.
+const (
+	mult = 0x015A4E35
+	inc  = 1
+)
+
+// A Rand is a random number generator which reproduces the random numbers of
+// the game.
+type Rand struct {
+	// The state of the generator.
+	seed int32
+	// The number of times the generator has been advanced since it was seeded.
+	count int
+}
+
+// New returns a new random number generator seeded with the given seed.
+func New(seed int32) *Rand {
+	return &Rand{seed: seed}
+}
+
+// SetSeed seeds the generator and resets its count.
+//
+// ref: SetRndSeed
+func (r *Rand) SetSeed(seed int32) {
+	r.seed = seed
+	r.count = 0
+}
+
+// Seed returns the current state of the generator.
+func (r *Rand) Seed() int32 {
+	return r.seed
+}
+
+// Count returns the number of times the generator has been advanced since it
+// was seeded.
+//
+// ref: SeedCount
+func (r *Rand) Count() int {
+	return r.count
+}
+
+// Next advances the generator and returns the absolute value of its state.
+//
+// ref: GetRndSeed
+func (r *Rand) Next() int32 {
+	r.count++
+	r.seed = mult*r.seed + inc
+	if r.seed < 0 {
+		// The absolute value of math.MinInt32 is math.MinInt32, as in the game.
+		return -r.seed
+	}
+	return r.seed
+}
+
+// Random returns a random number in [0, v), or 0 without advancing the
+// generator if v <= 0. See the package description for the quirk which yields
+// negative random numbers.
+//
+// ref: random
+func (r *Rand) Random(v int) int {
+	if v <= 0 {
+		return 0
+	}
+	if v < 0xFFFF {
+		return int((r.Next() >> 16) % int32(v))
+	}
+	return int(r.Next() % int32(v))
+}
+
+// LevelCount is the number of dungeon levels, including the town.
+const LevelCount = 17
+
+// LevelSeeds returns the seed of each dungeon level, based on the seed of the
+// game.
+//
+// ref: NetInit
+func LevelSeeds(gameSeed int32) (seeds [LevelCount]int32) {
+	r := New(gameSeed)
+	for i := range seeds {
+		seeds[i] = r.Next()
+	}
+	return seeds
+}
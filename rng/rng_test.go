@@ -0,0 +1,67 @@
+package rng
+
+import "testing"
+
+func TestNext(t *testing.T) {
+	golden := []struct {
+		seed int32
+		want []int32
+	}{
+		{seed: 0, want: []int32{1, 22695478, 2138921681, 1427733316, 71484141}},
+		// The state after seed 1457187811 is math.MinInt32, whose absolute
+		// value is math.MinInt32 in the game.
+		{seed: 1457187811, want: []int32{-2147483648}},
+	}
+	for _, g := range golden {
+		r := New(g.seed)
+		for i, want := range g.want {
+			if got := r.Next(); got != want {
+				t.Errorf("seed %d: output %d mismatch; expected %d, got %d", g.seed, i, want, got)
+			}
+		}
+		if r.Count() != len(g.want) {
+			t.Errorf("seed %d: count mismatch; expected %d, got %d", g.seed, len(g.want), r.Count())
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	golden := []struct {
+		seed int32
+		v    int
+		want []int
+	}{
+		// The state is shifted by 16 bits for v < 0xFFFF; 1>>16, 22695478>>16
+		// and 2138921681>>16.
+		{seed: 0, v: 100, want: []int{0, 46, 37}},
+		// but not for v >= 0xFFFF; 1 and 22695478.
+		{seed: 0, v: 0xFFFF, want: []int{1, 20368}},
+		// The absolute value of math.MinInt32 yields negative random numbers.
+		{seed: 1457187811, v: 100, want: []int{-68}},
+		{seed: 1457187811, v: 100000, want: []int{-83648}},
+	}
+	for _, g := range golden {
+		r := New(g.seed)
+		for i, want := range g.want {
+			if got := r.Random(g.v); got != want {
+				t.Errorf("seed %d, v %d: output %d mismatch; expected %d, got %d", g.seed, g.v, i, want, got)
+			}
+		}
+	}
+	// v <= 0 doesn't advance the generator.
+	r := New(0)
+	if got := r.Random(0); got != 0 || r.Count() != 0 {
+		t.Errorf("v 0: expected 0 without advancing, got %d after %d advances", got, r.Count())
+	}
+}
+
+func TestLevelSeeds(t *testing.T) {
+	want := [LevelCount]int32{
+		1394615462, 1993463203, 1188488384, 1336925121, 389500150, 1402871057,
+		980340092, 1792781651, 1350052910, 58513019, 1536171656, 569623079,
+		1252715502, 1760648263, 66339660, 852170427, 498511542,
+	}
+	if got := LevelSeeds(123456789); got != want {
+		t.Errorf("level seeds mismatch of game seed 123456789; expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,146 @@
+// Package shrines provides information about the shrines of the game, such as
+// their names and on which dungeon levels they may appear.
+package shrines
+
+// Shrine specifies a shrine type.
+type Shrine int
+
+// Shrine types, in the order used by the game.
+//
+// ref: shrinestrs
+const (
+	Mysterious Shrine = iota
+	Hidden
+	Gloomy
+	Weird
+	Magical
+	Stone
+	Religious
+	Enchanted
+	Thaumaturgic
+	Fascinating
+	Cryptic
+	Magical2
+	Eldritch
+	Eerie
+	Divine
+	Holy
+	Sacred
+	Spiritual
+	Spooky
+	Abandoned
+	Creepy
+	Quiet
+	Secluded
+	Ornate
+	Glimmering
+	Tainted
+)
+
+// ShrineCount is the number of shrine types.
+const ShrineCount = 26
+
+// Availability specifies the game modes in which a shrine may appear.
+type Availability int
+
+// Shrine availabilities.
+//
+// ref: shrineavail
+const (
+	// Any shrines appear in both single player and multiplayer games.
+	Any Availability = iota
+	// Single shrines only appear in single player games.
+	Single
+	// Multi shrines only appear in multiplayer games.
+	Multi
+)
+
+func (avail Availability) String() string {
+	switch avail {
+	case Any:
+		return "any"
+	case Single:
+		return "single player"
+	case Multi:
+		return "multiplayer"
+	}
+	return "unknown availability"
+}
+
+// Info contains information about a shrine type.
+type Info struct {
+	// The name of the shrine, as displayed by the game.
+	Name string
+	// The first and last dungeon level on which the shrine may appear.
+	MinLevel, MaxLevel int
+	// The game modes in which the shrine may appear.
+	Avail Availability
+}
+
+// Infos maps from shrine type to shrine information. Each column is transcribed
+// from the corresponding table of objects.cpp (version 1.09 of the game, as
+// reconstructed by devilution); shrinestrs (Name), shrinemin (MinLevel),
+// shrinemax (MaxLevel) and shrineavail (Avail). Every shrine may appear from
+// dungeon level 1 and up to level 16, except for Enchanted shrines which don't
+// appear below level 8.
+//
+// ref: shrinestrs, shrinemin, shrinemax and shrineavail (objects.cpp)
+var Infos = [ShrineCount]Info{
+	Mysterious:   {"Mysterious", 1, 16, Any},
+	Hidden:       {"Hidden", 1, 16, Any},
+	Gloomy:       {"Gloomy", 1, 16, Single},
+	Weird:        {"Weird", 1, 16, Single},
+	Magical:      {"Magical", 1, 16, Any},
+	Stone:        {"Stone", 1, 16, Any},
+	Religious:    {"Religious", 1, 16, Any},
+	Enchanted:    {"Enchanted", 1, 8, Any},
+	Thaumaturgic: {"Thaumaturgic", 1, 16, Single},
+	Fascinating:  {"Fascinating", 1, 16, Any},
+	Cryptic:      {"Cryptic", 1, 16, Any},
+	Magical2:     {"Magical", 1, 16, Any},
+	Eldritch:     {"Eldritch", 1, 16, Any},
+	Eerie:        {"Eerie", 1, 16, Any},
+	Divine:       {"Divine", 1, 16, Any},
+	Holy:         {"Holy", 1, 16, Any},
+	Sacred:       {"Sacred", 1, 16, Any},
+	Spiritual:    {"Spiritual", 1, 16, Any},
+	Spooky:       {"Spooky", 1, 16, Multi},
+	Abandoned:    {"Abandoned", 1, 16, Any},
+	Creepy:       {"Creepy", 1, 16, Any},
+	Quiet:        {"Quiet", 1, 16, Any},
+	Secluded:     {"Secluded", 1, 16, Any},
+	Ornate:       {"Ornate", 1, 16, Any},
+	Glimmering:   {"Glimmering", 1, 16, Any},
+	Tainted:      {"Tainted", 1, 16, Multi},
+}
+
+func (shrine Shrine) String() string {
+	if shrine < 0 || shrine >= ShrineCount {
+		return "unknown shrine"
+	}
+	return Infos[shrine].Name + " Shrine"
+}
+
+// Available returns the shrine types which may appear on the given dungeon
+// level, in single player or multiplayer games.
+//
+// ref: AddShrine
+func Available(level int, multiplayer bool) (shrines []Shrine) {
+	for i, info := range Infos {
+		if level < info.MinLevel || level > info.MaxLevel {
+			continue
+		}
+		switch info.Avail {
+		case Single:
+			if multiplayer {
+				continue
+			}
+		case Multi:
+			if !multiplayer {
+				continue
+			}
+		}
+		shrines = append(shrines, Shrine(i))
+	}
+	return shrines
+}
@@ -0,0 +1,38 @@
+package shrines
+
+import "testing"
+
+func TestAvailable(t *testing.T) {
+	golden := []struct {
+		level       int
+		multiplayer bool
+		// included and excluded shrine types.
+		in, out []Shrine
+	}{
+		{level: 1, multiplayer: false, in: []Shrine{Gloomy, Weird, Thaumaturgic, Enchanted}, out: []Shrine{Spooky, Tainted}},
+		{level: 1, multiplayer: true, in: []Shrine{Spooky, Tainted, Enchanted}, out: []Shrine{Gloomy, Weird, Thaumaturgic}},
+		// Enchanted shrines don't appear below dungeon level 8.
+		{level: 8, multiplayer: false, in: []Shrine{Enchanted}},
+		{level: 9, multiplayer: false, in: []Shrine{Mysterious}, out: []Shrine{Enchanted}},
+		{level: 16, multiplayer: true, in: []Shrine{Mysterious}, out: []Shrine{Enchanted}},
+		// No shrines appear in town or below the last dungeon level.
+		{level: 0, multiplayer: false, out: []Shrine{Mysterious}},
+		{level: 17, multiplayer: false, out: []Shrine{Mysterious}},
+	}
+	for _, g := range golden {
+		available := make(map[Shrine]bool)
+		for _, shrine := range Available(g.level, g.multiplayer) {
+			available[shrine] = true
+		}
+		for _, shrine := range g.in {
+			if !available[shrine] {
+				t.Errorf("level %d (multiplayer %v): expected %v to be available", g.level, g.multiplayer, shrine)
+			}
+		}
+		for _, shrine := range g.out {
+			if available[shrine] {
+				t.Errorf("level %d (multiplayer %v): expected %v to be unavailable", g.level, g.multiplayer, shrine)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package sounds implements functions for naming the sounds of the game by the
+// event, monster or character which uses them.
+//
+// The sound files of the game are named after the speaker and a line number
+// (e.g. "bsmith01.wav") or, for monsters, after the monster graphics, the
+// event and a variation (e.g. "acida1.wav"). Below are a few examples of the
+// names used instead:
+//
+//	sfx/towners/bsmith01.wav    -> towners/griswold_01.wav
+//	monsters/acid/acida1.wav    -> monsters/acid_attack_1.wav
+//	sfx/monsters/garbud01.wav   -> quest_monsters/gharbad_01.wav
+//	sfx/warrior/wario14b.wav    -> warrior/warrior_14b.wav
+package sounds
+
+import (
+	"path"
+	"strings"
+)
+
+// speakers maps from the prefix of a sound file to the name of the speaker.
+//
+// ref: sgSFX
+var speakers = map[string]string{
+	// sfx/towners/
+	"bmaid":   "gillian",
+	"bsmith":  "griswold",
+	"cow":     "cow",
+	"deadguy": "wounded_townsman",
+	"drunk":   "farnham",
+	"healer":  "pepin",
+	"pegboy":  "wirt",
+	"priest":  "priest",
+	"storyt":  "cain",
+	"tavown":  "ogden",
+	"witch":   "adria",
+	"wound":   "wounded_townsman",
+	// sfx/monsters/
+	"butcher": "butcher",
+	"diablod": "diablo_death",
+	"garbud":  "gharbad",
+	"izual":   "izual",
+	"lach":    "lachdanan",
+	"laz":     "lazarus",
+	"sking":   "skeleton_king",
+	"snot":    "snotspill",
+	"warlrd":  "warlord",
+	"wlock":   "warlock",
+	"zhar":    "zhar",
+	// sfx/narrator/
+	"nar": "narrator",
+	// sfx/warrior/, sfx/rogue/ and sfx/sorceror/
+	"wario": "warrior",
+	"rogue": "rogue",
+	"mage":  "sorceror",
+}
+
+// speakerDirs maps from the directory of speech sound files to the directory
+// of the named sounds.
+var speakerDirs = map[string]string{
+	"sfx/towners/":  "towners/",
+	"sfx/monsters/": "quest_monsters/",
+	"sfx/narrator/": "narrator/",
+	"sfx/warrior/":  "warrior/",
+	"sfx/rogue/":    "rogue/",
+	"sfx/sorceror/": "sorceror/",
+}
+
+// monsterEvents maps from the event character of a monster sound to the name
+// of the event.
+//
+// ref: MonstSndChar
+var monsterEvents = map[byte]string{
+	'a': "attack",
+	'h': "hit",
+	'd': "death",
+	's': "special",
+}
+
+// Name returns the relative path of the sound named by the event, monster or
+// character which uses it, based on the relative path of a sound file in the
+// MPQ archive. Sounds which are not associated with a specific speaker or
+// monster keep their name, without the leading "sfx/".
+func Name(relPath string) string {
+	dir, file := path.Split(relPath)
+	base := strings.TrimSuffix(file, path.Ext(file))
+	if strings.HasPrefix(dir, "monsters/") {
+		// e.g. "acida1", where 'a' is the event and "1" the variation. The
+		// monster is named after its directory, as the sound files of some
+		// monsters use abbreviated names (e.g. "phalla1").
+		if len(base) >= 2 {
+			event, ok := monsterEvents[base[len(base)-2]]
+			if ok {
+				mon := path.Base(dir)
+				return "monsters/" + mon + "_" + event + "_" + base[len(base)-1:] + ".wav"
+			}
+		}
+		return "monsters/" + file
+	}
+	if newDir, ok := speakerDirs[dir]; ok {
+		for i := len(base); i > 0; i-- {
+			speaker, ok := speakers[base[:i]]
+			if !ok {
+				continue
+			}
+			line := base[i:]
+			if line == "" {
+				return newDir + speaker + ".wav"
+			}
+			return newDir + speaker + "_" + line + ".wav"
+		}
+		return newDir + file
+	}
+	return strings.TrimPrefix(relPath, "sfx/")
+}